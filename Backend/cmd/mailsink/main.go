@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/mail"
+)
+
+// mailsink is a local SMTP stand-in. It prints every email the API sends instead of
+// delivering it. Run it and start the API with SMTP_HOST=127.0.0.1 SMTP_PORT=1025.
+func main() {
+	addr := flag.String("addr", "127.0.0.1:1025", "address to accept SMTP connections on")
+	flag.Parse()
+
+	server, err := mail.StartFakeServer(*addr, func(msg mail.ReceivedMessage) {
+		fmt.Println(strings.Repeat("=", 72))
+		fmt.Printf("From:    %s\n", msg.From)
+		fmt.Printf("To:      %s\n", strings.Join(msg.To, ", "))
+		fmt.Printf("Subject: %s\n\n", msg.Subject)
+		fmt.Println(msg.Text)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer server.Close()
+
+	fmt.Printf("Mail sink listening on %s\n", server.Addr())
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+}
@@ -46,6 +46,11 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to initialize server")
 	}
 
+	// Start background jobs
+	if err := srv.StartScheduler(context.Background()); err != nil {
+		log.Fatal().Err(err).Msg("Failed to start job scheduler")
+	}
+
 	// Create HTTP server
 	httpServer := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Port),
@@ -12,6 +12,13 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	// refreshCookiePath sends the refresh token cookie to the refresh and logout endpoints only
+	refreshCookiePath = "/api/v1/auth"
+	// legacyRefreshCookiePath is where refresh tokens were kept before logout needed them
+	legacyRefreshCookiePath = "/api/v1/auth/refresh"
+)
+
 type Handler struct {
 	service *Service
 }
@@ -25,8 +32,16 @@ func NewHandler(service *Service) *Handler {
 // RegisterRoutes registers authentication routes
 func (h *Handler) RegisterRoutes(r chi.Router) {
 	r.Post("/login", h.Login)
+	r.Post("/login/2fa", h.LoginTwoFactor)
+	r.Post("/login/2fa/setup", h.LoginTwoFactorSetup)
 	r.Post("/refresh", h.RefreshToken)
 	r.Post("/logout", h.Logout)
+	r.With(h.service.PasswordChangeMiddleware()).Post("/logout-all", h.LogoutAll)
+
+	// Password reset and change
+	r.Post("/password/forgot", h.ForgotPassword)
+	r.Post("/password/reset", h.ResetPassword)
+	r.With(h.service.PasswordChangeMiddleware()).Post("/password/change", h.ChangePassword)
 
 	// Google OAuth routes
 	r.Get("/google", h.GoogleLogin)
@@ -52,7 +67,7 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Authenticate user
-	response, err := h.service.Login(r.Context(), req)
+	response, err := h.service.Login(r.Context(), req, clientInfo(w, r))
 	if err != nil {
 		log.Warn().Err(err).Str("email", req.Email).Msg("Login failed")
 
@@ -60,23 +75,28 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 			h.writeErrorResponse(w, http.StatusUnauthorized, "Invalid email or password")
 		} else if errors.Is(err, ErrUserInactive) {
 			h.writeErrorResponse(w, http.StatusForbidden, "User account is inactive")
+		} else if errors.Is(err, ErrIPNotAllowed) {
+			h.writeErrorResponse(w, http.StatusForbidden, "Sign-in from this IP address is not allowed")
 		} else {
 			h.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
 		}
 		return
 	}
 
-	// Set refresh token as HttpOnly cookie
-	cookie := &http.Cookie{
-		Name:     "refresh_token",
-		Value:    response.RefreshToken,
-		Path:     "/api/v1/auth/refresh",
-		HttpOnly: true,
-		Secure:   true, // Always true for SameSite=None
-		SameSite: http.SameSiteNoneMode,
-		Expires:  time.Now().Add(7 * 24 * time.Hour),
+	if response.ChallengeToken != "" {
+		log.Info().Str("email", req.Email).Msg("Password accepted, waiting for second factor")
+		h.writeChallengeResponse(w, response)
+		return
 	}
-	http.SetCookie(w, cookie)
+
+	h.writeLoginResponse(w, response, nil)
+}
+
+// writeLoginResponse sets the refresh token cookie of a signed-in user and returns their
+// access token. recoveryCodes are included when the login enrolled two-factor authentication.
+func (h *Handler) writeLoginResponse(w http.ResponseWriter, response *LoginResponse, recoveryCodes []string) {
+	// Set refresh token as HttpOnly cookie
+	setRefreshCookie(w, response.RefreshToken, response.RefreshExpiresAt)
 
 	tenantIDStr := ""
 	if response.User.TenantID != nil {
@@ -97,10 +117,23 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		"expires_at": response.ExpiresAt,
 		"user":       response.User,
 	}
+	if recoveryCodes != nil {
+		loginResp["recovery_codes"] = recoveryCodes
+	}
 
 	h.writeJSONResponse(w, http.StatusOK, loginResp)
 }
 
+// writeChallengeResponse asks the client for the second login step
+func (h *Handler) writeChallengeResponse(w http.ResponseWriter, challenge *LoginResponse) {
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"two_factor_required":       challenge.TwoFactorRequired,
+		"two_factor_setup_required": challenge.TwoFactorSetupRequired,
+		"challenge_token":           challenge.ChallengeToken,
+		"expires_at":                challenge.ExpiresAt,
+	})
+}
+
 // RefreshToken handles token refresh
 func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	// Try to get refresh token from cookie
@@ -121,24 +154,20 @@ func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Refresh token
-	response, err := h.service.RefreshToken(r.Context(), refreshToken)
+	response, err := h.service.RefreshToken(r.Context(), refreshToken, clientInfo(w, r))
 	if err != nil {
 		log.Warn().Err(err).Msg("Token refresh failed")
 
-		// Clear cookie if invalid
-		http.SetCookie(w, &http.Cookie{
-			Name:     "refresh_token",
-			Value:    "",
-			Path:     "/api/v1/auth/refresh",
-			HttpOnly: true,
-			Secure:   true,
-			SameSite: http.SameSiteStrictMode,
-			MaxAge:   -1,
-		})
+		// Clear cookie if invalid. A token refused for the address it came from stays valid.
+		if err != ErrIPNotAllowed {
+			clearRefreshCookie(w)
+		}
 
 		switch err {
-		case ErrInvalidToken, ErrTokenExpired:
+		case ErrInvalidToken, ErrTokenExpired, ErrTokenReused:
 			h.writeErrorResponse(w, http.StatusUnauthorized, "Invalid or expired refresh token")
+		case ErrIPNotAllowed:
+			h.writeErrorResponse(w, http.StatusForbidden, "Sign-in from this IP address is not allowed")
 		case ErrUserInactive:
 			h.writeErrorResponse(w, http.StatusForbidden, "User account is inactive")
 		default:
@@ -148,15 +177,7 @@ func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Set new refresh token as HttpOnly cookie (rotation)
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    response.RefreshToken,
-		Path:     "/api/v1/auth/refresh",
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteNoneMode,
-		Expires:  time.Now().Add(7 * 24 * time.Hour),
-	})
+	setRefreshCookie(w, response.RefreshToken, response.RefreshExpiresAt)
 
 	tenantIDStr := ""
 	if response.User.TenantID != nil {
@@ -178,8 +199,46 @@ func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	h.writeJSONResponse(w, http.StatusOK, refreshResp)
 }
 
-// Logout handles user logout
+// Logout handles user logout. The session's refresh token family is revoked, so its refresh
+// token cannot be used again.
 func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie("refresh_token"); err == nil && cookie.Value != "" {
+		if err := h.service.RevokeRefreshToken(r.Context(), cookie.Value); err != nil {
+			log.Error().Err(err).Msg("Failed to revoke refresh token on logout")
+		}
+	}
+
+	h.clearSessionCookies(w)
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]string{
+		"message": "Logged out successfully",
+	})
+}
+
+// LogoutAll signs the current user out of every session
+func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	claims, ok := GetClaimsFromContext(r.Context())
+	if !ok {
+		h.writeErrorResponse(w, http.StatusUnauthorized, "Missing authentication")
+		return
+	}
+
+	if err := RevokeUserRefreshTokens(r.Context(), h.service.db, claims.UserID, RevokeReasonLogoutAll); err != nil {
+		log.Error().Err(err).Str("user_id", claims.UserID).Msg("Failed to log out all sessions")
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.clearSessionCookies(w)
+
+	log.Info().Str("user_id", claims.UserID).Msg("User logged out of all sessions")
+	h.writeJSONResponse(w, http.StatusOK, map[string]string{
+		"message": "Logged out of all sessions",
+	})
+}
+
+// clearSessionCookies removes the access and refresh token cookies
+func (h *Handler) clearSessionCookies(w http.ResponseWriter) {
 	// Clear the access token cookie
 	http.SetCookie(w, &http.Cookie{
 		Name:     "access_token",
@@ -192,19 +251,40 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 	})
 
 	// Clear the refresh token cookie
+	clearRefreshCookie(w)
+}
+
+// setRefreshCookie stores a refresh token in an HttpOnly cookie
+func setRefreshCookie(w http.ResponseWriter, token string, expiresAt time.Time) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "refresh_token",
+		Value:    token,
+		Path:     refreshCookiePath,
+		HttpOnly: true,
+		Secure:   true, // Always true for SameSite=None
+		SameSite: http.SameSiteNoneMode,
+		Expires:  expiresAt,
+	})
+	// A cookie left at the old path would be sent first and shadow the new one
+	clearCookie(w, "refresh_token", legacyRefreshCookiePath)
+}
+
+// clearRefreshCookie removes the refresh token cookie
+func clearRefreshCookie(w http.ResponseWriter) {
+	clearCookie(w, "refresh_token", refreshCookiePath)
+	clearCookie(w, "refresh_token", legacyRefreshCookiePath)
+}
+
+func clearCookie(w http.ResponseWriter, name, path string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
 		Value:    "",
-		Path:     "/api/v1/auth/refresh",
+		Path:     path,
 		HttpOnly: true,
 		Secure:   true,
-		SameSite: http.SameSiteStrictMode,
+		SameSite: http.SameSiteNoneMode,
 		MaxAge:   -1,
 	})
-
-	h.writeJSONResponse(w, http.StatusOK, map[string]string{
-		"message": "Logged out successfully",
-	})
 }
 
 // Profile returns the current user's profile
@@ -319,36 +399,32 @@ func (h *Handler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate JWT token
-	tenantIDStr := ""
-	if user.TenantID != nil {
-		tenantIDStr = *user.TenantID
-	}
-	jwtToken, err := h.service.GenerateAccessToken(user.ID, tenantIDStr, user.Email, user.Role)
+	challenge, err := h.service.TwoFactorChallenge(r.Context(), user)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to generate JWT token")
-		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to generate access token")
+		log.Error().Err(err).Msg("Failed to check two-factor authentication")
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to process user login")
+		return
+	}
+	if challenge != nil {
+		h.writeChallengeResponse(w, challenge)
 		return
 	}
 
-	// Generate refresh token
-	refreshToken, _, err := h.service.GenerateRefreshToken(user)
+	// Generate JWT and refresh tokens for a new session
+	response, err := h.service.issueTokens(r.Context(), user, clientInfo(w, r))
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to generate refresh token")
-		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to generate refresh token")
+		if errors.Is(err, ErrIPNotAllowed) {
+			log.Warn().Str("user_id", user.ID).Msg("Google sign-in from a disallowed IP address")
+			h.writeErrorResponse(w, http.StatusForbidden, "Sign-in from this IP address is not allowed")
+			return
+		}
+		log.Error().Err(err).Msg("Failed to generate tokens")
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to generate access token")
 		return
 	}
 
 	// Set refresh token as HttpOnly cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    refreshToken,
-		Path:     "/api/v1/auth/refresh",
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteNoneMode,
-		Expires:  time.Now().Add(7 * 24 * time.Hour),
-	})
+	setRefreshCookie(w, response.RefreshToken, response.RefreshExpiresAt)
 
 	log.Info().
 		Str("user_id", user.ID).
@@ -357,7 +433,7 @@ func (h *Handler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 
 	// Return JSON response with token and user data
 	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
-		"token": jwtToken,
+		"token": response.Token,
 		"user": map[string]interface{}{
 			"id":        user.ID,
 			"email":     user.Email,
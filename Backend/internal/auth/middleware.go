@@ -16,8 +16,21 @@ const (
 	ClaimsContextKey contextKey = "claims"
 )
 
-// Middleware creates an authentication middleware
+// Middleware creates an authentication middleware. Requests of inactive or deleted users and
+// organizations, and from addresses outside the user's IP restrictions, are refused with 403.
+// Users who must change their password, including those whose password expired, are refused
+// with 428 Precondition Required until they do.
 func (s *Service) Middleware() func(http.Handler) http.Handler {
+	return s.authenticate(false)
+}
+
+// PasswordChangeMiddleware authenticates like Middleware but also admits users who must
+// change their password, for the endpoints they need to do it
+func (s *Service) PasswordChangeMiddleware() func(http.Handler) http.Handler {
+	return s.authenticate(true)
+}
+
+func (s *Service) authenticate(allowPasswordChange bool) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Get token from cookie first, then fall back to Authorization header
@@ -68,8 +81,18 @@ func (s *Service) Middleware() func(http.Handler) http.Handler {
 				return
 			}
 
-			// Check if user and tenant are still active
-			if err := s.CheckUserStatus(r.Context(), claims.UserID); err != nil {
+			// The user, their organization, the session and the security policy may all have
+			// changed since the token was issued
+			access, err := s.loadRequestAccess(r.Context(), claims.UserID, claims.SessionID)
+			if err != nil && err != ErrUserNotFound {
+				log.Error().Err(err).Str("user_id", claims.UserID).Msg("Failed to check user access")
+				s.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			if err == nil {
+				err = access.status.err()
+			}
+			if err != nil {
 				log.Warn().Err(err).Str("user_id", claims.UserID).Msg("User or tenant inactive/deleted")
 				// Using 403 Forbidden to distinguish from invalid token (401)
 				// The frontend will intercept this and clear the session
@@ -77,6 +100,30 @@ func (s *Service) Middleware() func(http.Handler) http.Handler {
 				return
 			}
 
+			// Tokens issued before sessions were recorded carry no session
+			if !access.sessionActive {
+				log.Warn().Err(ErrSessionRevoked).Str("user_id", claims.UserID).Msg("Session check failed")
+				s.writeErrorResponse(w, http.StatusUnauthorized, "Session has been signed out")
+				return
+			}
+			if access.sessionStale {
+				if err := s.touchSession(r.Context(), claims.SessionID); err != nil {
+					log.Warn().Err(err).Str("user_id", claims.UserID).Msg("Failed to record session activity")
+				}
+			}
+
+			policy := access.policy
+			if err := policy.checkIP(requestIP(r)); err != nil {
+				log.Warn().Str("user_id", claims.UserID).Str("ip", requestIP(r)).Msg("Request from a disallowed IP address")
+				s.writeErrorResponse(w, http.StatusForbidden, "Sign-in from this IP address is not allowed")
+				return
+			}
+
+			if (claims.PasswordChangeRequired || policy.passwordExpired()) && !allowPasswordChange {
+				s.writeErrorResponse(w, http.StatusPreconditionRequired, "Password change required")
+				return
+			}
+
 			// Add claims to request context
 			ctx := context.WithValue(r.Context(), ClaimsContextKey, claims)
 			ctx = context.WithValue(ctx, UserContextKey, claims.UserID)
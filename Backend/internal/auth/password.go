@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired password reset link")
+	ErrIncorrectPassword = errors.New("current password is incorrect")
+	ErrPasswordUnchanged = errors.New("new password must differ from the current password")
+	ErrWeakPassword      = errors.New("password must be at least 8 characters and contain a letter and a number")
+)
+
+const (
+	// passwordResetTTL is how long a reset link works
+	passwordResetTTL = time.Hour
+	// passwordResetHourlyLimit caps the reset emails a user receives per hour
+	passwordResetHourlyLimit = 5
+
+	minPasswordLength = 8
+	maxPasswordLength = 128
+)
+
+// validatePassword checks a new password against the password rules
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return ErrWeakPassword
+	}
+	var letter, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	if !letter || !digit {
+		return ErrWeakPassword
+	}
+	return nil
+}
+
+// RequestPasswordReset emails a reset link to the user with an email address. Unknown and
+// inactive addresses are ignored without an error, so the response does not tell which
+// addresses have accounts. Requesting a new link invalidates the previous ones.
+func (s *Service) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.getUserByEmail(ctx, email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if !user.IsActive {
+		return nil
+	}
+	if s.notifier == nil {
+		return errors.New("password reset emails are not configured")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var recent int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM password_reset_tokens
+		WHERE user_id = $1 AND created_at > NOW() - INTERVAL '1 hour'`, user.ID).Scan(&recent)
+	if err != nil {
+		return fmt.Errorf("failed to count password resets: %w", err)
+	}
+	if recent >= passwordResetHourlyLimit {
+		log.Warn().Str("user_id", user.ID).Msg("Password reset limit reached, no email sent")
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL`, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to invalidate password resets: %w", err)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO password_reset_tokens (id, user_id, tenant_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())`,
+		uuid.New(), user.ID, user.TenantID, hashToken(token), time.Now().Add(passwordResetTTL))
+	if err != nil {
+		return fmt.Errorf("failed to store password reset: %w", err)
+	}
+
+	if err := s.notifier.QueuePasswordResetEmail(ctx, tx, user, token, passwordResetTTL); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit password reset: %w", err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password with the token of a reset link. The link stops working
+// and the user is signed out of every session.
+func (s *Service) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID string
+	var expiresAt time.Time
+	var usedAt *time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT user_id, expires_at, used_at
+		FROM password_reset_tokens
+		WHERE token_hash = $1
+		FOR UPDATE`, hashToken(token)).Scan(&userID, &expiresAt, &usedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrInvalidResetToken
+		}
+		return fmt.Errorf("failed to get password reset: %w", err)
+	}
+	if usedAt != nil || !expiresAt.After(time.Now()) {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.setPassword(ctx, tx, userID, newPassword); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to use password reset: %w", err)
+	}
+
+	if err := RevokeUserRefreshTokens(ctx, tx, userID, RevokeReasonPasswordReset); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit password reset: %w", err)
+	}
+	return nil
+}
+
+// ChangePassword replaces the password of a signed-in user and signs them out of their other
+// sessions. It returns a new access token for sessionID, since the current one may be limited
+// to changing the password.
+func (s *Service) ChangePassword(ctx context.Context, userID, sessionID, currentPassword, newPassword string) (string, time.Time, error) {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", time.Time{}, ErrUserNotFound
+		}
+		return "", time.Time{}, err
+	}
+
+	// Accounts that only sign in with Google have no password to change; they can set one
+	// through a reset link
+	if user.PasswordHash == "" {
+		return "", time.Time{}, ErrIncorrectPassword
+	}
+	valid, err := VerifyPassword(currentPassword, user.PasswordHash, s.pepperSecret)
+	if err != nil || !valid {
+		return "", time.Time{}, ErrIncorrectPassword
+	}
+	if newPassword == currentPassword {
+		return "", time.Time{}, ErrPasswordUnchanged
+	}
+	if err := validatePassword(newPassword); err != nil {
+		return "", time.Time{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.setPassword(ctx, tx, userID, newPassword); err != nil {
+		return "", time.Time{}, err
+	}
+
+	var keep *uuid.UUID
+	if id, err := uuid.Parse(sessionID); err == nil {
+		keep = &id
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW(), revoke_reason = $3
+		WHERE user_id = $1 AND revoked_at IS NULL AND ($2::UUID IS NULL OR family_id <> $2)`,
+		userID, keep, RevokeReasonPasswordChange)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to revoke other sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to commit password change: %w", err)
+	}
+
+	policy, err := s.getSecurityPolicy(ctx, userID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	user.MustChangePassword = false
+	return s.generateToken(user, sessionID, policy.limit(s.accessTokenTTL))
+}
+
+// setPassword stores a new password of a user, clears the must-change flag and records when
+// the password changed
+func (s *Service) setPassword(ctx context.Context, tx *sql.Tx, userID, password string) error {
+	hash, err := HashPassword(password, s.pepperSecret)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users SET password_hash = $2, must_change_password = FALSE, updated_at = NOW()
+		WHERE id = $1`, userID, hash)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO security_settings (user_id, tenant_id, last_password_change)
+		SELECT id, tenant_id, NOW() FROM users WHERE id = $1
+		ON CONFLICT (user_id) DO UPDATE
+		SET last_password_change = EXCLUDED.last_password_change, updated_at = CURRENT_TIMESTAMP`,
+		userID)
+	if err != nil {
+		return fmt.Errorf("failed to update security settings: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpiredPasswordResets deletes password reset tokens that expired more than a day ago
+func (s *Service) PurgeExpiredPasswordResets(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM password_reset_tokens WHERE expires_at < NOW() - INTERVAL '1 day'`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge password resets: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ForgotPassword handles POST /api/v1/auth/password/forgot
+// It answers the same whether or not the email has an account.
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req forgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Email is required")
+		return
+	}
+
+	if err := h.service.RequestPasswordReset(r.Context(), email); err != nil {
+		log.Error().Err(err).Msg("Failed to request password reset")
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]string{
+		"message": "If an account exists for this email, a password reset link has been sent",
+	})
+}
+
+// ResetPassword handles POST /api/v1/auth/password/reset
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Token == "" || req.NewPassword == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Token and new password are required")
+		return
+	}
+
+	if err := h.service.ResetPassword(r.Context(), req.Token, req.NewPassword); err != nil {
+		h.writePasswordError(w, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]string{
+		"message": "Password reset successfully, please sign in with your new password",
+	})
+}
+
+// ChangePassword handles POST /api/v1/auth/password/change
+// It returns a new access token, as the one used may only allow changing the password.
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	claims, ok := GetClaimsFromContext(r.Context())
+	if !ok {
+		h.writeErrorResponse(w, http.StatusUnauthorized, "Missing authentication")
+		return
+	}
+
+	var req changePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Current and new password are required")
+		return
+	}
+
+	token, expiresAt, err := h.service.ChangePassword(r.Context(), claims.UserID, claims.SessionID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		h.writePasswordError(w, err)
+		return
+	}
+
+	log.Info().Str("user_id", claims.UserID).Msg("Password changed")
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message":    "Password changed successfully",
+		"token":      token,
+		"expires_at": expiresAt,
+	})
+}
+
+// writePasswordError maps password errors to responses
+func (h *Handler) writePasswordError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrWeakPassword), errors.Is(err, ErrPasswordUnchanged),
+		errors.Is(err, ErrInvalidResetToken), errors.Is(err, ErrIncorrectPassword):
+		// Not 401: clients answer 401 by refreshing the session
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrUserNotFound):
+		h.writeErrorResponse(w, http.StatusNotFound, err.Error())
+	default:
+		log.Error().Err(err).Msg("Password request failed")
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrIPNotAllowed = errors.New("sign-in from this IP address is not allowed")
+
+// securityPolicy is the security policy that applies to a user: the defaults of their
+// organization, tightened by their own security settings. A user's settings cannot loosen
+// the organization's.
+type securityPolicy struct {
+	// ipLists are the IP restrictions of the organization and of the user that are set. An
+	// address must be allowed by every list.
+	ipLists            [][]*net.IPNet
+	sessionTimeout     time.Duration // zero means no limit beyond the token TTLs
+	passwordExpiryDays int           // zero means passwords do not expire
+	lastPasswordChange time.Time
+	hasPassword        bool // users who only sign in with Google have no password to expire
+}
+
+// ParseIPRestrictions parses a list of allowed addresses, each a CIDR range such as
+// 203.0.113.0/24 or a single IP address
+func ParseIPRestrictions(entries []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address or CIDR range: %s", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address or CIDR range: %s", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// IPAllowed reports whether ip is in one of networks. An empty list allows every address.
+func IPAllowed(networks []*net.IPNet, ip string) bool {
+	if len(networks) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// NetworksWithin reports whether every network of inner lies inside one of outer. An empty
+// outer list allows every network.
+func NetworksWithin(inner, outer []*net.IPNet) bool {
+	if len(outer) == 0 {
+		return true
+	}
+	for _, network := range inner {
+		innerOnes, innerBits := network.Mask.Size()
+		within := false
+		for _, o := range outer {
+			ones, bits := o.Mask.Size()
+			if bits == innerBits && ones <= innerOnes && o.Contains(network.IP) {
+				within = true
+				break
+			}
+		}
+		if !within {
+			return false
+		}
+	}
+	return true
+}
+
+// strictestLimit returns the smaller of two limits, where zero means no limit
+func strictestLimit(a, b int) int {
+	if a <= 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// securityPolicyColumns selects the policy settings of a user u, their security settings ss and
+// their tenant t, in the order policyRow scans them
+const securityPolicyColumns = `
+	COALESCE(ss.ip_restrictions, '{}'), COALESCE(t.default_ip_restrictions, '{}'),
+	COALESCE(ss.session_timeout, 0), COALESCE(t.default_session_timeout, 0),
+	COALESCE(ss.password_expiry_days, 0), COALESCE(t.default_password_expiry_days, 0),
+	COALESCE(ss.last_password_change, u.created_at),
+	COALESCE(u.password_hash, '') <> ''`
+
+// policyRow holds the scanned securityPolicyColumns
+type policyRow struct {
+	userIPs, orgIPs                                pq.StringArray
+	userTimeout, orgTimeout, userExpiry, orgExpiry int
+	lastPasswordChange                             time.Time
+	hasPassword                                    bool
+}
+
+func (p *policyRow) dest() []interface{} {
+	return []interface{}{&p.userIPs, &p.orgIPs, &p.userTimeout, &p.orgTimeout, &p.userExpiry, &p.orgExpiry,
+		&p.lastPasswordChange, &p.hasPassword}
+}
+
+// policy combines the organization's settings and the user's into the policy that applies
+func (p *policyRow) policy() *securityPolicy {
+	policy := &securityPolicy{lastPasswordChange: p.lastPasswordChange, hasPassword: p.hasPassword}
+	for _, list := range []pq.StringArray{p.orgIPs, p.userIPs} {
+		if len(list) > 0 {
+			policy.ipLists = append(policy.ipLists, parseStoredRestrictions(list))
+		}
+	}
+	policy.sessionTimeout = time.Duration(strictestLimit(p.userTimeout, p.orgTimeout)) * time.Second
+	policy.passwordExpiryDays = strictestLimit(p.userExpiry, p.orgExpiry)
+	return policy
+}
+
+// getSecurityPolicy loads the security policy of a user
+func (s *Service) getSecurityPolicy(ctx context.Context, userID string) (*securityPolicy, error) {
+	var row policyRow
+	err := s.db.QueryRowContext(ctx, `
+		SELECT `+securityPolicyColumns+`
+		FROM users u
+		LEFT JOIN security_settings ss ON ss.user_id = u.id
+		LEFT JOIN tenants t ON t.id = u.tenant_id
+		WHERE u.id = $1`, userID).Scan(row.dest()...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get security policy: %w", err)
+	}
+	return row.policy(), nil
+}
+
+// parseStoredRestrictions parses saved IP restrictions. Entries are validated when saved; one
+// that no longer parses is skipped, but the list still restricts: a broken entry must not open
+// sign-ins to every address.
+func parseStoredRestrictions(entries []string) []*net.IPNet {
+	networks := []*net.IPNet{}
+	for _, entry := range entries {
+		if parsed, err := ParseIPRestrictions([]string{entry}); err == nil {
+			networks = append(networks, parsed...)
+		}
+	}
+	return networks
+}
+
+// checkIP returns ErrIPNotAllowed when ip is outside the networks the policy allows
+func (p *securityPolicy) checkIP(ip string) error {
+	for _, networks := range p.ipLists {
+		if len(networks) == 0 || !IPAllowed(networks, ip) {
+			return ErrIPNotAllowed
+		}
+	}
+	return nil
+}
+
+// passwordExpired reports whether the user's password is older than the policy allows
+func (p *securityPolicy) passwordExpired() bool {
+	if !p.hasPassword || p.passwordExpiryDays <= 0 {
+		return false
+	}
+	return time.Since(p.lastPasswordChange) > time.Duration(p.passwordExpiryDays)*24*time.Hour
+}
+
+// limit shortens a token TTL to the session timeout of the policy
+func (p *securityPolicy) limit(ttl time.Duration) time.Duration {
+	if p.sessionTimeout > 0 && p.sessionTimeout < ttl {
+		return p.sessionTimeout
+	}
+	return ttl
+}
@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrTokenReused = errors.New("refresh token reuse detected")
+
+// Reasons recorded when refresh tokens are revoked
+const (
+	RevokeReasonLogout         = "logout"
+	RevokeReasonLogoutAll      = "logout_all"
+	RevokeReasonSessionRevoked = "session_revoked"
+	RevokeReasonPasswordReset  = "password_reset"
+	RevokeReasonPasswordChange = "password_changed"
+	RevokeReasonReuse          = "reuse_detected"
+	RevokeReasonUserInactive   = "user_deactivated"
+	RevokeReasonTenantBlocked  = "organization_blocked"
+)
+
+// execer runs statements on a database or inside a transaction
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// hashToken returns the stored form of a refresh or password reset token. Tokens are random,
+// so a plain hash is enough and lets a token be looked up by it.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// createRefreshToken stores a new refresh token of a family and returns it with its ID. Every
+// token of a family expires with the first one: the refresh TTL is an absolute timeout.
+func createRefreshToken(ctx context.Context, q execer, user *User, familyID uuid.UUID, expiresAt time.Time) (string, uuid.UUID, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", uuid.Nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	id := uuid.New()
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (id, family_id, user_id, tenant_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())`,
+		id, familyID, user.ID, user.TenantID, hashToken(token), expiresAt)
+	if err != nil {
+		return "", uuid.Nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return token, id, nil
+}
+
+// refreshedSession is the result of spending a refresh token
+type refreshedSession struct {
+	user         *User
+	sessionID    uuid.UUID
+	refreshToken string
+	expiresAt    time.Time
+	policy       *securityPolicy
+}
+
+// rotateRefreshToken spends a refresh token and returns the next token of its family. A token
+// that was already spent means it leaked, so its whole family is revoked.
+func (s *Service) rotateRefreshToken(ctx context.Context, refreshToken string, client ClientInfo) (*refreshedSession, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id, familyID uuid.UUID
+	var userID string
+	var expiresAt time.Time
+	var usedAt, revokedAt *time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, family_id, user_id, expires_at, used_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE`, hashToken(refreshToken)).Scan(&id, &familyID, &userID, &expiresAt, &usedAt, &revokedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	if revokedAt != nil {
+		return nil, ErrInvalidToken
+	}
+	if usedAt != nil {
+		if err := revokeRefreshFamily(ctx, tx, familyID, RevokeReasonReuse); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+		}
+		log.Warn().Str("user_id", userID).Str("family_id", familyID.String()).Msg("Refresh token reused, family revoked")
+		return nil, ErrTokenReused
+	}
+	if !expiresAt.After(time.Now()) {
+		return nil, ErrTokenExpired
+	}
+
+	// The user or their organization may have been blocked since the token was issued
+	user, err := s.GetUserByID(ctx, userID)
+	if err == nil {
+		err = s.CheckUserStatus(ctx, userID)
+	}
+	if err != nil {
+		if err := revokeRefreshFamily(ctx, tx, familyID, RevokeReasonUserInactive); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+		}
+		return nil, ErrUserInactive
+	}
+
+	// A refresh from a disallowed address is refused, but the session survives for when the
+	// user is back on an allowed network
+	policy, err := s.getSecurityPolicy(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := policy.checkIP(client.IPAddress); err != nil {
+		return nil, err
+	}
+
+	next, nextID, err := createRefreshToken(ctx, tx, user, familyID, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET used_at = NOW(), replaced_by = $2 WHERE id = $1`, id, nextID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	_, deviceTracking, err := s.getSessionSettings(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := touchSession(ctx, tx, familyID, client, deviceTracking); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit refresh token: %w", err)
+	}
+	return &refreshedSession{user: user, sessionID: familyID, refreshToken: next, expiresAt: expiresAt, policy: policy}, nil
+}
+
+// RevokeRefreshToken signs out the session of a refresh token by revoking its family
+func (s *Service) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW(), revoke_reason = $2
+		WHERE family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1)
+		  AND revoked_at IS NULL`,
+		hashToken(refreshToken), RevokeReasonLogout)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpiredRefreshTokens deletes refresh tokens, and the sessions they belong to, that
+// expired more than a day ago
+func (s *Service) PurgeExpiredRefreshTokens(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM refresh_tokens WHERE expires_at < NOW() - INTERVAL '1 day'`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge refresh tokens: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		DELETE FROM user_sessions WHERE expires_at < NOW() - INTERVAL '1 day'`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// RevokeUserRefreshTokens signs a user out of every session. Pass the transaction of the
+// change that requires it, such as deactivating the user.
+func RevokeUserRefreshTokens(ctx context.Context, q execer, userID, reason string) error {
+	_, err := q.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW(), revoke_reason = $2
+		WHERE user_id = $1 AND revoked_at IS NULL`, userID, reason)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// RevokeTenantRefreshTokens signs every user of an organization out of every session
+func RevokeTenantRefreshTokens(ctx context.Context, q execer, tenantID, reason string) error {
+	_, err := q.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW(), revoke_reason = $2
+		WHERE tenant_id = $1 AND revoked_at IS NULL`, tenantID, reason)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+func revokeRefreshFamily(ctx context.Context, q execer, familyID uuid.UUID, reason string) error {
+	_, err := q.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW(), revoke_reason = $2
+		WHERE family_id = $1 AND revoked_at IS NULL`, familyID, reason)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
@@ -27,24 +27,29 @@ type Claims struct {
 	Role         string `json:"role"`
 	DepartmentID string `json:"department_id,omitempty"`
 	TeamID       string `json:"team_id,omitempty"`
+	SessionID    string `json:"session_id,omitempty"`
+	// PasswordChangeRequired limits the token to changing the password
+	PasswordChangeRequired bool `json:"password_change_required,omitempty"`
 	jwt.RegisteredClaims
 }
 
 type User struct {
-	ID              string     `json:"id"`
-	TenantID        *string    `json:"tenant_id,omitempty"`
-	Email           string     `json:"email"`
-	PasswordHash    string     `json:"-"`
-	Role            string     `json:"role"`
-	FirstName       string     `json:"first_name"`
-	LastName        string     `json:"last_name"`
-	DepartmentID    *string    `json:"department_id,omitempty"`
-	TeamID          *string    `json:"team_id,omitempty"`
-	IsActive        bool       `json:"is_active"`
-	LastLoginAt     *time.Time `json:"last_login_at"`
-	EmailVerifiedAt *time.Time `json:"email_verified_at"`
-	CreatedAt       time.Time  `json:"created_at"`
-	UpdatedAt       time.Time  `json:"updated_at"`
+	ID           string  `json:"id"`
+	TenantID     *string `json:"tenant_id,omitempty"`
+	Email        string  `json:"email"`
+	PasswordHash string  `json:"-"`
+	Role         string  `json:"role"`
+	FirstName    string  `json:"first_name"`
+	LastName     string  `json:"last_name"`
+	DepartmentID *string `json:"department_id,omitempty"`
+	TeamID       *string `json:"team_id,omitempty"`
+	IsActive     bool    `json:"is_active"`
+	// MustChangePassword is set on accounts created with a temporary password
+	MustChangePassword bool       `json:"must_change_password"`
+	LastLoginAt        *time.Time `json:"last_login_at"`
+	EmailVerifiedAt    *time.Time `json:"email_verified_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
 }
 
 type LoginRequest struct {
@@ -52,33 +57,59 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required,min=6"`
 }
 
+// LoginResponse carries the tokens of a signed-in user, or the challenge token of a login
+// waiting for its second factor
 type LoginResponse struct {
-	Token        string    `json:"token"`
-	RefreshToken string    `json:"refresh_token"`
-	ExpiresAt    time.Time `json:"expires_at"`
-	User         User      `json:"user"`
+	Token                  string    `json:"token"`
+	RefreshToken           string    `json:"refresh_token"`
+	RefreshExpiresAt       time.Time `json:"-"`
+	ExpiresAt              time.Time `json:"expires_at"`
+	User                   User      `json:"user"`
+	ChallengeToken         string    `json:"challenge_token,omitempty"`
+	TwoFactorRequired      bool      `json:"two_factor_required,omitempty"`
+	TwoFactorSetupRequired bool      `json:"two_factor_setup_required,omitempty"`
 }
 
 type Service struct {
 	db              *sql.DB
 	jwtSecret       []byte
+	challengeKey    []byte
 	pepperSecret    string
+	encryptionKey   string
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
+	notifier        Notifier
 }
 
-func NewService(db *sql.DB, jwtSecret, pepperSecret string, accessTokenTTL, refreshTokenTTL int) *Service {
+// Notifier emails users about their account: sign-ins from devices they have not used before,
+// and password reset links
+type Notifier interface {
+	NotifyNewDeviceLogin(ctx context.Context, user *User, session *Session) error
+	// QueuePasswordResetEmail adds the reset email to the outbox through tx, so it is only sent
+	// if the reset token is stored
+	QueuePasswordResetEmail(ctx context.Context, tx *sql.Tx, user *User, token string, expiresIn time.Duration) error
+}
+
+func NewService(db *sql.DB, jwtSecret, pepperSecret, encryptionKey string, accessTokenTTL, refreshTokenTTL int) *Service {
 	return &Service{
 		db:              db,
 		jwtSecret:       []byte(jwtSecret),
+		challengeKey:    challengeSigningKey(jwtSecret),
 		pepperSecret:    pepperSecret,
+		encryptionKey:   encryptionKey,
 		accessTokenTTL:  time.Duration(accessTokenTTL) * time.Minute,
 		refreshTokenTTL: time.Duration(refreshTokenTTL) * time.Minute,
 	}
 }
 
-// Login authenticates a user and returns JWT token
-func (s *Service) Login(ctx context.Context, req LoginRequest) (*LoginResponse, error) {
+// SetNotifier sets who emails users about their account
+func (s *Service) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+// Login authenticates a user and returns JWT token. Users with two-factor authentication get
+// a challenge token instead, to exchange for tokens with CompleteTwoFactorLogin.
+func (s *Service) Login(ctx context.Context, req LoginRequest, client ClientInfo) (*LoginResponse, error) {
 	// Get user by email
 	user, err := s.getUserByEmail(ctx, req.Email)
 	if err != nil {
@@ -104,14 +135,48 @@ func (s *Service) Login(ctx context.Context, req LoginRequest) (*LoginResponse,
 		return nil, ErrInvalidCredentials
 	}
 
-	// Generate JWT token
-	token, expiresAt, err := s.generateToken(user)
+	// Refuse a disallowed address before asking for the second factor
+	policy, err := s.getSecurityPolicy(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := policy.checkIP(client.IPAddress); err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.TwoFactorChallenge(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if challenge != nil {
+		return challenge, nil
+	}
+
+	return s.issueTokens(ctx, user, client)
+}
+
+// issueTokens signs in a user who passed every login step, starting a session on their device.
+// The user's security policy decides where they may sign in from, how long the session lasts
+// and whether their password has expired.
+func (s *Service) issueTokens(ctx context.Context, user *User, client ClientInfo) (*LoginResponse, error) {
+	policy, err := s.getSecurityPolicy(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := policy.checkIP(client.IPAddress); err != nil {
+		return nil, err
+	}
+	if policy.passwordExpired() {
+		user.MustChangePassword = true
+	}
+
+	session, refreshToken, err := s.startSession(ctx, user, client, policy.limit(s.refreshTokenTTL))
 	if err != nil {
 		return nil, err
 	}
 
-	// Generate refresh token (simplified - in production, store in Redis)
-	refreshToken, _, err := s.GenerateRefreshToken(user)
+	// Generate JWT token
+	token, expiresAt, err := s.generateToken(user, session.ID, policy.limit(s.accessTokenTTL))
 	if err != nil {
 		return nil, err
 	}
@@ -122,10 +187,11 @@ func (s *Service) Login(ctx context.Context, req LoginRequest) (*LoginResponse,
 	}
 
 	return &LoginResponse{
-		Token:        token,
-		RefreshToken: refreshToken,
-		ExpiresAt:    expiresAt,
-		User:         *user,
+		Token:            token,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+		ExpiresAt:        expiresAt,
+		User:             *user,
 	}, nil
 }
 
@@ -155,41 +221,31 @@ func (s *Service) ValidateToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
-// RefreshToken generates a new access token from a refresh token
-func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (*LoginResponse, error) {
-	// Validate refresh token
-	claims, err := s.ValidateToken(refreshToken)
+// RefreshToken generates a new access token from a refresh token. The refresh token is spent
+// and replaced by the next token of its family.
+func (s *Service) RefreshToken(ctx context.Context, refreshToken string, client ClientInfo) (*LoginResponse, error) {
+	refreshed, err := s.rotateRefreshToken(ctx, refreshToken, client)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get user to ensure they're still active
-	user, err := s.GetUserByID(ctx, claims.UserID)
-	if err != nil {
-		return nil, err
-	}
-
-	if !user.IsActive {
-		return nil, ErrUserInactive
+	if refreshed.policy.passwordExpired() {
+		refreshed.user.MustChangePassword = true
 	}
 
 	// Generate new access token
-	token, expiresAt, err := s.generateToken(user)
-	if err != nil {
-		return nil, err
-	}
-
-	// Generate new refresh token
-	newRefreshToken, _, err := s.GenerateRefreshToken(user)
+	token, expiresAt, err := s.generateToken(refreshed.user, refreshed.sessionID.String(),
+		refreshed.policy.limit(s.accessTokenTTL))
 	if err != nil {
 		return nil, err
 	}
 
 	return &LoginResponse{
-		Token:        token,
-		RefreshToken: newRefreshToken,
-		ExpiresAt:    expiresAt,
-		User:         *user,
+		Token:            token,
+		RefreshToken:     refreshed.refreshToken,
+		RefreshExpiresAt: refreshed.expiresAt,
+		ExpiresAt:        expiresAt,
+		User:             *refreshed.user,
 	}, nil
 }
 
@@ -198,9 +254,9 @@ func (s *Service) HashPassword(password string) (string, error) {
 	return HashPassword(password, s.pepperSecret)
 }
 
-// generateToken creates a JWT token for the user
-func (s *Service) generateToken(user *User) (string, time.Time, error) {
-	expiresAt := time.Now().Add(s.accessTokenTTL)
+// generateToken creates a JWT token for the user's session that expires after ttl
+func (s *Service) generateToken(user *User, sessionID string, ttl time.Duration) (string, time.Time, error) {
+	expiresAt := time.Now().Add(ttl)
 
 	// Helper to safely dereference pointers
 	tenantID := ""
@@ -217,12 +273,14 @@ func (s *Service) generateToken(user *User) (string, time.Time, error) {
 	}
 
 	claims := &Claims{
-		UserID:       user.ID,
-		TenantID:     tenantID,
-		Email:        user.Email,
-		Role:         user.Role,
-		DepartmentID: departmentID,
-		TeamID:       teamID,
+		UserID:                 user.ID,
+		TenantID:               tenantID,
+		Email:                  user.Email,
+		Role:                   user.Role,
+		DepartmentID:           departmentID,
+		TeamID:                 teamID,
+		SessionID:              sessionID,
+		PasswordChangeRequired: user.MustChangePassword,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -241,55 +299,12 @@ func (s *Service) generateToken(user *User) (string, time.Time, error) {
 	return tokenString, expiresAt, nil
 }
 
-// GenerateRefreshToken creates a refresh token (longer TTL)
-func (s *Service) GenerateRefreshToken(user *User) (string, time.Time, error) {
-	expiresAt := time.Now().Add(s.refreshTokenTTL)
-
-	// Helper to safely dereference pointers
-	tenantID := ""
-	if user.TenantID != nil {
-		tenantID = *user.TenantID
-	}
-	departmentID := ""
-	if user.DepartmentID != nil {
-		departmentID = *user.DepartmentID
-	}
-	teamID := ""
-	if user.TeamID != nil {
-		teamID = *user.TeamID
-	}
-
-	claims := &Claims{
-		UserID:       user.ID,
-		TenantID:     tenantID,
-		Email:        user.Email,
-		Role:         user.Role,
-		DepartmentID: departmentID,
-		TeamID:       teamID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "peopleos-api-refresh",
-			Subject:   user.ID,
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(s.jwtSecret)
-	if err != nil {
-		return "", time.Time{}, err
-	}
-
-	return tokenString, expiresAt, nil
-}
-
 // getUserByEmail retrieves a user by email
 func (s *Service) getUserByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
 		SELECT u.id, u.tenant_id, u.email, u.password_hash, u.role, u.first_name, u.last_name, 
 			   u.is_active, u.last_login_at, u.email_verified_at, u.created_at, u.updated_at,
-			   e.department_id, u.team_id
+			   e.department_id, u.team_id, u.must_change_password
 		FROM users u
 		LEFT JOIN employees e ON u.id = e.user_id
 		WHERE u.email = $1 AND u.deleted_at IS NULL
@@ -310,7 +325,7 @@ func (s *Service) getUserByEmail(ctx context.Context, email string) (*User, erro
 		&user.ID, &tenantID, &user.Email, &passwordHash,
 		&user.Role, &user.FirstName, &user.LastName, &user.IsActive,
 		&user.LastLoginAt, &user.EmailVerifiedAt, &user.CreatedAt, &user.UpdatedAt,
-		&departmentID, &teamID,
+		&departmentID, &teamID, &user.MustChangePassword,
 	)
 
 	if err != nil {
@@ -354,7 +369,7 @@ func (s *Service) GetUserByID(ctx context.Context, userID string) (*User, error)
 	query := `
 		SELECT u.id, u.tenant_id, u.email, u.password_hash, u.role, u.first_name, u.last_name, 
 			   u.is_active, u.last_login_at, u.email_verified_at, u.created_at, u.updated_at,
-			   e.department_id, u.team_id
+			   e.department_id, u.team_id, u.must_change_password
 		FROM users u
 		LEFT JOIN employees e ON u.id = e.user_id
 		WHERE u.id = $1 AND u.deleted_at IS NULL
@@ -370,7 +385,7 @@ func (s *Service) GetUserByID(ctx context.Context, userID string) (*User, error)
 		&user.ID, &tenantID, &user.Email, &passwordHash,
 		&user.Role, &user.FirstName, &user.LastName, &user.IsActive,
 		&user.LastLoginAt, &user.EmailVerifiedAt, &user.CreatedAt, &user.UpdatedAt,
-		&departmentID, &teamID,
+		&departmentID, &teamID, &user.MustChangePassword,
 	)
 
 	if err != nil {
@@ -532,64 +547,62 @@ func (s *Service) getUserByGoogleID(ctx context.Context, googleID string) (*User
 	return user, nil
 }
 
-// CheckUserStatus verifies if the user and their tenant are active and not deleted
-func (s *Service) CheckUserStatus(ctx context.Context, userID string) error {
-	// 1. Check User Status
-	query := `
-		SELECT u.is_active, u.deleted_at, u.tenant_id
-		FROM users u
-		WHERE u.id = $1
-	`
-	var isActive bool
-	var deletedAt sql.NullTime
-	var tenantID sql.NullString
+// accountStatusColumns selects the state of a user u and their tenant t, in the order
+// accountStatus scans them
+const accountStatusColumns = `u.is_active, u.deleted_at IS NOT NULL, u.tenant_id IS NOT NULL,
+	t.id IS NOT NULL, COALESCE(t.status, ''), t.deleted_at IS NOT NULL`
+
+// accountStatus is the state of a user account and of its organization
+type accountStatus struct {
+	active, deleted        bool
+	hasTenant, tenantFound bool
+	tenantStatus           string
+	tenantDeleted          bool
+}
 
-	err := s.db.QueryRowContext(ctx, query, userID).Scan(&isActive, &deletedAt, &tenantID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return ErrUserNotFound
-		}
-		return err
-	}
+func (a *accountStatus) dest() []interface{} {
+	return []interface{}{&a.active, &a.deleted, &a.hasTenant, &a.tenantFound, &a.tenantStatus, &a.tenantDeleted}
+}
 
-	if deletedAt.Valid {
+// err returns why the account may not be used, or nil when the user and their tenant are active
+func (a *accountStatus) err() error {
+	if a.deleted {
 		return errors.New("user account has been deleted")
 	}
-
-	if !isActive {
+	if !a.active {
 		return ErrUserInactive
 	}
-
-	// 2. Check Tenant Status (if user belongs to a tenant)
-	if tenantID.Valid {
-		tenantQuery := `
-			SELECT status, deleted_at
-			FROM tenants
-			WHERE id = $1
-		`
-		var status string
-		var tenantDeletedAt sql.NullTime
-
-		err := s.db.QueryRowContext(ctx, tenantQuery, tenantID.String).Scan(&status, &tenantDeletedAt)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				return errors.New("organization not found")
-			}
-			return err
+	if a.hasTenant {
+		if !a.tenantFound {
+			return errors.New("organization not found")
 		}
-
-		if tenantDeletedAt.Valid {
+		if a.tenantDeleted {
 			return errors.New("organization has been deleted")
 		}
-
-		if status != "active" {
+		if a.tenantStatus != "active" {
 			return errors.New("organization is suspended")
 		}
 	}
-
 	return nil
 }
 
+// CheckUserStatus verifies if the user and their tenant are active and not deleted
+func (s *Service) CheckUserStatus(ctx context.Context, userID string) error {
+	var status accountStatus
+	err := s.db.QueryRowContext(ctx, `
+		SELECT `+accountStatusColumns+`
+		FROM users u
+		LEFT JOIN tenants t ON t.id = u.tenant_id
+		WHERE u.id = $1`, userID).Scan(status.dest()...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return status.err()
+}
+
 // GenerateAccessToken generates a JWT access token for a user
 func (s *Service) GenerateAccessToken(userID, tenantID, email, role string) (string, error) {
 	claims := Claims{
@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// ListSessions handles GET /api/v1/company/employee/settings/security/sessions
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := GetClaimsFromContext(r.Context())
+	if !ok {
+		h.writeErrorResponse(w, http.StatusUnauthorized, "Missing authentication")
+		return
+	}
+
+	sessions, err := h.service.ListSessions(r.Context(), claims.UserID, claims.SessionID)
+	if err != nil {
+		h.writeSessionError(w, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"sessions": sessions,
+	})
+}
+
+// RevokeSession handles DELETE /api/v1/company/employee/settings/security/sessions/{sessionID}
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	claims, ok := GetClaimsFromContext(r.Context())
+	if !ok {
+		h.writeErrorResponse(w, http.StatusUnauthorized, "Missing authentication")
+		return
+	}
+
+	sessionID := chi.URLParam(r, "sessionID")
+	if err := h.service.RevokeSession(r.Context(), claims.UserID, sessionID); err != nil {
+		h.writeSessionError(w, err)
+		return
+	}
+
+	log.Info().Str("user_id", claims.UserID).Str("session_id", sessionID).Msg("Session revoked")
+	h.writeJSONResponse(w, http.StatusOK, map[string]string{
+		"message": "Session signed out",
+	})
+}
+
+// RevokeOtherSessions handles DELETE /api/v1/company/employee/settings/security/sessions
+// It signs the user out of every session but the current one.
+func (h *Handler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := GetClaimsFromContext(r.Context())
+	if !ok {
+		h.writeErrorResponse(w, http.StatusUnauthorized, "Missing authentication")
+		return
+	}
+
+	revoked, err := h.service.RevokeOtherSessions(r.Context(), claims.UserID, claims.SessionID)
+	if err != nil {
+		h.writeSessionError(w, err)
+		return
+	}
+
+	log.Info().Str("user_id", claims.UserID).Int64("revoked", revoked).Msg("Other sessions revoked")
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Other sessions signed out",
+		"revoked": revoked,
+	})
+}
+
+// ListEmployeeSessions handles GET /api/v1/company/admin/employees/{employeeID}/sessions
+func (h *Handler) ListEmployeeSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := GetClaimsFromContext(r.Context())
+	if !ok {
+		h.writeErrorResponse(w, http.StatusUnauthorized, "Missing authentication")
+		return
+	}
+
+	userID, err := h.service.GetEmployeeUserID(r.Context(), claims.TenantID, chi.URLParam(r, "employeeID"))
+	if err != nil {
+		h.writeSessionError(w, err)
+		return
+	}
+
+	sessions, err := h.service.ListSessions(r.Context(), userID, claims.SessionID)
+	if err != nil {
+		h.writeSessionError(w, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"sessions": sessions,
+	})
+}
+
+// RevokeEmployeeSession handles DELETE /api/v1/company/admin/employees/{employeeID}/sessions/{sessionID}
+func (h *Handler) RevokeEmployeeSession(w http.ResponseWriter, r *http.Request) {
+	claims, ok := GetClaimsFromContext(r.Context())
+	if !ok {
+		h.writeErrorResponse(w, http.StatusUnauthorized, "Missing authentication")
+		return
+	}
+
+	userID, err := h.service.GetEmployeeUserID(r.Context(), claims.TenantID, chi.URLParam(r, "employeeID"))
+	if err != nil {
+		h.writeSessionError(w, err)
+		return
+	}
+
+	sessionID := chi.URLParam(r, "sessionID")
+	if err := h.service.RevokeSession(r.Context(), userID, sessionID); err != nil {
+		h.writeSessionError(w, err)
+		return
+	}
+
+	log.Info().
+		Str("admin_id", claims.UserID).
+		Str("user_id", userID).
+		Str("session_id", sessionID).
+		Msg("Session revoked by admin")
+	h.writeJSONResponse(w, http.StatusOK, map[string]string{
+		"message": "Session signed out",
+	})
+}
+
+// RevokeEmployeeSessions handles DELETE /api/v1/company/admin/employees/{employeeID}/sessions
+// It signs the employee out of every session.
+func (h *Handler) RevokeEmployeeSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := GetClaimsFromContext(r.Context())
+	if !ok {
+		h.writeErrorResponse(w, http.StatusUnauthorized, "Missing authentication")
+		return
+	}
+
+	userID, err := h.service.GetEmployeeUserID(r.Context(), claims.TenantID, chi.URLParam(r, "employeeID"))
+	if err != nil {
+		h.writeSessionError(w, err)
+		return
+	}
+
+	// An admin signing themselves out this way keeps the session they are using
+	keep := ""
+	if userID == claims.UserID {
+		keep = claims.SessionID
+	}
+	revoked, err := h.service.RevokeOtherSessions(r.Context(), userID, keep)
+	if err != nil {
+		h.writeSessionError(w, err)
+		return
+	}
+
+	log.Info().
+		Str("admin_id", claims.UserID).
+		Str("user_id", userID).
+		Int64("revoked", revoked).
+		Msg("Sessions revoked by admin")
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Sessions signed out",
+		"revoked": revoked,
+	})
+}
+
+// writeSessionError maps session errors to responses
+func (h *Handler) writeSessionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrSessionNotFound), err.Error() == "employee not found":
+		h.writeErrorResponse(w, http.StatusNotFound, err.Error())
+	default:
+		log.Error().Err(err).Msg("Session request failed")
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
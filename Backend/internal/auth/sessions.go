@@ -0,0 +1,417 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionRevoked  = errors.New("session has been revoked")
+)
+
+const (
+	// deviceCookieName identifies a browser across sign-ins, so sign-ins from new devices can
+	// be told apart
+	deviceCookieName = "device_id"
+	deviceCookieAge  = 365 * 24 * time.Hour
+
+	// sessionTouchInterval limits how often requests update the last seen time of a session
+	sessionTouchInterval = time.Minute
+)
+
+// ClientInfo describes the device a sign-in or refresh came from
+type ClientInfo struct {
+	DeviceID  string
+	UserAgent string
+	IPAddress string
+	Location  string // approximate, from the geolocation headers of the CDN in front of the API
+}
+
+// Session is a signed-in device of a user. Its ID is the ID of its refresh token family, so a
+// session lasts as long as the family has a live token.
+type Session struct {
+	ID         string    `json:"id"`
+	DeviceName string    `json:"device_name"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  *string   `json:"ip_address"`
+	Location   *string   `json:"location"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// activeSessionCondition matches sessions whose refresh token family still has a live token
+const activeSessionCondition = `EXISTS (
+	SELECT 1 FROM refresh_tokens rt
+	WHERE rt.family_id = s.id AND rt.revoked_at IS NULL AND rt.used_at IS NULL AND rt.expires_at > NOW()
+)`
+
+// clientInfo reads the device of a request. Browsers without a device cookie get a new one.
+func clientInfo(w http.ResponseWriter, r *http.Request) ClientInfo {
+	deviceID := ""
+	if cookie, err := r.Cookie(deviceCookieName); err == nil {
+		if id, err := uuid.Parse(cookie.Value); err == nil {
+			deviceID = id.String()
+		}
+	}
+	if deviceID == "" {
+		deviceID = uuid.New().String()
+		http.SetCookie(w, &http.Cookie{
+			Name:     deviceCookieName,
+			Value:    deviceID,
+			Path:     refreshCookiePath,
+			HttpOnly: true,
+			Secure:   true,
+			SameSite: http.SameSiteNoneMode,
+			MaxAge:   int(deviceCookieAge.Seconds()),
+		})
+	}
+
+	return ClientInfo{
+		DeviceID:  deviceID,
+		UserAgent: r.UserAgent(),
+		IPAddress: requestIP(r),
+		Location:  requestLocation(r),
+	}
+}
+
+// requestIP returns the client address of a request, or "" when it is not an IP address
+func requestIP(r *http.Request) string {
+	// The RealIP middleware has already replaced RemoteAddr with the forwarded address
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	if net.ParseIP(ip) == nil {
+		return ""
+	}
+	return ip
+}
+
+// requestLocation returns the city and country a CDN resolved the client address to, if any
+func requestLocation(r *http.Request) string {
+	for _, h := range [][2]string{
+		{"CF-IPCity", "CF-IPCountry"},
+		{"X-Vercel-IP-City", "X-Vercel-IP-Country"},
+		{"X-AppEngine-City", "X-AppEngine-Country"},
+	} {
+		country := strings.TrimSpace(r.Header.Get(h[1]))
+		if country == "" || country == "XX" || country == "ZZ" {
+			continue
+		}
+		if city := strings.TrimSpace(r.Header.Get(h[0])); city != "" {
+			return city + ", " + country
+		}
+		return country
+	}
+	return ""
+}
+
+// describeUserAgent names the browser and operating system of a user agent, such as
+// "Chrome on Windows"
+func describeUserAgent(ua string) string {
+	browser := ""
+	switch {
+	case strings.Contains(ua, "Edg/"), strings.Contains(ua, "EdgA/"), strings.Contains(ua, "EdgiOS/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"), strings.Contains(ua, "Opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "Firefox/"), strings.Contains(ua, "FxiOS/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Chrome/"), strings.Contains(ua, "CriOS/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(ua, "Windows"):
+		os = "Windows"
+	case strings.Contains(ua, "Android"):
+		os = "Android"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		os = "iOS"
+	case strings.Contains(ua, "Mac OS X"), strings.Contains(ua, "Macintosh"):
+		os = "macOS"
+	case strings.Contains(ua, "CrOS"):
+		os = "ChromeOS"
+	case strings.Contains(ua, "Linux"):
+		os = "Linux"
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	default:
+		return "Unknown device"
+	}
+}
+
+// getSessionSettings returns whether a user wants to hear about sign-ins from new devices and
+// whether their devices are tracked. Users without security settings get the defaults.
+func (s *Service) getSessionSettings(ctx context.Context, userID string) (loginNotifications, deviceTracking bool, err error) {
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(login_notifications, TRUE), COALESCE(device_tracking, TRUE)
+		FROM security_settings WHERE user_id = $1`, userID).Scan(&loginNotifications, &deviceTracking)
+	if err == sql.ErrNoRows {
+		return true, true, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to get security settings: %w", err)
+	}
+	return loginNotifications, deviceTracking, nil
+}
+
+// startSession records a sign-in and returns its session, which lasts ttl, with the first
+// refresh token of the session's family. The IP address and location are only kept for users
+// with device tracking on. The user is told about a sign-in from a device none of their
+// sessions used before.
+func (s *Service) startSession(ctx context.Context, user *User, client ClientInfo, ttl time.Duration) (*Session, string, error) {
+	loginNotifications, deviceTracking, err := s.getSessionSettings(ctx, user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sessionID := uuid.New()
+	session := &Session{
+		ID:         sessionID.String(),
+		DeviceName: describeUserAgent(client.UserAgent),
+		UserAgent:  client.UserAgent,
+		CreatedAt:  time.Now(),
+		LastSeenAt: time.Now(),
+		ExpiresAt:  time.Now().Add(ttl),
+		Current:    true,
+	}
+	if deviceTracking {
+		if client.IPAddress != "" {
+			session.IPAddress = &client.IPAddress
+		}
+		if client.Location != "" {
+			session.Location = &client.Location
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var signedInBefore, knownDevice bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0, COALESCE(BOOL_OR(device_id = $2), FALSE)
+		FROM user_sessions WHERE user_id = $1`, user.ID, client.DeviceID).Scan(&signedInBefore, &knownDevice)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to check known devices: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_sessions (id, user_id, tenant_id, device_id, device_name, user_agent, ip_address,
+		                           location, created_at, last_seen_at, expires_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, ''), $7::INET, $8, $9, $9, $10)`,
+		session.ID, user.ID, user.TenantID, client.DeviceID, session.DeviceName, client.UserAgent,
+		session.IPAddress, session.Location, session.CreatedAt, session.ExpiresAt)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	refreshToken, _, err := createRefreshToken(ctx, tx, user, sessionID, session.ExpiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", fmt.Errorf("failed to commit session: %w", err)
+	}
+
+	// A user's first sign-in is not news to them
+	if signedInBefore && !knownDevice && loginNotifications && s.notifier != nil {
+		if err := s.notifier.NotifyNewDeviceLogin(ctx, user, session); err != nil {
+			log.Error().Err(err).Str("user_id", user.ID).Msg("Failed to notify about new device login")
+		}
+	}
+
+	return session, refreshToken, nil
+}
+
+// touchSession updates the last seen time of a session after a refresh, and its address when
+// its user has device tracking on
+func touchSession(ctx context.Context, q execer, sessionID uuid.UUID, client ClientInfo, deviceTracking bool) error {
+	var ip, location *string
+	if deviceTracking {
+		if client.IPAddress != "" {
+			ip = &client.IPAddress
+		}
+		if client.Location != "" {
+			location = &client.Location
+		}
+	}
+
+	_, err := q.ExecContext(ctx, `
+		UPDATE user_sessions
+		SET last_seen_at = NOW(),
+		    ip_address = COALESCE($2::INET, ip_address),
+		    location = COALESCE($3, location)
+		WHERE id = $1`, sessionID, ip, location)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+// requestAccess is what authenticating a request needs to know about its user and session
+type requestAccess struct {
+	status        accountStatus
+	sessionActive bool // also true for tokens issued before sessions were recorded
+	sessionStale  bool // last seen longer than sessionTouchInterval ago
+	policy        *securityPolicy
+}
+
+// loadRequestAccess loads the account status, the session of an access token and the security
+// policy of a user in one query. sessionID is empty for tokens that carry no session.
+func (s *Service) loadRequestAccess(ctx context.Context, userID, sessionID string) (*requestAccess, error) {
+	access := &requestAccess{}
+	var policy policyRow
+	dest := append(access.status.dest(), &access.sessionActive, &access.sessionStale)
+	dest = append(dest, policy.dest()...)
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT `+accountStatusColumns+`,
+		       $2 = '' OR (s.id IS NOT NULL AND `+activeSessionCondition+`),
+		       COALESCE(s.last_seen_at < NOW() - make_interval(secs => $3), false),
+		       `+securityPolicyColumns+`
+		FROM users u
+		LEFT JOIN tenants t ON t.id = u.tenant_id
+		LEFT JOIN security_settings ss ON ss.user_id = u.id
+		LEFT JOIN user_sessions s ON s.id = NULLIF($2, '')::UUID AND s.user_id = u.id
+		WHERE u.id = $1`, userID, sessionID, sessionTouchInterval.Seconds()).Scan(dest...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to check user access: %w", err)
+	}
+
+	access.policy = policy.policy()
+	return access, nil
+}
+
+// touchSession records that a session was seen
+func (s *Service) touchSession(ctx context.Context, sessionID string) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE user_sessions SET last_seen_at = NOW() WHERE id = $1", sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+// ListSessions returns the signed-in devices of a user, most recently seen first.
+// currentSessionID marks the session of the request.
+func (s *Service) ListSessions(ctx context.Context, userID, currentSessionID string) ([]Session, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT s.id, s.device_name, COALESCE(s.user_agent, ''), host(s.ip_address), s.location,
+		       s.created_at, s.last_seen_at, s.expires_at
+		FROM user_sessions s
+		WHERE s.user_id = $1 AND `+activeSessionCondition+`
+		ORDER BY s.last_seen_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var session Session
+		err := rows.Scan(&session.ID, &session.DeviceName, &session.UserAgent, &session.IPAddress,
+			&session.Location, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		session.Current = session.ID == currentSessionID
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs a user out of one of their sessions
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW(), revoke_reason = $3
+		WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL`,
+		id, userID, RevokeReasonSessionRevoked)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeOtherSessions signs a user out of every session but keepSessionID, and returns how
+// many sessions were signed out
+func (s *Service) RevokeOtherSessions(ctx context.Context, userID, keepSessionID string) (int64, error) {
+	var keep *uuid.UUID
+	if id, err := uuid.Parse(keepSessionID); err == nil {
+		keep = &id
+	}
+
+	// Each live session has one unspent, unexpired token
+	var revoked int64
+	err := s.db.QueryRowContext(ctx, `
+		WITH revoked AS (
+		    UPDATE refresh_tokens SET revoked_at = NOW(), revoke_reason = $3
+		    WHERE user_id = $1 AND revoked_at IS NULL
+		      AND ($2::UUID IS NULL OR family_id <> $2)
+		    RETURNING used_at, expires_at
+		)
+		SELECT COUNT(*) FROM revoked WHERE used_at IS NULL AND expires_at > NOW()`,
+		userID, keep, RevokeReasonSessionRevoked).Scan(&revoked)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return revoked, nil
+}
+
+// GetEmployeeUserID returns the user of an employee of a tenant
+func (s *Service) GetEmployeeUserID(ctx context.Context, tenantID, employeeID string) (string, error) {
+	if _, err := uuid.Parse(employeeID); err != nil {
+		return "", errors.New("employee not found")
+	}
+
+	var userID string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id FROM employees
+		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL`, employeeID, tenantID).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", errors.New("employee not found")
+		}
+		return "", fmt.Errorf("failed to get employee: %w", err)
+	}
+	return userID, nil
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of RFC 6238 Appendix B, base32 encoded
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+	// The RFC lists 8-digit codes; 6-digit codes are their last six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode(T=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+		step, ok := verifyTOTP(rfc6238Secret, tt.want, time.Unix(tt.unix, 0))
+		if !ok || step != tt.unix/totpPeriod {
+			t.Errorf("verifyTOTP(T=%d) = %d, %v; want step %d", tt.unix, step, ok, tt.unix/totpPeriod)
+		}
+	}
+}
+
+func TestVerifyTOTPSkew(t *testing.T) {
+	key := []byte("12345678901234567890")
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name   string
+		offset int64
+		wantOK bool
+	}{
+		{name: "current step", offset: 0, wantOK: true},
+		{name: "one step behind", offset: -1, wantOK: true},
+		{name: "one step ahead", offset: 1, wantOK: true},
+		{name: "two steps behind", offset: -2, wantOK: false},
+		{name: "two steps ahead", offset: 2, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The returned step is what replay protection stores as last_used_step, so it must
+			// be the step of the code and not the current one
+			step, ok := verifyTOTP(rfc6238Secret, totpCode(key, current+tt.offset), now)
+			if ok != tt.wantOK {
+				t.Fatalf("verifyTOTP() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && step != current+tt.offset {
+				t.Errorf("verifyTOTP() step = %d, want %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+func TestVerifyTOTPInput(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		wantOK bool
+	}{
+		{name: "spaces typed in the code", secret: rfc6238Secret, code: " 005 924 ", wantOK: true},
+		{name: "lower case secret", secret: "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code: "005924", wantOK: true},
+		{name: "wrong code", secret: rfc6238Secret, code: "005925"},
+		{name: "too short", secret: rfc6238Secret, code: "05924"},
+		{name: "eight digits", secret: rfc6238Secret, code: "89005924"},
+		{name: "invalid secret", secret: "not base32!", code: "005924"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := verifyTOTP(tt.secret, tt.code, now); ok != tt.wantOK {
+				t.Errorf("verifyTOTP() ok = %v, want %v", ok, tt.wantOK)
+			}
+		})
+	}
+}
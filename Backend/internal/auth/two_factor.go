@@ -0,0 +1,568 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lib/pq"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/security"
+)
+
+var (
+	ErrTwoFactorInvalidCode     = errors.New("invalid two-factor code")
+	ErrTwoFactorLocked          = errors.New("too many invalid two-factor codes, try again later")
+	ErrTwoFactorNotEnabled      = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorSetupNotStarted = errors.New("two-factor setup has not been started")
+	ErrTwoFactorRequired        = errors.New("two-factor authentication is required for your role")
+	ErrInvalidChallenge         = errors.New("invalid or expired two-factor challenge")
+	ErrInvalidTwoFactorRole     = errors.New("two-factor authentication can only be required for company roles")
+)
+
+const (
+	// twoFactorChallengeTTL is how long a user has to enter their code after their password
+	twoFactorChallengeTTL = 5 * time.Minute
+	// twoFactorMaxAttempts invalid codes in a row lock the second step for twoFactorLockout
+	twoFactorMaxAttempts = 5
+	twoFactorLockout     = 15 * time.Minute
+
+	challengeVerify = "verify" // the user enters a code of their enrolled authenticator
+	challengeSetup  = "setup"  // the user's role requires 2FA and they must enrol first
+)
+
+// twoFactorPolicyRoles are the roles a tenant may require two-factor authentication for
+var twoFactorPolicyRoles = map[string]bool{
+	"admin":     true,
+	"hr":        true,
+	"manager":   true,
+	"team_lead": true,
+	"employee":  true,
+}
+
+// TwoFactorStatus describes the two-factor authentication of a user
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	Required               bool       `json:"required"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorSetup is what a user needs to add PeopleOS to their authenticator app
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI to render as a QR code
+}
+
+// challengeClaims identify a user who passed the password step of a login
+type challengeClaims struct {
+	UserID  string `json:"user_id"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// twoFactorRecord is a row of user_two_factor
+type twoFactorRecord struct {
+	secret      string
+	confirmedAt *time.Time
+	lockedUntil *time.Time
+}
+
+// challengeSigningKey derives the key of challenge tokens from the JWT secret. Using a separate
+// key keeps a challenge token from ever being accepted as an access token.
+func challengeSigningKey(jwtSecret string) []byte {
+	key := sha256.Sum256([]byte(jwtSecret + ":two-factor-challenge"))
+	return key[:]
+}
+
+// TwoFactorChallenge returns the second login step a user has to pass, or nil when their
+// password is enough. Users with an enrolled authenticator must enter a code; users whose
+// role requires two-factor authentication must enrol first.
+func (s *Service) TwoFactorChallenge(ctx context.Context, user *User) (*LoginResponse, error) {
+	var enabled, required bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM user_two_factor WHERE user_id = $1 AND confirmed_at IS NOT NULL),
+		       COALESCE((SELECT $2::TEXT = ANY(two_factor_required_roles) FROM tenants WHERE id = $3::UUID), false)`,
+		user.ID, user.Role, user.TenantID).Scan(&enabled, &required)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check two-factor authentication: %w", err)
+	}
+
+	purpose := ""
+	switch {
+	case enabled:
+		purpose = challengeVerify
+	case required:
+		purpose = challengeSetup
+	default:
+		return nil, nil
+	}
+
+	expiresAt := time.Now().Add(twoFactorChallengeTTL)
+	claims := &challengeClaims{
+		UserID:  user.ID,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "peopleos-api-2fa",
+			Subject:   user.ID,
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.challengeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		ChallengeToken:         token,
+		TwoFactorRequired:      purpose == challengeVerify,
+		TwoFactorSetupRequired: purpose == challengeSetup,
+		ExpiresAt:              expiresAt,
+	}, nil
+}
+
+// parseChallenge validates a challenge token and returns its claims
+func (s *Service) parseChallenge(challengeToken string) (*challengeClaims, error) {
+	token, err := jwt.ParseWithClaims(challengeToken, &challengeClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidChallenge
+		}
+		return s.challengeKey, nil
+	})
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	claims, ok := token.Claims.(*challengeClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidChallenge
+	}
+	return claims, nil
+}
+
+// BeginChallengeSetup starts the enrolment of a user whose login is waiting for it
+func (s *Service) BeginChallengeSetup(ctx context.Context, challengeToken string) (*TwoFactorSetup, error) {
+	claims, err := s.parseChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != challengeSetup {
+		return nil, ErrInvalidChallenge
+	}
+	return s.BeginTwoFactorSetup(ctx, claims.UserID)
+}
+
+// CompleteTwoFactorLogin finishes a login with the code of the user's authenticator or one of
+// their recovery codes. When the login was waiting for enrolment, the code confirms it and the
+// user's new recovery codes are returned.
+func (s *Service) CompleteTwoFactorLogin(ctx context.Context, challengeToken, code string, client ClientInfo) (*LoginResponse, []string, error) {
+	claims, err := s.parseChallenge(challengeToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrUserNotFound
+		}
+		return nil, nil, err
+	}
+	if !user.IsActive {
+		return nil, nil, ErrUserInactive
+	}
+
+	var recoveryCodes []string
+	switch claims.Purpose {
+	case challengeVerify:
+		err = s.verifyTwoFactorCode(ctx, user.ID, code)
+	case challengeSetup:
+		recoveryCodes, err = s.ConfirmTwoFactorSetup(ctx, user.ID, code)
+	default:
+		err = ErrInvalidChallenge
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	response, err := s.issueTokens(ctx, user, client)
+	if err != nil {
+		return nil, nil, err
+	}
+	return response, recoveryCodes, nil
+}
+
+// GetTwoFactorStatus returns whether a user has two-factor authentication and whether their
+// role requires it
+func (s *Service) GetTwoFactorStatus(ctx context.Context, userID string) (*TwoFactorStatus, error) {
+	var status TwoFactorStatus
+	err := s.db.QueryRowContext(ctx, `
+		SELECT tf.confirmed_at,
+		       COALESCE(u.role = ANY(t.two_factor_required_roles), false),
+		       (SELECT COUNT(*) FROM two_factor_recovery_codes WHERE user_id = u.id AND used_at IS NULL)
+		FROM users u
+		LEFT JOIN tenants t ON t.id = u.tenant_id
+		LEFT JOIN user_two_factor tf ON tf.user_id = u.id
+		WHERE u.id = $1`, userID).Scan(&status.EnabledAt, &status.Required, &status.RecoveryCodesRemaining)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get two-factor status: %w", err)
+	}
+	status.Enabled = status.EnabledAt != nil
+	if !status.Enabled {
+		status.RecoveryCodesRemaining = 0
+	}
+	return &status, nil
+}
+
+// BeginTwoFactorSetup generates a new authenticator secret for a user. It replaces any
+// enrolment that was started but not confirmed.
+func (s *Service) BeginTwoFactorSetup(ctx context.Context, userID string) (*TwoFactorSetup, error) {
+	var email string
+	var tenantID sql.NullString
+	var confirmedAt *time.Time
+	err := s.db.QueryRowContext(ctx, `
+		SELECT u.email, u.tenant_id, tf.confirmed_at
+		FROM users u
+		LEFT JOIN user_two_factor tf ON tf.user_id = u.id
+		WHERE u.id = $1 AND u.deleted_at IS NULL`, userID).Scan(&email, &tenantID, &confirmedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if confirmedAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate two-factor secret: %w", err)
+	}
+	encrypted, err := security.Encrypt(secret, s.encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt two-factor secret: %w", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO user_two_factor (user_id, tenant_id, secret_encrypted, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_encrypted = EXCLUDED.secret_encrypted, last_used_step = NULL,
+		    failed_attempts = 0, locked_until = NULL, created_at = NOW(), updated_at = NOW()
+		WHERE user_two_factor.confirmed_at IS NULL`,
+		userID, tenantID, encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start two-factor setup: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	return &TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactorSetup enables two-factor authentication once the user enters the first code
+// of their authenticator, and returns their recovery codes. The codes are only shown here.
+func (s *Service) ConfirmTwoFactorSetup(ctx context.Context, userID, code string) ([]string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rec, err := getTwoFactor(ctx, tx, userID, true)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, ErrTwoFactorSetupNotStarted
+	}
+	if rec.confirmedAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if rec.lockedUntil != nil && rec.lockedUntil.After(time.Now()) {
+		return nil, ErrTwoFactorLocked
+	}
+
+	secret, err := security.Decrypt(rec.secret, s.encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt two-factor secret: %w", err)
+	}
+	step, ok := verifyTOTP(secret, code, time.Now())
+	if !ok {
+		tx.Rollback()
+		return nil, s.recordTwoFactorFailure(ctx, userID)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE user_two_factor
+		SET confirmed_at = NOW(), last_used_step = $2, failed_attempts = 0, locked_until = NULL, updated_at = NOW()
+		WHERE user_id = $1`, userID, step)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	codes, err := s.replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := setTwoFactorEnabled(ctx, tx, userID, true); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit two-factor setup: %w", err)
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor removes a user's authenticator and recovery codes after checking a code.
+// Users whose role requires two-factor authentication cannot disable it.
+func (s *Service) DisableTwoFactor(ctx context.Context, userID, code string) error {
+	status, err := s.GetTwoFactorStatus(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !status.Enabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if status.Required {
+		return ErrTwoFactorRequired
+	}
+
+	if err := s.verifyTwoFactorCode(ctx, userID, code); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM two_factor_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_two_factor WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+	if err := setTwoFactorEnabled(ctx, tx, userID, false); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit two-factor change: %w", err)
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes after checking a code
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	if err := s.verifyTwoFactorCode(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	codes, err := s.replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// GetTwoFactorPolicy returns the roles a tenant requires two-factor authentication for
+func (s *Service) GetTwoFactorPolicy(ctx context.Context, tenantID string) ([]string, error) {
+	var roles pq.StringArray
+	err := s.db.QueryRowContext(ctx,
+		"SELECT two_factor_required_roles FROM tenants WHERE id = $1", tenantID).Scan(&roles)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("organization not found")
+		}
+		return nil, fmt.Errorf("failed to get two-factor policy: %w", err)
+	}
+	if roles == nil {
+		roles = pq.StringArray{}
+	}
+	return roles, nil
+}
+
+// UpdateTwoFactorPolicy sets the roles a tenant requires two-factor authentication for. Users
+// of those roles without an authenticator are asked to enrol at their next login.
+func (s *Service) UpdateTwoFactorPolicy(ctx context.Context, tenantID string, roles []string) ([]string, error) {
+	seen := make(map[string]bool)
+	required := pq.StringArray{}
+	for _, role := range roles {
+		if !twoFactorPolicyRoles[role] {
+			return nil, ErrInvalidTwoFactorRole
+		}
+		if !seen[role] {
+			seen[role] = true
+			required = append(required, role)
+		}
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE tenants SET two_factor_required_roles = $1, updated_at = NOW()
+		WHERE id = $2 AND deleted_at IS NULL`, required, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update two-factor policy: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, errors.New("organization not found")
+	}
+	return required, nil
+}
+
+// verifyTwoFactorCode checks a code of a user's enrolled authenticator, or one of their unused
+// recovery codes, which it uses up. A code of the authenticator is accepted once.
+func (s *Service) verifyTwoFactorCode(ctx context.Context, userID, code string) error {
+	rec, err := getTwoFactor(ctx, s.db, userID, false)
+	if err != nil {
+		return err
+	}
+	if rec == nil || rec.confirmedAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+	if rec.lockedUntil != nil && rec.lockedUntil.After(time.Now()) {
+		return ErrTwoFactorLocked
+	}
+
+	secret, err := security.Decrypt(rec.secret, s.encryptionKey)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt two-factor secret: %w", err)
+	}
+	if step, ok := verifyTOTP(secret, code, time.Now()); ok {
+		result, err := s.db.ExecContext(ctx, `
+			UPDATE user_two_factor
+			SET last_used_step = $2, failed_attempts = 0, locked_until = NULL, updated_at = NOW()
+			WHERE user_id = $1 AND (last_used_step IS NULL OR last_used_step < $2)`, userID, step)
+		if err != nil {
+			return fmt.Errorf("failed to record two-factor code: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 1 {
+			return nil
+		}
+		// The code was already used
+		return s.recordTwoFactorFailure(ctx, userID)
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE two_factor_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		userID, hashRecoveryCode(code, s.pepperSecret))
+	if err != nil {
+		return fmt.Errorf("failed to check recovery code: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 1 {
+		_, err := s.db.ExecContext(ctx, `
+			UPDATE user_two_factor SET failed_attempts = 0, locked_until = NULL, updated_at = NOW()
+			WHERE user_id = $1`, userID)
+		if err != nil {
+			return fmt.Errorf("failed to record recovery code: %w", err)
+		}
+		return nil
+	}
+
+	return s.recordTwoFactorFailure(ctx, userID)
+}
+
+// recordTwoFactorFailure counts an invalid code and locks the second step after too many.
+// It returns the error to report to the user.
+func (s *Service) recordTwoFactorFailure(ctx context.Context, userID string) error {
+	var locked bool
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE user_two_factor
+		SET failed_attempts = CASE WHEN failed_attempts + 1 >= $2 THEN 0 ELSE failed_attempts + 1 END,
+		    locked_until = CASE WHEN failed_attempts + 1 >= $2 THEN NOW() + make_interval(secs => $3) ELSE locked_until END,
+		    updated_at = NOW()
+		WHERE user_id = $1
+		RETURNING locked_until IS NOT NULL AND locked_until > NOW()`,
+		userID, twoFactorMaxAttempts, twoFactorLockout.Seconds()).Scan(&locked)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to record two-factor failure: %w", err)
+	}
+	if locked {
+		return ErrTwoFactorLocked
+	}
+	return ErrTwoFactorInvalidCode
+}
+
+// replaceRecoveryCodes generates new recovery codes for a user, replacing the old ones
+func (s *Service) replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string) ([]string, error) {
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM two_factor_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	for _, code := range codes {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO two_factor_recovery_codes (user_id, tenant_id, code_hash, created_at)
+			SELECT id, tenant_id, $2, NOW() FROM users WHERE id = $1`,
+			userID, hashRecoveryCode(code, s.pepperSecret))
+		if err != nil {
+			return nil, fmt.Errorf("failed to store recovery code: %w", err)
+		}
+	}
+	return codes, nil
+}
+
+// getTwoFactor returns the two-factor enrolment of a user, or nil when there is none
+func getTwoFactor(ctx context.Context, q interface {
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}, userID string, forUpdate bool) (*twoFactorRecord, error) {
+	query := `
+		SELECT secret_encrypted, confirmed_at, locked_until
+		FROM user_two_factor WHERE user_id = $1`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	var rec twoFactorRecord
+	err := q.QueryRowContext(ctx, query, userID).Scan(&rec.secret, &rec.confirmedAt, &rec.lockedUntil)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor authentication: %w", err)
+	}
+	return &rec, nil
+}
+
+// setTwoFactorEnabled keeps the two_factor_enabled flag of the user's security settings in
+// step with their enrolment
+func setTwoFactorEnabled(ctx context.Context, tx *sql.Tx, userID string, enabled bool) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO security_settings (user_id, tenant_id, two_factor_enabled)
+		SELECT id, tenant_id, $2 FROM users WHERE id = $1
+		ON CONFLICT (user_id) DO UPDATE
+		SET two_factor_enabled = EXCLUDED.two_factor_enabled, updated_at = CURRENT_TIMESTAMP`,
+		userID, enabled)
+	if err != nil {
+		return fmt.Errorf("failed to update security settings: %w", err)
+	}
+	return nil
+}
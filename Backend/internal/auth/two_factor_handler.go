@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+type twoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"` // authenticator code or recovery code
+}
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type twoFactorPolicyRequest struct {
+	RequiredRoles []string `json:"required_roles"`
+}
+
+// LoginTwoFactor handles POST /api/v1/auth/login/2fa
+// It completes a login with the challenge token returned by Login and a code.
+func (h *Handler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req twoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.ChallengeToken == "" || req.Code == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Challenge token and code are required")
+		return
+	}
+
+	response, recoveryCodes, err := h.service.CompleteTwoFactorLogin(r.Context(), req.ChallengeToken, req.Code, clientInfo(w, r))
+	if err != nil {
+		log.Warn().Err(err).Msg("Two-factor login failed")
+		h.writeTwoFactorError(w, err)
+		return
+	}
+
+	h.writeLoginResponse(w, response, recoveryCodes)
+}
+
+// LoginTwoFactorSetup handles POST /api/v1/auth/login/2fa/setup
+// Users whose role requires two-factor authentication enrol here during their login, then
+// confirm the first code through LoginTwoFactor.
+func (h *Handler) LoginTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	var req twoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	setup, err := h.service.BeginChallengeSetup(r.Context(), req.ChallengeToken)
+	if err != nil {
+		log.Warn().Err(err).Msg("Two-factor setup during login failed")
+		h.writeTwoFactorError(w, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, setup)
+}
+
+// GetTwoFactorStatus handles GET /api/v1/company/employee/settings/security/2fa
+func (h *Handler) GetTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	claims, ok := GetClaimsFromContext(r.Context())
+	if !ok {
+		h.writeErrorResponse(w, http.StatusUnauthorized, "Missing authentication")
+		return
+	}
+
+	status, err := h.service.GetTwoFactorStatus(r.Context(), claims.UserID)
+	if err != nil {
+		h.writeTwoFactorError(w, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, status)
+}
+
+// BeginTwoFactorSetup handles POST /api/v1/company/employee/settings/security/2fa/setup
+// It returns a new secret and its provisioning URI; two-factor authentication is enabled once
+// a code is confirmed through ConfirmTwoFactorSetup.
+func (h *Handler) BeginTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	claims, ok := GetClaimsFromContext(r.Context())
+	if !ok {
+		h.writeErrorResponse(w, http.StatusUnauthorized, "Missing authentication")
+		return
+	}
+
+	setup, err := h.service.BeginTwoFactorSetup(r.Context(), claims.UserID)
+	if err != nil {
+		h.writeTwoFactorError(w, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, setup)
+}
+
+// ConfirmTwoFactorSetup handles POST /api/v1/company/employee/settings/security/2fa/verify
+func (h *Handler) ConfirmTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	claims, ok := GetClaimsFromContext(r.Context())
+	if !ok {
+		h.writeErrorResponse(w, http.StatusUnauthorized, "Missing authentication")
+		return
+	}
+
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	codes, err := h.service.ConfirmTwoFactorSetup(r.Context(), claims.UserID, req.Code)
+	if err != nil {
+		h.writeTwoFactorError(w, err)
+		return
+	}
+
+	log.Info().Str("user_id", claims.UserID).Msg("Two-factor authentication enabled")
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor handles POST /api/v1/company/employee/settings/security/2fa/disable
+func (h *Handler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims, ok := GetClaimsFromContext(r.Context())
+	if !ok {
+		h.writeErrorResponse(w, http.StatusUnauthorized, "Missing authentication")
+		return
+	}
+
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.service.DisableTwoFactor(r.Context(), claims.UserID, req.Code); err != nil {
+		h.writeTwoFactorError(w, err)
+		return
+	}
+
+	log.Info().Str("user_id", claims.UserID).Msg("Two-factor authentication disabled")
+	h.writeJSONResponse(w, http.StatusOK, map[string]string{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes handles POST /api/v1/company/employee/settings/security/2fa/recovery-codes
+func (h *Handler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	claims, ok := GetClaimsFromContext(r.Context())
+	if !ok {
+		h.writeErrorResponse(w, http.StatusUnauthorized, "Missing authentication")
+		return
+	}
+
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(r.Context(), claims.UserID, req.Code)
+	if err != nil {
+		h.writeTwoFactorError(w, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"recovery_codes": codes,
+	})
+}
+
+// GetTwoFactorPolicy handles GET /api/v1/company/admin/security/two-factor
+func (h *Handler) GetTwoFactorPolicy(w http.ResponseWriter, r *http.Request) {
+	claims, ok := GetClaimsFromContext(r.Context())
+	if !ok {
+		h.writeErrorResponse(w, http.StatusUnauthorized, "Missing authentication")
+		return
+	}
+
+	roles, err := h.service.GetTwoFactorPolicy(r.Context(), claims.TenantID)
+	if err != nil {
+		h.writeTwoFactorError(w, err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"required_roles": roles,
+	})
+}
+
+// UpdateTwoFactorPolicy handles PUT /api/v1/company/admin/security/two-factor
+func (h *Handler) UpdateTwoFactorPolicy(w http.ResponseWriter, r *http.Request) {
+	claims, ok := GetClaimsFromContext(r.Context())
+	if !ok {
+		h.writeErrorResponse(w, http.StatusUnauthorized, "Missing authentication")
+		return
+	}
+
+	var req twoFactorPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	roles, err := h.service.UpdateTwoFactorPolicy(r.Context(), claims.TenantID, req.RequiredRoles)
+	if err != nil {
+		h.writeTwoFactorError(w, err)
+		return
+	}
+
+	log.Info().
+		Str("tenant_id", claims.TenantID).
+		Strs("required_roles", roles).
+		Msg("Two-factor policy updated")
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"required_roles": roles,
+	})
+}
+
+// writeTwoFactorError maps two-factor errors to responses
+func (h *Handler) writeTwoFactorError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidChallenge), errors.Is(err, ErrTwoFactorInvalidCode):
+		h.writeErrorResponse(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, ErrTwoFactorLocked):
+		h.writeErrorResponse(w, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, ErrUserInactive), errors.Is(err, ErrTwoFactorRequired), errors.Is(err, ErrIPNotAllowed):
+		h.writeErrorResponse(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrTwoFactorNotEnabled), errors.Is(err, ErrTwoFactorAlreadyEnabled),
+		errors.Is(err, ErrTwoFactorSetupNotStarted):
+		h.writeErrorResponse(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrInvalidTwoFactorRole):
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrUserNotFound), err.Error() == "organization not found":
+		h.writeErrorResponse(w, http.StatusNotFound, err.Error())
+	default:
+		log.Error().Err(err).Msg("Two-factor request failed")
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
@@ -0,0 +1,140 @@
+// Package biometric talks to attendance terminals. Each supported protocol has a Driver that
+// pulls the punches a device recorded in a time window; BiometricService feeds them through
+// its regular log processing.
+package biometric
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+// Event types stored in biometric_attendance_logs
+const (
+	EventCheckIn    = "check_in"
+	EventCheckOut   = "check_out"
+	EventBreakStart = "break_start"
+	EventBreakEnd   = "break_end"
+)
+
+// defaultTimeout applies when a device has no TimeoutSeconds configured
+const defaultTimeout = 30 * time.Second
+
+// Driver pulls attendance punches from a device.
+// FetchLogs returns the punches recorded between start and end, inclusive. Devices that keep
+// local time without a zone are read in the location of start. Only EmployeeCode, Timestamp,
+// EventType and Confidence of the returned logs are set.
+type Driver interface {
+	FetchLogs(ctx context.Context, device *models.BiometricDevice, start, end time.Time) ([]models.BiometricAttendanceLog, error)
+}
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Driver{
+		"TCP":    ZKTecoDriver{},
+		"ZK":     ZKTecoDriver{},
+		"ZKTECO": ZKTecoDriver{},
+		"HTTP":   HTTPDriver{},
+		"HTTPS":  HTTPDriver{},
+		"PUSH":   HTTPDriver{},
+		"MQTT":   MQTTDriver{},
+	}
+)
+
+// Register makes a driver available for a protocol name, replacing any existing one
+func Register(protocol string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	drivers[strings.ToUpper(strings.TrimSpace(protocol))] = driver
+}
+
+// DriverFor returns the driver of a device's protocol. Without a protocol, ZKTeco and eSSL
+// terminals default to the ZKTeco TCP driver.
+func DriverFor(config models.BiometricDeviceConfig) (Driver, error) {
+	protocol := strings.ToUpper(strings.TrimSpace(config.Protocol))
+	if protocol == "" {
+		switch strings.ToUpper(strings.TrimSpace(config.Vendor)) {
+		case "ZKTECO", "ESSL":
+			protocol = "ZKTECO"
+		default:
+			return nil, fmt.Errorf("device has no protocol configured")
+		}
+	}
+
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	driver, ok := drivers[protocol]
+	if !ok {
+		return nil, fmt.Errorf("unsupported device protocol: %s", config.Protocol)
+	}
+	return driver, nil
+}
+
+// deviceTimeout returns the network timeout configured for a device
+func deviceTimeout(device *models.BiometricDevice) time.Duration {
+	if device.Config.TimeoutSeconds > 0 {
+		return time.Duration(device.Config.TimeoutSeconds) * time.Second
+	}
+	return defaultTimeout
+}
+
+// deviceAddress returns host:port of a device, using defaultPort when none is set
+func deviceAddress(device *models.BiometricDevice, defaultPort int) (string, error) {
+	if device.IPAddress == nil || *device.IPAddress == "" {
+		return "", fmt.Errorf("device has no IP address configured")
+	}
+	host := *device.IPAddress
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i] // INET columns may carry a netmask
+	}
+	port := defaultPort
+	if device.Port != nil && *device.Port > 0 {
+		port = *device.Port
+	}
+	if strings.Contains(host, ":") {
+		return fmt.Sprintf("[%s]:%d", host, port), nil
+	}
+	return fmt.Sprintf("%s:%d", host, port), nil
+}
+
+// punchRecord is the JSON form of a punch used by the HTTP and MQTT drivers
+type punchRecord struct {
+	EmployeeCode string    `json:"employee_code"`
+	Timestamp    time.Time `json:"timestamp"`
+	EventType    string    `json:"event_type"`
+	Confidence   *float64  `json:"confidence,omitempty"`
+}
+
+func (p punchRecord) toLog() models.BiometricAttendanceLog {
+	return models.BiometricAttendanceLog{
+		EmployeeCode: strings.TrimSpace(p.EmployeeCode),
+		Timestamp:    p.Timestamp,
+		EventType:    NormalizeEventType(p.EventType),
+		Confidence:   p.Confidence,
+	}
+}
+
+// NormalizeEventType maps the punch names used by terminals to the stored event types.
+// Unknown names are returned unchanged.
+func NormalizeEventType(eventType string) string {
+	switch strings.ToLower(strings.TrimSpace(eventType)) {
+	case "check_in", "checkin", "in", "0", "ot_in", "overtime_in":
+		return EventCheckIn
+	case "check_out", "checkout", "out", "1", "ot_out", "overtime_out":
+		return EventCheckOut
+	case "break_start", "break_out", "2":
+		return EventBreakStart
+	case "break_end", "break_in", "3":
+		return EventBreakEnd
+	}
+	return eventType
+}
+
+// inWindow reports whether t lies between start and end, inclusive
+func inWindow(t, start, end time.Time) bool {
+	return !t.Before(start) && !t.After(end)
+}
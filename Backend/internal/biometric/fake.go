@@ -0,0 +1,443 @@
+package biometric
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+// The fake devices below run in-process on a loopback port and speak the same protocol as the
+// real terminals, so device sync can be exercised without hardware. Each Device() returns a
+// device configured to reach the fake.
+
+// FakePunch is a punch stored on a fake device
+type FakePunch struct {
+	EmployeeCode string
+	Timestamp    time.Time
+	EventType    string
+}
+
+// fakeLog is the punch buffer shared by the fakes
+type fakeLog struct {
+	mu      sync.Mutex
+	punches []FakePunch
+}
+
+// AddPunch records a punch on the device
+func (l *fakeLog) AddPunch(employeeCode string, timestamp time.Time, eventType string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.punches = append(l.punches, FakePunch{EmployeeCode: employeeCode, Timestamp: timestamp, EventType: eventType})
+}
+
+func (l *fakeLog) snapshot() []FakePunch {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]FakePunch(nil), l.punches...)
+}
+
+func fakeDevice(serial, protocol string) *models.BiometricDevice {
+	now := time.Now()
+	return &models.BiometricDevice{
+		ID:           uuid.New(),
+		DeviceName:   "Fake " + protocol + " terminal",
+		DeviceType:   models.DeviceTypeFingerprint,
+		SerialNumber: serial,
+		Status:       models.DeviceStatusActive,
+		IsActive:     true,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Config: models.BiometricDeviceConfig{
+			Protocol:       protocol,
+			Vendor:         "Fake",
+			Model:          "Fake",
+			TimeoutSeconds: 5,
+		},
+	}
+}
+
+// FakeZKDevice is a ZKTeco-compatible terminal listening on TCP
+type FakeZKDevice struct {
+	fakeLog
+	Serial   string
+	CommKey  uint32
+	Location *time.Location
+
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+// NewFakeZKDevice starts a fake terminal. A non-zero comm key makes it require authentication.
+// Punch times are stored as local time in loc, like a real terminal.
+func NewFakeZKDevice(serial string, commKey uint32, loc *time.Location) (*FakeZKDevice, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	d := &FakeZKDevice{Serial: serial, CommKey: commKey, Location: loc, listener: listener}
+	d.wg.Add(1)
+	go d.serve()
+	return d, nil
+}
+
+// Device returns a device configured to reach the fake terminal
+func (d *FakeZKDevice) Device() *models.BiometricDevice {
+	addr := d.listener.Addr().(*net.TCPAddr)
+	device := fakeDevice(d.Serial, "TCP")
+	ip, port := addr.IP.String(), addr.Port
+	device.IPAddress, device.Port = &ip, &port
+	device.Config.Vendor = "ZKTeco"
+	if d.CommKey != 0 {
+		key := strconv.FormatUint(uint64(d.CommKey), 10)
+		device.Config.APIKey = &key
+	}
+	return device
+}
+
+// Close stops the fake terminal
+func (d *FakeZKDevice) Close() error {
+	err := d.listener.Close()
+	d.wg.Wait()
+	return err
+}
+
+func (d *FakeZKDevice) serve() {
+	defer d.wg.Done()
+	for {
+		conn, err := d.listener.Accept()
+		if err != nil {
+			return
+		}
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			defer conn.Close()
+			d.handle(conn)
+		}()
+	}
+}
+
+func (d *FakeZKDevice) handle(conn net.Conn) {
+	session := uint16(time.Now().UnixNano()%0xFFFE) + 1
+	authenticated := d.CommKey == 0
+
+	reply := func(request *zkPacket, command uint16, data []byte) error {
+		return writeZKPacket(conn, zkPacket{Command: command, Session: session, Reply: request.Reply, Data: data})
+	}
+
+	for {
+		conn.SetDeadline(time.Now().Add(30 * time.Second))
+		request, err := readZKPacket(conn)
+		if err != nil {
+			return
+		}
+
+		switch request.Command {
+		case zkCmdConnect:
+			if authenticated {
+				err = reply(request, zkCmdAckOK, nil)
+			} else {
+				err = reply(request, zkCmdAckUnauth, nil)
+			}
+		case zkCmdAuth:
+			authenticated = string(request.Data) == string(zkCommKey(d.CommKey, session, 50))
+			if authenticated {
+				err = reply(request, zkCmdAckOK, nil)
+			} else {
+				err = reply(request, zkCmdAckUnauth, nil)
+			}
+		case zkCmdAttLogRRQ:
+			if !authenticated {
+				err = reply(request, zkCmdAckUnauth, nil)
+				break
+			}
+			data := d.attendanceLog()
+			size := make([]byte, 4)
+			binary.LittleEndian.PutUint32(size, uint32(len(data)))
+			if err = reply(request, zkCmdPrepareData, size); err != nil {
+				return
+			}
+			for offset := 0; offset < len(data) && err == nil; offset += 1024 {
+				chunk := data[offset:min(offset+1024, len(data))]
+				err = reply(request, zkCmdData, chunk)
+			}
+			if err == nil {
+				err = reply(request, zkCmdAckOK, nil)
+			}
+		case zkCmdFreeData:
+			err = reply(request, zkCmdAckOK, nil)
+		case zkCmdExit:
+			reply(request, zkCmdAckOK, nil)
+			return
+		default:
+			err = reply(request, zkCmdAckError, nil)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// attendanceLog encodes the stored punches in the terminal's record format
+func (d *FakeZKDevice) attendanceLog() []byte {
+	punches := d.snapshot()
+	records := make([]byte, 0, len(punches)*zkRecordSize)
+	for i, punch := range punches {
+		record := make([]byte, zkRecordSize)
+		binary.LittleEndian.PutUint16(record[0:], uint16(i+1))
+		copy(record[2:26], punch.EmployeeCode)
+		record[26] = 1 // fingerprint
+		binary.LittleEndian.PutUint32(record[27:], encodeZKTime(punch.Timestamp.In(d.Location)))
+		record[31] = zkPunchState(punch.EventType)
+		records = append(records, record...)
+	}
+
+	data := make([]byte, 4, 4+len(records))
+	binary.LittleEndian.PutUint32(data, uint32(len(records)))
+	return append(data, records...)
+}
+
+// FakeHTTPDevice serves its punch buffer the way HTTPDriver expects
+type FakeHTTPDevice struct {
+	fakeLog
+	Serial string
+	APIKey string
+
+	server *httptest.Server
+}
+
+// NewFakeHTTPDevice starts a fake HTTP terminal. A non-empty API key is required as bearer token.
+func NewFakeHTTPDevice(serial, apiKey string) *FakeHTTPDevice {
+	d := &FakeHTTPDevice{Serial: serial, APIKey: apiKey}
+	d.server = httptest.NewServer(http.HandlerFunc(d.serveHTTP))
+	return d
+}
+
+// Device returns a device configured to reach the fake terminal
+func (d *FakeHTTPDevice) Device() *models.BiometricDevice {
+	device := fakeDevice(d.Serial, "HTTP")
+	endpoint := d.server.URL + "/attendance"
+	device.Config.APIEndpoint = &endpoint
+	if d.APIKey != "" {
+		key := d.APIKey
+		device.Config.APIKey = &key
+	}
+	return device
+}
+
+// Close stops the fake terminal
+func (d *FakeHTTPDevice) Close() {
+	d.server.Close()
+}
+
+func (d *FakeHTTPDevice) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/attendance" {
+		http.NotFound(w, r)
+		return
+	}
+	if d.APIKey != "" && r.Header.Get("Authorization") != "Bearer "+d.APIKey {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	start, err := time.Parse(time.RFC3339, r.URL.Query().Get("start"))
+	if err != nil {
+		http.Error(w, "Invalid start", http.StatusBadRequest)
+		return
+	}
+	end, err := time.Parse(time.RFC3339, r.URL.Query().Get("end"))
+	if err != nil {
+		http.Error(w, "Invalid end", http.StatusBadRequest)
+		return
+	}
+
+	records := make([]punchRecord, 0)
+	for _, punch := range d.snapshot() {
+		if inWindow(punch.Timestamp, start, end) {
+			records = append(records, punchRecord{
+				EmployeeCode: punch.EmployeeCode,
+				Timestamp:    punch.Timestamp,
+				EventType:    punch.EventType,
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"records": records,
+	})
+}
+
+// FakeMQTTDevice is a terminal publishing punches to an in-process MQTT broker. The broker
+// queues punches until a subscriber acknowledges them, like a broker holding a persistent session.
+type FakeMQTTDevice struct {
+	Serial string
+	Topic  string
+
+	listener net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	queue    [][]byte
+}
+
+// NewFakeMQTTDevice starts a fake broker for a terminal publishing to attendance/<serial>
+func NewFakeMQTTDevice(serial string) (*FakeMQTTDevice, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	d := &FakeMQTTDevice{Serial: serial, Topic: "attendance/" + serial, listener: listener}
+	d.wg.Add(1)
+	go d.serve()
+	return d, nil
+}
+
+// AddPunch publishes a punch to the device topic
+func (d *FakeMQTTDevice) AddPunch(employeeCode string, timestamp time.Time, eventType string) {
+	payload, _ := json.Marshal(punchRecord{EmployeeCode: employeeCode, Timestamp: timestamp, EventType: eventType})
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queue = append(d.queue, payload)
+}
+
+// Pending returns the number of punches not yet acknowledged by a subscriber
+func (d *FakeMQTTDevice) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.queue)
+}
+
+// Device returns a device configured to reach the fake broker
+func (d *FakeMQTTDevice) Device() *models.BiometricDevice {
+	device := fakeDevice(d.Serial, "MQTT")
+	endpoint := "mqtt://" + d.listener.Addr().String() + "/" + d.Topic
+	device.Config.APIEndpoint = &endpoint
+	return device
+}
+
+// Close stops the fake broker
+func (d *FakeMQTTDevice) Close() error {
+	err := d.listener.Close()
+	d.wg.Wait()
+	return err
+}
+
+func (d *FakeMQTTDevice) serve() {
+	defer d.wg.Done()
+	for {
+		conn, err := d.listener.Accept()
+		if err != nil {
+			return
+		}
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			defer conn.Close()
+			d.handle(conn)
+		}()
+	}
+}
+
+func (d *FakeMQTTDevice) handle(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	inflight := make(map[uint16][]byte)
+	var nextID uint16
+
+	for {
+		conn.SetDeadline(time.Now().Add(30 * time.Second))
+		header, body, err := readMQTTPacket(reader)
+		if err != nil {
+			return
+		}
+
+		switch header >> 4 {
+		case mqttConnect:
+			err = writeMQTTPacket(conn, mqttConnAck<<4, []byte{0, 0})
+		case mqttSubscribe:
+			if len(body) < 5 {
+				return
+			}
+			topicLen := int(binary.BigEndian.Uint16(body[2:]))
+			if 4+topicLen > len(body) {
+				return
+			}
+			topic := string(body[4 : 4+topicLen])
+			granted := byte(1)
+			if topic != d.Topic && topic != "#" && !(strings.HasSuffix(topic, "/#") && strings.HasPrefix(d.Topic, strings.TrimSuffix(topic, "#"))) {
+				granted = 0x80
+			}
+			if err = writeMQTTPacket(conn, mqttSubAck<<4, []byte{body[0], body[1], granted}); err != nil || granted == 0x80 {
+				break
+			}
+
+			d.mu.Lock()
+			queued := append([][]byte(nil), d.queue...)
+			d.mu.Unlock()
+			for _, payload := range queued {
+				nextID++
+				inflight[nextID] = payload
+				publish := mqttString(d.Topic)
+				publish = binary.BigEndian.AppendUint16(publish, nextID)
+				publish = append(publish, payload...)
+				if err = writeMQTTPacket(conn, mqttPublish<<4|0x02, publish); err != nil {
+					break
+				}
+			}
+		case mqttPubAck:
+			if len(body) >= 2 {
+				d.acknowledge(inflight[binary.BigEndian.Uint16(body)])
+			}
+		case mqttPublish:
+			qos := (header >> 1) & 0x03
+			payload, packetID, perr := parseMQTTPublish(body, qos)
+			if perr != nil {
+				return
+			}
+			d.mu.Lock()
+			d.queue = append(d.queue, payload)
+			d.mu.Unlock()
+			if qos > 0 {
+				err = writeMQTTPacket(conn, mqttPubAck<<4, packetID)
+			}
+		case mqttPingReq:
+			err = writeMQTTPacket(conn, mqttPingResp<<4, nil)
+		case mqttDisconnect:
+			return
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// acknowledge removes a delivered punch from the queue
+func (d *FakeMQTTDevice) acknowledge(payload []byte) {
+	if payload == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, queued := range d.queue {
+		if string(queued) == string(payload) {
+			d.queue = append(d.queue[:i], d.queue[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package biometric
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+// HTTPDefaultPort is used when an HTTP device has no APIEndpoint and no port
+const HTTPDefaultPort = 80
+
+// maxHTTPResponse limits the attendance log read from an HTTP device
+const maxHTTPResponse = 32 << 20
+
+// HTTPDriver reads the punch buffer of terminals running in HTTP push mode. Pushed punches are
+// kept on the terminal or its vendor gateway; sync backfills whatever was not delivered by
+// requesting GET APIEndpoint?start=..&end=.. with the APIKey as a bearer token. The response is
+// {"records": [...]} or a bare array of punches with employee_code, timestamp, event_type and
+// an optional confidence.
+type HTTPDriver struct {
+	Client *http.Client
+}
+
+// FetchLogs implements Driver
+func (d HTTPDriver) FetchLogs(ctx context.Context, device *models.BiometricDevice, start, end time.Time) ([]models.BiometricAttendanceLog, error) {
+	endpoint, err := httpEndpoint(device)
+	if err != nil {
+		return nil, err
+	}
+
+	query := endpoint.Query()
+	query.Set("start", start.Format(time.RFC3339))
+	query.Set("end", end.Format(time.RFC3339))
+	query.Set("serial_number", device.SerialNumber)
+	endpoint.RawQuery = query.Encode()
+
+	ctx, cancel := context.WithTimeout(ctx, deviceTimeout(device))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create device request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if device.Config.APIKey != nil && *device.Config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+*device.Config.APIKey)
+	}
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to device: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("device returned %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPResponse))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device response: %w", err)
+	}
+
+	records, err := decodePunchRecords(body)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make([]models.BiometricAttendanceLog, 0, len(records))
+	for _, record := range records {
+		if inWindow(record.Timestamp, start, end) {
+			logs = append(logs, record.toLog())
+		}
+	}
+	return logs, nil
+}
+
+// httpEndpoint returns the configured APIEndpoint, or the device's own address
+func httpEndpoint(device *models.BiometricDevice) (*url.URL, error) {
+	if device.Config.APIEndpoint != nil && *device.Config.APIEndpoint != "" {
+		endpoint, err := url.Parse(*device.Config.APIEndpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return nil, fmt.Errorf("invalid device API endpoint")
+		}
+		return endpoint, nil
+	}
+
+	addr, err := deviceAddress(device, HTTPDefaultPort)
+	if err != nil {
+		return nil, fmt.Errorf("device has no API endpoint or IP address configured")
+	}
+	return &url.URL{Scheme: "http", Host: addr, Path: "/attendance"}, nil
+}
+
+// decodePunchRecords accepts {"records": [...]} or a bare array
+func decodePunchRecords(body []byte) ([]punchRecord, error) {
+	var wrapped struct {
+		Records []punchRecord `json:"records"`
+	}
+	if err := json.Unmarshal(body, &wrapped); err == nil {
+		return wrapped.Records, nil
+	}
+
+	var records []punchRecord
+	if err := json.Unmarshal(body, &records); err != nil {
+		return nil, fmt.Errorf("invalid attendance data from device: %w", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,367 @@
+package biometric
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+// ZKTeco standalone protocol commands
+const (
+	zkCmdConnect     = 1000
+	zkCmdExit        = 1001
+	zkCmdAuth        = 1102
+	zkCmdAttLogRRQ   = 13
+	zkCmdPrepareData = 1500
+	zkCmdData        = 1501
+	zkCmdFreeData    = 1502
+	zkCmdAckOK       = 2000
+	zkCmdAckError    = 2001
+	zkCmdAckUnauth   = 2005
+)
+
+const (
+	// ZKTecoDefaultPort is the TCP port terminals listen on out of the box
+	ZKTecoDefaultPort = 4370
+
+	zkRecordSize = 40
+	zkMaxPacket  = 1 << 20
+)
+
+var zkMagic = []byte{0x50, 0x50, 0x82, 0x7d}
+
+// ZKTecoDriver pulls the attendance log of ZKTeco-compatible terminals (ZKTeco, eSSL and most
+// OEM variants) over their TCP protocol on IPAddress:Port. A numeric comm key configured on
+// the terminal goes in the device's APIKey.
+type ZKTecoDriver struct{}
+
+// FetchLogs implements Driver
+func (ZKTecoDriver) FetchLogs(ctx context.Context, device *models.BiometricDevice, start, end time.Time) ([]models.BiometricAttendanceLog, error) {
+	addr, err := deviceAddress(device, ZKTecoDefaultPort)
+	if err != nil {
+		return nil, err
+	}
+
+	var commKey uint32
+	if device.Config.APIKey != nil && strings.TrimSpace(*device.Config.APIKey) != "" {
+		key, err := strconv.ParseUint(strings.TrimSpace(*device.Config.APIKey), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("comm key must be numeric")
+		}
+		commKey = uint32(key)
+	}
+
+	timeout := deviceTimeout(device)
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to device: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	client := &zkClient{conn: conn, reply: 0xFFFF - 1}
+	if err := client.connect(commKey); err != nil {
+		return nil, err
+	}
+	defer client.command(zkCmdExit, nil)
+
+	data, err := client.readAttendance()
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := parseZKAttendance(data, start.Location())
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make([]models.BiometricAttendanceLog, 0, len(records))
+	for _, record := range records {
+		if inWindow(record.Timestamp, start, end) {
+			logs = append(logs, record)
+		}
+	}
+	return logs, nil
+}
+
+// zkClient is a session with a terminal
+type zkClient struct {
+	conn    net.Conn
+	session uint16
+	reply   uint16
+}
+
+// zkPacket is a decoded protocol packet
+type zkPacket struct {
+	Command uint16
+	Session uint16
+	Reply   uint16
+	Data    []byte
+}
+
+func (c *zkClient) connect(commKey uint32) error {
+	resp, err := c.command(zkCmdConnect, nil)
+	if err != nil {
+		return err
+	}
+	c.session = resp.Session
+
+	if resp.Command == zkCmdAckUnauth {
+		resp, err = c.command(zkCmdAuth, zkCommKey(commKey, c.session, 50))
+		if err != nil {
+			return err
+		}
+	}
+
+	switch resp.Command {
+	case zkCmdAckOK:
+		return nil
+	case zkCmdAckUnauth:
+		return fmt.Errorf("device rejected the comm key")
+	}
+	return fmt.Errorf("device refused the connection (reply %d)", resp.Command)
+}
+
+// readAttendance returns the raw attendance log, which the terminal sends inline or in chunks
+func (c *zkClient) readAttendance() ([]byte, error) {
+	resp, err := c.command(zkCmdAttLogRRQ, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.Command {
+	case zkCmdData:
+		return resp.Data, nil
+	case zkCmdAckOK:
+		return nil, nil
+	case zkCmdAckUnauth:
+		return nil, fmt.Errorf("device requires authentication")
+	case zkCmdPrepareData:
+	default:
+		return nil, fmt.Errorf("device refused to send the attendance log (reply %d)", resp.Command)
+	}
+
+	if len(resp.Data) < 4 {
+		return nil, fmt.Errorf("malformed attendance log size")
+	}
+	size := int(binary.LittleEndian.Uint32(resp.Data))
+	if size > zkMaxPacket*16 {
+		return nil, fmt.Errorf("attendance log of %d bytes is too large", size)
+	}
+
+	data := make([]byte, 0, size)
+	for len(data) < size {
+		packet, err := readZKPacket(c.conn)
+		if err != nil {
+			return nil, err
+		}
+		if packet.Command != zkCmdData {
+			return nil, fmt.Errorf("unexpected reply %d while reading the attendance log", packet.Command)
+		}
+		data = append(data, packet.Data...)
+	}
+
+	if packet, err := readZKPacket(c.conn); err == nil && packet.Command != zkCmdAckOK {
+		return nil, fmt.Errorf("unexpected reply %d after the attendance log", packet.Command)
+	}
+	c.command(zkCmdFreeData, nil)
+
+	return data, nil
+}
+
+// command sends a command and waits for its reply
+func (c *zkClient) command(command uint16, data []byte) (*zkPacket, error) {
+	c.reply++
+	if c.reply == 0xFFFF {
+		c.reply = 0
+	}
+	if err := writeZKPacket(c.conn, zkPacket{Command: command, Session: c.session, Reply: c.reply, Data: data}); err != nil {
+		return nil, fmt.Errorf("failed to send command to device: %w", err)
+	}
+	resp, err := readZKPacket(c.conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device reply: %w", err)
+	}
+	return resp, nil
+}
+
+// writeZKPacket frames a packet for TCP transport
+func writeZKPacket(w io.Writer, p zkPacket) error {
+	payload := make([]byte, 8, 8+len(p.Data))
+	binary.LittleEndian.PutUint16(payload[0:], p.Command)
+	binary.LittleEndian.PutUint16(payload[4:], p.Session)
+	binary.LittleEndian.PutUint16(payload[6:], p.Reply)
+	payload = append(payload, p.Data...)
+	binary.LittleEndian.PutUint16(payload[2:], zkChecksum(payload))
+
+	frame := make([]byte, 8, 8+len(payload))
+	copy(frame, zkMagic)
+	binary.LittleEndian.PutUint32(frame[4:], uint32(len(payload)))
+	frame = append(frame, payload...)
+
+	_, err := w.Write(frame)
+	return err
+}
+
+// readZKPacket reads one framed packet
+func readZKPacket(r io.Reader) (*zkPacket, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:4], zkMagic) {
+		return nil, fmt.Errorf("invalid packet header")
+	}
+
+	size := binary.LittleEndian.Uint32(header[4:])
+	if size < 8 || size > zkMaxPacket {
+		return nil, fmt.Errorf("invalid packet size %d", size)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	return &zkPacket{
+		Command: binary.LittleEndian.Uint16(payload[0:]),
+		Session: binary.LittleEndian.Uint16(payload[4:]),
+		Reply:   binary.LittleEndian.Uint16(payload[6:]),
+		Data:    payload[8:],
+	}, nil
+}
+
+// zkChecksum is the one's complement sum of the packet's 16-bit words, with the checksum field zeroed
+func zkChecksum(payload []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(payload); i += 2 {
+		if i == 2 {
+			continue
+		}
+		sum += uint32(binary.LittleEndian.Uint16(payload[i:]))
+		if sum > 0xFFFF {
+			sum -= 0xFFFF
+		}
+	}
+	if len(payload)%2 == 1 {
+		sum += uint32(payload[len(payload)-1])
+	}
+	for sum > 0xFFFF {
+		sum -= 0xFFFF
+	}
+	return uint16(^sum)
+}
+
+// zkCommKey scrambles the comm key with the session ID as terminals expect in CMD_AUTH
+func zkCommKey(key uint32, session uint16, ticks byte) []byte {
+	var k uint32
+	for i := 0; i < 32; i++ {
+		k <<= 1
+		if key&(1<<uint(i)) != 0 {
+			k |= 1
+		}
+	}
+	k += uint32(session)
+
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, k)
+	b[0] ^= 'Z'
+	b[1] ^= 'K'
+	b[2] ^= 'S'
+	b[3] ^= 'O'
+	b[0], b[1], b[2], b[3] = b[2], b[3], b[0], b[1]
+
+	return []byte{b[0] ^ ticks, b[1] ^ ticks, ticks, b[3] ^ ticks}
+}
+
+// parseZKAttendance decodes the attendance log: a 4-byte length followed by 40-byte records of
+// uid, user ID, verify mode, timestamp, punch state and padding
+func parseZKAttendance(data []byte, loc *time.Location) ([]models.BiometricAttendanceLog, error) {
+	if len(data) < 4 {
+		return nil, nil
+	}
+	size := int(binary.LittleEndian.Uint32(data))
+	data = data[4:]
+	if size > len(data) {
+		return nil, fmt.Errorf("truncated attendance log")
+	}
+	data = data[:size]
+	if len(data)%zkRecordSize != 0 {
+		return nil, fmt.Errorf("unsupported attendance record format")
+	}
+
+	logs := make([]models.BiometricAttendanceLog, 0, len(data)/zkRecordSize)
+	for offset := 0; offset < len(data); offset += zkRecordSize {
+		record := data[offset : offset+zkRecordSize]
+		userID := string(bytes.TrimRight(record[2:26], "\x00 "))
+		timestamp := decodeZKTime(binary.LittleEndian.Uint32(record[27:31]), loc)
+
+		logs = append(logs, models.BiometricAttendanceLog{
+			EmployeeCode: userID,
+			Timestamp:    timestamp,
+			EventType:    zkPunchEvent(record[31]),
+		})
+	}
+	return logs, nil
+}
+
+// zkPunchEvent maps a punch state to an event type. Overtime punches count as check in and out.
+func zkPunchEvent(punch byte) string {
+	switch punch {
+	case 1, 5:
+		return EventCheckOut
+	case 2:
+		return EventBreakStart
+	case 3:
+		return EventBreakEnd
+	}
+	return EventCheckIn
+}
+
+// zkPunchState is the inverse of zkPunchEvent
+func zkPunchState(eventType string) byte {
+	switch eventType {
+	case EventCheckOut:
+		return 1
+	case EventBreakStart:
+		return 2
+	case EventBreakEnd:
+		return 3
+	}
+	return 0
+}
+
+// decodeZKTime converts the terminal's packed local time
+func decodeZKTime(t uint32, loc *time.Location) time.Time {
+	second := int(t % 60)
+	t /= 60
+	minute := int(t % 60)
+	t /= 60
+	hour := int(t % 24)
+	t /= 24
+	day := int(t%31) + 1
+	t /= 31
+	month := time.Month(t%12 + 1)
+	t /= 12
+	return time.Date(int(t)+2000, month, day, hour, minute, second, 0, loc)
+}
+
+// encodeZKTime packs a local time the way terminals store it
+func encodeZKTime(t time.Time) uint32 {
+	days := ((t.Year()%100)*12*31 + (int(t.Month())-1)*31 + t.Day() - 1)
+	return uint32(days*24*60*60 + (t.Hour()*60+t.Minute())*60 + t.Second())
+}
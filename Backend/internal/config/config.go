@@ -56,6 +56,10 @@ type Config struct {
 	SMTPUsername string `json:"smtp_username"`
 	SMTPPassword string `json:"smtp_password"`
 	FromEmail    string `json:"from_email"`
+	AppURL       string `json:"app_url"` // frontend base URL used in email links
+
+	// Background jobs
+	SchedulerEnabled bool `json:"scheduler_enabled"`
 }
 
 func Load() (*Config, error) {
@@ -109,6 +113,10 @@ func Load() (*Config, error) {
 		SMTPUsername: getEnv("SMTP_USERNAME", ""),
 		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
 		FromEmail:    getEnv("FROM_EMAIL", "noreply@peopleos.com"),
+		AppURL:       getEnv("APP_URL", "http://localhost:3000"),
+
+		// Background jobs
+		SchedulerEnabled: getEnvAsBool("SCHEDULER_ENABLED", true),
 
 		// CORS configuration
 		AllowedOrigins: getEnvAsSlice("ALLOWED_ORIGINS", []string{"http://localhost:3000", "https://*.peopleos.com"}),
@@ -133,6 +141,15 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvAsSlice(key string, defaultValue []string) []string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -26,6 +26,7 @@ func NewAttendanceHandler(attendanceService *services.AttendanceService) *Attend
 }
 
 // CheckIn handles employee check-in
+// The body may carry the device's "latitude" and "longitude" and a "source" of web or mobile.
 func (h *AttendanceHandler) CheckIn(w http.ResponseWriter, r *http.Request) {
 	userClaims, ok := auth.GetClaimsFromContext(r.Context())
 	if !ok {
@@ -58,9 +59,13 @@ func (h *AttendanceHandler) CheckIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	record, err := h.attendanceService.CheckIn(r.Context(), tenantID, employeeID, req.Notes)
+	record, err := h.attendanceService.CheckInFromClient(r.Context(), tenantID, employeeID, req, clientIP(r))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		if err.Error() == "check-in is outside the allowed locations" {
+			http.Error(w, err.Error(), http.StatusForbidden)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
 		return
 	}
 
@@ -90,6 +95,13 @@ func (h *AttendanceHandler) CheckOut(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	if req.Source == "" {
+		req.Source = models.SourceWeb
+	}
+	if req.Source != models.SourceWeb && req.Source != models.SourceMobile {
+		http.Error(w, "invalid source, expected web or mobile", http.StatusBadRequest)
+		return
+	}
 
 	userID, err := uuid.Parse(userClaims.UserID)
 	if err != nil {
@@ -104,7 +116,7 @@ func (h *AttendanceHandler) CheckOut(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	record, err := h.attendanceService.CheckOut(r.Context(), tenantID, employeeID, req.Notes)
+	record, err := h.attendanceService.CheckOut(r.Context(), tenantID, employeeID, req.Notes, req.Source, nil)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -117,6 +129,61 @@ func (h *AttendanceHandler) CheckOut(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// StartBreak handles POST /api/v1/company/employee/attendance/break/start
+func (h *AttendanceHandler) StartBreak(w http.ResponseWriter, r *http.Request) {
+	h.recordBreak(w, r, true)
+}
+
+// EndBreak handles POST /api/v1/company/employee/attendance/break/end
+func (h *AttendanceHandler) EndBreak(w http.ResponseWriter, r *http.Request) {
+	h.recordBreak(w, r, false)
+}
+
+func (h *AttendanceHandler) recordBreak(w http.ResponseWriter, r *http.Request, start bool) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := uuid.Parse(userClaims.UserID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	employeeID, err := h.attendanceService.GetEmployeeIDByUserID(r.Context(), tenantID, userID)
+	if err != nil {
+		http.Error(w, "Employee record not found", http.StatusNotFound)
+		return
+	}
+
+	var attendanceBreak *models.AttendanceBreak
+	message := "Break started"
+	if start {
+		attendanceBreak, err = h.attendanceService.StartBreak(r.Context(), tenantID, employeeID)
+	} else {
+		attendanceBreak, err = h.attendanceService.EndBreak(r.Context(), tenantID, employeeID)
+		message = "Break ended"
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": message,
+		"break":   attendanceBreak,
+	})
+}
+
 // GetEmployeeAttendance gets attendance records for a specific employee
 func (h *AttendanceHandler) GetEmployeeAttendance(w http.ResponseWriter, r *http.Request) {
 	userClaims, ok := auth.GetClaimsFromContext(r.Context())
@@ -438,6 +505,154 @@ func (h *AttendanceHandler) UpdateAttendanceRecord(w http.ResponseWriter, r *htt
 	})
 }
 
+// ApproveAttendanceRecord handles PUT /api/v1/company/hr/attendance/records/{recordId}/approve
+// The body may set "approved" to false to withdraw an approval.
+func (h *AttendanceHandler) ApproveAttendanceRecord(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := uuid.Parse(userClaims.UserID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	recordID, err := uuid.Parse(chi.URLParam(r, "recordId"))
+	if err != nil {
+		http.Error(w, "Invalid record ID", http.StatusBadRequest)
+		return
+	}
+
+	req := struct {
+		Approved *bool `json:"approved"`
+	}{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	approved := req.Approved == nil || *req.Approved
+
+	err = h.attendanceService.ApproveAttendanceRecord(r.Context(), tenantID, recordID, userID, approved)
+	if err != nil {
+		if err.Error() == "attendance record not found" {
+			http.Error(w, "Attendance record not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Attendance record updated successfully",
+		"approved": approved,
+	})
+}
+
+// ReviewAttendanceRecord handles PUT /api/v1/company/hr/attendance/records/{recordId}/review
+// It confirms a record flagged for review: one the end-of-day close created or checked out
+// automatically, or a check-in from outside the attendance zones.
+func (h *AttendanceHandler) ReviewAttendanceRecord(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := uuid.Parse(userClaims.UserID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	recordID, err := uuid.Parse(chi.URLParam(r, "recordId"))
+	if err != nil {
+		http.Error(w, "Invalid record ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.attendanceService.ReviewAttendanceRecord(r.Context(), tenantID, recordID, userID)
+	if err != nil {
+		if err.Error() == "attendance record not found" {
+			http.Error(w, "Attendance record not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Attendance record reviewed successfully",
+	})
+}
+
+// CloseAttendanceDay handles POST /api/v1/company/hr/attendance/close-day
+// The body may name a past "date" (YYYY-MM-DD); it defaults to yesterday.
+func (h *AttendanceHandler) CloseAttendanceDay(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	req := struct {
+		Date string `json:"date"`
+	}{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var result *models.AttendanceDayCloseResult
+	if req.Date == "" {
+		result, err = h.attendanceService.ClosePreviousDay(r.Context(), tenantID)
+	} else {
+		date, parseErr := time.Parse("2006-01-02", req.Date)
+		if parseErr != nil {
+			http.Error(w, "Invalid date format (use YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		result, err = h.attendanceService.CloseAttendanceDay(r.Context(), tenantID, date)
+	}
+	if err != nil {
+		if err.Error() == "only past days can be closed" {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
 // GetDepartmentAttendance gets attendance records for the user's department (Manager only)
 func (h *AttendanceHandler) GetDepartmentAttendance(w http.ResponseWriter, r *http.Request) {
 	userClaims, ok := auth.GetClaimsFromContext(r.Context())
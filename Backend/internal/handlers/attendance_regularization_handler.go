@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/auth"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+// regularizationContext returns the tenant, user and role of the request
+func regularizationContext(r *http.Request) (uuid.UUID, uuid.UUID, string, error) {
+	claims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		return uuid.Nil, uuid.Nil, "", errors.New("unauthorized")
+	}
+	tenantID, err := uuid.Parse(claims.TenantID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, "", err
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, "", err
+	}
+	return tenantID, userID, claims.Role, nil
+}
+
+// CreateRegularization handles POST /api/v1/company/employee/attendance/regularizations
+func (h *AttendanceHandler) CreateRegularization(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, _, err := regularizationContext(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateRegularizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	employeeID, err := h.attendanceService.GetEmployeeIDByUserID(r.Context(), tenantID, userID)
+	if err != nil {
+		http.Error(w, "Employee record not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	regularization, err := h.attendanceService.CreateRegularization(r.Context(), tenantID, employeeID, req)
+	if err != nil {
+		writeRegularizationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(regularization)
+}
+
+// GetMyRegularizations handles GET /api/v1/company/employee/attendance/regularizations
+func (h *AttendanceHandler) GetMyRegularizations(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, _, err := regularizationContext(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	employeeID, err := h.attendanceService.GetEmployeeIDByUserID(r.Context(), tenantID, userID)
+	if err != nil {
+		http.Error(w, "Employee record not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	regularizations, err := h.attendanceService.GetRegularizations(r.Context(), tenantID, &employeeID, r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(regularizations)
+}
+
+// CancelRegularization handles PUT /api/v1/company/employee/attendance/regularizations/{id}/cancel
+func (h *AttendanceHandler) CancelRegularization(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, _, err := regularizationContext(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	requestID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid regularization request ID", http.StatusBadRequest)
+		return
+	}
+
+	employeeID, err := h.attendanceService.GetEmployeeIDByUserID(r.Context(), tenantID, userID)
+	if err != nil {
+		http.Error(w, "Employee record not found: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := h.attendanceService.CancelRegularization(r.Context(), tenantID, requestID, employeeID); err != nil {
+		writeRegularizationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Regularization request cancelled",
+	})
+}
+
+// GetPendingRegularizations handles GET /api/v1/company/employee/approvals/regularizations
+// Lists the regularization requests waiting on the current user, including delegated ones.
+func (h *AttendanceHandler) GetPendingRegularizations(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, role, err := regularizationContext(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	regularizations, err := h.attendanceService.GetPendingRegularizations(r.Context(), tenantID, userID, role)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get pending regularizations")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(regularizations)
+}
+
+// GetRegularizations handles GET /api/v1/company/hr/attendance/regularizations
+// An optional "status" query parameter filters the list.
+func (h *AttendanceHandler) GetRegularizations(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, _, err := regularizationContext(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	regularizations, err := h.attendanceService.GetRegularizations(r.Context(), tenantID, nil, r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(regularizations)
+}
+
+// ApproveRegularization handles PUT /api/v1/company/employee/approvals/regularizations/{id}/approve
+func (h *AttendanceHandler) ApproveRegularization(w http.ResponseWriter, r *http.Request) {
+	h.decideRegularization(w, r, true)
+}
+
+// RejectRegularization handles PUT /api/v1/company/employee/approvals/regularizations/{id}/reject
+func (h *AttendanceHandler) RejectRegularization(w http.ResponseWriter, r *http.Request) {
+	h.decideRegularization(w, r, false)
+}
+
+func (h *AttendanceHandler) decideRegularization(w http.ResponseWriter, r *http.Request, approve bool) {
+	tenantID, userID, role, err := regularizationContext(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	requestID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid regularization request ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.RegularizationDecisionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var regularization *models.AttendanceRegularization
+	if approve {
+		regularization, err = h.attendanceService.ApproveRegularization(r.Context(), tenantID, requestID, userID, role, req.Comment)
+	} else {
+		regularization, err = h.attendanceService.RejectRegularization(r.Context(), tenantID, requestID, userID, role, req.Comment)
+	}
+	if err != nil {
+		writeRegularizationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(regularization)
+}
+
+func writeRegularizationError(w http.ResponseWriter, err error) {
+	switch err.Error() {
+	case "regularization request not found", "employee not found":
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case "not authorized to act on this regularization request":
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
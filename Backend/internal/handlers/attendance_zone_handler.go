@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/auth"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+// clientIP returns the address of the client. The RealIP middleware has already replaced
+// RemoteAddr with the forwarded address when the request came through a proxy.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// zoneTenantID reads the tenant of the request, writing the error response when it is missing
+func zoneTenantID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, false
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return tenantID, true
+}
+
+// GetAttendanceZones handles GET /api/v1/company/hr/attendance/zones
+func (h *AttendanceHandler) GetAttendanceZones(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := zoneTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	zones, err := h.attendanceService.GetAttendanceZones(r.Context(), tenantID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(zones)
+}
+
+// CreateAttendanceZone handles POST /api/v1/company/hr/attendance/zones
+func (h *AttendanceHandler) CreateAttendanceZone(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := zoneTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AttendanceZone
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	zone, err := h.attendanceService.CreateAttendanceZone(r.Context(), tenantID, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(zone)
+}
+
+// UpdateAttendanceZone handles PUT /api/v1/company/hr/attendance/zones/{zoneId}
+func (h *AttendanceHandler) UpdateAttendanceZone(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := zoneTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	zoneID, err := uuid.Parse(chi.URLParam(r, "zoneId"))
+	if err != nil {
+		http.Error(w, "Invalid zone ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.AttendanceZone
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	zone, err := h.attendanceService.UpdateAttendanceZone(r.Context(), tenantID, zoneID, req)
+	if err != nil {
+		if err.Error() == "attendance zone not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(zone)
+}
+
+// DeleteAttendanceZone handles DELETE /api/v1/company/hr/attendance/zones/{zoneId}
+func (h *AttendanceHandler) DeleteAttendanceZone(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := zoneTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	zoneID, err := uuid.Parse(chi.URLParam(r, "zoneId"))
+	if err != nil {
+		http.Error(w, "Invalid zone ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.attendanceService.DeleteAttendanceZone(r.Context(), tenantID, zoneID); err != nil {
+		if err.Error() == "attendance zone not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Attendance zone deleted successfully",
+	})
+}
+
+// SetRemoteCheckIn handles PUT /api/v1/company/hr/attendance/employees/{employeeId}/remote-check-in
+// It exempts an employee from the attendance zones, optionally until a date.
+func (h *AttendanceHandler) SetRemoteCheckIn(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := zoneTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	employeeID, err := uuid.Parse(chi.URLParam(r, "employeeId"))
+	if err != nil {
+		http.Error(w, "Invalid employee ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.RemoteCheckInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.attendanceService.SetRemoteCheckIn(r.Context(), tenantID, employeeID, req); err != nil {
+		if err.Error() == "employee not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Remote check-in updated successfully",
+	})
+}
+
+// SetEmployeeAttendancePolicy handles PUT /api/v1/company/hr/attendance/employees/{employeeId}/policy
+func (h *AttendanceHandler) SetEmployeeAttendancePolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := zoneTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	employeeID, err := uuid.Parse(chi.URLParam(r, "employeeId"))
+	if err != nil {
+		http.Error(w, "Invalid employee ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.AttendancePolicyAssignmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.attendanceService.SetEmployeeAttendancePolicy(r.Context(), tenantID, employeeID, req); err != nil {
+		writePolicyAssignmentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Attendance policy assigned successfully",
+	})
+}
+
+// SetDepartmentAttendancePolicy handles PUT /api/v1/company/hr/attendance/departments/{departmentId}/policy
+func (h *AttendanceHandler) SetDepartmentAttendancePolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := zoneTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	departmentID, err := uuid.Parse(chi.URLParam(r, "departmentId"))
+	if err != nil {
+		http.Error(w, "Invalid department ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.AttendancePolicyAssignmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.attendanceService.SetDepartmentAttendancePolicy(r.Context(), tenantID, departmentID, req); err != nil {
+		writePolicyAssignmentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Attendance policy assigned successfully",
+	})
+}
+
+func writePolicyAssignmentError(w http.ResponseWriter, err error) {
+	switch err.Error() {
+	case "employee not found", "department not found", "attendance policy not found":
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case "invalid policy ID":
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
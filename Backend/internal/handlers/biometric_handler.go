@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -159,6 +162,10 @@ func (h *BiometricHandler) ProcessBiometricData(w http.ResponseWriter, r *http.R
 
 	err = h.biometricService.ProcessBiometricLog(r.Context(), tenantID, log)
 	if err != nil {
+		if errors.Is(err, services.ErrDuplicateBiometricLog) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -169,7 +176,7 @@ func (h *BiometricHandler) ProcessBiometricData(w http.ResponseWriter, r *http.R
 	})
 }
 
-// SyncDeviceData handles POST /api/v1/{tenantId}/biometric/devices/{deviceId}/sync
+// SyncDeviceData handles POST /api/v1/company/admin/biometric/devices/{deviceID}/sync
 func (h *BiometricHandler) SyncDeviceData(w http.ResponseWriter, r *http.Request) {
 	claims, ok := auth.GetClaimsFromContext(r.Context())
 	if !ok {
@@ -191,15 +198,30 @@ func (h *BiometricHandler) SyncDeviceData(w http.ResponseWriter, r *http.Request
 	}
 
 	var req models.BiometricSyncRequest
-	req.DeviceID = deviceID
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
 	}
+	req.DeviceID = deviceID
 
 	response, err := h.biometricService.SyncDeviceData(r.Context(), tenantID, req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if errors.Is(err, services.ErrDeviceNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		switch err.Error() {
+		case "device is not active", "end date is before start date":
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			if strings.HasPrefix(err.Error(), "unsupported device protocol") || strings.HasPrefix(err.Error(), "device has no") {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -307,3 +329,85 @@ func (h *BiometricHandler) SimulateBiometricEntry(w http.ResponseWriter, r *http
 		"confidence":    logEntry.Confidence,
 	})
 }
+
+// maxDevicePushBody limits the size of a pushed batch
+const maxDevicePushBody = 2 << 20
+
+// PushDeviceData handles POST /api/v1/devices/push
+// Called by biometric terminals, which authenticate with X-Device-Serial and either
+// X-Device-Secret or X-Signature (hex HMAC-SHA256 of "<X-Timestamp>.<body>") plus X-Timestamp.
+func (h *BiometricHandler) PushDeviceData(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxDevicePushBody))
+	if err != nil {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	creds := services.DevicePushCredentials{
+		SerialNumber: r.Header.Get("X-Device-Serial"),
+		Secret:       r.Header.Get("X-Device-Secret"),
+		Signature:    r.Header.Get("X-Signature"),
+		Timestamp:    r.Header.Get("X-Timestamp"),
+	}
+
+	device, err := h.biometricService.AuthenticateDevice(r.Context(), creds, body)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDeviceCredentials) {
+			http.Error(w, "Invalid device credentials", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var req models.DevicePushRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.biometricService.IngestDevicePunches(r.Context(), device, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RotateDeviceSecret handles POST /api/v1/company/admin/biometric/devices/{deviceID}/secret
+// Issues a new push secret, returned only in this response.
+func (h *BiometricHandler) RotateDeviceSecret(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(claims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	deviceID, err := uuid.Parse(chi.URLParam(r, "deviceID"))
+	if err != nil {
+		http.Error(w, "Invalid device ID", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.biometricService.RotateDeviceSecret(r.Context(), tenantID, deviceID)
+	if err != nil {
+		if errors.Is(err, services.ErrDeviceNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/auth"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/services"
+)
+
+// maxHolidayImportSize limits the size of an uploaded iCalendar file
+const maxHolidayImportSize = 2 << 20
+
+type HolidayHandler struct {
+	holidayService *services.HolidayService
+}
+
+func NewHolidayHandler(holidayService *services.HolidayService) *HolidayHandler {
+	return &HolidayHandler{
+		holidayService: holidayService,
+	}
+}
+
+// GetHolidays handles GET /api/v1/company/hr/holidays?year=2025&location=...
+func (h *HolidayHandler) GetHolidays(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	year := time.Now().Year()
+	if value := r.URL.Query().Get("year"); value != "" {
+		year, err = strconv.Atoi(value)
+		if err != nil || year < 1900 || year > 9999 {
+			http.Error(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+	}
+
+	holidays, err := h.holidayService.GetHolidays(r.Context(), tenantID, year, strings.TrimSpace(r.URL.Query().Get("location")))
+	if err != nil {
+		http.Error(w, "Failed to get holidays: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"year":     year,
+		"holidays": holidays,
+	})
+}
+
+// CreateHoliday handles POST /api/v1/company/hr/holidays
+func (h *HolidayHandler) CreateHoliday(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateHolidayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	holiday, err := h.holidayService.CreateHoliday(r.Context(), tenantID, req)
+	if err != nil {
+		http.Error(w, "Failed to create holiday: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(holiday)
+}
+
+// UpdateHoliday handles PUT /api/v1/company/hr/holidays/{id}
+func (h *HolidayHandler) UpdateHoliday(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	holidayID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid holiday ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateHolidayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	holiday, err := h.holidayService.UpdateHoliday(r.Context(), tenantID, holidayID, req)
+	if err != nil {
+		if err.Error() == "holiday not found" {
+			http.Error(w, "Holiday not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to update holiday: "+err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holiday)
+}
+
+// DeleteHoliday handles DELETE /api/v1/company/hr/holidays/{id}
+func (h *HolidayHandler) DeleteHoliday(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	holidayID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid holiday ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.holidayService.DeleteHoliday(r.Context(), tenantID, holidayID); err != nil {
+		if err.Error() == "holiday not found" {
+			http.Error(w, "Holiday not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to delete holiday: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Holiday deleted successfully",
+	})
+}
+
+// ImportHolidays handles POST /api/v1/company/hr/holidays/import
+// The calendar is sent either as a multipart "file" field or as a raw text/calendar body.
+// An optional "location" form or query value restricts the imported holidays to that location.
+func (h *HolidayHandler) ImportHolidays(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxHolidayImportSize)
+
+	var calendar io.Reader = r.Body
+	locationValue := r.URL.Query().Get("location")
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxHolidayImportSize); err != nil {
+			http.Error(w, "Invalid upload: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Missing calendar file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		calendar = file
+		if value := r.FormValue("location"); value != "" {
+			locationValue = value
+		}
+	}
+
+	var location *string
+	if value := strings.TrimSpace(locationValue); value != "" {
+		location = &value
+	}
+
+	result, err := h.holidayService.ImportICS(r.Context(), tenantID, calendar, location)
+	if err != nil {
+		http.Error(w, "Failed to import holidays: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
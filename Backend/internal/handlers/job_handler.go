@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/auth"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/scheduler"
+)
+
+// JobHandler lets super admins inspect and control background jobs
+type JobHandler struct {
+	scheduler *scheduler.Scheduler
+}
+
+func NewJobHandler(s *scheduler.Scheduler) *JobHandler {
+	return &JobHandler{scheduler: s}
+}
+
+// ListJobs handles GET /api/v1/platform/jobs
+func (h *JobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	jobs, err := h.scheduler.ListJobs(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// GetJobRuns handles GET /api/v1/platform/jobs/{name}/runs
+func (h *JobHandler) GetJobRuns(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	runs, err := h.scheduler.GetJobRuns(r.Context(), chi.URLParam(r, "name"), limit)
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+// TriggerJob handles POST /api/v1/platform/jobs/{name}/trigger
+func (h *JobHandler) TriggerJob(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if err := h.scheduler.TriggerJob(r.Context(), name, jobUserID(r)); err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Job " + name + " triggered"})
+}
+
+// PauseJob handles POST /api/v1/platform/jobs/{name}/pause
+func (h *JobHandler) PauseJob(w http.ResponseWriter, r *http.Request) {
+	h.setPaused(w, r, true)
+}
+
+// ResumeJob handles POST /api/v1/platform/jobs/{name}/resume
+func (h *JobHandler) ResumeJob(w http.ResponseWriter, r *http.Request) {
+	h.setPaused(w, r, false)
+}
+
+func (h *JobHandler) setPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	job, err := h.scheduler.SetJobPaused(r.Context(), chi.URLParam(r, "name"), paused, jobUserID(r))
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// jobUserID returns the acting user, if the claims carry a valid one
+func jobUserID(r *http.Request) *uuid.UUID {
+	claims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		return nil
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil
+	}
+	return &userID
+}
+
+func writeJobError(w http.ResponseWriter, err error) {
+	switch err.Error() {
+	case "job not found":
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case "scheduler is not running":
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
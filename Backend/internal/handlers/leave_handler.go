@@ -3,7 +3,10 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/auth"
 	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
@@ -190,6 +193,8 @@ func (h *LeaveHandler) GetPendingLeaves(w http.ResponseWriter, r *http.Request)
 }
 
 // ApproveLeave handles PUT /api/leaves/{id}/approve
+// The caller must be the approver of the request's current step, a delegate of that approver,
+// or a member of the approving role. The request is approved once its last step is approved.
 func (h *LeaveHandler) ApproveLeave(w http.ResponseWriter, r *http.Request) {
 	tenantID, userID, role, err := h.getContextInfo(r)
 	if err != nil {
@@ -197,16 +202,50 @@ func (h *LeaveHandler) ApproveLeave(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Only managers, HR, and admins can approve leaves
-	if role != "manager" && role != "hr" && role != "admin" && role != "super_admin" {
-		http.Error(w, "Unauthorized", http.StatusForbidden)
+	leaveIDStr := chi.URLParam(r, "leaveID") // Updated param name to match server.go
+	if leaveIDStr == "" {
+		leaveIDStr = chi.URLParam(r, "id") // Fallback
+	}
+
+	leaveID, err := uuid.Parse(leaveIDStr)
+	if err != nil {
+		http.Error(w, "Invalid leave ID", http.StatusBadRequest)
+		return
+	}
+
+	// The approval comment is optional
+	var req models.ApproveLeaveRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	status, err := h.leaveService.ApproveLeaveRequest(r.Context(), *tenantID, leaveID, *userID, role, req.ApproverNotes)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to approve leave request")
+		writeLeaveDecisionError(w, err)
 		return
 	}
 
-	// Get Approver Employee ID
-	approverID, err := h.leaveService.GetEmployeeIDByUserID(r.Context(), *tenantID, *userID)
+	message := "Leave request approved successfully"
+	if status == models.LeaveStatusPending {
+		message = "Approval recorded, leave request forwarded to the next approver"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": message,
+		"status":  string(status),
+	})
+}
+
+// RejectLeave handles PUT /api/leaves/{id}/reject
+func (h *LeaveHandler) RejectLeave(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, role, err := h.getContextInfo(r)
 	if err != nil {
-		http.Error(w, "Approver employee record not found", http.StatusForbidden)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -221,67 +260,325 @@ func (h *LeaveHandler) ApproveLeave(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.leaveService.ApproveLeaveRequest(r.Context(), *tenantID, leaveID, approverID)
+	var req models.RejectLeaveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err = h.leaveService.RejectLeaveRequest(r.Context(), *tenantID, leaveID, *userID, role, req.RejectionReason)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to approve leave request")
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		log.Error().Err(err).Msg("Failed to reject leave request")
+		writeLeaveDecisionError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Leave request approved successfully",
+		"message": "Leave request rejected successfully",
 	})
 }
 
-// RejectLeave handles PUT /api/leaves/{id}/reject
-func (h *LeaveHandler) RejectLeave(w http.ResponseWriter, r *http.Request) {
-	tenantID, userID, role, err := h.getContextInfo(r)
+// CancelLeave handles PUT /api/v1/company/employee/leaves/{id}/cancel
+// Withdraws a pending request, or cancels approved leave that has not started yet.
+func (h *LeaveHandler) CancelLeave(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, _, err := h.getContextInfo(r)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Only managers, HR, and admins can reject leaves
-	if role != "manager" && role != "hr" && role != "admin" && role != "super_admin" {
-		http.Error(w, "Unauthorized", http.StatusForbidden)
+	employeeID, err := h.leaveService.GetEmployeeIDByUserID(r.Context(), *tenantID, *userID)
+	if err != nil {
+		http.Error(w, "Employee record not found", http.StatusForbidden)
 		return
 	}
 
-	// Get Approver Employee ID
-	approverID, err := h.leaveService.GetEmployeeIDByUserID(r.Context(), *tenantID, *userID)
+	leaveID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Approver employee record not found", http.StatusForbidden)
+		http.Error(w, "Invalid leave ID", http.StatusBadRequest)
 		return
 	}
 
-	leaveIDStr := chi.URLParam(r, "leaveID") // Updated param name to match server.go
-	if leaveIDStr == "" {
-		leaveIDStr = chi.URLParam(r, "id") // Fallback
+	// The cancellation reason is optional
+	var req models.CancelLeaveRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
 	}
 
-	leaveID, err := uuid.Parse(leaveIDStr)
+	if err := h.leaveService.CancelLeaveRequest(r.Context(), *tenantID, employeeID, leaveID, req.Reason); err != nil {
+		log.Error().Err(err).Msg("Failed to cancel leave request")
+		writeLeaveDecisionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Leave request cancelled successfully",
+	})
+}
+
+// ModifyLeave handles POST /api/v1/company/employee/leaves/{id}/modify
+// Submits new dates for approved leave. The modification goes through approval and replaces
+// the original leave once approved.
+func (h *LeaveHandler) ModifyLeave(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, _, err := h.getContextInfo(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	employeeID, err := h.leaveService.GetEmployeeIDByUserID(r.Context(), *tenantID, *userID)
+	if err != nil {
+		http.Error(w, "Employee record not found", http.StatusForbidden)
+		return
+	}
+
+	leaveID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
 		http.Error(w, "Invalid leave ID", http.StatusBadRequest)
 		return
 	}
 
-	var req models.RejectLeaveRequest
+	var req models.ModifyLeaveRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	err = h.leaveService.RejectLeaveRequest(r.Context(), *tenantID, leaveID, approverID, req.RejectionReason)
+	leave, err := h.leaveService.ModifyLeaveRequest(r.Context(), *tenantID, employeeID, leaveID, req)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to reject leave request")
+		log.Error().Err(err).Msg("Failed to modify leave request")
+		writeLeaveDecisionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(leave)
+}
+
+// writeLeaveDecisionError maps approval errors to HTTP status codes
+func writeLeaveDecisionError(w http.ResponseWriter, err error) {
+	switch err.Error() {
+	case "leave request not found":
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case "not authorized to act on this leave request":
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// GetPendingApprovals handles GET /api/v1/company/employee/approvals
+// Lists the leave requests waiting on the current user, including those delegated to them.
+func (h *LeaveHandler) GetPendingApprovals(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, role, err := h.getContextInfo(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	leaves, err := h.leaveService.GetPendingApprovals(r.Context(), *tenantID, *userID, role)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get pending approvals")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(leaves)
+}
+
+// GetApprovalWorkflows handles GET /api/v1/company/hr/leaves/workflows
+func (h *LeaveHandler) GetApprovalWorkflows(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, _, err := h.getContextInfo(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	workflows, err := h.leaveService.GetApprovalWorkflows(r.Context(), *tenantID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get approval workflows")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"workflows": workflows,
+	})
+}
+
+// CreateApprovalWorkflow handles POST /api/v1/company/hr/leaves/workflows
+func (h *LeaveHandler) CreateApprovalWorkflow(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, _, err := h.getContextInfo(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateLeaveApprovalWorkflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	workflow, err := h.leaveService.CreateApprovalWorkflow(r.Context(), *tenantID, req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create approval workflow")
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(workflow)
+}
+
+// UpdateApprovalWorkflow handles PUT /api/v1/company/hr/leaves/workflows/{workflowId}
+func (h *LeaveHandler) UpdateApprovalWorkflow(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, _, err := h.getContextInfo(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	workflowID, err := uuid.Parse(chi.URLParam(r, "workflowId"))
+	if err != nil {
+		http.Error(w, "Invalid workflow ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateLeaveApprovalWorkflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	workflow, err := h.leaveService.UpdateApprovalWorkflow(r.Context(), *tenantID, workflowID, req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to update approval workflow")
+		if err.Error() == "approval workflow not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(workflow)
+}
+
+// DeleteApprovalWorkflow handles DELETE /api/v1/company/hr/leaves/workflows/{workflowId}
+func (h *LeaveHandler) DeleteApprovalWorkflow(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, _, err := h.getContextInfo(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	workflowID, err := uuid.Parse(chi.URLParam(r, "workflowId"))
+	if err != nil {
+		http.Error(w, "Invalid workflow ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.leaveService.DeleteApprovalWorkflow(r.Context(), *tenantID, workflowID); err != nil {
+		log.Error().Err(err).Msg("Failed to delete approval workflow")
+		if err.Error() == "approval workflow not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Leave request rejected successfully",
+		"message": "Approval workflow deleted successfully",
+	})
+}
+
+// GetApprovalDelegations handles GET /api/v1/company/employee/approvals/delegations
+func (h *LeaveHandler) GetApprovalDelegations(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, _, err := h.getContextInfo(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	delegations, err := h.leaveService.GetApprovalDelegations(r.Context(), *tenantID, *userID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get approval delegations")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"delegations": delegations,
+	})
+}
+
+// CreateApprovalDelegation handles POST /api/v1/company/employee/approvals/delegations
+func (h *LeaveHandler) CreateApprovalDelegation(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, _, err := h.getContextInfo(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateApprovalDelegationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	delegation, err := h.leaveService.CreateApprovalDelegation(r.Context(), *tenantID, *userID, req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create approval delegation")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(delegation)
+}
+
+// RevokeApprovalDelegation handles DELETE /api/v1/company/employee/approvals/delegations/{delegationId}
+func (h *LeaveHandler) RevokeApprovalDelegation(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, _, err := h.getContextInfo(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	delegationID, err := uuid.Parse(chi.URLParam(r, "delegationId"))
+	if err != nil {
+		http.Error(w, "Invalid delegation ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.leaveService.RevokeApprovalDelegation(r.Context(), *tenantID, delegationID, *userID); err != nil {
+		log.Error().Err(err).Msg("Failed to revoke approval delegation")
+		if err.Error() == "approval delegation not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Approval delegation revoked successfully",
 	})
 }
 
@@ -316,3 +613,192 @@ func (h *LeaveHandler) CreateLeaveType(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(leaveType)
 }
+
+// parseLeaveYear reads the optional ?year= query parameter, defaulting to the current year
+func parseLeaveYear(r *http.Request) (int, error) {
+	yearStr := r.URL.Query().Get("year")
+	if yearStr == "" {
+		return time.Now().Year(), nil
+	}
+	return strconv.Atoi(yearStr)
+}
+
+// GetMyLeaveBalances handles GET /api/v1/company/employee/leaves/balances
+func (h *LeaveHandler) GetMyLeaveBalances(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, _, err := h.getContextInfo(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	employeeID, err := h.leaveService.GetEmployeeIDByUserID(r.Context(), *tenantID, *userID)
+	if err != nil {
+		http.Error(w, "Employee record not found", http.StatusForbidden)
+		return
+	}
+
+	year, err := parseLeaveYear(r)
+	if err != nil {
+		http.Error(w, "Invalid year", http.StatusBadRequest)
+		return
+	}
+
+	balances, err := h.leaveService.GetLeaveBalances(r.Context(), *tenantID, employeeID, year)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get leave balances")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"year":     year,
+		"balances": balances,
+	})
+}
+
+// GetEmployeeLeaveBalances handles GET /api/v1/company/hr/leaves/balances/{employeeId}
+func (h *LeaveHandler) GetEmployeeLeaveBalances(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, _, err := h.getContextInfo(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	employeeID, err := uuid.Parse(chi.URLParam(r, "employeeId"))
+	if err != nil {
+		http.Error(w, "Invalid employee ID", http.StatusBadRequest)
+		return
+	}
+
+	year, err := parseLeaveYear(r)
+	if err != nil {
+		http.Error(w, "Invalid year", http.StatusBadRequest)
+		return
+	}
+
+	balances, err := h.leaveService.GetLeaveBalances(r.Context(), *tenantID, employeeID, year)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get leave balances")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ledger, err := h.leaveService.GetLeaveLedger(r.Context(), *tenantID, employeeID, year)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get leave ledger")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"year":     year,
+		"balances": balances,
+		"ledger":   ledger,
+	})
+}
+
+// AdjustLeaveBalance handles POST /api/v1/company/hr/leaves/balances/{employeeId}/adjustments
+func (h *LeaveHandler) AdjustLeaveBalance(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, _, err := h.getContextInfo(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	employeeID, err := uuid.Parse(chi.URLParam(r, "employeeId"))
+	if err != nil {
+		http.Error(w, "Invalid employee ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.LeaveAdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.leaveService.AdjustLeaveBalance(r.Context(), *tenantID, employeeID, *userID, req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to adjust leave balance")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// RunLeaveAccrual handles POST /api/v1/company/hr/leaves/accruals/run
+func (h *LeaveHandler) RunLeaveAccrual(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, _, err := h.getContextInfo(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.LeaveAccrualRunRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	asOf := time.Now()
+	if req.AsOf != "" {
+		asOf, err = time.Parse("2006-01-02", req.AsOf)
+		if err != nil {
+			http.Error(w, "Invalid as_of format (use YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	written, err := h.leaveService.RunLeaveAccrual(r.Context(), *tenantID, asOf)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to run leave accrual")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":         "Leave accrual completed",
+		"entries_written": written,
+	})
+}
+
+// RunYearEndRollover handles POST /api/v1/company/hr/leaves/rollover
+func (h *LeaveHandler) RunYearEndRollover(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, _, err := h.getContextInfo(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.LeaveRolloverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Year == 0 || req.Year >= time.Now().Year() {
+		http.Error(w, "Year must be a closed year", http.StatusBadRequest)
+		return
+	}
+
+	written, err := h.leaveService.RunYearEndRollover(r.Context(), *tenantID, req.Year)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to run leave rollover")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":         "Leave year rolled over",
+		"entries_written": written,
+	})
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/auth"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/services"
+)
+
+const (
+	// notificationPollInterval is how often a stream looks for notifications written by other
+	// API instances, whose publishes do not reach this one
+	notificationPollInterval = 15 * time.Second
+	// notificationHeartbeat keeps idle streams from being closed by proxies
+	notificationHeartbeat = 25 * time.Second
+	// notificationWriteTimeout bounds each write to a stream
+	notificationWriteTimeout = 30 * time.Second
+	// notificationStreamMargin ends a stream this long before its request deadline, so the
+	// client reconnects instead of seeing the request time out
+	notificationStreamMargin = 5 * time.Second
+)
+
+// NotificationHandler serves the in-app notification inbox of the current user
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+}
+
+func NewNotificationHandler(notificationService *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+// notificationContext returns the tenant and user of the request
+func notificationContext(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	claims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		return uuid.Nil, uuid.Nil, errors.New("unauthorized")
+	}
+	tenantID, err := uuid.Parse(claims.TenantID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	return tenantID, userID, nil
+}
+
+// GetNotifications handles GET /api/v1/company/employee/notifications
+// Pass unread=true to list only unread notifications.
+func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, err := notificationContext(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notifications, total, unread, err := h.notificationService.GetNotifications(r.Context(), tenantID, userID, unreadOnly, page, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get notifications")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"notifications": notifications,
+		"total":         total,
+		"unread_count":  unread,
+		"page":          page,
+		"limit":         limit,
+		"has_more":      page*limit < total,
+	})
+}
+
+// GetUnreadCount handles GET /api/v1/company/employee/notifications/unread-count
+func (h *NotificationHandler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, err := notificationContext(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	unread, err := h.notificationService.GetUnreadNotificationCount(r.Context(), tenantID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"unread_count": unread,
+	})
+}
+
+// MarkRead handles PUT /api/v1/company/employee/notifications/{id}/read
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, err := notificationContext(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	notificationID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid notification ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.notificationService.MarkNotificationRead(r.Context(), tenantID, userID, notificationID); err != nil {
+		if err.Error() == "notification not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Notification marked as read",
+	})
+}
+
+// MarkAllRead handles PUT /api/v1/company/employee/notifications/read-all
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, err := notificationContext(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	marked, err := h.notificationService.MarkAllNotificationsRead(r.Context(), tenantID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Notifications marked as read",
+		"marked":  marked,
+	})
+}
+
+// Stream handles GET /api/v1/company/employee/notifications/stream
+// It sends the user's new notifications as Server-Sent Events: "notification" events carry a
+// notification and its ID, and "unread_count" events the number of unread notifications. A
+// stream ends before the request timeout; EventSource clients reconnect with the Last-Event-ID
+// header and receive what they missed. Other clients may pass last_event_id instead.
+func (h *NotificationHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, err := notificationContext(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	ctx := r.Context()
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	var after *uuid.UUID
+	if lastEventID != "" {
+		id, err := uuid.Parse(lastEventID)
+		if err != nil {
+			http.Error(w, "Invalid last event ID", http.StatusBadRequest)
+			return
+		}
+		after = &id
+	}
+
+	// Subscribe before reading the cursor so nothing published in between is missed
+	wake, unsubscribe := h.notificationService.Subscribe(userID)
+	defer unsubscribe()
+
+	cursor, err := h.notificationService.GetNotificationCursor(ctx, tenantID, userID, after)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to start notification stream")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string) bool {
+		rc.SetWriteDeadline(time.Now().Add(notificationWriteTimeout))
+		if _, err := fmt.Fprint(w, event); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	// sendNew sends the notifications created after the cursor, then the unread count
+	sendNew := func(always bool) bool {
+		notifications, err := h.notificationService.GetNotificationsAfter(ctx, tenantID, userID, cursor)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get new notifications")
+			return ctx.Err() == nil
+		}
+		for _, n := range notifications {
+			if !send(notificationEvent(n)) {
+				return false
+			}
+			cursor = services.NotificationCursor{CreatedAt: n.CreatedAt, ID: n.ID}
+		}
+		if len(notifications) == 0 && !always {
+			return true
+		}
+		unread, err := h.notificationService.GetUnreadNotificationCount(ctx, tenantID, userID)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to count unread notifications")
+			return ctx.Err() == nil
+		}
+		return send(fmt.Sprintf("event: unread_count\ndata: {\"unread_count\":%d}\n\n", unread))
+	}
+
+	if !send(fmt.Sprintf("retry: %d\n\n", time.Second.Milliseconds())) || !sendNew(true) {
+		return
+	}
+
+	var end <-chan time.Time
+	if deadline, ok := ctx.Deadline(); ok {
+		timer := time.NewTimer(time.Until(deadline) - notificationStreamMargin)
+		defer timer.Stop()
+		end = timer.C
+	}
+	poll := time.NewTicker(notificationPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(notificationHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-end:
+			return
+		case <-wake:
+			if !sendNew(false) {
+				return
+			}
+		case <-poll.C:
+			if !sendNew(false) {
+				return
+			}
+		case <-heartbeat.C:
+			if !send(": keep-alive\n\n") {
+				return
+			}
+		}
+	}
+}
+
+// notificationEvent formats a notification as a Server-Sent Event
+func notificationEvent(n models.Notification) string {
+	data, _ := json.Marshal(n)
+	return fmt.Sprintf("id: %s\nevent: notification\ndata: %s\n\n", n.ID, data)
+}
@@ -0,0 +1,424 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/auth"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/services"
+)
+
+// payrollClaims returns the tenant and user of the request
+func payrollClaims(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userClaims.UserID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return tenantID, userID, true
+}
+
+func writePayrollRunError(w http.ResponseWriter, err error) {
+	switch {
+	case err.Error() == "payroll run not found":
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.Contains(err.Error(), "already exists"), strings.HasPrefix(err.Error(), "payroll run is"),
+		strings.HasPrefix(err.Error(), "only a"):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// GetPayrollRuns handles GET /api/v1/company/hr/payroll-runs?year=2025
+func (h *PayslipHandler) GetPayrollRuns(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := payrollClaims(w, r)
+	if !ok {
+		return
+	}
+
+	year := 0
+	if value := r.URL.Query().Get("year"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+		year = parsed
+	}
+
+	runs, err := h.PayslipService.GetPayrollRuns(r.Context(), tenantID, year)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"payroll_runs": runs,
+	})
+}
+
+// GetPayrollRun handles GET /api/v1/company/hr/payroll-runs/{runId}
+func (h *PayslipHandler) GetPayrollRun(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := payrollClaims(w, r)
+	if !ok {
+		return
+	}
+
+	runID, err := uuid.Parse(chi.URLParam(r, "runId"))
+	if err != nil {
+		http.Error(w, "Invalid payroll run ID", http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.PayslipService.GetPayrollRun(r.Context(), tenantID, runID)
+	if err != nil {
+		writePayrollRunError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+// CreatePayrollRun handles POST /api/v1/company/hr/payroll-runs
+// Generates draft payslips for every employee of the month, or for the department or employees given.
+func (h *PayslipHandler) CreatePayrollRun(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := payrollClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreatePayrollRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.PayslipService.CreatePayrollRun(r.Context(), tenantID, userID, req)
+	if err != nil {
+		writePayrollRunError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+// GeneratePayrollRun handles POST /api/v1/company/hr/payroll-runs/{runId}/generate
+// Re-runs a draft payroll run, optionally only for a department or a list of employees.
+func (h *PayslipHandler) GeneratePayrollRun(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := payrollClaims(w, r)
+	if !ok {
+		return
+	}
+
+	runID, err := uuid.Parse(chi.URLParam(r, "runId"))
+	if err != nil {
+		http.Error(w, "Invalid payroll run ID", http.StatusBadRequest)
+		return
+	}
+
+	var filter models.PayrollRunFilter
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := h.PayslipService.GeneratePayrollRun(r.Context(), tenantID, runID, filter)
+	if err != nil {
+		writePayrollRunError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// LockPayrollRun handles POST /api/v1/company/hr/payroll-runs/{runId}/lock
+func (h *PayslipHandler) LockPayrollRun(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := payrollClaims(w, r)
+	if !ok {
+		return
+	}
+
+	runID, err := uuid.Parse(chi.URLParam(r, "runId"))
+	if err != nil {
+		http.Error(w, "Invalid payroll run ID", http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.PayslipService.LockPayrollRun(r.Context(), tenantID, runID, userID)
+	if err != nil {
+		writePayrollRunError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+// PublishPayrollRun handles POST /api/v1/company/hr/payroll-runs/{runId}/publish
+func (h *PayslipHandler) PublishPayrollRun(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := payrollClaims(w, r)
+	if !ok {
+		return
+	}
+
+	runID, err := uuid.Parse(chi.URLParam(r, "runId"))
+	if err != nil {
+		http.Error(w, "Invalid payroll run ID", http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.PayslipService.PublishPayrollRun(r.Context(), tenantID, runID, userID)
+	if err != nil {
+		writePayrollRunError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+// ReopenPayrollRun handles POST /api/v1/company/hr/payroll-runs/{runId}/reopen
+// A reason is required and the reopen is recorded in the audit log.
+func (h *PayslipHandler) ReopenPayrollRun(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := payrollClaims(w, r)
+	if !ok {
+		return
+	}
+
+	runID, err := uuid.Parse(chi.URLParam(r, "runId"))
+	if err != nil {
+		http.Error(w, "Invalid payroll run ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ReopenPayrollRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.PayslipService.ReopenPayrollRun(r.Context(), tenantID, runID, userID, req.Reason)
+	if err != nil {
+		writePayrollRunError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+// GetMyPayslips handles GET /api/v1/company/employee/payslips
+// Only published payslips of the current user are listed.
+func (h *PayslipHandler) GetMyPayslips(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := payrollClaims(w, r)
+	if !ok {
+		return
+	}
+
+	employeeID, err := h.PayslipService.GetEmployeeIDByUserID(r.Context(), tenantID, userID)
+	if err != nil {
+		http.Error(w, "Employee record not found", http.StatusNotFound)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 10
+	}
+
+	filter := &models.PayslipFilter{
+		EmployeeID:    &employeeID,
+		PublishedOnly: true,
+	}
+
+	payslips, totalCount, err := h.PayslipService.GetPayslipsByTenant(tenantID, filter, page, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"payslips": payslips,
+		"total":    totalCount,
+		"page":     page,
+		"limit":    limit,
+		"has_more": totalCount > page*limit,
+	})
+}
+
+// GetMyPayslip handles GET /api/v1/company/employee/payslips/{id}
+func (h *PayslipHandler) GetMyPayslip(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := payrollClaims(w, r)
+	if !ok {
+		return
+	}
+
+	payslipID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid payslip ID", http.StatusBadRequest)
+		return
+	}
+
+	employeeID, err := h.PayslipService.GetEmployeeIDByUserID(r.Context(), tenantID, userID)
+	if err != nil {
+		http.Error(w, "Employee record not found", http.StatusNotFound)
+		return
+	}
+
+	payslip, err := h.PayslipService.GetPayslipByID(tenantID, payslipID)
+	if err != nil || payslip.EmployeeID != employeeID || payslip.PublishedAt == nil {
+		http.Error(w, "Payslip not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payslip)
+}
+
+// GetMyPayslipPDF handles GET /api/v1/company/employee/payslips/{id}/pdf
+func (h *PayslipHandler) GetMyPayslipPDF(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := payrollClaims(w, r)
+	if !ok {
+		return
+	}
+
+	payslipID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid payslip ID", http.StatusBadRequest)
+		return
+	}
+
+	employeeID, err := h.PayslipService.GetEmployeeIDByUserID(r.Context(), tenantID, userID)
+	if err != nil {
+		http.Error(w, "Employee record not found", http.StatusNotFound)
+		return
+	}
+
+	payslip, err := h.PayslipService.GetPayslipByID(tenantID, payslipID)
+	if err != nil || payslip.EmployeeID != employeeID || payslip.PublishedAt == nil {
+		http.Error(w, "Payslip not found", http.StatusNotFound)
+		return
+	}
+
+	pdfBytes, err := h.PayslipService.GeneratePayslipPDF(r.Context(), payslip)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", services.PayslipFileName(payslip)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(pdfBytes)
+}
+
+// DownloadPayslips handles GET /api/v1/company/hr/payslips/download?start_date=2025-03-01&end_date=2025-03-31
+// Returns a zip archive with the PDF of every payslip of the pay period or of ?payroll_run_id=,
+// optionally limited to ?department_id=.
+func (h *PayslipHandler) DownloadPayslips(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := payrollClaims(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := &models.PayslipFilter{}
+
+	if value := query.Get("payroll_run_id"); value != "" {
+		runID, err := uuid.Parse(value)
+		if err != nil {
+			http.Error(w, "Invalid payroll run ID", http.StatusBadRequest)
+			return
+		}
+		filter.PayrollRunID = &runID
+	}
+
+	if value := query.Get("department_id"); value != "" {
+		departmentID, err := uuid.Parse(value)
+		if err != nil {
+			http.Error(w, "Invalid department ID", http.StatusBadRequest)
+			return
+		}
+		filter.DepartmentID = &departmentID
+	}
+
+	if value := query.Get("start_date"); value != "" {
+		startDate, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			http.Error(w, "Invalid start_date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		filter.PayPeriodStart = &startDate
+	}
+
+	if value := query.Get("end_date"); value != "" {
+		endDate, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			http.Error(w, "Invalid end_date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		filter.PayPeriodEnd = &endDate
+	}
+
+	if filter.PayrollRunID == nil && (filter.PayPeriodStart == nil || filter.PayPeriodEnd == nil) {
+		http.Error(w, "start_date and end_date, or payroll_run_id, are required", http.StatusBadRequest)
+		return
+	}
+
+	zipBytes, _, err := h.PayslipService.GeneratePayslipsZip(r.Context(), tenantID, filter)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "no payslips found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	name := "payslips.zip"
+	if filter.PayPeriodStart != nil && filter.PayPeriodEnd != nil {
+		name = fmt.Sprintf("payslips-%s-to-%s.zip", filter.PayPeriodStart.Format("2006-01-02"), filter.PayPeriodEnd.Format("2006-01-02"))
+	} else if filter.PayrollRunID != nil {
+		name = fmt.Sprintf("payslips-%s.zip", filter.PayrollRunID)
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", name))
+	w.WriteHeader(http.StatusOK)
+	w.Write(zipBytes)
+}
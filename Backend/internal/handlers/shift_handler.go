@@ -0,0 +1,379 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/auth"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/services"
+)
+
+type ShiftHandler struct {
+	shiftService *services.ShiftService
+}
+
+func NewShiftHandler(shiftService *services.ShiftService) *ShiftHandler {
+	return &ShiftHandler{
+		shiftService: shiftService,
+	}
+}
+
+// GetShiftTemplates handles GET /api/v1/company/hr/shifts/templates
+func (h *ShiftHandler) GetShiftTemplates(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	templates, err := h.shiftService.GetShiftTemplates(r.Context(), tenantID)
+	if err != nil {
+		http.Error(w, "Failed to get shift templates: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"templates": templates,
+	})
+}
+
+// CreateShiftTemplate handles POST /api/v1/company/hr/shifts/templates
+func (h *ShiftHandler) CreateShiftTemplate(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateShiftTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.shiftService.CreateShiftTemplate(r.Context(), tenantID, req)
+	if err != nil {
+		http.Error(w, "Failed to create shift template: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(template)
+}
+
+// UpdateShiftTemplate handles PUT /api/v1/company/hr/shifts/templates/{id}
+func (h *ShiftHandler) UpdateShiftTemplate(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	templateID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid shift template ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateShiftTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.shiftService.UpdateShiftTemplate(r.Context(), tenantID, templateID, req)
+	if err != nil {
+		if err.Error() == "shift template not found" {
+			http.Error(w, "Shift template not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to update shift template: "+err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(template)
+}
+
+// DeleteShiftTemplate handles DELETE /api/v1/company/hr/shifts/templates/{id}
+func (h *ShiftHandler) DeleteShiftTemplate(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	templateID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid shift template ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.shiftService.DeleteShiftTemplate(r.Context(), tenantID, templateID); err != nil {
+		if err.Error() == "shift template not found" {
+			http.Error(w, "Shift template not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to delete shift template: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetRoster handles GET /api/v1/company/hr/shifts/roster?week_start=YYYY-MM-DD&employee_id=
+func (h *ShiftHandler) GetRoster(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	weekStart, err := parseWeekStart(r.URL.Query().Get("week_start"))
+	if err != nil {
+		http.Error(w, "Invalid week_start format (use YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+
+	var employeeID *uuid.UUID
+	if employeeIDStr := r.URL.Query().Get("employee_id"); employeeIDStr != "" {
+		id, err := uuid.Parse(employeeIDStr)
+		if err != nil {
+			http.Error(w, "Invalid employee ID", http.StatusBadRequest)
+			return
+		}
+		employeeID = &id
+	}
+
+	roster, err := h.shiftService.GetRoster(r.Context(), tenantID, weekStart, weekStart.AddDate(0, 0, 6), employeeID, false)
+	if err != nil {
+		http.Error(w, "Failed to get roster: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"week_start": weekStart.Format("2006-01-02"),
+		"roster":     roster,
+	})
+}
+
+// CreateRoster handles POST /api/v1/company/hr/shifts/roster
+func (h *ShiftHandler) CreateRoster(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := uuid.Parse(userClaims.UserID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateRosterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	assignments, err := h.shiftService.CreateRoster(r.Context(), tenantID, userID, req)
+	if err != nil {
+		http.Error(w, "Failed to create roster: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "Roster draft saved",
+		"assignments": assignments,
+	})
+}
+
+// PublishRoster handles POST /api/v1/company/hr/shifts/roster/publish
+func (h *ShiftHandler) PublishRoster(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.PublishRosterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	weekStart, err := time.Parse("2006-01-02", req.WeekStart)
+	if err != nil {
+		http.Error(w, "Invalid week_start format (use YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+
+	published, err := h.shiftService.PublishRoster(r.Context(), tenantID, weekStart)
+	if err != nil {
+		http.Error(w, "Failed to publish roster: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "Roster published",
+		"published": published,
+	})
+}
+
+// SwapShifts handles POST /api/v1/company/hr/shifts/roster/swap
+func (h *ShiftHandler) SwapShifts(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.SwapShiftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	assignmentID, err := uuid.Parse(req.AssignmentID)
+	if err != nil {
+		http.Error(w, "Invalid assignment ID", http.StatusBadRequest)
+		return
+	}
+	otherAssignmentID, err := uuid.Parse(req.OtherAssignmentID)
+	if err != nil {
+		http.Error(w, "Invalid other assignment ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.shiftService.SwapShifts(r.Context(), tenantID, assignmentID, otherAssignmentID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to swap shifts: "+err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Shifts swapped successfully",
+	})
+}
+
+// GetMyShifts handles GET /api/v1/company/employee/shifts?week_start=YYYY-MM-DD
+func (h *ShiftHandler) GetMyShifts(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := auth.GetClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID, err := uuid.Parse(userClaims.TenantID)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := uuid.Parse(userClaims.UserID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	employeeID, err := h.shiftService.GetEmployeeIDByUserID(r.Context(), tenantID, userID)
+	if err != nil {
+		http.Error(w, "Employee record not found", http.StatusNotFound)
+		return
+	}
+
+	weekStart, err := parseWeekStart(r.URL.Query().Get("week_start"))
+	if err != nil {
+		http.Error(w, "Invalid week_start format (use YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+
+	shifts, err := h.shiftService.GetRoster(r.Context(), tenantID, weekStart, weekStart.AddDate(0, 0, 6), &employeeID, true)
+	if err != nil {
+		http.Error(w, "Failed to get shifts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"week_start": weekStart.Format("2006-01-02"),
+		"shifts":     shifts,
+	})
+}
+
+// parseWeekStart parses a YYYY-MM-DD week start, defaulting to the Monday of the current week
+func parseWeekStart(value string) (time.Time, error) {
+	if value != "" {
+		return time.Parse("2006-01-02", value)
+	}
+
+	now := time.Now()
+	offset := (int(now.Weekday()) + 6) % 7
+	monday := now.AddDate(0, 0, -offset)
+	return time.Date(monday.Year(), monday.Month(), monday.Day(), 0, 0, 0, 0, time.UTC), nil
+}
@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/auth"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
 	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/services"
 	"github.com/rs/zerolog/log"
 )
@@ -73,6 +75,27 @@ func (h *TenantHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 	delete(updates, "storage_used_mb")
 	delete(updates, "api_requests_count")
 
+	// Security defaults live in their own columns and are validated as a whole
+	if raw, ok := updates["security_defaults"]; ok {
+		delete(updates, "security_defaults")
+
+		var defaults models.SecurityDefaults
+		body, _ := json.Marshal(raw)
+		if err := json.Unmarshal(body, &defaults); err != nil {
+			http.Error(w, "Invalid security defaults", http.StatusBadRequest)
+			return
+		}
+		if err := h.organizationService.UpdateSecurityDefaults(r.Context(), tenantID, defaults, clientIP(r)); err != nil {
+			if errors.Is(err, services.ErrInvalidSecurityPolicy) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Error().Err(err).Str("tenant_id", claims.TenantID).Msg("Failed to update security defaults")
+			http.Error(w, "Failed to update configuration", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	updatedTenant, err := h.organizationService.UpdateOrganization(r.Context(), tenantID, updates)
 	if err != nil {
 		log.Error().Err(err).Str("tenant_id", claims.TenantID).Msg("Failed to update tenant config")
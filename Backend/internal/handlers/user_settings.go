@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -233,8 +234,12 @@ func (h *UserSettingsHandler) UpdateSecuritySettings(w http.ResponseWriter, r *h
 
 	settings.UserID = *userID
 
-	err = h.userSettingsService.UpdateSecuritySettings(*userID, settings)
+	err = h.userSettingsService.UpdateSecuritySettings(*userID, settings, clientIP(r))
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidSecurityPolicy) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,210 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+)
+
+// The fake server below is a minimal SMTP server that keeps the messages it receives instead of
+// delivering them. It stands in for a mail relay in development and tests: point SMTP_HOST and
+// SMTP_PORT at it and leave SMTP_USERNAME empty, as it offers neither STARTTLS nor AUTH.
+
+// ReceivedMessage is a message accepted by a FakeServer
+type ReceivedMessage struct {
+	From    string
+	To      []string
+	Subject string
+	Text    string
+	Raw     []byte
+}
+
+// FakeServer accepts SMTP connections on a local address
+type FakeServer struct {
+	listener  net.Listener
+	onMessage func(ReceivedMessage)
+
+	mu       sync.Mutex
+	messages []ReceivedMessage
+}
+
+// StartFakeServer listens on addr, such as "127.0.0.1:1025" or "127.0.0.1:0" for a free port.
+// onMessage, when not nil, is called for every message received.
+func StartFakeServer(addr string, onMessage func(ReceivedMessage)) (*FakeServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	s := &FakeServer{listener: listener, onMessage: onMessage}
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the address the server listens on
+func (s *FakeServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Messages returns the messages received so far
+func (s *FakeServer) Messages() []ReceivedMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ReceivedMessage(nil), s.messages...)
+}
+
+// Close stops accepting connections
+func (s *FakeServer) Close() error {
+	return s.listener.Close()
+}
+
+func (s *FakeServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *FakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	reply("220 localhost PeopleOS mail sink")
+	var from string
+	var to []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(line)
+		if i := strings.IndexByte(verb, ' '); i >= 0 {
+			verb = verb[:i]
+		}
+
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			from, to = smtpPath(line), nil
+			reply("250 OK")
+		case "RCPT":
+			to = append(to, smtpPath(line))
+			reply("250 OK")
+		case "DATA":
+			if from == "" || len(to) == 0 {
+				reply("503 MAIL and RCPT first")
+				continue
+			}
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			raw, err := readData(r)
+			if err != nil {
+				return
+			}
+			s.receive(from, to, raw)
+			from, to = "", nil
+			reply("250 OK")
+		case "RSET":
+			from, to = "", nil
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func (s *FakeServer) receive(from string, to []string, raw []byte) {
+	msg := ReceivedMessage{From: from, To: to, Raw: raw}
+	if parsed, err := mail.ReadMessage(bytes.NewReader(raw)); err == nil {
+		decoder := new(mime.WordDecoder)
+		if subject, err := decoder.DecodeHeader(parsed.Header.Get("Subject")); err == nil {
+			msg.Subject = subject
+		}
+		msg.Text = plainText(parsed.Header.Get("Content-Type"), parsed.Header.Get("Content-Transfer-Encoding"), parsed.Body)
+	}
+
+	s.mu.Lock()
+	s.messages = append(s.messages, msg)
+	s.mu.Unlock()
+
+	if s.onMessage != nil {
+		s.onMessage(msg)
+	}
+}
+
+// smtpPath extracts the address of a MAIL FROM:<...> or RCPT TO:<...> command
+func smtpPath(line string) string {
+	start, end := strings.IndexByte(line, '<'), strings.LastIndexByte(line, '>')
+	if start >= 0 && end > start {
+		return line[start+1 : end]
+	}
+	if i := strings.IndexByte(line, ':'); i >= 0 {
+		return strings.TrimSpace(line[i+1:])
+	}
+	return ""
+}
+
+// readData reads a DATA section up to the terminating dot line, undoing dot-stuffing
+func readData(r *bufio.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if line == ".\r\n" || line == ".\n" {
+			return buf.Bytes(), nil
+		}
+		buf.WriteString(strings.TrimPrefix(line, "."))
+	}
+}
+
+// plainText returns the text/plain content of a message body, looking into multipart bodies
+func plainText(contentType, encoding string, body io.Reader) string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		parts := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := parts.NextRawPart()
+			if err != nil {
+				return ""
+			}
+			text := plainText(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if text != "" {
+				return text
+			}
+		}
+	}
+	if mediaType != "text/plain" {
+		return ""
+	}
+
+	if strings.EqualFold(encoding, "quoted-printable") {
+		body = quotedprintable.NewReader(body)
+	}
+	text, _ := io.ReadAll(body)
+	return strings.ReplaceAll(string(text), "\r\n", "\n")
+}
@@ -0,0 +1,58 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Templates of the transactional emails. Each has a <name>.txt file defining "subject" and
+// "body", and a <name>.html file defining "body" that is rendered inside layout.html.
+const (
+	TemplateAccountCreated   = "account_created"
+	TemplatePasswordReset    = "password_reset"
+	TemplateLeaveSubmitted   = "leave_submitted"
+	TemplateLeaveApproved    = "leave_approved"
+	TemplateLeaveRejected    = "leave_rejected"
+	TemplatePayslipPublished = "payslip_published"
+	TemplateInvoiceIssued    = "invoice_issued"
+	TemplateNewDeviceLogin   = "new_device_login"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Render renders a template for a recipient. data is available to the templates as "."
+func Render(name, to string, data interface{}) (*Message, error) {
+	text, err := texttemplate.New(name+".txt").Option("missingkey=error").ParseFS(templateFS, "templates/"+name+".txt")
+	if err != nil {
+		return nil, fmt.Errorf("unknown email template %q: %w", name, err)
+	}
+
+	var subject, body bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render subject of %s: %w", name, err)
+	}
+	if err := text.ExecuteTemplate(&body, "body", data); err != nil {
+		return nil, fmt.Errorf("failed to render body of %s: %w", name, err)
+	}
+
+	html, err := htmltemplate.New("layout.html").Option("missingkey=error").ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html")
+	if err != nil {
+		return nil, fmt.Errorf("unknown email template %q: %w", name, err)
+	}
+	var htmlBody bytes.Buffer
+	if err := html.ExecuteTemplate(&htmlBody, "layout", data); err != nil {
+		return nil, fmt.Errorf("failed to render HTML of %s: %w", name, err)
+	}
+
+	return &Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(body.String()) + "\n",
+		HTML:    htmlBody.String(),
+	}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 // AttendancePolicy represents an attendance policy for a tenant
@@ -12,10 +13,13 @@ type AttendancePolicy struct {
 	TenantID                 uuid.UUID  `json:"tenant_id" db:"tenant_id"`
 	Name                     string     `json:"name" db:"name"`
 	WorkingHoursPerDay       float64    `json:"working_hours_per_day" db:"working_hours_per_day"`
+	WorkStartTime            string     `json:"work_start_time" db:"work_start_time"` // HH:MM in tenant timezone
 	WorkingDays              []string   `json:"working_days" db:"working_days"`
 	GracePeriodMinutes       int        `json:"grace_period_minutes" db:"grace_period_minutes"`
 	BreakDurationMinutes     int        `json:"break_duration_minutes" db:"break_duration_minutes"`
 	OvertimeThresholdMinutes int        `json:"overtime_threshold_minutes" db:"overtime_threshold_minutes"`
+	DefaultCheckoutTime      *string    `json:"default_checkout_time,omitempty" db:"default_checkout_time"` // HH:MM, closes forgotten check-outs
+	LocationEnforcement      string     `json:"location_enforcement" db:"location_enforcement"`             // off, flag or reject
 	IsDefault                bool       `json:"is_default" db:"is_default"`
 	IsActive                 bool       `json:"is_active" db:"is_active"`
 	CreatedAt                time.Time  `json:"created_at" db:"created_at"`
@@ -35,6 +39,8 @@ type AttendanceRecord struct {
 	TotalHours           *float64         `json:"total_hours" db:"total_hours"`
 	OvertimeHours        float64          `json:"overtime_hours" db:"overtime_hours"`
 	Status               string           `json:"status" db:"status"`
+	IsEarlyDeparture     bool             `json:"is_early_departure" db:"is_early_departure"`
+	IsExcessiveBreak     bool             `json:"is_excessive_break" db:"is_excessive_break"`
 	IsApproved           bool             `json:"is_approved" db:"is_approved"`
 	ApprovedBy           *uuid.UUID       `json:"approved_by" db:"approved_by"`
 	ApprovedAt           *time.Time       `json:"approved_at" db:"approved_at"`
@@ -42,26 +48,73 @@ type AttendanceRecord struct {
 	Source               AttendanceSource `json:"source" db:"source"`
 	DeviceID             *uuid.UUID       `json:"device_id,omitempty" db:"device_id"`
 	BiometricLogID       *uuid.UUID       `json:"biometric_log_id,omitempty" db:"biometric_log_id"`
+	ShiftAssignmentID    *uuid.UUID       `json:"shift_assignment_id,omitempty" db:"shift_assignment_id"`
+	CheckInLatitude      *float64         `json:"check_in_latitude,omitempty" db:"check_in_latitude"`
+	CheckInLongitude     *float64         `json:"check_in_longitude,omitempty" db:"check_in_longitude"`
+	CheckInIP            *string          `json:"check_in_ip,omitempty" db:"check_in_ip"`
+	CheckInZoneID        *uuid.UUID       `json:"check_in_zone_id,omitempty" db:"check_in_zone_id"`
+	NeedsReview          bool             `json:"needs_review" db:"needs_review"`
+	ReviewReason         *string          `json:"review_reason,omitempty" db:"review_reason"`
+	ReviewedBy           *uuid.UUID       `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt           *time.Time       `json:"reviewed_at,omitempty" db:"reviewed_at"`
 	CreatedAt            time.Time        `json:"created_at" db:"created_at"`
 	UpdatedAt            time.Time        `json:"updated_at" db:"updated_at"`
 
 	// Joined fields for API responses
-	EmployeeName string `json:"employee_name,omitempty" db:"employee_name"`
-	FirstName    string `json:"first_name,omitempty" db:"first_name"`
-	LastName     string `json:"last_name,omitempty" db:"last_name"`
-	Role         string `json:"role,omitempty" db:"role"`
+	EmployeeName string              `json:"employee_name,omitempty" db:"employee_name"`
+	FirstName    string              `json:"first_name,omitempty" db:"first_name"`
+	LastName     string              `json:"last_name,omitempty" db:"last_name"`
+	Role         string              `json:"role,omitempty" db:"role"`
+	Sessions     []AttendanceSession `json:"sessions,omitempty"`
+	Breaks       []AttendanceBreak   `json:"breaks,omitempty"`
+}
+
+// AttendanceSession is one check-in/check-out pair of an attendance day. The record holds the
+// first check-in and last check-out; CheckOutTime is nil while the session is open.
+type AttendanceSession struct {
+	ID                 uuid.UUID        `json:"id" db:"id"`
+	TenantID           uuid.UUID        `json:"tenant_id" db:"tenant_id"`
+	AttendanceRecordID uuid.UUID        `json:"attendance_record_id" db:"attendance_record_id"`
+	EmployeeID         uuid.UUID        `json:"employee_id" db:"employee_id"`
+	CheckInTime        time.Time        `json:"check_in_time" db:"check_in_time"`
+	CheckOutTime       *time.Time       `json:"check_out_time,omitempty" db:"check_out_time"`
+	Source             AttendanceSource `json:"source" db:"source"`
+	DeviceID           *uuid.UUID       `json:"device_id,omitempty" db:"device_id"`
+	BiometricLogID     *uuid.UUID       `json:"biometric_log_id,omitempty" db:"biometric_log_id"`
+	CreatedAt          time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// AttendanceBreak is a break taken during an attendance day. EndTime is nil while the break
+// is in progress.
+type AttendanceBreak struct {
+	ID                 uuid.UUID        `json:"id" db:"id"`
+	TenantID           uuid.UUID        `json:"tenant_id" db:"tenant_id"`
+	AttendanceRecordID uuid.UUID        `json:"attendance_record_id" db:"attendance_record_id"`
+	EmployeeID         uuid.UUID        `json:"employee_id" db:"employee_id"`
+	StartTime          time.Time        `json:"start_time" db:"start_time"`
+	EndTime            *time.Time       `json:"end_time,omitempty" db:"end_time"`
+	DurationMinutes    *int             `json:"duration_minutes,omitempty" db:"duration_minutes"`
+	Source             AttendanceSource `json:"source" db:"source"`
+	DeviceID           *uuid.UUID       `json:"device_id,omitempty" db:"device_id"`
+	CreatedAt          time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time        `json:"updated_at" db:"updated_at"`
 }
 
 // CheckInRequest represents a check-in request
 type CheckInRequest struct {
-	EmployeeID string `json:"employee_id,omitempty"`
-	Notes      string `json:"notes,omitempty"`
+	EmployeeID string           `json:"employee_id,omitempty"`
+	Notes      string           `json:"notes,omitempty"`
+	Source     AttendanceSource `json:"source,omitempty"` // web or mobile, defaults to web
+	Latitude   *float64         `json:"latitude,omitempty"`
+	Longitude  *float64         `json:"longitude,omitempty"`
 }
 
 // CheckOutRequest represents a check-out request
 type CheckOutRequest struct {
-	EmployeeID string `json:"employee_id,omitempty"`
-	Notes      string `json:"notes,omitempty"`
+	EmployeeID string           `json:"employee_id,omitempty"`
+	Notes      string           `json:"notes,omitempty"`
+	Source     AttendanceSource `json:"source,omitempty"` // web or mobile, defaults to web
 }
 
 // AttendanceStats represents attendance statistics
@@ -70,10 +123,62 @@ type AttendanceStats struct {
 	PresentToday     int     `json:"present_today"`
 	AbsentToday      int     `json:"absent_today"`
 	LateToday        int     `json:"late_today"`
+	OnHolidayToday   int     `json:"on_holiday_today"`
+	IsWorkingDay     bool    `json:"is_working_day"`
 	AverageHoursWeek float64 `json:"average_hours_week"`
 	AttendanceRate   float64 `json:"attendance_rate"`
 }
 
+// Reasons an attendance record is waiting for HR review
+const (
+	ReviewReasonAutoAbsent   = "auto_absent"
+	ReviewReasonAutoOnLeave  = "auto_on_leave"
+	ReviewReasonAutoCheckout = "auto_checkout"
+	ReviewReasonOutsideZone  = "outside_zone"
+)
+
+// Location enforcement modes of an attendance policy
+const (
+	LocationEnforcementOff    = "off"
+	LocationEnforcementFlag   = "flag"
+	LocationEnforcementReject = "reject"
+)
+
+// AttendanceZone is an office area web and mobile check-ins are accepted from: a circle around
+// a point, the IP ranges of the office network, or both
+type AttendanceZone struct {
+	ID              uuid.UUID      `json:"id" db:"id"`
+	TenantID        uuid.UUID      `json:"tenant_id" db:"tenant_id"`
+	Name            string         `json:"name" db:"name"`
+	WorkLocation    *string        `json:"work_location,omitempty" db:"work_location"` // applies to everybody when empty
+	Latitude        *float64       `json:"latitude,omitempty" db:"latitude"`
+	Longitude       *float64       `json:"longitude,omitempty" db:"longitude"`
+	RadiusMeters    *int           `json:"radius_meters,omitempty" db:"radius_meters"`
+	AllowedIPRanges pq.StringArray `json:"allowed_ip_ranges" db:"allowed_ip_ranges"` // CIDRs or single addresses
+	IsActive        bool           `json:"is_active" db:"is_active"`
+	CreatedAt       time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// RemoteCheckInRequest lets an employee check in outside the attendance zones
+type RemoteCheckInRequest struct {
+	Allowed bool    `json:"allowed"`
+	Until   *string `json:"until,omitempty"` // YYYY-MM-DD, open-ended when empty
+}
+
+// AttendancePolicyAssignmentRequest assigns an attendance policy to an employee or department
+type AttendancePolicyAssignmentRequest struct {
+	PolicyID *string `json:"policy_id"` // null or empty falls back to the department or tenant default
+}
+
+// AttendanceDayCloseResult reports what closing an attendance day changed
+type AttendanceDayCloseResult struct {
+	Date           string `json:"date"`
+	MarkedAbsent   int    `json:"marked_absent"`
+	MarkedOnLeave  int    `json:"marked_on_leave"`
+	AutoCheckedOut int    `json:"auto_checked_out"`
+}
+
 // AttendanceSummary represents a summary for an employee
 type AttendanceSummary struct {
 	EmployeeID         uuid.UUID `json:"employee_id"`
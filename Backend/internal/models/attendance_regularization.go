@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RegularizationType is what an attendance regularization request corrects
+type RegularizationType string
+
+const (
+	RegularizationMissedCheckIn  RegularizationType = "missed_check_in"
+	RegularizationMissedCheckOut RegularizationType = "missed_check_out"
+	RegularizationWrongTime      RegularizationType = "wrong_time"
+	RegularizationWorkFromHome   RegularizationType = "work_from_home"
+)
+
+// RegularizationStatus represents the state of a regularization request
+type RegularizationStatus string
+
+const (
+	RegularizationPending   RegularizationStatus = "pending"
+	RegularizationApproved  RegularizationStatus = "approved"
+	RegularizationRejected  RegularizationStatus = "rejected"
+	RegularizationCancelled RegularizationStatus = "cancelled"
+)
+
+// AttendanceRegularization is an employee's request to correct the attendance of a day
+type AttendanceRegularization struct {
+	ID                 uuid.UUID            `json:"id" db:"id"`
+	TenantID           uuid.UUID            `json:"tenant_id" db:"tenant_id"`
+	EmployeeID         uuid.UUID            `json:"employee_id" db:"employee_id"`
+	AttendanceRecordID *uuid.UUID           `json:"attendance_record_id,omitempty" db:"attendance_record_id"`
+	Date               time.Time            `json:"date" db:"date"`
+	Type               RegularizationType   `json:"type" db:"type"`
+	RequestedCheckIn   *time.Time           `json:"requested_check_in,omitempty" db:"requested_check_in"`
+	RequestedCheckOut  *time.Time           `json:"requested_check_out,omitempty" db:"requested_check_out"`
+	Reason             string               `json:"reason" db:"reason"`
+	Status             RegularizationStatus `json:"status" db:"status"`
+	ApproverUserID     *uuid.UUID           `json:"approver_user_id,omitempty" db:"approver_user_id"`
+	ApproverRole       *string              `json:"approver_role,omitempty" db:"approver_role"`
+	DecidedBy          *uuid.UUID           `json:"decided_by,omitempty" db:"decided_by"`
+	DecidedAt          *time.Time           `json:"decided_at,omitempty" db:"decided_at"`
+	DecisionComment    *string              `json:"decision_comment,omitempty" db:"decision_comment"`
+	OriginalValues     json.RawMessage      `json:"original_values,omitempty" db:"original_values"` // the record before approval, null when there was none
+	CreatedAt          time.Time            `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time            `json:"updated_at" db:"updated_at"`
+
+	// Joined fields for API responses
+	EmployeeName string `json:"employee_name,omitempty" db:"employee_name"`
+}
+
+// CreateRegularizationRequest is the body of a regularization request. Times are full
+// timestamps on the requested date; work from home defaults them to the scheduled day.
+type CreateRegularizationRequest struct {
+	Date     string             `json:"date"` // YYYY-MM-DD
+	Type     RegularizationType `json:"type"`
+	CheckIn  *time.Time         `json:"check_in,omitempty"`
+	CheckOut *time.Time         `json:"check_out,omitempty"`
+	Reason   string             `json:"reason"`
+}
+
+// RegularizationDecisionRequest carries the optional comment of an approval or rejection
+type RegularizationDecisionRequest struct {
+	Comment string `json:"comment"`
+}
+
+// RegularizationOriginalValues is the part of an attendance record a regularization replaces
+type RegularizationOriginalValues struct {
+	CheckInTime      *time.Time       `json:"check_in_time"`
+	CheckOutTime     *time.Time       `json:"check_out_time"`
+	TotalHours       *float64         `json:"total_hours"`
+	OvertimeHours    float64          `json:"overtime_hours"`
+	Status           string           `json:"status"`
+	IsEarlyDeparture bool             `json:"is_early_departure"`
+	Source           AttendanceSource `json:"source"`
+	Notes            *string          `json:"notes"`
+}
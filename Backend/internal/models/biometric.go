@@ -56,7 +56,7 @@ type BiometricDeviceConfig struct {
 	APIKey         *string `json:"api_key,omitempty"`
 	SyncInterval   int     `json:"sync_interval"` // in minutes
 	TimeoutSeconds int     `json:"timeout_seconds"`
-	Protocol       string  `json:"protocol"` // TCP, HTTP, MQTT, etc.
+	Protocol       string  `json:"protocol"` // TCP (ZKTeco), HTTP, MQTT
 	Vendor         string  `json:"vendor"`   // ZKTeco, eSSL, etc.
 	Model          string  `json:"model"`
 }
@@ -86,11 +86,13 @@ type BiometricAttendanceLog struct {
 type AttendanceSource string
 
 const (
-	SourceManual    AttendanceSource = "manual"
-	SourceBiometric AttendanceSource = "biometric"
-	SourceMobile    AttendanceSource = "mobile"
-	SourceWeb       AttendanceSource = "web"
-	SourceAPI       AttendanceSource = "api"
+	SourceManual      AttendanceSource = "manual"
+	SourceBiometric   AttendanceSource = "biometric"
+	SourceMobile      AttendanceSource = "mobile"
+	SourceWeb         AttendanceSource = "web"
+	SourceAPI         AttendanceSource = "api"
+	SourceSystem      AttendanceSource = "system"      // created by the end-of-day close
+	SourceRegularized AttendanceSource = "regularized" // patched by an approved regularization request
 )
 
 // BiometricSyncRequest represents a request to sync data from a biometric device
@@ -109,7 +111,7 @@ type BiometricSyncResponse struct {
 	RecordsSynced  int       `json:"records_synced"`
 	RecordsSkipped int       `json:"records_skipped"`
 	Errors         []string  `json:"errors,omitempty"`
-	Status         string    `json:"status"` // "success", "partial", "failed"
+	Status         string    `json:"status"` // "success", "partial", "failed", "skipped"
 }
 
 // DeviceRegistrationRequest represents a request to register a new biometric device
@@ -124,3 +126,33 @@ type DeviceRegistrationRequest struct {
 	Department   *string               `json:"department,omitempty"`
 	Config       BiometricDeviceConfig `json:"config"`
 }
+
+// DevicePunch is a single punch pushed by a device
+type DevicePunch struct {
+	EmployeeCode string    `json:"employee_code"`
+	Timestamp    time.Time `json:"timestamp"`
+	EventType    string    `json:"event_type"`
+	Confidence   *float64  `json:"confidence,omitempty"`
+}
+
+// DevicePushRequest represents a batch of punches pushed by a device
+type DevicePushRequest struct {
+	Punches []DevicePunch `json:"punches"`
+}
+
+// DevicePushResponse reports how a pushed batch was handled
+type DevicePushResponse struct {
+	Accepted   int      `json:"accepted"`
+	Duplicates int      `json:"duplicates"`
+	Rejected   int      `json:"rejected"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// DeviceSecretResponse returns a newly issued push secret. The secret is only shown once.
+type DeviceSecretResponse struct {
+	DeviceID                uuid.UUID  `json:"device_id"`
+	SerialNumber            string     `json:"serial_number"`
+	Secret                  string     `json:"secret"`
+	RotatedAt               time.Time  `json:"rotated_at"`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"`
+}
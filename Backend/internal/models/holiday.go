@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Holiday is a public holiday in a tenant's calendar. Holidays without a location
+// apply to all employees.
+type Holiday struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	TenantID    uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	Name        string    `json:"name" db:"name"`
+	Date        time.Time `json:"date" db:"holiday_date"`
+	Location    *string   `json:"location,omitempty" db:"location"`
+	Description *string   `json:"description,omitempty" db:"description"`
+	Source      string    `json:"source" db:"source"`
+	ExternalUID *string   `json:"external_uid,omitempty" db:"external_uid"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CreateHolidayRequest represents the request to create or update a holiday
+type CreateHolidayRequest struct {
+	Name        string  `json:"name"`
+	Date        string  `json:"date"` // YYYY-MM-DD
+	Location    *string `json:"location,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+// HolidayImportResult summarises an ICS calendar import
+type HolidayImportResult struct {
+	Imported int      `json:"imported"`
+	Updated  int      `json:"updated"`
+	Skipped  int      `json:"skipped"`
+	Errors   []string `json:"errors,omitempty"`
+}
@@ -48,40 +48,65 @@ const (
 
 // LeaveRequest represents an employee's leave request
 type LeaveRequest struct {
-	ID              uuid.UUID   `json:"id" db:"id"`
-	TenantID        uuid.UUID   `json:"tenant_id" db:"tenant_id"`
-	EmployeeID      uuid.UUID   `json:"employee_id" db:"employee_id"`
-	LeaveType       LeaveType   `json:"leave_type" db:"leave_type"`
-	StartDate       time.Time   `json:"start_date" db:"start_date"`
-	EndDate         time.Time   `json:"end_date" db:"end_date"`
-	DaysRequested   float64     `json:"days_requested" db:"days_requested"`
-	Reason          string      `json:"reason" db:"reason"`
-	Status          LeaveStatus `json:"status" db:"status"`
-	ApprovedBy      *uuid.UUID  `json:"approved_by,omitempty" db:"approved_by"`
-	ApprovedAt      *time.Time  `json:"approved_at,omitempty" db:"approved_at"`
-	RejectionReason *string     `json:"rejection_reason,omitempty" db:"rejection_reason"`
-	CreatedAt       time.Time   `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time   `json:"updated_at" db:"updated_at"`
+	ID                 uuid.UUID   `json:"id" db:"id"`
+	TenantID           uuid.UUID   `json:"tenant_id" db:"tenant_id"`
+	EmployeeID         uuid.UUID   `json:"employee_id" db:"employee_id"`
+	LeaveType          LeaveType   `json:"leave_type" db:"leave_type"`
+	StartDate          time.Time   `json:"start_date" db:"start_date"`
+	EndDate            time.Time   `json:"end_date" db:"end_date"`
+	DaysRequested      float64     `json:"days_requested" db:"days_requested"`
+	StartHalfDay       bool        `json:"start_half_day" db:"start_half_day"`
+	EndHalfDay         bool        `json:"end_half_day" db:"end_half_day"`
+	Reason             string      `json:"reason" db:"reason"`
+	Status             LeaveStatus `json:"status" db:"status"`
+	ApprovedBy         *uuid.UUID  `json:"approved_by,omitempty" db:"approved_by"`
+	ApprovedAt         *time.Time  `json:"approved_at,omitempty" db:"approved_at"`
+	RejectionReason    *string     `json:"rejection_reason,omitempty" db:"rejection_reason"`
+	ModifiesRequestID  *uuid.UUID  `json:"modifies_request_id,omitempty" db:"modifies_request_id"` // approved request replaced once this one is approved
+	CancelledAt        *time.Time  `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	CancellationReason *string     `json:"cancellation_reason,omitempty" db:"cancellation_reason"`
+	CreatedAt          time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time   `json:"updated_at" db:"updated_at"`
 
 	// Joined fields for API responses
 	EmployeeName string `json:"employee_name,omitempty" db:"employee_name"`
 	EmployeeCode string `json:"employee_code,omitempty" db:"employee_code"`
 	Role         string `json:"role,omitempty" db:"role"`
 	ApproverName string `json:"approver_name,omitempty" db:"approver_name"`
+
+	// Approval trail, in step order
+	ApprovalSteps []LeaveApprovalStep `json:"approval_steps,omitempty"`
 }
 
-// CreateLeaveRequest represents a request to create a leave
+// CreateLeaveRequest represents a request to create a leave. The number of days is
+// computed by the server from the dates, working days and holiday calendar.
 type CreateLeaveRequest struct {
-	LeaveType     LeaveType `json:"leave_type" binding:"required"`
-	StartDate     string    `json:"start_date" binding:"required"` // YYYY-MM-DD
-	EndDate       string    `json:"end_date" binding:"required"`   // YYYY-MM-DD
-	DaysRequested float64   `json:"days_requested" binding:"required"`
-	Reason        string    `json:"reason" binding:"required"`
+	LeaveType    LeaveType `json:"leave_type" binding:"required"`
+	StartDate    string    `json:"start_date" binding:"required"` // YYYY-MM-DD
+	EndDate      string    `json:"end_date" binding:"required"`   // YYYY-MM-DD
+	StartHalfDay bool      `json:"start_half_day,omitempty"`      // leave starts at midday on the start date
+	EndHalfDay   bool      `json:"end_half_day,omitempty"`        // leave ends at midday on the end date
+	Reason       string    `json:"reason" binding:"required"`
 }
 
 // ApproveLeaveRequest represents a request to approve a leave
 type ApproveLeaveRequest struct {
-	ApproverNotes string `json:"approver_notes,omitempty"`
+	ApproverNotes string `json:"approver_notes,omitempty"` // recorded as the approval step comment
+}
+
+// CancelLeaveRequest represents a request to withdraw or cancel a leave
+type CancelLeaveRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// ModifyLeaveRequest represents a request to change the dates of an approved leave.
+// The change goes through approval again; the original leave stands until it is approved.
+type ModifyLeaveRequest struct {
+	StartDate    string `json:"start_date"` // YYYY-MM-DD
+	EndDate      string `json:"end_date"`   // YYYY-MM-DD
+	StartHalfDay bool   `json:"start_half_day,omitempty"`
+	EndHalfDay   bool   `json:"end_half_day,omitempty"`
+	Reason       string `json:"reason"`
 }
 
 // RejectLeaveRequest represents a request to reject a leave
@@ -91,14 +116,62 @@ type RejectLeaveRequest struct {
 
 // LeaveBalance represents an employee's leave balance
 type LeaveBalance struct {
-	ID            uuid.UUID `json:"id" db:"id"`
-	TenantID      uuid.UUID `json:"tenant_id" db:"tenant_id"`
-	EmployeeID    uuid.UUID `json:"employee_id" db:"employee_id"`
-	LeaveType     LeaveType `json:"leave_type" db:"leave_type"`
-	TotalDays     float64   `json:"total_days" db:"total_days"`
-	UsedDays      float64   `json:"used_days" db:"used_days"`
-	AvailableDays float64   `json:"available_days" db:"available_days"`
-	Year          int       `json:"year" db:"year"`
-	CreatedAt     time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+	ID                 uuid.UUID `json:"id" db:"id"`
+	TenantID           uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	EmployeeID         uuid.UUID `json:"employee_id" db:"employee_id"`
+	LeaveType          LeaveType `json:"leave_type" db:"leave_type"`
+	TotalDays          float64   `json:"total_days" db:"total_days"`
+	UsedDays           float64   `json:"used_days" db:"used_days"`
+	AvailableDays      float64   `json:"available_days" db:"available_days"`
+	CarriedForwardDays float64   `json:"carried_forward_days" db:"carried_forward_days"`
+	Year               int       `json:"year" db:"year"`
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// LeaveEntryType represents the kind of movement recorded in the leave ledger
+type LeaveEntryType string
+
+const (
+	LeaveEntryOpening      LeaveEntryType = "opening"
+	LeaveEntryAccrual      LeaveEntryType = "accrual"
+	LeaveEntryDebit        LeaveEntryType = "debit"
+	LeaveEntryRefund       LeaveEntryType = "refund"
+	LeaveEntryCarryForward LeaveEntryType = "carry_forward"
+	LeaveEntryExpiry       LeaveEntryType = "expiry"
+	LeaveEntryAdjustment   LeaveEntryType = "adjustment"
+)
+
+// LeaveLedgerEntry is an append-only movement of leave days. Credits are positive, debits negative.
+type LeaveLedgerEntry struct {
+	ID             uuid.UUID      `json:"id" db:"id"`
+	TenantID       uuid.UUID      `json:"tenant_id" db:"tenant_id"`
+	EmployeeID     uuid.UUID      `json:"employee_id" db:"employee_id"`
+	LeaveType      LeaveType      `json:"leave_type" db:"leave_type"`
+	Year           int            `json:"year" db:"year"`
+	EntryType      LeaveEntryType `json:"entry_type" db:"entry_type"`
+	Days           float64        `json:"days" db:"days"`
+	Period         *string        `json:"period,omitempty" db:"period"`
+	LeaveRequestID *uuid.UUID     `json:"leave_request_id,omitempty" db:"leave_request_id"`
+	Notes          *string        `json:"notes,omitempty" db:"notes"`
+	CreatedBy      *uuid.UUID     `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+}
+
+// LeaveAdjustmentRequest represents a manual correction to an employee's leave balance
+type LeaveAdjustmentRequest struct {
+	LeaveType LeaveType `json:"leave_type"`
+	Year      int       `json:"year,omitempty"`
+	Days      float64   `json:"days"`
+	Notes     string    `json:"notes"`
+}
+
+// LeaveAccrualRunRequest triggers an accrual run up to the given date (defaults to today)
+type LeaveAccrualRunRequest struct {
+	AsOf string `json:"as_of,omitempty"` // YYYY-MM-DD
+}
+
+// LeaveRolloverRequest triggers the year-end carry-forward for the given year
+type LeaveRolloverRequest struct {
+	Year int `json:"year"`
 }
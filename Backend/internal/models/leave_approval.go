@@ -0,0 +1,115 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ApproverType identifies who approves a step of a leave approval chain
+type ApproverType string
+
+const (
+	ApproverTeamLead       ApproverType = "team_lead"       // team lead of the employee's team
+	ApproverManager        ApproverType = "manager"         // employee's manager, else department head
+	ApproverDepartmentHead ApproverType = "department_head" // head of the employee's department
+	ApproverHR             ApproverType = "hr"              // any HR user
+	ApproverUser           ApproverType = "user"            // a specific user
+)
+
+// ApprovalStepStatus represents the state of a step in a leave request's approval trail
+type ApprovalStepStatus string
+
+const (
+	ApprovalStepWaiting  ApprovalStepStatus = "waiting"
+	ApprovalStepPending  ApprovalStepStatus = "pending"
+	ApprovalStepApproved ApprovalStepStatus = "approved"
+	ApprovalStepRejected ApprovalStepStatus = "rejected"
+	ApprovalStepSkipped  ApprovalStepStatus = "skipped"
+)
+
+// LeaveApprovalWorkflow is an approval chain for leave requests matching its leave type,
+// department and minimum length. NULL filters match any request.
+type LeaveApprovalWorkflow struct {
+	ID           uuid.UUID                   `json:"id" db:"id"`
+	TenantID     uuid.UUID                   `json:"tenant_id" db:"tenant_id"`
+	Name         string                      `json:"name" db:"name"`
+	LeaveType    *string                     `json:"leave_type,omitempty" db:"leave_type"`
+	DepartmentID *uuid.UUID                  `json:"department_id,omitempty" db:"department_id"`
+	MinDays      *float64                    `json:"min_days,omitempty" db:"min_days"`
+	Priority     int                         `json:"priority" db:"priority"`
+	IsActive     bool                        `json:"is_active" db:"is_active"`
+	Steps        []LeaveApprovalWorkflowStep `json:"steps"`
+	CreatedAt    time.Time                   `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time                   `json:"updated_at" db:"updated_at"`
+}
+
+// LeaveApprovalWorkflowStep is one level of an approval chain
+type LeaveApprovalWorkflowStep struct {
+	ID             uuid.UUID    `json:"id" db:"id"`
+	StepOrder      int          `json:"step_order" db:"step_order"`
+	ApproverType   ApproverType `json:"approver_type" db:"approver_type"`
+	ApproverUserID *uuid.UUID   `json:"approver_user_id,omitempty" db:"approver_user_id"`
+	MinDays        *float64     `json:"min_days,omitempty" db:"min_days"` // step only applies to requests of at least this many days
+}
+
+// LeaveApprovalStep is a step in the approval trail of a leave request
+type LeaveApprovalStep struct {
+	ID             uuid.UUID          `json:"id" db:"id"`
+	LeaveRequestID uuid.UUID          `json:"leave_request_id" db:"leave_request_id"`
+	StepOrder      int                `json:"step_order" db:"step_order"`
+	ApproverType   ApproverType       `json:"approver_type" db:"approver_type"`
+	ApproverUserID *uuid.UUID         `json:"approver_user_id,omitempty" db:"approver_user_id"`
+	ApproverRole   *string            `json:"approver_role,omitempty" db:"approver_role"`
+	Status         ApprovalStepStatus `json:"status" db:"status"`
+	Comment        *string            `json:"comment,omitempty" db:"comment"`
+	ActedBy        *uuid.UUID         `json:"acted_by,omitempty" db:"acted_by"`
+	DelegatedFrom  *uuid.UUID         `json:"delegated_from,omitempty" db:"delegated_from"`
+	ActedAt        *time.Time         `json:"acted_at,omitempty" db:"acted_at"`
+	CreatedAt      time.Time          `json:"created_at" db:"created_at"`
+
+	// Joined fields for API responses
+	ApproverName string `json:"approver_name,omitempty" db:"approver_name"`
+	ActedByName  string `json:"acted_by_name,omitempty" db:"acted_by_name"`
+}
+
+// LeaveApprovalWorkflowStepRequest represents one step of a workflow create or update request
+type LeaveApprovalWorkflowStepRequest struct {
+	ApproverType   ApproverType `json:"approver_type"`
+	ApproverUserID string       `json:"approver_user_id,omitempty"`
+	MinDays        *float64     `json:"min_days,omitempty"`
+}
+
+// CreateLeaveApprovalWorkflowRequest represents the request to create or update an approval workflow
+type CreateLeaveApprovalWorkflowRequest struct {
+	Name         string                             `json:"name"`
+	LeaveType    *string                            `json:"leave_type,omitempty"`
+	DepartmentID *string                            `json:"department_id,omitempty"`
+	MinDays      *float64                           `json:"min_days,omitempty"`
+	Priority     int                                `json:"priority"`
+	IsActive     *bool                              `json:"is_active,omitempty"`
+	Steps        []LeaveApprovalWorkflowStepRequest `json:"steps"`
+}
+
+// ApprovalDelegation lets a delegate act on the delegator's approval steps between two dates
+type ApprovalDelegation struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	TenantID     uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	DelegatorID  uuid.UUID  `json:"delegator_id" db:"delegator_id"`
+	DelegateID   uuid.UUID  `json:"delegate_id" db:"delegate_id"`
+	StartDate    time.Time  `json:"start_date" db:"start_date"`
+	EndDate      time.Time  `json:"end_date" db:"end_date"`
+	Reason       *string    `json:"reason,omitempty" db:"reason"`
+	CreatedBy    *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	DelegateName string     `json:"delegate_name,omitempty" db:"delegate_name"`
+}
+
+// CreateApprovalDelegationRequest represents the request to delegate approvals
+type CreateApprovalDelegationRequest struct {
+	DelegateID string  `json:"delegate_id"`
+	StartDate  string  `json:"start_date"` // YYYY-MM-DD
+	EndDate    string  `json:"end_date"`   // YYYY-MM-DD
+	Reason     *string `json:"reason,omitempty"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationCategory groups notifications; users turn categories on and off in their
+// preferences
+type NotificationCategory string
+
+const (
+	NotificationCategoryLeave      NotificationCategory = "leave"
+	NotificationCategoryAttendance NotificationCategory = "attendance"
+	NotificationCategoryPayslip    NotificationCategory = "payslip"
+	NotificationCategoryBilling    NotificationCategory = "billing"
+	NotificationCategorySecurity   NotificationCategory = "security"
+)
+
+// Notification types
+const (
+	NotificationLeaveSubmitted          = "leave_submitted"
+	NotificationLeaveApproved           = "leave_approved"
+	NotificationLeaveRejected           = "leave_rejected"
+	NotificationRegularizationSubmitted = "regularization_submitted"
+	NotificationRegularizationApproved  = "regularization_approved"
+	NotificationRegularizationRejected  = "regularization_rejected"
+	NotificationPayslipPublished        = "payslip_published"
+	NotificationInvoiceIssued           = "invoice_issued"
+	NotificationNewDeviceLogin          = "new_device_login"
+)
+
+// Notification is an item of a user's in-app inbox
+type Notification struct {
+	ID           uuid.UUID            `json:"id" db:"id"`
+	TenantID     uuid.UUID            `json:"tenant_id" db:"tenant_id"`
+	UserID       uuid.UUID            `json:"user_id" db:"user_id"`
+	Category     NotificationCategory `json:"category" db:"category"`
+	Type         string               `json:"type" db:"type"`
+	Title        string               `json:"title" db:"title"`
+	Body         *string              `json:"body,omitempty" db:"body"`
+	Link         *string              `json:"link,omitempty" db:"link"` // frontend path
+	ResourceType *string              `json:"resource_type,omitempty" db:"resource_type"`
+	ResourceID   *uuid.UUID           `json:"resource_id,omitempty" db:"resource_id"`
+	ReadAt       *time.Time           `json:"read_at,omitempty" db:"read_at"`
+	CreatedAt    time.Time            `json:"created_at" db:"created_at"`
+}
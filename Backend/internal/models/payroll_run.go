@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PayrollRunStatus represents the lifecycle state of a payroll run
+type PayrollRunStatus string
+
+const (
+	PayrollRunStatusDraft     PayrollRunStatus = "draft"
+	PayrollRunStatusLocked    PayrollRunStatus = "locked"
+	PayrollRunStatusPublished PayrollRunStatus = "published"
+)
+
+// PayrollRun represents the payslips of a tenant for one month
+type PayrollRun struct {
+	ID           uuid.UUID        `json:"id" db:"id"`
+	TenantID     uuid.UUID        `json:"tenant_id" db:"tenant_id"`
+	PeriodYear   int              `json:"period_year" db:"period_year"`
+	PeriodMonth  int              `json:"period_month" db:"period_month"`
+	PeriodStart  time.Time        `json:"period_start" db:"period_start"`
+	PeriodEnd    time.Time        `json:"period_end" db:"period_end"`
+	PaymentDate  *time.Time       `json:"payment_date,omitempty" db:"payment_date"`
+	Status       PayrollRunStatus `json:"status" db:"status"`
+	Notes        *string          `json:"notes,omitempty" db:"notes"`
+	CreatedBy    *uuid.UUID       `json:"created_by,omitempty" db:"created_by"`
+	LockedAt     *time.Time       `json:"locked_at,omitempty" db:"locked_at"`
+	LockedBy     *uuid.UUID       `json:"locked_by,omitempty" db:"locked_by"`
+	PublishedAt  *time.Time       `json:"published_at,omitempty" db:"published_at"`
+	PublishedBy  *uuid.UUID       `json:"published_by,omitempty" db:"published_by"`
+	ReopenedAt   *time.Time       `json:"reopened_at,omitempty" db:"reopened_at"`
+	ReopenedBy   *uuid.UUID       `json:"reopened_by,omitempty" db:"reopened_by"`
+	ReopenReason *string          `json:"reopen_reason,omitempty" db:"reopen_reason"`
+	CreatedAt    time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at" db:"updated_at"`
+
+	// Summary of the run's payslips
+	PayslipCount    int               `json:"payslip_count"`
+	ErrorCount      int               `json:"error_count"`
+	TotalGross      float64           `json:"total_gross"`
+	TotalDeductions float64           `json:"total_deductions"`
+	TotalNet        float64           `json:"total_net"`
+	Errors          []PayrollRunError `json:"errors,omitempty"`
+}
+
+// PayrollRunError records why an employee's payslip could not be generated
+type PayrollRunError struct {
+	EmployeeID   uuid.UUID `json:"employee_id" db:"employee_id"`
+	EmployeeName string    `json:"employee_name,omitempty"`
+	EmployeeCode string    `json:"employee_code,omitempty"`
+	Error        string    `json:"error" db:"error"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// PayrollRunFilter selects the employees a run generates payslips for. Without a filter
+// every employee employed during the period is included.
+type PayrollRunFilter struct {
+	DepartmentID *uuid.UUID  `json:"department_id,omitempty"`
+	EmployeeIDs  []uuid.UUID `json:"employee_ids,omitempty"`
+}
+
+// CreatePayrollRunRequest represents the request to start a payroll run for a month
+type CreatePayrollRunRequest struct {
+	Year        int     `json:"year"`
+	Month       int     `json:"month"`
+	PaymentDate *string `json:"payment_date,omitempty"` // YYYY-MM-DD
+	Notes       *string `json:"notes,omitempty"`
+	PayrollRunFilter
+}
+
+// ReopenPayrollRunRequest represents the request to return a locked run to draft
+type ReopenPayrollRunRequest struct {
+	Reason string `json:"reason"`
+}
+
+// PayrollRunGeneration reports the outcome of generating a run's payslips
+type PayrollRunGeneration struct {
+	Run       *PayrollRun       `json:"run"`
+	Generated int               `json:"generated"`
+	Failed    int               `json:"failed"`
+	Errors    []PayrollRunError `json:"errors"`
+}
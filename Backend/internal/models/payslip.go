@@ -8,20 +8,25 @@ import (
 
 // SalaryComponent represents a salary component (earning or deduction)
 type SalaryComponent struct {
-	ID           uuid.UUID  `json:"id" db:"id"`
-	TenantID     uuid.UUID  `json:"tenant_id" db:"tenant_id"`
-	Name         string     `json:"name" db:"name"`
-	Type         string     `json:"type" db:"type"` // "earning" or "deduction"
-	IsFixed      bool       `json:"is_fixed" db:"is_fixed"`
-	Amount       float64    `json:"amount" db:"amount"`
-	Percentage   float64    `json:"percentage" db:"percentage"`
-	Description  string     `json:"description" db:"description"`
-	IsPercentage bool       `json:"is_percentage" db:"is_percentage"`
-	IsTaxable    bool       `json:"is_taxable" db:"is_taxable"`
-	IsActive     bool       `json:"is_active" db:"is_active"`
-	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
-	DeletedAt    *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	ID               uuid.UUID  `json:"id" db:"id"`
+	TenantID         uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	Name             string     `json:"name" db:"name"`
+	Type             string     `json:"type" db:"type"` // "earning" or "deduction"
+	IsFixed          bool       `json:"is_fixed" db:"is_fixed"`
+	Amount           float64    `json:"amount" db:"amount"`
+	Percentage       float64    `json:"percentage" db:"percentage"`
+	Description      string     `json:"description" db:"description"`
+	IsPercentage     bool       `json:"is_percentage" db:"is_percentage"`
+	IsTaxable        bool       `json:"is_taxable" db:"is_taxable"`
+	IsActive         bool       `json:"is_active" db:"is_active"`
+	Code             string     `json:"code,omitempty" db:"code"`
+	Formula          string     `json:"formula,omitempty" db:"formula"`
+	CalculationOrder int        `json:"calculation_order" db:"calculation_order"`
+	MinAmount        *float64   `json:"min_amount,omitempty" db:"min_amount"`
+	MaxAmount        *float64   `json:"max_amount,omitempty" db:"max_amount"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
 }
 
 // EmployeeSalaryStructure represents an employee's salary configuration
@@ -46,6 +51,7 @@ type SalaryStructureComponent struct {
 	ComponentID       uuid.UUID        `json:"component_id" db:"component_id"`
 	Amount            *float64         `json:"amount,omitempty" db:"amount"`
 	Percentage        *float64         `json:"percentage,omitempty" db:"percentage"`
+	Formula           *string          `json:"formula,omitempty" db:"formula"`
 	CreatedAt         time.Time        `json:"created_at" db:"created_at"`
 	Component         *SalaryComponent `json:"component,omitempty"`
 }
@@ -65,6 +71,14 @@ type Payslip struct {
 	NetSalary         float64            `json:"net_salary" db:"net_salary"`
 	Status            string             `json:"status" db:"status"`
 	Notes             *string            `json:"notes,omitempty" db:"notes"`
+	WorkingDays       *float64           `json:"working_days,omitempty" db:"working_days"`
+	PaidDays          *float64           `json:"paid_days,omitempty" db:"paid_days"`
+	ProjectedDays     float64            `json:"projected_days" db:"projected_days"`
+	UnpaidLeaveDays   float64            `json:"unpaid_leave_days" db:"unpaid_leave_days"`
+	AbsentDays        float64            `json:"absent_days" db:"absent_days"`
+	OvertimeHours     float64            `json:"overtime_hours" db:"overtime_hours"`
+	PayrollRunID      *uuid.UUID         `json:"payroll_run_id,omitempty" db:"payroll_run_id"`
+	PublishedAt       *time.Time         `json:"published_at,omitempty" db:"published_at"`
 	CreatedAt         time.Time          `json:"created_at" db:"created_at"`
 	UpdatedAt         time.Time          `json:"updated_at" db:"updated_at"`
 	Employee          *Employee          `json:"employee,omitempty"`
@@ -78,8 +92,10 @@ type PayslipComponent struct {
 	PayslipID     uuid.UUID `json:"payslip_id" db:"payslip_id"`
 	ComponentID   uuid.UUID `json:"component_id" db:"component_id"`
 	ComponentName string    `json:"component_name" db:"component_name"`
+	ComponentCode *string   `json:"component_code,omitempty" db:"component_code"`
 	ComponentType string    `json:"component_type" db:"component_type"`
 	Amount        float64   `json:"amount" db:"amount"`
+	IsTaxable     bool      `json:"is_taxable" db:"is_taxable"`
 	CreatedAt     time.Time `json:"created_at" db:"created_at"`
 }
 
@@ -108,6 +124,8 @@ type PayslipFilter struct {
 	PayPeriodEnd   *time.Time `json:"pay_period_end,omitempty"`
 	PaymentMonth   *int       `json:"payment_month,omitempty"`
 	PaymentYear    *int       `json:"payment_year,omitempty"`
+	PayrollRunID   *uuid.UUID `json:"payroll_run_id,omitempty"`
+	PublishedOnly  bool       `json:"published_only,omitempty"`
 }
 
 // PayslipStats represents payslip statistics
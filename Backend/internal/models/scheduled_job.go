@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScheduledJobRunStatus is the outcome of a job run
+type ScheduledJobRunStatus string
+
+const (
+	JobRunRunning ScheduledJobRunStatus = "running"
+	JobRunSuccess ScheduledJobRunStatus = "success"
+	JobRunFailed  ScheduledJobRunStatus = "failed"
+	JobRunSkipped ScheduledJobRunStatus = "skipped"
+)
+
+// ScheduledJob is a background job registered with the scheduler
+type ScheduledJob struct {
+	Name        string                 `json:"name" db:"name"`
+	Description string                 `json:"description" db:"description"`
+	Schedule    string                 `json:"schedule" db:"schedule"`
+	IsPaused    bool                   `json:"is_paused" db:"is_paused"`
+	PausedBy    *uuid.UUID             `json:"paused_by,omitempty" db:"paused_by"`
+	PausedAt    *time.Time             `json:"paused_at,omitempty" db:"paused_at"`
+	NextRunAt   *time.Time             `json:"next_run_at,omitempty" db:"next_run_at"`
+	LastRunAt   *time.Time             `json:"last_run_at,omitempty" db:"last_run_at"`
+	LastStatus  *ScheduledJobRunStatus `json:"last_status,omitempty" db:"last_status"`
+	CreatedAt   time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at" db:"updated_at"`
+}
+
+// ScheduledJobRun is one execution of a scheduled job
+type ScheduledJobRun struct {
+	ID          uuid.UUID             `json:"id" db:"id"`
+	JobName     string                `json:"job_name" db:"job_name"`
+	Trigger     string                `json:"trigger" db:"trigger"` // "schedule" or "manual"
+	TriggeredBy *uuid.UUID            `json:"triggered_by,omitempty" db:"triggered_by"`
+	Status      ScheduledJobRunStatus `json:"status" db:"status"`
+	Output      *string               `json:"output,omitempty" db:"output"`
+	Error       *string               `json:"error,omitempty" db:"error"`
+	Instance    string                `json:"instance" db:"instance"`
+	StartedAt   time.Time             `json:"started_at" db:"started_at"`
+	FinishedAt  *time.Time            `json:"finished_at,omitempty" db:"finished_at"`
+}
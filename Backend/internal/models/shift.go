@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShiftStatus represents the publication state of a roster entry
+type ShiftStatus string
+
+const (
+	ShiftStatusDraft     ShiftStatus = "draft"
+	ShiftStatusPublished ShiftStatus = "published"
+)
+
+// ShiftTemplate defines a reusable shift. A shift whose end time is not after its
+// start time crosses midnight and ends on the following day.
+type ShiftTemplate struct {
+	ID                   uuid.UUID  `json:"id" db:"id"`
+	TenantID             uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	Name                 string     `json:"name" db:"name"`
+	StartTime            string     `json:"start_time" db:"start_time"` // HH:MM in tenant timezone
+	EndTime              string     `json:"end_time" db:"end_time"`     // HH:MM in tenant timezone
+	BreakDurationMinutes int        `json:"break_duration_minutes" db:"break_duration_minutes"`
+	GracePeriodMinutes   *int       `json:"grace_period_minutes,omitempty" db:"grace_period_minutes"`
+	Color                *string    `json:"color,omitempty" db:"color"`
+	IsActive             bool       `json:"is_active" db:"is_active"`
+	CrossesMidnight      bool       `json:"crosses_midnight"`
+	CreatedAt            time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt            *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// ShiftAssignment is a single roster entry assigning an employee to a shift on a date
+type ShiftAssignment struct {
+	ID              uuid.UUID   `json:"id" db:"id"`
+	TenantID        uuid.UUID   `json:"tenant_id" db:"tenant_id"`
+	EmployeeID      uuid.UUID   `json:"employee_id" db:"employee_id"`
+	ShiftTemplateID uuid.UUID   `json:"shift_template_id" db:"shift_template_id"`
+	ShiftDate       time.Time   `json:"shift_date" db:"shift_date"`
+	Status          ShiftStatus `json:"status" db:"status"`
+	Notes           *string     `json:"notes,omitempty" db:"notes"`
+	CreatedBy       *uuid.UUID  `json:"created_by,omitempty" db:"created_by"`
+	PublishedAt     *time.Time  `json:"published_at,omitempty" db:"published_at"`
+	CreatedAt       time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at" db:"updated_at"`
+
+	// Joined fields for API responses
+	EmployeeName string `json:"employee_name,omitempty" db:"employee_name"`
+	ShiftName    string `json:"shift_name,omitempty" db:"shift_name"`
+	StartTime    string `json:"start_time,omitempty" db:"start_time"`
+	EndTime      string `json:"end_time,omitempty" db:"end_time"`
+}
+
+// CreateShiftTemplateRequest represents the request to create or update a shift template
+type CreateShiftTemplateRequest struct {
+	Name                 string  `json:"name"`
+	StartTime            string  `json:"start_time"`
+	EndTime              string  `json:"end_time"`
+	BreakDurationMinutes int     `json:"break_duration_minutes"`
+	GracePeriodMinutes   *int    `json:"grace_period_minutes,omitempty"`
+	Color                *string `json:"color,omitempty"`
+	IsActive             *bool   `json:"is_active,omitempty"`
+}
+
+// RosterEntryRequest assigns a shift to an employee or to every member of a team
+// on the given weekdays of the roster week
+type RosterEntryRequest struct {
+	EmployeeID      string   `json:"employee_id,omitempty"`
+	TeamID          string   `json:"team_id,omitempty"`
+	ShiftTemplateID string   `json:"shift_template_id"`
+	Weekdays        []string `json:"weekdays,omitempty"`
+	Notes           string   `json:"notes,omitempty"`
+}
+
+// CreateRosterRequest represents a weekly roster draft
+type CreateRosterRequest struct {
+	WeekStart string               `json:"week_start"` // YYYY-MM-DD
+	Entries   []RosterEntryRequest `json:"entries"`
+}
+
+// PublishRosterRequest publishes all draft entries of a roster week
+type PublishRosterRequest struct {
+	WeekStart string `json:"week_start"` // YYYY-MM-DD
+}
+
+// SwapShiftRequest exchanges the employees of two roster entries
+type SwapShiftRequest struct {
+	AssignmentID      string `json:"assignment_id"`
+	OtherAssignmentID string `json:"other_assignment_id"`
+}
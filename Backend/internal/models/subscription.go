@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 // SubscriptionPlan represents a pricing tier/plan
@@ -138,4 +139,13 @@ type Tenant struct {
 	OrganizationDetail *OrganizationDetail `json:"organization_detail,omitempty"`
 	TotalUsers         int                 `json:"total_users,omitempty"`
 	ActiveEmployees    int                 `json:"active_employees,omitempty"`
+	SecurityDefaults   *SecurityDefaults   `json:"security_defaults,omitempty"`
+}
+
+// SecurityDefaults is the security policy of an organization's users, which their own security
+// settings may only tighten. A nil timeout or expiry means no limit; zero means the same.
+type SecurityDefaults struct {
+	IPRestrictions     pq.StringArray `json:"ip_restrictions" db:"default_ip_restrictions"`           // CIDR ranges or IP addresses
+	SessionTimeout     *int           `json:"session_timeout" db:"default_session_timeout"`           // seconds
+	PasswordExpiryDays *int           `json:"password_expiry_days" db:"default_password_expiry_days"` // days
 }
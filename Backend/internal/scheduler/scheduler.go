@@ -0,0 +1,425 @@
+// Package scheduler runs periodic background jobs inside the API process. Schedule state and
+// run history live in Postgres so that, with several replicas, each due run is claimed by one
+// replica and a job never runs twice at the same time.
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// pollInterval is how often due jobs are looked for
+	pollInterval = 15 * time.Second
+
+	// defaultJobTimeout bounds a run when the job sets no timeout
+	defaultJobTimeout = 30 * time.Minute
+)
+
+// Job is a unit of periodic work. Run returns a short summary stored in the run history.
+type Job struct {
+	Name        string
+	Description string
+	Schedule    string
+	Timeout     time.Duration
+	Run         func(ctx context.Context) (string, error)
+
+	schedule Schedule
+}
+
+// Scheduler runs registered jobs on their schedules
+type Scheduler struct {
+	db       *sql.DB
+	instance string
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+}
+
+// New creates a scheduler
+func New(db *sql.DB) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		db:       db,
+		instance: fmt.Sprintf("%s:%d", host, os.Getpid()),
+		jobs:     make(map[string]*Job),
+	}
+}
+
+// Register adds a job. Jobs must be registered before Start.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return fmt.Errorf("job needs a name and a run function")
+	}
+	schedule, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		return err
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return fmt.Errorf("schedule %q of job %s never runs", job.Schedule, job.Name)
+	}
+	job.schedule = schedule
+	if job.Timeout <= 0 {
+		job.Timeout = defaultJobTimeout
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.jobs[job.Name]; exists {
+		return fmt.Errorf("job %s is already registered", job.Name)
+	}
+	s.jobs[job.Name] = &job
+	return nil
+}
+
+// Start records the registered jobs and begins running them on schedule
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return fmt.Errorf("scheduler is already running")
+	}
+
+	now := time.Now()
+	for _, job := range s.jobs {
+		// A changed schedule takes effect immediately; otherwise the stored next run is kept
+		_, err := s.db.ExecContext(ctx,
+			`INSERT INTO scheduled_jobs (name, description, schedule, next_run_at)
+			 VALUES ($1, $2, $3, $4)
+			 ON CONFLICT (name) DO UPDATE SET
+			     description = EXCLUDED.description,
+			     schedule = EXCLUDED.schedule,
+			     next_run_at = CASE WHEN scheduled_jobs.schedule <> EXCLUDED.schedule OR scheduled_jobs.next_run_at IS NULL
+			                        THEN EXCLUDED.next_run_at ELSE scheduled_jobs.next_run_at END`,
+			job.Name, job.Description, job.Schedule, job.schedule.Next(now))
+		if err != nil {
+			return fmt.Errorf("failed to register job %s: %w", job.Name, err)
+		}
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.running.Add(1)
+	go s.loop()
+
+	log.Info().Int("jobs", len(s.jobs)).Str("instance", s.instance).Msg("Job scheduler started")
+	return nil
+}
+
+// Stop stops scheduling and waits for running jobs, which see their context cancelled
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	s.running.Wait()
+	log.Info().Msg("Job scheduler stopped")
+}
+
+func (s *Scheduler) loop() {
+	defer s.running.Done()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.runDueJobs()
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDueJobs claims every due job by advancing its next run, then runs the claimed ones
+func (s *Scheduler) runDueJobs() {
+	for _, job := range s.registeredJobs() {
+		next := job.schedule.Next(time.Now())
+		result, err := s.db.ExecContext(s.ctx,
+			`UPDATE scheduled_jobs SET next_run_at = $2
+			 WHERE name = $1 AND NOT is_paused AND next_run_at <= NOW()`,
+			job.Name, next)
+		if err != nil {
+			if s.ctx.Err() == nil {
+				log.Error().Err(err).Str("job", job.Name).Msg("Failed to claim scheduled job")
+			}
+			continue
+		}
+		if claimed, _ := result.RowsAffected(); claimed == 0 {
+			continue
+		}
+
+		s.running.Add(1)
+		go func(job *Job) {
+			defer s.running.Done()
+			s.execute(job, "schedule", nil)
+		}(job)
+	}
+}
+
+// execute runs a job under a Postgres advisory lock and records the run
+func (s *Scheduler) execute(job *Job, trigger string, triggeredBy *uuid.UUID) {
+	ctx := s.ctx
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		log.Error().Err(err).Str("job", job.Name).Msg("Failed to get connection for job")
+		return
+	}
+	defer conn.Close()
+
+	var locked bool
+	lockKey := jobLockKey(job.Name)
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, lockKey).Scan(&locked); err != nil {
+		log.Error().Err(err).Str("job", job.Name).Msg("Failed to lock job")
+		return
+	}
+	if !locked {
+		s.recordSkippedRun(ctx, job, trigger, triggeredBy, "job is already running")
+		return
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	var runID uuid.UUID
+	err = s.db.QueryRowContext(ctx,
+		`INSERT INTO scheduled_job_runs (job_name, trigger, triggered_by, status, instance)
+		 VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		job.Name, trigger, triggeredBy, models.JobRunRunning, s.instance).Scan(&runID)
+	if err != nil {
+		log.Error().Err(err).Str("job", job.Name).Msg("Failed to record job run")
+		return
+	}
+
+	log.Info().Str("job", job.Name).Str("trigger", trigger).Msg("Running scheduled job")
+	output, runErr := s.run(ctx, job)
+
+	status := models.JobRunSuccess
+	var errorMessage *string
+	if runErr != nil {
+		status = models.JobRunFailed
+		message := runErr.Error()
+		errorMessage = &message
+		log.Error().Err(runErr).Str("job", job.Name).Msg("Scheduled job failed")
+	} else {
+		log.Info().Str("job", job.Name).Str("output", output).Msg("Scheduled job finished")
+	}
+
+	// Record the outcome even when the scheduler is stopping
+	finishCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = s.db.ExecContext(finishCtx,
+		`UPDATE scheduled_job_runs SET status = $2, output = $3, error = $4, finished_at = NOW()
+		 WHERE id = $1`,
+		runID, status, nullIfEmpty(output), errorMessage)
+	if err == nil {
+		_, err = s.db.ExecContext(finishCtx,
+			`UPDATE scheduled_jobs SET last_run_at = NOW(), last_status = $2 WHERE name = $1`,
+			job.Name, status)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("job", job.Name).Msg("Failed to record job result")
+	}
+}
+
+// run calls the job with its timeout and turns a panic into an error
+func (s *Scheduler) run(ctx context.Context, job *Job) (output string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, job.Timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return job.Run(ctx)
+}
+
+func (s *Scheduler) recordSkippedRun(ctx context.Context, job *Job, trigger string, triggeredBy *uuid.UUID, reason string) {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO scheduled_job_runs (job_name, trigger, triggered_by, status, error, instance, finished_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, NOW())`,
+		job.Name, trigger, triggeredBy, models.JobRunSkipped, reason, s.instance)
+	if err != nil {
+		log.Error().Err(err).Str("job", job.Name).Msg("Failed to record skipped job run")
+	}
+}
+
+// TriggerJob starts a run of a job now, outside its schedule. Paused jobs can be triggered.
+func (s *Scheduler) TriggerJob(ctx context.Context, name string, triggeredBy *uuid.UUID) error {
+	job := s.job(name)
+	if job == nil {
+		return fmt.Errorf("job not found")
+	}
+
+	s.mu.Lock()
+	started := s.cancel != nil && s.ctx.Err() == nil
+	s.mu.Unlock()
+	if !started {
+		return fmt.Errorf("scheduler is not running")
+	}
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.execute(job, "manual", triggeredBy)
+	}()
+	return nil
+}
+
+// SetJobPaused pauses or resumes a job on every replica
+func (s *Scheduler) SetJobPaused(ctx context.Context, name string, paused bool, userID *uuid.UUID) (*models.ScheduledJob, error) {
+	job := s.job(name)
+	if job == nil {
+		return nil, fmt.Errorf("job not found")
+	}
+
+	var err error
+	if paused {
+		_, err = s.db.ExecContext(ctx,
+			`UPDATE scheduled_jobs SET is_paused = true, paused_by = $2, paused_at = NOW() WHERE name = $1`,
+			name, userID)
+	} else {
+		// Resuming does not replay the runs missed while paused
+		_, err = s.db.ExecContext(ctx,
+			`UPDATE scheduled_jobs SET is_paused = false, paused_by = NULL, paused_at = NULL,
+			     next_run_at = GREATEST(next_run_at, $2)
+			 WHERE name = $1`,
+			name, job.schedule.Next(time.Now()))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update job: %w", err)
+	}
+
+	return s.GetJob(ctx, name)
+}
+
+// ListJobs returns the registered jobs with their schedule state
+func (s *Scheduler) ListJobs(ctx context.Context) ([]models.ScheduledJob, error) {
+	names := make([]string, 0)
+	for _, job := range s.registeredJobs() {
+		names = append(names, job.Name)
+	}
+
+	jobs := make([]models.ScheduledJob, 0, len(names))
+	for _, name := range names {
+		job, err := s.GetJob(ctx, name)
+		if err != nil {
+			// Jobs are only recorded once a scheduler starts
+			if err.Error() == "job not found" {
+				continue
+			}
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, nil
+}
+
+// GetJob returns a registered job with its schedule state
+func (s *Scheduler) GetJob(ctx context.Context, name string) (*models.ScheduledJob, error) {
+	if s.job(name) == nil {
+		return nil, fmt.Errorf("job not found")
+	}
+
+	var job models.ScheduledJob
+	var lastStatus sql.NullString
+	err := s.db.QueryRowContext(ctx,
+		`SELECT name, description, schedule, is_paused, paused_by, paused_at, next_run_at,
+		        last_run_at, last_status, created_at, updated_at
+		 FROM scheduled_jobs WHERE name = $1`, name).Scan(
+		&job.Name, &job.Description, &job.Schedule, &job.IsPaused, &job.PausedBy, &job.PausedAt,
+		&job.NextRunAt, &job.LastRunAt, &lastStatus, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("job not found")
+		}
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	if lastStatus.Valid {
+		status := models.ScheduledJobRunStatus(lastStatus.String)
+		job.LastStatus = &status
+	}
+	return &job, nil
+}
+
+// GetJobRuns returns the most recent runs of a job, newest first
+func (s *Scheduler) GetJobRuns(ctx context.Context, name string, limit int) ([]models.ScheduledJobRun, error) {
+	if s.job(name) == nil {
+		return nil, fmt.Errorf("job not found")
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, job_name, trigger, triggered_by, status, output, error, instance, started_at, finished_at
+		 FROM scheduled_job_runs
+		 WHERE job_name = $1
+		 ORDER BY started_at DESC
+		 LIMIT $2`, name, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := make([]models.ScheduledJobRun, 0)
+	for rows.Next() {
+		var run models.ScheduledJobRun
+		if err := rows.Scan(&run.ID, &run.JobName, &run.Trigger, &run.TriggeredBy, &run.Status,
+			&run.Output, &run.Error, &run.Instance, &run.StartedAt, &run.FinishedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan job run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func (s *Scheduler) job(name string) *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[name]
+}
+
+// registeredJobs returns the jobs sorted by name
+func (s *Scheduler) registeredJobs() []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
+
+// jobLockKey derives the advisory lock key of a job
+func jobLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("scheduled_job:" + name))
+	return int64(h.Sum64())
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
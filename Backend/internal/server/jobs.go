@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/scheduler"
+	"github.com/rs/zerolog/log"
+)
+
+// registerJobs adds the periodic background jobs to the scheduler
+func (s *Server) registerJobs() error {
+	jobs := []scheduler.Job{
+		{
+			Name:        "biometric_device_sync",
+			Description: "Pull punches from biometric devices whose sync interval has elapsed",
+			Schedule:    "* * * * *",
+			Timeout:     10 * time.Minute,
+			Run: func(ctx context.Context) (string, error) {
+				synced, failed, err := s.biometricService.SyncDueDevices(ctx)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%d devices synced, %d failed", synced, failed), nil
+			},
+		},
+		{
+			Name:        "email_delivery",
+			Description: "Send queued emails and retry failed deliveries",
+			Schedule:    "* * * * *",
+			Timeout:     10 * time.Minute,
+			Run: func(ctx context.Context) (string, error) {
+				sent, failed, err := s.notificationService.DeliverPending(ctx, 100)
+				return fmt.Sprintf("%d emails sent, %d failed", sent, failed), err
+			},
+		},
+		{
+			Name:        "refresh_token_cleanup",
+			Description: "Delete expired refresh tokens and password reset links",
+			Schedule:    "30 3 * * *",
+			Run: func(ctx context.Context) (string, error) {
+				deleted, err := s.authService.PurgeExpiredRefreshTokens(ctx)
+				if err != nil {
+					return "", err
+				}
+				resets, err := s.authService.PurgeExpiredPasswordResets(ctx)
+				return fmt.Sprintf("%d refresh tokens and %d password reset links deleted", deleted, resets), err
+			},
+		},
+		{
+			Name:        "usage_daily_metrics",
+			Description: "Record the previous day's usage metrics of every organization",
+			Schedule:    "15 0 * * *",
+			Run: func(ctx context.Context) (string, error) {
+				yesterday := time.Now().UTC().AddDate(0, 0, -1)
+				return s.forEachTenant(ctx, func(ctx context.Context, tenantID uuid.UUID) error {
+					return s.usageTrackingService.RecordDailyMetrics(ctx, tenantID, yesterday)
+				})
+			},
+		},
+		{
+			Name:        "subscription_expiry",
+			Description: "Expire trials and subscriptions whose period has ended",
+			Schedule:    "@hourly",
+			Run: func(ctx context.Context) (string, error) {
+				changed, err := s.subscriptionService.ExpireSubscriptions(ctx, time.Now())
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%d subscriptions updated", changed), nil
+			},
+		},
+		{
+			Name:        "attendance_day_close",
+			Description: "Mark absences and close forgotten check-outs of each organization's previous day",
+			Schedule:    "5 * * * *",
+			Run: func(ctx context.Context) (string, error) {
+				absent, onLeave, checkedOut := 0, 0, 0
+				output, err := s.forEachTenant(ctx, func(ctx context.Context, tenantID uuid.UUID) error {
+					result, err := s.attendanceService.ClosePreviousDay(ctx, tenantID)
+					if err != nil {
+						return err
+					}
+					absent += result.MarkedAbsent
+					onLeave += result.MarkedOnLeave
+					checkedOut += result.AutoCheckedOut
+					return nil
+				})
+				return fmt.Sprintf("%s, %d absent, %d on leave, %d checked out", output, absent, onLeave, checkedOut), err
+			},
+		},
+		{
+			Name:        "leave_accrual",
+			Description: "Credit leave accruals due to active employees",
+			Schedule:    "30 0 * * *",
+			Run: func(ctx context.Context) (string, error) {
+				written := 0
+				output, err := s.forEachTenant(ctx, func(ctx context.Context, tenantID uuid.UUID) error {
+					n, err := s.leaveService.RunLeaveAccrual(ctx, tenantID, time.Now())
+					written += n
+					return err
+				})
+				return fmt.Sprintf("%s, %d ledger entries written", output, written), err
+			},
+		},
+		{
+			// Runs every day of January so a missed run catches up; a year already rolled over
+			// is only brought up to date
+			Name:        "leave_year_end_rollover",
+			Description: "Expire last year's leave balances and carry forward what the leave type allows",
+			Schedule:    "45 0 * 1 *",
+			Run: func(ctx context.Context) (string, error) {
+				year := time.Now().Year() - 1
+				written := 0
+				output, err := s.forEachTenant(ctx, func(ctx context.Context, tenantID uuid.UUID) error {
+					n, err := s.leaveService.RunYearEndRollover(ctx, tenantID, year)
+					written += n
+					return err
+				})
+				return fmt.Sprintf("%s, %d ledger entries written for %d", output, written, year), err
+			},
+		},
+	}
+
+	for _, job := range jobs {
+		if err := s.scheduler.Register(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forEachTenant runs fn for every active organization. A failing organization does not stop
+// the others; the job fails if any organization failed.
+func (s *Server) forEachTenant(ctx context.Context, fn func(ctx context.Context, tenantID uuid.UUID) error) (string, error) {
+	tenantIDs, err := s.organizationService.GetActiveTenantIDs(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	failed := 0
+	for _, tenantID := range tenantIDs {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if err := fn(ctx, tenantID); err != nil {
+			log.Error().Err(err).Str("tenant_id", tenantID.String()).Msg("Background job failed for organization")
+			failed++
+		}
+	}
+
+	output := fmt.Sprintf("%d organizations processed", len(tenantIDs)-failed)
+	if failed > 0 {
+		return output, fmt.Errorf("%d of %d organizations failed", failed, len(tenantIDs))
+	}
+	return output, nil
+}
+
+// StartScheduler starts the background jobs unless they are disabled in the configuration
+func (s *Server) StartScheduler(ctx context.Context) error {
+	if !s.config.SchedulerEnabled {
+		log.Info().Msg("Job scheduler disabled")
+		return nil
+	}
+	return s.scheduler.Start(ctx)
+}
@@ -13,7 +13,9 @@ import (
 	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/config"
 	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/db"
 	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/handlers"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/mail"
 	custommiddleware "github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/middleware"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/scheduler"
 	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/services"
 	"github.com/rs/zerolog/log"
 )
@@ -23,10 +25,16 @@ type Server struct {
 	router               chi.Router
 	db                   *sql.DB
 	authService          *auth.Service
+	notificationService  *services.NotificationService
+	notificationHandler  *handlers.NotificationHandler
 	employeeService      *services.EmployeeService
 	employeeHandler      *handlers.EmployeeHandler
 	attendanceService    *services.AttendanceService
 	attendanceHandler    *handlers.AttendanceHandler
+	shiftService         *services.ShiftService
+	shiftHandler         *handlers.ShiftHandler
+	holidayService       *services.HolidayService
+	holidayHandler       *handlers.HolidayHandler
 	biometricService     *services.BiometricService
 	biometricHandler     *handlers.BiometricHandler
 	leaveService         *services.LeaveService
@@ -49,6 +57,8 @@ type Server struct {
 	superAdminHandler    *handlers.SuperAdminHandler
 	tenantHandler        *handlers.TenantHandler
 	organizationHandler  *handlers.OrganizationHandler
+	scheduler            *scheduler.Scheduler
+	jobHandler           *handlers.JobHandler
 	rlsMiddleware        *custommiddleware.RLSMiddleware
 }
 
@@ -77,29 +87,44 @@ func New(cfg *config.Config) (*Server, error) {
 		database,
 		cfg.JWTSecret,
 		cfg.PepperSecret,
+		cfg.EncryptionKey,
 		cfg.AccessTokenTTL,
 		cfg.RefreshTokenTTL,
 	)
 
+	// Initialize email and in-app notifications
+	mailSender := mail.NewSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.FromEmail)
+	notificationService := services.NewNotificationService(database, mailSender, cfg.AppURL)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	authService.SetNotifier(notificationService)
+
 	// Initialize employee service and handler
-	employeeService := services.NewEmployeeService(database, cfg.PepperSecret, cfg.EncryptionKey)
+	employeeService := services.NewEmployeeService(database, notificationService, cfg.PepperSecret, cfg.EncryptionKey)
 	employeeHandler := handlers.NewEmployeeHandler(employeeService)
 
 	// Initialize attendance service and handler
-	attendanceService := services.NewAttendanceService(database)
+	attendanceService := services.NewAttendanceService(database, notificationService)
 	attendanceHandler := handlers.NewAttendanceHandler(attendanceService)
 
+	// Initialize shift service and handler
+	shiftService := services.NewShiftService(database)
+	shiftHandler := handlers.NewShiftHandler(shiftService)
+
+	// Initialize holiday calendar service and handler
+	holidayService := services.NewHolidayService(database)
+	holidayHandler := handlers.NewHolidayHandler(holidayService)
+
 	// Initialize biometric service and handler
-	biometricService := services.NewBiometricService(database)
+	biometricService := services.NewBiometricService(database, cfg.EncryptionKey)
 	biometricHandler := handlers.NewBiometricHandler(biometricService)
 
 	// Initialize leave service and handler
-	leaveService := services.NewLeaveService(database)
+	leaveService := services.NewLeaveService(database, notificationService)
 	leaveHandler := handlers.NewLeaveHandler(leaveService)
 
 	// Initialize payslip service and handler
 	dbx := sqlx.NewDb(database, "pgx")
-	payslipService := services.NewPayslipService(dbx)
+	payslipService := services.NewPayslipService(dbx, notificationService)
 	payslipHandler := handlers.NewPayslipHandler(payslipService)
 
 	// Initialize system management service and handler
@@ -117,7 +142,7 @@ func New(cfg *config.Config) (*Server, error) {
 	// Initialize Super Admin services
 	subscriptionService := services.NewSubscriptionService(database)
 	organizationService := services.NewOrganizationService(database, subscriptionService, cfg.PepperSecret)
-	invoiceService := services.NewInvoiceService(database)
+	invoiceService := services.NewInvoiceService(database, notificationService)
 	usageTrackingService := services.NewUsageTrackingService(database)
 	analyticsService := services.NewAnalyticsService(database)
 	departmentService := services.NewDepartmentService(database)
@@ -138,6 +163,10 @@ func New(cfg *config.Config) (*Server, error) {
 	tenantHandler := handlers.NewTenantHandler(organizationService)
 	organizationHandler := handlers.NewOrganizationHandler(organizationService)
 
+	// Initialize background job scheduler
+	jobScheduler := scheduler.New(database)
+	jobHandler := handlers.NewJobHandler(jobScheduler)
+
 	// Initialize RLS middleware
 	rlsMiddleware := custommiddleware.NewRLSMiddleware(database)
 
@@ -146,10 +175,16 @@ func New(cfg *config.Config) (*Server, error) {
 		router:               chi.NewRouter(),
 		db:                   database,
 		authService:          authService,
+		notificationService:  notificationService,
+		notificationHandler:  notificationHandler,
 		employeeService:      employeeService,
 		employeeHandler:      employeeHandler,
 		attendanceService:    attendanceService,
 		attendanceHandler:    attendanceHandler,
+		shiftService:         shiftService,
+		shiftHandler:         shiftHandler,
+		holidayService:       holidayService,
+		holidayHandler:       holidayHandler,
 		biometricService:     biometricService,
 		biometricHandler:     biometricHandler,
 		leaveService:         leaveService,
@@ -172,9 +207,15 @@ func New(cfg *config.Config) (*Server, error) {
 		superAdminHandler:    superAdminHandler,
 		tenantHandler:        tenantHandler,
 		organizationHandler:  organizationHandler,
+		scheduler:            jobScheduler,
+		jobHandler:           jobHandler,
 		rlsMiddleware:        rlsMiddleware,
 	}
 
+	if err := s.registerJobs(); err != nil {
+		return nil, err
+	}
+
 	// Initialize Google OAuth
 	auth.InitGoogleOAuth()
 
@@ -222,21 +263,24 @@ func (s *Server) setupRoutes() {
 	s.router.Get("/ready", s.readinessHandler)
 
 	// API v1 routes
+	authHandler := auth.NewHandler(s.authService)
+
 	s.router.Route("/api/v1", func(r chi.Router) {
 		// ========================================
 		// PUBLIC ROUTES
 		// ========================================
 		r.Route("/auth", func(r chi.Router) {
-			authHandler := auth.NewHandler(s.authService)
 			authHandler.RegisterRoutes(r)
 		})
 
+		// Biometric terminals authenticate with their own credentials
+		r.Post("/devices/push", s.biometricHandler.PushDeviceData)
+
 		// ========================================
 		// PLATFORM ROUTES (Super Admin ONLY)
 		// ========================================
 		r.Route("/platform", func(r chi.Router) {
 			r.Use(s.authService.Middleware())                 // JWT validation
-			r.Use(custommiddleware.CheckUserStatus(s.db))     // Check user is_active status
 			r.Use(s.rlsMiddleware.SetSessionContextEfficient) // RLS context
 			r.Use(custommiddleware.RequireSuperAdmin)         // RBAC check
 
@@ -279,6 +323,15 @@ func (s *Server) setupRoutes() {
 				r.Get("/revenue", s.superAdminHandler.GetRevenueMetrics)
 			})
 
+			// Background Jobs
+			r.Route("/jobs", func(r chi.Router) {
+				r.Get("/", s.jobHandler.ListJobs)
+				r.Get("/{name}/runs", s.jobHandler.GetJobRuns)
+				r.Post("/{name}/trigger", s.jobHandler.TriggerJob)
+				r.Post("/{name}/pause", s.jobHandler.PauseJob)
+				r.Post("/{name}/resume", s.jobHandler.ResumeJob)
+			})
+
 			r.Route("/usage", func(r chi.Router) {
 				r.Get("/organizations/{id}", s.superAdminHandler.GetOrganizationUsage)
 			})
@@ -314,7 +367,6 @@ func (s *Server) setupRoutes() {
 		// ========================================
 		r.Route("/company", func(r chi.Router) {
 			r.Use(s.authService.Middleware())                      // JWT validation
-			r.Use(custommiddleware.CheckUserStatus(s.db))          // Check user is_active status
 			r.Use(s.rlsMiddleware.SetSessionContextEfficient)      // RLS context
 			r.Use(custommiddleware.BlockSuperAdminFromCompanyData) // Prevent Super Admin access
 
@@ -332,6 +384,11 @@ func (s *Server) setupRoutes() {
 					r.Put("/{employeeID}", s.updateEmployeeHandler)
 					r.Put("/{employeeID}/status", s.employeeHandler.UpdateEmployeeStatus)
 					r.Delete("/{employeeID}", s.deleteEmployeeHandler)
+
+					// Signed-in devices of the employee
+					r.Get("/{employeeID}/sessions", authHandler.ListEmployeeSessions)
+					r.Delete("/{employeeID}/sessions", authHandler.RevokeEmployeeSessions)
+					r.Delete("/{employeeID}/sessions/{sessionID}", authHandler.RevokeEmployeeSession)
 				})
 
 				// Department Management
@@ -367,11 +424,17 @@ func (s *Server) setupRoutes() {
 				r.Route("/biometric", func(r chi.Router) {
 					r.Get("/devices", s.biometricHandler.GetDevices)
 					r.Post("/devices", s.biometricHandler.RegisterDevice)
+					r.Post("/devices/{deviceID}/sync", s.biometricHandler.SyncDeviceData)
+					r.Post("/devices/{deviceID}/secret", s.biometricHandler.RotateDeviceSecret)
 				})
 
 				// Organization Profile
 				r.Get("/organization", s.organizationHandler.GetOrganizationProfile)
 				r.Put("/organization", s.organizationHandler.UpdateOrganizationProfile)
+
+				// Roles that must sign in with two-factor authentication
+				r.Get("/security/two-factor", authHandler.GetTwoFactorPolicy)
+				r.Put("/security/two-factor", authHandler.UpdateTwoFactorPolicy)
 			})
 
 			// ------------------------------------
@@ -414,15 +477,55 @@ func (s *Server) setupRoutes() {
 					r.Get("/stats", s.attendanceHandler.GetAttendanceStats)
 					r.Get("/employees/{employeeId}", s.attendanceHandler.GetEmployeeAttendance)
 					r.Put("/records/{recordId}", s.attendanceHandler.UpdateAttendanceRecord)
+					r.Put("/records/{recordId}/approve", s.attendanceHandler.ApproveAttendanceRecord)
+					r.Put("/records/{recordId}/review", s.attendanceHandler.ReviewAttendanceRecord)
+					r.Post("/close-day", s.attendanceHandler.CloseAttendanceDay)
+					r.Get("/regularizations", s.attendanceHandler.GetRegularizations)
+					r.Put("/employees/{employeeId}/remote-check-in", s.attendanceHandler.SetRemoteCheckIn)
+					r.Put("/employees/{employeeId}/policy", s.attendanceHandler.SetEmployeeAttendancePolicy)
+					r.Put("/departments/{departmentId}/policy", s.attendanceHandler.SetDepartmentAttendancePolicy)
+					r.Get("/zones", s.attendanceHandler.GetAttendanceZones)
+					r.Post("/zones", s.attendanceHandler.CreateAttendanceZone)
+					r.Put("/zones/{zoneId}", s.attendanceHandler.UpdateAttendanceZone)
+					r.Delete("/zones/{zoneId}", s.attendanceHandler.DeleteAttendanceZone)
 					r.Post("/policies", s.attendanceHandler.CreateAttendancePolicy)
 				})
 
+				// Shift Scheduling & Rostering
+				r.Route("/shifts", func(r chi.Router) {
+					r.Get("/templates", s.shiftHandler.GetShiftTemplates)
+					r.Post("/templates", s.shiftHandler.CreateShiftTemplate)
+					r.Put("/templates/{id}", s.shiftHandler.UpdateShiftTemplate)
+					r.Delete("/templates/{id}", s.shiftHandler.DeleteShiftTemplate)
+					r.Get("/roster", s.shiftHandler.GetRoster)
+					r.Post("/roster", s.shiftHandler.CreateRoster)
+					r.Post("/roster/publish", s.shiftHandler.PublishRoster)
+					r.Post("/roster/swap", s.shiftHandler.SwapShifts)
+				})
+
+				// Holiday Calendar
+				r.Route("/holidays", func(r chi.Router) {
+					r.Get("/", s.holidayHandler.GetHolidays)
+					r.Post("/", s.holidayHandler.CreateHoliday)
+					r.Post("/import", s.holidayHandler.ImportHolidays)
+					r.Put("/{id}", s.holidayHandler.UpdateHoliday)
+					r.Delete("/{id}", s.holidayHandler.DeleteHoliday)
+				})
+
 				// Leave Management
 				r.Route("/leaves", func(r chi.Router) {
 					r.Get("/", s.getLeavesHandler)
 					r.Put("/{id}/approve", s.approveLeaveHandler)
 					r.Put("/{id}/reject", s.rejectLeaveHandler)
 					r.Post("/types", s.leaveHandler.CreateLeaveType)
+					r.Get("/balances/{employeeId}", s.leaveHandler.GetEmployeeLeaveBalances)
+					r.Post("/balances/{employeeId}/adjustments", s.leaveHandler.AdjustLeaveBalance)
+					r.Post("/accruals/run", s.leaveHandler.RunLeaveAccrual)
+					r.Post("/rollover", s.leaveHandler.RunYearEndRollover)
+					r.Get("/workflows", s.leaveHandler.GetApprovalWorkflows)
+					r.Post("/workflows", s.leaveHandler.CreateApprovalWorkflow)
+					r.Put("/workflows/{workflowId}", s.leaveHandler.UpdateApprovalWorkflow)
+					r.Delete("/workflows/{workflowId}", s.leaveHandler.DeleteApprovalWorkflow)
 				})
 
 				// Payslip Management
@@ -430,10 +533,22 @@ func (s *Server) setupRoutes() {
 					r.Get("/", s.payslipHandler.GetPayslips)
 					r.Post("/", s.payslipHandler.CreatePayslip)
 					r.Get("/stats", s.payslipHandler.GetPayslipStats)
+					r.Get("/download", s.payslipHandler.DownloadPayslips)
 					r.Put("/{id}", s.payslipHandler.UpdatePayslip)
 					r.Delete("/{id}", s.payslipHandler.DeletePayslip)
 				})
 
+				// Payroll Runs
+				r.Route("/payroll-runs", func(r chi.Router) {
+					r.Get("/", s.payslipHandler.GetPayrollRuns)
+					r.Post("/", s.payslipHandler.CreatePayrollRun)
+					r.Get("/{runId}", s.payslipHandler.GetPayrollRun)
+					r.Post("/{runId}/generate", s.payslipHandler.GeneratePayrollRun)
+					r.Post("/{runId}/lock", s.payslipHandler.LockPayrollRun)
+					r.Post("/{runId}/publish", s.payslipHandler.PublishPayrollRun)
+					r.Post("/{runId}/reopen", s.payslipHandler.ReopenPayrollRun)
+				})
+
 				// Biometric Logs
 				r.Get("/biometric/logs", s.biometricHandler.GetBiometricLogs)
 			})
@@ -465,22 +580,59 @@ func (s *Server) setupRoutes() {
 					r.Get("/my-status", s.attendanceHandler.GetCurrentUserStatus)
 					r.Post("/checkin", s.attendanceHandler.CheckIn)
 					r.Post("/checkout", s.attendanceHandler.CheckOut)
+					r.Post("/break/start", s.attendanceHandler.StartBreak)
+					r.Post("/break/end", s.attendanceHandler.EndBreak)
+					r.Get("/regularizations", s.attendanceHandler.GetMyRegularizations)
+					r.Post("/regularizations", s.attendanceHandler.CreateRegularization)
+					r.Put("/regularizations/{id}/cancel", s.attendanceHandler.CancelRegularization)
 				})
 
+				// Shifts
+				r.Get("/shifts", s.shiftHandler.GetMyShifts)
+
+				// Holidays
+				r.Get("/holidays", s.holidayHandler.GetHolidays)
+
 				// Leaves
 				r.Route("/leaves", func(r chi.Router) {
 					r.Get("/", s.getLeavesHandler) // Will filter by self via RLS
 					r.Post("/", s.createLeaveHandler)
 					r.Get("/types", s.policyHandler.GetLeaveTypes)
+					r.Get("/balances", s.leaveHandler.GetMyLeaveBalances)
+					r.Put("/{id}/cancel", s.leaveHandler.CancelLeave)
+					r.Post("/{id}/modify", s.leaveHandler.ModifyLeave)
+				})
+
+				// Leave and attendance approvals assigned or delegated to the current user
+				r.Route("/approvals", func(r chi.Router) {
+					r.Get("/", s.leaveHandler.GetPendingApprovals)
+					r.Put("/{id}/approve", s.leaveHandler.ApproveLeave)
+					r.Put("/{id}/reject", s.leaveHandler.RejectLeave)
+					r.Get("/regularizations", s.attendanceHandler.GetPendingRegularizations)
+					r.Put("/regularizations/{id}/approve", s.attendanceHandler.ApproveRegularization)
+					r.Put("/regularizations/{id}/reject", s.attendanceHandler.RejectRegularization)
+					r.Get("/delegations", s.leaveHandler.GetApprovalDelegations)
+					r.Post("/delegations", s.leaveHandler.CreateApprovalDelegation)
+					r.Delete("/delegations/{delegationId}", s.leaveHandler.RevokeApprovalDelegation)
 				})
 
 				// Payslips
-				r.Get("/payslips", s.payslipHandler.GetPayslips) // Will filter by self via RLS (need to verify handler)
-				r.Get("/payslips/{id}", s.payslipHandler.GetPayslip)
+				r.Get("/payslips", s.payslipHandler.GetMyPayslips)
+				r.Get("/payslips/{id}", s.payslipHandler.GetMyPayslip)
+				r.Get("/payslips/{id}/pdf", s.payslipHandler.GetMyPayslipPDF)
 
 				// Dashboard
 				r.Get("/dashboard/stats", s.getDashboardStatsHandler)
 
+				// Notifications
+				r.Route("/notifications", func(r chi.Router) {
+					r.Get("/", s.notificationHandler.GetNotifications)
+					r.Get("/unread-count", s.notificationHandler.GetUnreadCount)
+					r.Get("/stream", s.notificationHandler.Stream)
+					r.Put("/read-all", s.notificationHandler.MarkAllRead)
+					r.Put("/{id}/read", s.notificationHandler.MarkRead)
+				})
+
 				// Settings
 				r.Route("/settings", func(r chi.Router) {
 					r.Get("/profile", s.userSettingsHandler.GetUserProfile)
@@ -489,6 +641,18 @@ func (s *Server) setupRoutes() {
 					r.Put("/preferences", s.userSettingsHandler.UpdateUserPreferences)
 					r.Get("/security", s.userSettingsHandler.GetSecuritySettings)
 					r.Put("/security", s.userSettingsHandler.UpdateSecuritySettings)
+					r.Route("/security/2fa", func(r chi.Router) {
+						r.Get("/", authHandler.GetTwoFactorStatus)
+						r.Post("/setup", authHandler.BeginTwoFactorSetup)
+						r.Post("/verify", authHandler.ConfirmTwoFactorSetup)
+						r.Post("/disable", authHandler.DisableTwoFactor)
+						r.Post("/recovery-codes", authHandler.RegenerateRecoveryCodes)
+					})
+					r.Route("/security/sessions", func(r chi.Router) {
+						r.Get("/", authHandler.ListSessions)
+						r.Delete("/", authHandler.RevokeOtherSessions)
+						r.Delete("/{sessionID}", authHandler.RevokeSession)
+					})
 					r.Get("/theme", s.userSettingsHandler.GetUserTheme)
 					r.Put("/theme", s.userSettingsHandler.UpdateUserTheme)
 				})
@@ -502,6 +666,9 @@ func (s *Server) Routes() http.Handler {
 }
 
 func (s *Server) Close() {
+	// Let running jobs finish before their database goes away
+	s.scheduler.Stop()
+
 	// Close database connections, Redis, etc.
 	if s.db != nil {
 		db.Close(s.db)
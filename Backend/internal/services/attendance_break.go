@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+const attendanceBreakColumns = `id, tenant_id, attendance_record_id, employee_id, start_time, end_time,
+	duration_minutes, source, device_id, created_at, updated_at`
+
+func scanAttendanceBreak(row interface{ Scan(...interface{}) error }) (*models.AttendanceBreak, error) {
+	var b models.AttendanceBreak
+	err := row.Scan(&b.ID, &b.TenantID, &b.AttendanceRecordID, &b.EmployeeID, &b.StartTime, &b.EndTime,
+		&b.DurationMinutes, &b.Source, &b.DeviceID, &b.CreatedAt, &b.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// StartBreak starts a break on the employee's open attendance record of today
+func (s *AttendanceService) StartBreak(ctx context.Context, tenantID, employeeID uuid.UUID) (*models.AttendanceBreak, error) {
+	recordID, err := s.openRecordForBreak(ctx, tenantID, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	return startBreak(ctx, s.db, tenantID, recordID, employeeID, time.Now(), models.SourceWeb, nil)
+}
+
+// EndBreak ends the employee's break in progress and updates the break total of the day
+func (s *AttendanceService) EndBreak(ctx context.Context, tenantID, employeeID uuid.UUID) (*models.AttendanceBreak, error) {
+	recordID, err := s.openRecordForBreak(ctx, tenantID, employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	allowance, err := breakAllowance(ctx, s.db, tenantID, employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := endBreak(ctx, s.db, tenantID, recordID, time.Now(), allowance)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, fmt.Errorf("no break in progress")
+	}
+	return b, nil
+}
+
+// openRecordForBreak returns today's record of an employee who is checked in and has not yet
+// checked out
+func (s *AttendanceService) openRecordForBreak(ctx context.Context, tenantID, employeeID uuid.UUID) (uuid.UUID, error) {
+	schedule, err := resolveSchedule(ctx, s.db, tenantID, employeeID, time.Now())
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var recordID uuid.UUID
+	var checkIn, checkOut sql.NullTime
+	err = s.db.QueryRowContext(ctx, `
+		SELECT id, check_in_time, check_out_time
+		FROM attendance_records
+		WHERE tenant_id = $1 AND employee_id = $2 AND date = $3`,
+		tenantID, employeeID, schedule.Date.Format("2006-01-02")).Scan(&recordID, &checkIn, &checkOut)
+	if err == sql.ErrNoRows || (err == nil && !checkIn.Valid) {
+		return uuid.Nil, fmt.Errorf("employee has not checked in today")
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get attendance record: %w", err)
+	}
+	if checkOut.Valid {
+		return uuid.Nil, fmt.Errorf("employee already checked out today")
+	}
+	return recordID, nil
+}
+
+// getRecordBreaks lists the breaks of an attendance record in the order they were taken
+func getRecordBreaks(ctx context.Context, q dbExecutor, recordID uuid.UUID) ([]models.AttendanceBreak, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+attendanceBreakColumns+`
+		FROM attendance_breaks
+		WHERE attendance_record_id = $1
+		ORDER BY start_time`, recordID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attendance breaks: %w", err)
+	}
+	defer rows.Close()
+
+	breaks := make([]models.AttendanceBreak, 0)
+	for rows.Next() {
+		b, err := scanAttendanceBreak(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attendance break: %w", err)
+		}
+		breaks = append(breaks, *b)
+	}
+	return breaks, rows.Err()
+}
+
+// startBreak opens a break on a record
+func startBreak(ctx context.Context, q dbExecutor, tenantID, recordID, employeeID uuid.UUID, at time.Time, source models.AttendanceSource, deviceID *uuid.UUID) (*models.AttendanceBreak, error) {
+	b, err := scanAttendanceBreak(q.QueryRowContext(ctx, `
+		INSERT INTO attendance_breaks (id, tenant_id, attendance_record_id, employee_id, start_time, source, device_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		RETURNING `+attendanceBreakColumns,
+		uuid.New(), tenantID, recordID, employeeID, at, source, deviceID))
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, fmt.Errorf("a break is already in progress")
+		}
+		return nil, fmt.Errorf("failed to start break: %w", err)
+	}
+	return b, nil
+}
+
+// endBreak closes the break in progress on a record at the given time and updates the
+// record's break total. It returns nil when no break is in progress.
+func endBreak(ctx context.Context, q dbExecutor, tenantID, recordID uuid.UUID, at time.Time, allowance int) (*models.AttendanceBreak, error) {
+	b, err := scanAttendanceBreak(q.QueryRowContext(ctx, `
+		UPDATE attendance_breaks
+		SET end_time = GREATEST($1, start_time),
+		    duration_minutes = ROUND(EXTRACT(EPOCH FROM GREATEST($1, start_time) - start_time) / 60),
+		    updated_at = NOW()
+		WHERE tenant_id = $2 AND attendance_record_id = $3 AND end_time IS NULL
+		RETURNING `+attendanceBreakColumns,
+		at, tenantID, recordID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to end break: %w", err)
+	}
+
+	if err := updateBreakTotal(ctx, q, recordID, allowance); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// updateBreakTotal sums the finished breaks of a record into break_duration_minutes and flags
+// the record when the total is over the allowance. An allowance of zero sets no limit.
+func updateBreakTotal(ctx context.Context, q dbExecutor, recordID uuid.UUID, allowance int) error {
+	_, err := q.ExecContext(ctx, `
+		UPDATE attendance_records ar
+		SET break_duration_minutes = totals.minutes,
+		    is_excessive_break = ($2 > 0 AND totals.minutes > $2),
+		    updated_at = NOW()
+		FROM (
+		    SELECT COALESCE(SUM(duration_minutes), 0) AS minutes
+		    FROM attendance_breaks
+		    WHERE attendance_record_id = $1 AND end_time IS NOT NULL
+		) totals
+		WHERE ar.id = $1`, recordID, allowance)
+	if err != nil {
+		return fmt.Errorf("failed to update break total: %w", err)
+	}
+	return nil
+}
+
+// breakAllowance returns the break minutes a day may include under the employee's policy
+func breakAllowance(ctx context.Context, db *sql.DB, tenantID, employeeID uuid.UUID) (int, error) {
+	policy, _, err := getEffectivePolicy(ctx, db, tenantID, employeeID)
+	if err != nil {
+		return 0, err
+	}
+	return policy.BreakDurationMinutes, nil
+}
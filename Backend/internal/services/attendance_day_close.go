@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+// ClosePreviousDay closes the attendance day before today in the tenant's timezone. It is
+// safe to run repeatedly; a day already closed is left as it is.
+func (s *AttendanceService) ClosePreviousDay(ctx context.Context, tenantID uuid.UUID) (*models.AttendanceDayCloseResult, error) {
+	now := time.Now().In(tenantLocation(ctx, s.db, tenantID))
+	yesterday := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, time.UTC)
+	return s.CloseAttendanceDay(ctx, tenantID, yesterday)
+}
+
+// CloseAttendanceDay finishes the attendance of a past day:
+//   - employees expected at work without a record are marked "on leave" when approved leave
+//     covers the day and "absent" otherwise. Days off under the employee's attendance policy
+//     and holidays of their location are skipped unless a shift was published for them.
+//   - records of the day or earlier that were checked in but never checked out are closed at
+//     the policy's default checkout time, or at the end of the shift or working day.
+//
+// Every record created or closed here is flagged for HR review.
+func (s *AttendanceService) CloseAttendanceDay(ctx context.Context, tenantID uuid.UUID, date time.Time) (*models.AttendanceDayCloseResult, error) {
+	loc := tenantLocation(ctx, s.db, tenantID)
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+	now := time.Now().In(loc)
+	if day.AddDate(0, 0, 1).After(now) {
+		return nil, fmt.Errorf("only past days can be closed")
+	}
+
+	policies, err := loadAttendancePolicies(ctx, s.db, tenantID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.AttendanceDayCloseResult{Date: day.Format("2006-01-02")}
+
+	if err := s.markMissingAttendance(ctx, tenantID, day, policies, result); err != nil {
+		return nil, err
+	}
+	if err := s.closeOpenRecords(ctx, tenantID, day, now, policies, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// markMissingAttendance creates the "absent" and "on leave" records of a day
+func (s *AttendanceService) markMissingAttendance(ctx context.Context, tenantID uuid.UUID, day time.Time, policies *attendancePolicies, result *models.AttendanceDayCloseResult) error {
+	key := day.Format("2006-01-02")
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT e.id, COALESCE(TRIM(up.work_location), ''),
+		       EXISTS (
+		           SELECT 1 FROM shift_assignments sa
+		           WHERE sa.tenant_id = e.tenant_id AND sa.employee_id = e.id
+		             AND sa.shift_date = $2 AND sa.status = 'published'
+		       )
+		FROM employees e
+		LEFT JOIN user_profiles up ON up.user_id = e.user_id
+		WHERE e.tenant_id = $1 AND e.employment_status = 'active'
+		  AND (e.date_of_joining IS NULL OR e.date_of_joining <= $2)
+		  AND (e.date_of_leaving IS NULL OR e.date_of_leaving >= $2)
+		  AND NOT EXISTS (
+		      SELECT 1 FROM attendance_records ar
+		      WHERE ar.tenant_id = e.tenant_id AND ar.employee_id = e.id AND ar.date = $2
+		  )`, tenantID, key)
+	if err != nil {
+		return fmt.Errorf("failed to get employees without attendance: %w", err)
+	}
+
+	type missingEmployee struct {
+		ID       uuid.UUID
+		Location string
+		HasShift bool
+	}
+	employees := make([]missingEmployee, 0)
+	for rows.Next() {
+		var e missingEmployee
+		if err := rows.Scan(&e.ID, &e.Location, &e.HasShift); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan employee: %w", err)
+		}
+		employees = append(employees, e)
+	}
+	rows.Close()
+
+	holidaysByLocation := make(map[string]bool)
+
+	for _, e := range employees {
+		if !e.HasShift {
+			if !containsWeekday(policies.forEmployee(e.ID).WorkingDays, day.Weekday()) {
+				continue
+			}
+			holiday, ok := holidaysByLocation[e.Location]
+			if !ok {
+				holidays, err := holidayDates(ctx, s.db, tenantID, e.Location, day, day)
+				if err != nil {
+					return err
+				}
+				holiday = holidays[key]
+				holidaysByLocation[e.Location] = holiday
+			}
+			if holiday {
+				continue
+			}
+		}
+
+		// Half days of approved leave are left unmarked at approval so the employee can work
+		// the other half; they end up here when the employee did not come in
+		var leaveID uuid.NullUUID
+		err := s.db.QueryRowContext(ctx, `
+			SELECT id FROM leave_requests
+			WHERE tenant_id = $1 AND employee_id = $2 AND status = 'approved'
+			  AND start_date <= $3 AND end_date >= $3
+			ORDER BY created_at DESC
+			LIMIT 1`, tenantID, e.ID, key).Scan(&leaveID)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to get approved leave: %w", err)
+		}
+
+		status, reason, notes := "absent", models.ReviewReasonAutoAbsent, "Marked absent: no attendance recorded"
+		var leaveRequestID *uuid.UUID
+		if leaveID.Valid {
+			status, reason, notes = "on_leave", models.ReviewReasonAutoOnLeave, "Marked on leave from approved leave"
+			leaveRequestID = &leaveID.UUID
+		}
+
+		res, err := s.db.ExecContext(ctx, `
+			INSERT INTO attendance_records (id, tenant_id, employee_id, date, status, leave_request_id, notes,
+			                                needs_review, review_reason, source, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, true, $8, $9, NOW(), NOW())
+			ON CONFLICT (tenant_id, employee_id, date) DO NOTHING`,
+			uuid.New(), tenantID, e.ID, key, status, leaveRequestID, notes, reason, models.SourceSystem)
+		if err != nil {
+			return fmt.Errorf("failed to mark attendance: %w", err)
+		}
+		if inserted, _ := res.RowsAffected(); inserted == 0 {
+			continue
+		}
+
+		if leaveRequestID != nil {
+			result.MarkedOnLeave++
+		} else {
+			result.MarkedAbsent++
+		}
+	}
+
+	return nil
+}
+
+// closeOpenRecords checks out the records of the day or earlier that are still open. A record
+// whose shift has not yet ended, such as an overnight shift, is left open.
+func (s *AttendanceService) closeOpenRecords(ctx context.Context, tenantID uuid.UUID, day, now time.Time, policies *attendancePolicies, result *models.AttendanceDayCloseResult) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT ar.id, ar.employee_id, ar.date, ar.check_in_time,
+		       COALESCE((SELECT MAX(s.check_in_time) FROM attendance_sessions s
+		                 WHERE s.attendance_record_id = ar.id AND s.check_out_time IS NULL), ar.check_in_time),
+		       sa.id, to_char(st.start_time, 'HH24:MI'), to_char(st.end_time, 'HH24:MI'),
+		       st.break_duration_minutes, st.grace_period_minutes
+		FROM attendance_records ar
+		LEFT JOIN shift_assignments sa ON sa.id = ar.shift_assignment_id
+		LEFT JOIN shift_templates st ON st.id = sa.shift_template_id
+		WHERE ar.tenant_id = $1 AND ar.date <= $2
+		  AND ar.check_in_time IS NOT NULL AND ar.check_out_time IS NULL`,
+		tenantID, day.Format("2006-01-02"))
+	if err != nil {
+		return fmt.Errorf("failed to get open attendance records: %w", err)
+	}
+
+	type openRecord struct {
+		ID           uuid.UUID
+		EmployeeID   uuid.UUID
+		Date         time.Time
+		CheckIn      time.Time // first check-in of the day
+		LastIn       time.Time // check-in of the open session
+		ShiftID      uuid.NullUUID
+		StartTime    sql.NullString
+		EndTime      sql.NullString
+		BreakMinutes sql.NullInt64
+		GraceMinutes sql.NullInt64
+	}
+	records := make([]openRecord, 0)
+	for rows.Next() {
+		var r openRecord
+		if err := rows.Scan(&r.ID, &r.EmployeeID, &r.Date, &r.CheckIn, &r.LastIn, &r.ShiftID, &r.StartTime,
+			&r.EndTime, &r.BreakMinutes, &r.GraceMinutes); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan attendance record: %w", err)
+		}
+		r.CheckIn = r.CheckIn.In(day.Location())
+		r.LastIn = r.LastIn.In(day.Location())
+		records = append(records, r)
+	}
+	rows.Close()
+
+	for _, r := range records {
+		policy := policies.forEmployee(r.EmployeeID)
+
+		var schedule *workSchedule
+		recordDay := time.Date(r.Date.Year(), r.Date.Month(), r.Date.Day(), 0, 0, 0, 0, day.Location())
+		onShift := r.ShiftID.Valid && r.StartTime.Valid && r.EndTime.Valid
+		if onShift {
+			grace := policy.GracePeriodMinutes
+			if r.GraceMinutes.Valid {
+				grace = int(r.GraceMinutes.Int64)
+			}
+			schedule = shiftSchedule(recordDay, r.StartTime.String, r.EndTime.String, int(r.BreakMinutes.Int64), grace)
+			schedule.ShiftAssignmentID = &r.ShiftID.UUID
+		} else {
+			schedule = policySchedule(policy, recordDay)
+		}
+
+		checkOut := schedule.End
+		if !onShift && policy.DefaultCheckoutTime != nil {
+			if clock, err := time.Parse("15:04", *policy.DefaultCheckoutTime); err == nil {
+				checkOut = schedule.Date.Add(time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute)
+			}
+		}
+		if checkOut.Add(overnightCheckOutSlack).After(now) {
+			continue
+		}
+		if checkOut.Before(r.LastIn) {
+			checkOut = r.LastIn
+		}
+
+		if _, err := endBreak(ctx, s.db, tenantID, r.ID, checkOut, policy.BreakDurationMinutes); err != nil {
+			return err
+		}
+		totalHours, err := closeSession(ctx, s.db, tenantID, r.ID, r.EmployeeID, r.CheckIn, checkOut, models.SourceSystem, nil)
+		if err != nil {
+			return err
+		}
+		overtime, earlyDeparture := evaluateWorkedDay(schedule, time.Duration(totalHours*float64(time.Hour)), checkOut)
+
+		res, err := s.db.ExecContext(ctx, `
+			UPDATE attendance_records
+			SET check_out_time = $1, total_hours = $2, overtime_hours = $3, is_early_departure = $4,
+			    notes = CONCAT_WS('; ', NULLIF(notes, ''), $5::TEXT),
+			    needs_review = true, review_reason = $6, updated_at = NOW()
+			WHERE id = $7 AND check_out_time IS NULL`,
+			checkOut, totalHours, overtime, earlyDeparture,
+			"Checked out automatically at "+checkOut.Format("15:04"), models.ReviewReasonAutoCheckout, r.ID)
+		if err != nil {
+			return fmt.Errorf("failed to close attendance record: %w", err)
+		}
+		if closed, _ := res.RowsAffected(); closed > 0 {
+			result.AutoCheckedOut++
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,520 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+const regularizationColumns = `
+	r.id, r.tenant_id, r.employee_id, r.attendance_record_id, r.date, r.type, r.requested_check_in,
+	r.requested_check_out, r.reason, r.status, r.approver_user_id, r.approver_role, r.decided_by,
+	r.decided_at, r.decision_comment, r.original_values, r.created_at, r.updated_at,
+	CONCAT(u.first_name, ' ', u.last_name)`
+
+const regularizationFrom = `
+	FROM attendance_regularization_requests r
+	JOIN employees e ON e.id = r.employee_id
+	LEFT JOIN users u ON u.id = e.user_id`
+
+func scanRegularization(row interface{ Scan(...interface{}) error }) (*models.AttendanceRegularization, error) {
+	var req models.AttendanceRegularization
+	var originalValues []byte
+	err := row.Scan(&req.ID, &req.TenantID, &req.EmployeeID, &req.AttendanceRecordID, &req.Date, &req.Type,
+		&req.RequestedCheckIn, &req.RequestedCheckOut, &req.Reason, &req.Status, &req.ApproverUserID,
+		&req.ApproverRole, &req.DecidedBy, &req.DecidedAt, &req.DecisionComment, &originalValues,
+		&req.CreatedAt, &req.UpdatedAt, &req.EmployeeName)
+	if err != nil {
+		return nil, err
+	}
+	if len(originalValues) > 0 {
+		req.OriginalValues = originalValues
+	}
+	return &req, nil
+}
+
+// CreateRegularization files an employee's request to correct the attendance of a past day or
+// today. It is routed to the employee's manager, or the department head, and to HR when the
+// employee has neither.
+func (s *AttendanceService) CreateRegularization(ctx context.Context, tenantID, employeeID uuid.UUID, req models.CreateRegularizationRequest) (*models.AttendanceRegularization, error) {
+	loc := tenantLocation(ctx, s.db, tenantID)
+
+	date, err := time.ParseInLocation("2006-01-02", req.Date, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format (use YYYY-MM-DD)")
+	}
+	now := time.Now().In(loc)
+	if date.After(now) {
+		return nil, fmt.Errorf("attendance of a future day cannot be regularized")
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return nil, fmt.Errorf("reason is required")
+	}
+
+	switch req.Type {
+	case models.RegularizationMissedCheckIn:
+		if req.CheckIn == nil {
+			return nil, fmt.Errorf("check_in is required for a missed check-in")
+		}
+	case models.RegularizationMissedCheckOut:
+		if req.CheckOut == nil {
+			return nil, fmt.Errorf("check_out is required for a missed check-out")
+		}
+	case models.RegularizationWrongTime:
+		if req.CheckIn == nil && req.CheckOut == nil {
+			return nil, fmt.Errorf("check_in or check_out is required to correct a time")
+		}
+	case models.RegularizationWorkFromHome:
+	default:
+		return nil, fmt.Errorf("invalid regularization type")
+	}
+
+	// Check-in falls on the day; check-out may run into the next day for overnight work
+	if req.CheckIn != nil && (req.CheckIn.Before(date) || !req.CheckIn.Before(date.AddDate(0, 0, 1))) {
+		return nil, fmt.Errorf("check_in must be on %s", req.Date)
+	}
+	if req.CheckOut != nil && (!req.CheckOut.After(date) || req.CheckOut.After(date.AddDate(0, 0, 2))) {
+		return nil, fmt.Errorf("check_out must be on %s or the following day", req.Date)
+	}
+	for _, t := range []*time.Time{req.CheckIn, req.CheckOut} {
+		if t != nil && t.After(now) {
+			return nil, fmt.Errorf("requested times cannot be in the future")
+		}
+	}
+	if req.CheckIn != nil && req.CheckOut != nil && !req.CheckOut.After(*req.CheckIn) {
+		return nil, fmt.Errorf("check_out must be after check_in")
+	}
+
+	var pending bool
+	err = s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM attendance_regularization_requests
+			WHERE tenant_id = $1 AND employee_id = $2 AND date = $3 AND status = 'pending'
+		)`, tenantID, employeeID, req.Date).Scan(&pending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check pending regularizations: %w", err)
+	}
+	if pending {
+		return nil, fmt.Errorf("a regularization request for this day is already pending")
+	}
+
+	var requesterID uuid.NullUUID
+	err = s.db.QueryRowContext(ctx,
+		"SELECT user_id FROM employees WHERE id = $1 AND tenant_id = $2", employeeID, tenantID).Scan(&requesterID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("employee not found")
+		}
+		return nil, fmt.Errorf("failed to get employee: %w", err)
+	}
+
+	approverUserID, _, err := resolveApprover(ctx, s.db, tenantID, employeeID, approvalChainStep{ApproverType: models.ApproverManager})
+	if err != nil {
+		return nil, err
+	}
+	var approverRole *string
+	if approverUserID == nil || (requesterID.Valid && *approverUserID == requesterID.UUID) {
+		role := "hr"
+		approverUserID, approverRole = nil, &role
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	id := uuid.New()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO attendance_regularization_requests (
+			id, tenant_id, employee_id, date, type, requested_check_in, requested_check_out, reason,
+			status, approver_user_id, approver_role
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'pending', $9, $10)`,
+		id, tenantID, employeeID, req.Date, req.Type, req.CheckIn, req.CheckOut, req.Reason,
+		approverUserID, approverRole)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create regularization request: %w", err)
+	}
+
+	notifications, err := s.notificationService.QueueRegularizationSubmitted(ctx, tx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit regularization request: %w", err)
+	}
+	s.notificationService.Publish(notifications)
+
+	return s.GetRegularization(ctx, tenantID, id)
+}
+
+// GetRegularization returns a regularization request
+func (s *AttendanceService) GetRegularization(ctx context.Context, tenantID, requestID uuid.UUID) (*models.AttendanceRegularization, error) {
+	req, err := scanRegularization(s.db.QueryRowContext(ctx,
+		`SELECT `+regularizationColumns+regularizationFrom+` WHERE r.id = $1 AND r.tenant_id = $2`,
+		requestID, tenantID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("regularization request not found")
+		}
+		return nil, fmt.Errorf("failed to get regularization request: %w", err)
+	}
+	return req, nil
+}
+
+// GetRegularizations lists regularization requests, newest first. A nil employee lists the
+// whole tenant and an empty status lists every status.
+func (s *AttendanceService) GetRegularizations(ctx context.Context, tenantID uuid.UUID, employeeID *uuid.UUID, status string) ([]models.AttendanceRegularization, error) {
+	query := `SELECT ` + regularizationColumns + regularizationFrom + ` WHERE r.tenant_id = $1`
+	args := []interface{}{tenantID}
+
+	if employeeID != nil {
+		args = append(args, *employeeID)
+		query += fmt.Sprintf(" AND r.employee_id = $%d", len(args))
+	}
+	if status != "" && status != "all" {
+		args = append(args, status)
+		query += fmt.Sprintf(" AND r.status = $%d", len(args))
+	}
+	query += " ORDER BY r.date DESC, r.created_at DESC"
+
+	return s.queryRegularizations(ctx, query, args...)
+}
+
+// GetPendingRegularizations lists the pending requests the user may decide, directly, through
+// their role or as a delegate of the approver
+func (s *AttendanceService) GetPendingRegularizations(ctx context.Context, tenantID, userID uuid.UUID, role string) ([]models.AttendanceRegularization, error) {
+	return s.queryRegularizations(ctx, `SELECT `+regularizationColumns+regularizationFrom+`
+		WHERE r.tenant_id = $1 AND r.status = 'pending'
+		  AND (e.user_id IS NULL OR e.user_id <> $2)
+		  AND (
+		      r.approver_user_id = $2
+		      OR (r.approver_user_id IS NULL AND r.approver_role = $3)
+		      OR r.approver_user_id IN (
+		          SELECT delegator_id FROM approval_delegations
+		          WHERE tenant_id = $1 AND delegate_id = $2 AND revoked_at IS NULL
+		            AND CURRENT_DATE BETWEEN start_date AND end_date
+		      )
+		  )
+		ORDER BY r.created_at`, tenantID, userID, role)
+}
+
+func (s *AttendanceService) queryRegularizations(ctx context.Context, query string, args ...interface{}) ([]models.AttendanceRegularization, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query regularization requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := make([]models.AttendanceRegularization, 0)
+	for rows.Next() {
+		req, err := scanRegularization(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan regularization request: %w", err)
+		}
+		requests = append(requests, *req)
+	}
+	return requests, rows.Err()
+}
+
+// pendingRegularization is the part of a request needed to decide it
+type pendingRegularization struct {
+	EmployeeID        uuid.UUID
+	RequesterID       uuid.NullUUID
+	Date              time.Time
+	Type              models.RegularizationType
+	RequestedCheckIn  *time.Time
+	RequestedCheckOut *time.Time
+	Reason            string
+	Status            models.RegularizationStatus
+	ApproverUserID    *uuid.UUID
+	ApproverRole      *string
+}
+
+// lockRegularizationForDecision loads a pending request for update and checks that the actor
+// may decide it
+func lockRegularizationForDecision(ctx context.Context, tx *sql.Tx, tenantID, requestID, actorID uuid.UUID, actorRole string) (*pendingRegularization, error) {
+	var p pendingRegularization
+	err := tx.QueryRowContext(ctx, `
+		SELECT r.employee_id, e.user_id, r.date, r.type, r.requested_check_in, r.requested_check_out,
+		       r.reason, r.status, r.approver_user_id, r.approver_role
+		FROM attendance_regularization_requests r
+		JOIN employees e ON e.id = r.employee_id
+		WHERE r.id = $1 AND r.tenant_id = $2
+		FOR UPDATE OF r`, requestID, tenantID).Scan(
+		&p.EmployeeID, &p.RequesterID, &p.Date, &p.Type, &p.RequestedCheckIn, &p.RequestedCheckOut,
+		&p.Reason, &p.Status, &p.ApproverUserID, &p.ApproverRole)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("regularization request not found")
+		}
+		return nil, fmt.Errorf("failed to get regularization request: %w", err)
+	}
+
+	if p.Status != models.RegularizationPending {
+		return nil, fmt.Errorf("regularization request is already %s", p.Status)
+	}
+
+	notAuthorized := fmt.Errorf("not authorized to act on this regularization request")
+	if p.RequesterID.Valid && p.RequesterID.UUID == actorID {
+		return nil, notAuthorized
+	}
+	if isAdminRole(actorRole) {
+		return &p, nil
+	}
+	if p.ApproverUserID == nil {
+		if p.ApproverRole != nil && *p.ApproverRole == actorRole {
+			return &p, nil
+		}
+		return nil, notAuthorized
+	}
+	if *p.ApproverUserID == actorID {
+		return &p, nil
+	}
+
+	var delegated bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM approval_delegations
+			WHERE tenant_id = $1 AND delegator_id = $2 AND delegate_id = $3
+			  AND revoked_at IS NULL AND CURRENT_DATE BETWEEN start_date AND end_date
+		)`, tenantID, *p.ApproverUserID, actorID).Scan(&delegated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check approval delegation: %w", err)
+	}
+	if !delegated {
+		return nil, notAuthorized
+	}
+	return &p, nil
+}
+
+// ApproveRegularization approves a request and patches the attendance record of its day,
+// creating it when the employee has none. The record is marked as regularized and its previous
+// values are stored on the request.
+func (s *AttendanceService) ApproveRegularization(ctx context.Context, tenantID, requestID, actorID uuid.UUID, actorRole, comment string) (*models.AttendanceRegularization, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	p, err := lockRegularizationForDecision(ctx, tx, tenantID, requestID, actorID, actorRole)
+	if err != nil {
+		return nil, err
+	}
+
+	day := p.Date.Format("2006-01-02")
+
+	var recordID uuid.UUID
+	var original models.RegularizationOriginalValues
+	var status sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, check_in_time, check_out_time, total_hours, overtime_hours, status,
+		       is_early_departure, source, notes
+		FROM attendance_records
+		WHERE tenant_id = $1 AND employee_id = $2 AND date = $3
+		FOR UPDATE`, tenantID, p.EmployeeID, day).Scan(
+		&recordID, &original.CheckInTime, &original.CheckOutTime, &original.TotalHours,
+		&original.OvertimeHours, &status, &original.IsEarlyDeparture, &original.Source, &original.Notes)
+	hasRecord := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get attendance record: %w", err)
+	}
+	original.Status = status.String
+
+	checkIn, checkOut := original.CheckInTime, original.CheckOutTime
+	if p.RequestedCheckIn != nil {
+		checkIn = p.RequestedCheckIn
+	}
+	if p.RequestedCheckOut != nil {
+		checkOut = p.RequestedCheckOut
+	}
+
+	loc := tenantLocation(ctx, s.db, tenantID)
+	at := time.Date(p.Date.Year(), p.Date.Month(), p.Date.Day(), 12, 0, 0, 0, loc)
+	if checkIn != nil {
+		at = *checkIn
+	}
+	schedule, err := resolveSchedule(ctx, s.db, tenantID, p.EmployeeID, at)
+	if err != nil {
+		return nil, err
+	}
+
+	// Working from home without times counts as the scheduled working day
+	if p.Type == models.RegularizationWorkFromHome {
+		if checkIn == nil {
+			start := schedule.Start
+			checkIn = &start
+		}
+		if checkOut == nil {
+			end := schedule.End
+			checkOut = &end
+		}
+	}
+
+	if checkIn != nil && checkOut != nil && !checkOut.After(*checkIn) {
+		return nil, fmt.Errorf("check-out would not be after check-in")
+	}
+
+	newStatus := original.Status
+	if checkIn != nil {
+		newStatus = evaluateCheckInStatus(schedule, checkIn.In(schedule.Location))
+	}
+	var totalHours *float64
+	overtime, earlyDeparture := 0.0, false
+
+	// A missed check-out on a day worked in several sessions closes the open session; any other
+	// regularization replaces the day's sessions with one from check-in to check-out
+	closesOpenSession := hasRecord && p.RequestedCheckIn == nil && original.CheckInTime != nil &&
+		original.CheckOutTime == nil && checkOut != nil
+	if closesOpenSession {
+		hours, err := closeSession(ctx, tx, tenantID, recordID, p.EmployeeID, *checkIn, *checkOut, models.SourceRegularized, nil)
+		if err != nil {
+			return nil, err
+		}
+		totalHours = &hours
+		overtime, earlyDeparture = evaluateWorkedDay(schedule, time.Duration(hours*float64(time.Hour)), checkOut.In(schedule.Location))
+	} else if checkIn != nil && checkOut != nil {
+		hours := checkOut.Sub(*checkIn).Hours()
+		totalHours = &hours
+		overtime, earlyDeparture = evaluateCheckOut(schedule, checkIn.In(schedule.Location), checkOut.In(schedule.Location))
+	}
+
+	note := fmt.Sprintf("Regularized (%s): %s", strings.ReplaceAll(string(p.Type), "_", " "), p.Reason)
+	now := time.Now()
+
+	var originalJSON []byte
+	if hasRecord {
+		originalJSON, err = json.Marshal(original)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode original attendance: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE attendance_records
+			SET check_in_time = $1, check_out_time = $2, total_hours = $3, overtime_hours = $4,
+			    is_early_departure = $5, status = $6, source = $7, regularization_request_id = $8,
+			    notes = CONCAT_WS('; ', NULLIF(notes, ''), $9::TEXT), needs_review = false, updated_at = $10
+			WHERE id = $11`,
+			checkIn, checkOut, totalHours, overtime, earlyDeparture, newStatus, models.SourceRegularized,
+			requestID, note, now, recordID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to regularize attendance record: %w", err)
+		}
+	} else {
+		recordID = uuid.New()
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO attendance_records (
+				id, tenant_id, employee_id, date, check_in_time, check_out_time, total_hours, overtime_hours,
+				is_early_departure, status, source, regularization_request_id, shift_assignment_id, notes,
+				created_at, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $15)`,
+			recordID, tenantID, p.EmployeeID, day, checkIn, checkOut, totalHours, overtime,
+			earlyDeparture, newStatus, models.SourceRegularized, requestID, schedule.ShiftAssignmentID, note, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create regularized attendance record: %w", err)
+		}
+	}
+
+	if !closesOpenSession {
+		if err := replaceSessions(ctx, tx, tenantID, recordID, p.EmployeeID, checkIn, checkOut, models.SourceRegularized); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE attendance_regularization_requests
+		SET status = 'approved', decided_by = $1, decided_at = $2, decision_comment = NULLIF($3, ''),
+		    attendance_record_id = $4, original_values = $5, updated_at = $2
+		WHERE id = $6`,
+		actorID, now, strings.TrimSpace(comment), recordID, nullableJSON(originalJSON), requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to approve regularization request: %w", err)
+	}
+
+	notifications, err := s.notificationService.QueueRegularizationDecision(ctx, tx, tenantID, requestID, true, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit regularization: %w", err)
+	}
+	s.notificationService.Publish(notifications)
+
+	return s.GetRegularization(ctx, tenantID, requestID)
+}
+
+// RejectRegularization rejects a request; the attendance record is left unchanged
+func (s *AttendanceService) RejectRegularization(ctx context.Context, tenantID, requestID, actorID uuid.UUID, actorRole, comment string) (*models.AttendanceRegularization, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockRegularizationForDecision(ctx, tx, tenantID, requestID, actorID, actorRole); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE attendance_regularization_requests
+		SET status = 'rejected', decided_by = $1, decided_at = NOW(), decision_comment = NULLIF($2, ''),
+		    updated_at = NOW()
+		WHERE id = $3`,
+		actorID, strings.TrimSpace(comment), requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reject regularization request: %w", err)
+	}
+
+	notifications, err := s.notificationService.QueueRegularizationDecision(ctx, tx, tenantID, requestID, false, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit regularization: %w", err)
+	}
+	s.notificationService.Publish(notifications)
+
+	return s.GetRegularization(ctx, tenantID, requestID)
+}
+
+// CancelRegularization withdraws an employee's own pending request
+func (s *AttendanceService) CancelRegularization(ctx context.Context, tenantID, requestID, employeeID uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE attendance_regularization_requests
+		SET status = 'cancelled', updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2 AND employee_id = $3 AND status = 'pending'`,
+		requestID, tenantID, employeeID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel regularization request: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("regularization request not found")
+	}
+
+	return nil
+}
+
+// nullableJSON stores an empty document as NULL
+func nullableJSON(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return data
+}
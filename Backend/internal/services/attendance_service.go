@@ -3,21 +3,25 @@ package services
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 
 	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
 )
 
 type AttendanceService struct {
-	db *sql.DB
+	db                  *sql.DB
+	notificationService *NotificationService
 }
 
-func NewAttendanceService(db *sql.DB) *AttendanceService {
-	return &AttendanceService{db: db}
+func NewAttendanceService(db *sql.DB, notificationService *NotificationService) *AttendanceService {
+	return &AttendanceService{db: db, notificationService: notificationService}
 }
 
 // CheckIn records employee check-in
@@ -27,70 +31,119 @@ func (s *AttendanceService) CheckIn(ctx context.Context, tenantID, employeeID uu
 
 // CheckInWithSource allows specifying the attendance source and optional device/log references
 func (s *AttendanceService) CheckInWithSource(ctx context.Context, tenantID, employeeID uuid.UUID, notes string, source models.AttendanceSource, deviceID, biometricLogID *uuid.UUID) (*models.AttendanceRecord, error) {
-	now := time.Now()
-	today := now.Format("2006-01-02")
+	return s.checkIn(ctx, tenantID, employeeID, notes, source, deviceID, biometricLogID, nil)
+}
+
+// checkIn records a check-in. origin carries where a web or mobile check-in came from and is
+// nil for device punches.
+func (s *AttendanceService) checkIn(ctx context.Context, tenantID, employeeID uuid.UUID, notes string, source models.AttendanceSource, deviceID, biometricLogID *uuid.UUID, origin *checkInOrigin) (*models.AttendanceRecord, error) {
+	schedule, err := resolveSchedule(ctx, s.db, tenantID, employeeID, time.Now())
+	if err != nil {
+		return nil, err
+	}
 
-	// Check if already checked in today
+	now := time.Now().In(schedule.Location)
+	today := schedule.Date.Format("2006-01-02")
+
+	// Check if already checked in today; checking in again after a check-out starts another session
 	var existingID sql.NullString
-	var existingCheckIn sql.NullTime
-	err := s.db.QueryRowContext(ctx,
-		"SELECT id, check_in_time FROM attendance_records WHERE tenant_id = $1 AND employee_id = $2 AND date = $3",
-		tenantID, employeeID, today).Scan(&existingID, &existingCheckIn)
+	var existingCheckIn, existingCheckOut sql.NullTime
+	err = s.db.QueryRowContext(ctx,
+		"SELECT id, check_in_time, check_out_time FROM attendance_records WHERE tenant_id = $1 AND employee_id = $2 AND date = $3",
+		tenantID, employeeID, today).Scan(&existingID, &existingCheckIn, &existingCheckOut)
 
 	if err == nil && existingCheckIn.Valid {
-		return nil, fmt.Errorf("employee already checked in today")
+		if !existingCheckOut.Valid {
+			return nil, fmt.Errorf("employee already checked in today")
+		}
+		return s.resumeAttendance(ctx, tenantID, employeeID, uuid.MustParse(existingID.String), now, notes, source, deviceID, biometricLogID, origin)
 	}
 
-	// Determine status (simple logic for now)
-	status := "present"
-	hour := now.Hour()
-	if hour > 9 { // If after 9 AM, consider late
-		status = "late"
-	}
+	status := evaluateCheckInStatus(schedule, now)
 
 	record := models.AttendanceRecord{
-		ID:             uuid.New(),
-		TenantID:       tenantID,
-		EmployeeID:     employeeID,
-		Date:           now,
-		CheckInTime:    &now,
-		Status:         status,
-		Notes:          &notes,
-		Source:         source,
-		DeviceID:       deviceID,
-		BiometricLogID: biometricLogID,
-		CreatedAt:      now,
-		UpdatedAt:      now,
+		ID:                uuid.New(),
+		TenantID:          tenantID,
+		EmployeeID:        employeeID,
+		Date:              schedule.Date,
+		CheckInTime:       &now,
+		Status:            status,
+		Notes:             &notes,
+		Source:            source,
+		DeviceID:          deviceID,
+		BiometricLogID:    biometricLogID,
+		ShiftAssignmentID: schedule.ShiftAssignmentID,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if origin != nil {
+		record.CheckInLatitude = origin.Latitude
+		record.CheckInLongitude = origin.Longitude
+		record.CheckInIP = origin.IP
+		record.CheckInZoneID = origin.ZoneID
+		if origin.OutsideZone {
+			reason := models.ReviewReasonOutsideZone
+			record.NeedsReview = true
+			record.ReviewReason = &reason
+		}
 	}
 
 	if err == sql.ErrNoRows {
 		// Insert new record
 		_, err = s.db.ExecContext(ctx, `
-			INSERT INTO attendance_records (id, tenant_id, employee_id, date, check_in_time, status, notes, source, device_id, biometric_log_id, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+			INSERT INTO attendance_records (id, tenant_id, employee_id, date, check_in_time, status, notes, source, device_id, biometric_log_id, shift_assignment_id,
+			                                check_in_latitude, check_in_longitude, check_in_ip, check_in_zone_id, needs_review, review_reason, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)`,
 			record.ID, record.TenantID, record.EmployeeID, today, record.CheckInTime,
-			record.Status, record.Notes, record.Source, record.DeviceID, record.BiometricLogID, record.CreatedAt, record.UpdatedAt)
+			record.Status, record.Notes, record.Source, record.DeviceID, record.BiometricLogID, record.ShiftAssignmentID,
+			record.CheckInLatitude, record.CheckInLongitude, record.CheckInIP, record.CheckInZoneID, record.NeedsReview, record.ReviewReason,
+			record.CreatedAt, record.UpdatedAt)
 	} else if existingID.Valid {
-		// Update existing record
+		// Update existing record; a review flag already on it is kept
 		record.ID = uuid.MustParse(existingID.String)
 		_, err = s.db.ExecContext(ctx, `
 			UPDATE attendance_records 
-			SET check_in_time = $1, status = $2, notes = $3, source = $4, device_id = $5, biometric_log_id = $6, updated_at = $7
-			WHERE id = $8`,
-			record.CheckInTime, record.Status, record.Notes, record.Source, record.DeviceID, record.BiometricLogID, record.UpdatedAt, record.ID)
+			SET check_in_time = $1, status = $2, notes = $3, source = $4, device_id = $5, biometric_log_id = $6, shift_assignment_id = $7,
+			    check_in_latitude = $8, check_in_longitude = $9, check_in_ip = $10, check_in_zone_id = $11,
+			    needs_review = needs_review OR $12, review_reason = COALESCE($13, review_reason), updated_at = $14
+			WHERE id = $15`,
+			record.CheckInTime, record.Status, record.Notes, record.Source, record.DeviceID, record.BiometricLogID, record.ShiftAssignmentID,
+			record.CheckInLatitude, record.CheckInLongitude, record.CheckInIP, record.CheckInZoneID,
+			record.NeedsReview, record.ReviewReason, record.UpdatedAt, record.ID)
 	}
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to save check-in record: %w", err)
 	}
 
+	if err := openSession(ctx, s.db, tenantID, record.ID, employeeID, now, source, deviceID, biometricLogID); err != nil {
+		return nil, err
+	}
+
 	return &record, nil
 }
 
-// CheckOut records employee check-out
-func (s *AttendanceService) CheckOut(ctx context.Context, tenantID, employeeID uuid.UUID, notes string) (*models.AttendanceRecord, error) {
-	now := time.Now()
-	today := now.Format("2006-01-02")
+// CheckOut records employee check-out. The session is closed with the source and device the
+// check-out came from.
+func (s *AttendanceService) CheckOut(ctx context.Context, tenantID, employeeID uuid.UUID, notes string, source models.AttendanceSource, deviceID *uuid.UUID) (*models.AttendanceRecord, error) {
+	schedule, err := resolveSchedule(ctx, s.db, tenantID, employeeID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	allowance, err := breakAllowance(ctx, s.db, tenantID, employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(schedule.Location)
+	today := schedule.Date.Format("2006-01-02")
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
 	// Get existing record
 	var record models.AttendanceRecord
@@ -98,10 +151,11 @@ func (s *AttendanceService) CheckOut(ctx context.Context, tenantID, employeeID u
 	var totalHours sql.NullFloat64
 	var existingNotes sql.NullString
 
-	err := s.db.QueryRowContext(ctx,
+	err = tx.QueryRowContext(ctx,
 		`SELECT id, tenant_id, employee_id, date, check_in_time, check_out_time, 
 		        total_hours, overtime_hours, status, notes, created_at, updated_at
-		 FROM attendance_records WHERE tenant_id = $1 AND employee_id = $2 AND date = $3`,
+		 FROM attendance_records WHERE tenant_id = $1 AND employee_id = $2 AND date = $3
+		 FOR UPDATE`,
 		tenantID, employeeID, today).Scan(
 		&record.ID, &record.TenantID, &record.EmployeeID, &record.Date,
 		&checkInTime, &checkOutTime, &totalHours, &record.OvertimeHours,
@@ -125,14 +179,15 @@ func (s *AttendanceService) CheckOut(ctx context.Context, tenantID, employeeID u
 	record.CheckInTime = &checkInTime.Time
 	record.CheckOutTime = &now
 
-	// Calculate total hours
-	totalHoursFloat := now.Sub(checkInTime.Time).Hours()
+	// Total hours are the sum of the day's sessions
+	totalHoursFloat, err := closeSession(ctx, tx, tenantID, record.ID, employeeID, checkInTime.Time, now, source, deviceID)
+	if err != nil {
+		return nil, err
+	}
 	record.TotalHours = &totalHoursFloat
 
-	// Calculate overtime (assuming 8 hours is standard)
-	if totalHoursFloat > 8.0 {
-		record.OvertimeHours = totalHoursFloat - 8.0
-	}
+	// Overtime and early departure are judged against the shift or policy the record belongs to
+	record.OvertimeHours, record.IsEarlyDeparture = evaluateWorkedDay(schedule, time.Duration(totalHoursFloat*float64(time.Hour)), now)
 
 	// Combine notes if provided
 	if notes != "" {
@@ -148,17 +203,28 @@ func (s *AttendanceService) CheckOut(ctx context.Context, tenantID, employeeID u
 
 	record.UpdatedAt = now
 
+	// A break still in progress ends with the day
+	if _, err := endBreak(ctx, tx, tenantID, record.ID, now, allowance); err != nil {
+		return nil, err
+	}
+
 	// Update record
-	_, err = s.db.ExecContext(ctx, `
+	err = tx.QueryRowContext(ctx, `
 		UPDATE attendance_records 
-		SET check_out_time = $1, total_hours = $2, overtime_hours = $3, notes = $4, updated_at = $5
-		WHERE id = $6`,
-		record.CheckOutTime, record.TotalHours, record.OvertimeHours, record.Notes, record.UpdatedAt, record.ID)
+		SET check_out_time = $1, total_hours = $2, overtime_hours = $3, is_early_departure = $4, notes = $5, updated_at = $6
+		WHERE id = $7
+		RETURNING break_duration_minutes, is_excessive_break`,
+		record.CheckOutTime, record.TotalHours, record.OvertimeHours, record.IsEarlyDeparture, record.Notes, record.UpdatedAt, record.ID).Scan(
+		&record.BreakDurationMinutes, &record.IsExcessiveBreak)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to save check-out record: %w", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit check-out: %w", err)
+	}
+
 	return &record, nil
 }
 
@@ -235,17 +301,17 @@ func (s *AttendanceService) GetTodayAttendance(ctx context.Context, tenantID uui
 
 // GetAttendanceStats gets attendance statistics
 func (s *AttendanceService) GetAttendanceStats(ctx context.Context, tenantID uuid.UUID) (*models.AttendanceStats, error) {
-	today := time.Now().Format("2006-01-02")
-
-	stats := &models.AttendanceStats{}
+	loc := tenantLocation(ctx, s.db, tenantID)
+	now := time.Now().In(loc)
+	today := now.Format("2006-01-02")
 
-	// Total employees
-	err := s.db.QueryRowContext(ctx,
-		"SELECT COUNT(*) FROM employees WHERE tenant_id = $1 AND employment_status = 'active'", tenantID).Scan(&stats.TotalEmployees)
+	policies, err := loadAttendancePolicies(ctx, s.db, tenantID, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get total employees: %w", err)
+		return nil, err
 	}
 
+	stats := &models.AttendanceStats{}
+
 	// Today's stats
 	err = s.db.QueryRowContext(ctx,
 		"SELECT COUNT(*) FROM attendance_records WHERE tenant_id = $1 AND date = $2 AND status IN ('present', 'late')",
@@ -261,10 +327,63 @@ func (s *AttendanceService) GetAttendanceStats(ctx context.Context, tenantID uui
 		return nil, fmt.Errorf("failed to get late count: %w", err)
 	}
 
-	stats.AbsentToday = stats.TotalEmployees - stats.PresentToday
+	// Active employees with whether they attended or are on leave today, and whether today is a
+	// holiday for them, either tenant-wide or at their work location
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT e.id,
+		       EXISTS (
+		           SELECT 1 FROM attendance_records ar
+		           WHERE ar.employee_id = e.id AND ar.tenant_id = e.tenant_id AND ar.date = $2
+		             AND ar.status IN ('present', 'late', 'on_leave')
+		       ),
+		       EXISTS (
+		           SELECT 1 FROM holidays h
+		           WHERE h.tenant_id = e.tenant_id AND h.holiday_date = $2
+		             AND (h.location IS NULL OR EXISTS (
+		                 SELECT 1 FROM user_profiles up
+		                 WHERE up.user_id = e.user_id AND LOWER(up.work_location) = LOWER(h.location)))
+		       )
+		FROM employees e
+		WHERE e.tenant_id = $1 AND e.employment_status = 'active'`,
+		tenantID, today)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get employee attendance: %w", err)
+	}
+	defer rows.Close()
+
+	// Nobody is absent on a day off under their own policy, on their holiday or on approved leave
+	expected := 0
+	for rows.Next() {
+		var employeeID uuid.UUID
+		var attended, holiday bool
+		if err := rows.Scan(&employeeID, &attended, &holiday); err != nil {
+			return nil, fmt.Errorf("failed to scan employee attendance: %w", err)
+		}
+		stats.TotalEmployees++
+		if holiday {
+			stats.OnHolidayToday++
+		}
+		if !containsWeekday(policies.forEmployee(employeeID).WorkingDays, now.Weekday()) {
+			continue
+		}
+		stats.IsWorkingDay = true
+		if holiday {
+			continue
+		}
+		expected++
+		if !attended {
+			stats.AbsentToday++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get employee attendance: %w", err)
+	}
+	if stats.TotalEmployees == 0 {
+		stats.IsWorkingDay = containsWeekday(policies.tenantDefault.WorkingDays, now.Weekday())
+	}
 
 	// Average hours this week
-	weekStart := time.Now().AddDate(0, 0, -7).Format("2006-01-02")
+	weekStart := now.AddDate(0, 0, -7).Format("2006-01-02")
 	err = s.db.QueryRowContext(ctx,
 		`SELECT COALESCE(AVG(total_hours), 0) FROM attendance_records 
 		 WHERE tenant_id = $1 AND date >= $2 AND total_hours IS NOT NULL`,
@@ -273,9 +392,9 @@ func (s *AttendanceService) GetAttendanceStats(ctx context.Context, tenantID uui
 		return nil, fmt.Errorf("failed to get average hours: %w", err)
 	}
 
-	// Simple attendance rate calculation
-	if stats.TotalEmployees > 0 {
-		stats.AttendanceRate = float64(stats.PresentToday) / float64(stats.TotalEmployees) * 100
+	// Simple attendance rate calculation over employees expected to work today
+	if expected > 0 {
+		stats.AttendanceRate = math.Min(float64(stats.PresentToday)/float64(expected)*100, 100)
 	}
 
 	return stats, nil
@@ -293,7 +412,7 @@ func (s *AttendanceService) GetEmployeeCurrentStatus(ctx context.Context, tenant
 	err := s.db.QueryRowContext(ctx,
 		`SELECT ar.id, ar.tenant_id, ar.employee_id, ar.date, ar.check_in_time, 
 		        ar.check_out_time, ar.total_hours, ar.overtime_hours, ar.status, 
-		        ar.notes, ar.created_at, ar.updated_at,
+		        ar.notes, ar.break_duration_minutes, ar.is_excessive_break, ar.created_at, ar.updated_at,
 		        u.first_name, u.last_name,
 		        CONCAT(u.first_name, ' ', u.last_name) as employee_name
 		 FROM attendance_records ar
@@ -303,7 +422,7 @@ func (s *AttendanceService) GetEmployeeCurrentStatus(ctx context.Context, tenant
 		tenantID, employeeID, today).Scan(
 		&record.ID, &record.TenantID, &record.EmployeeID, &record.Date,
 		&checkInTime, &checkOutTime, &totalHours, &record.OvertimeHours,
-		&record.Status, &notes, &record.CreatedAt, &record.UpdatedAt,
+		&record.Status, &notes, &record.BreakDurationMinutes, &record.IsExcessiveBreak, &record.CreatedAt, &record.UpdatedAt,
 		&record.FirstName, &record.LastName, &record.EmployeeName)
 
 	if err == sql.ErrNoRows {
@@ -334,6 +453,16 @@ func (s *AttendanceService) GetEmployeeCurrentStatus(ctx context.Context, tenant
 		record.Notes = &notes.String
 	}
 
+	// The session and break in progress, if any, are the ones without an end time
+	record.Sessions, err = getRecordSessions(ctx, s.db, record.ID)
+	if err != nil {
+		return nil, err
+	}
+	record.Breaks, err = getRecordBreaks(ctx, s.db, record.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &record, nil
 }
 
@@ -381,7 +510,8 @@ func (s *AttendanceService) GetAttendanceRecords(ctx context.Context, tenantID u
 		SELECT ar.id, ar.tenant_id, ar.employee_id, ar.date, ar.check_in_time, 
 		       ar.check_out_time, ar.break_duration_minutes, ar.total_hours, 
 		       ar.overtime_hours, ar.status, ar.is_approved, ar.approved_by, 
-		       ar.approved_at, ar.notes, ar.created_at, ar.updated_at,
+		       ar.approved_at, ar.notes, ar.needs_review, ar.review_reason, ar.created_at, ar.updated_at,
+		       ar.source, ar.check_in_latitude, ar.check_in_longitude, ar.check_in_ip, ar.check_in_zone_id, ar.is_excessive_break,
 		       u.first_name, u.last_name, u.role,
 		       CONCAT(u.first_name, ' ', u.last_name) as employee_name
 		FROM attendance_records ar
@@ -392,7 +522,10 @@ func (s *AttendanceService) GetAttendanceRecords(ctx context.Context, tenantID u
 	args = []interface{}{tenantID, startDate, endDate}
 	argIdx = 4
 
-	if status != "" && status != "all" {
+	// "needs_review" lists the records created or closed automatically that HR has not confirmed
+	if status == "needs_review" {
+		query += " AND ar.needs_review = true"
+	} else if status != "" && status != "all" {
 		query += fmt.Sprintf(" AND ar.status = $%d", argIdx)
 		args = append(args, status)
 		argIdx++
@@ -429,7 +562,8 @@ func (s *AttendanceService) GetAttendanceRecords(ctx context.Context, tenantID u
 			&checkInTime, &checkOutTime, &record.BreakDurationMinutes,
 			&totalHours, &record.OvertimeHours, &record.Status,
 			&record.IsApproved, &approvedBy, &approvedAt,
-			&notes, &record.CreatedAt, &record.UpdatedAt,
+			&notes, &record.NeedsReview, &record.ReviewReason, &record.CreatedAt, &record.UpdatedAt,
+			&record.Source, &record.CheckInLatitude, &record.CheckInLongitude, &record.CheckInIP, &record.CheckInZoneID, &record.IsExcessiveBreak,
 			&record.FirstName, &record.LastName, &record.Role, &record.EmployeeName)
 
 		if err != nil {
@@ -471,7 +605,9 @@ func (s *AttendanceService) GetAttendanceRecords(ctx context.Context, tenantID u
 	countArgs := []interface{}{tenantID, startDate, endDate}
 	countArgIdx := 4
 
-	if status != "" && status != "all" {
+	if status == "needs_review" {
+		countQuery += " AND ar.needs_review = true"
+	} else if status != "" && status != "all" {
 		countQuery += fmt.Sprintf(" AND ar.status = $%d", countArgIdx)
 		countArgs = append(countArgs, status)
 		countArgIdx++
@@ -754,6 +890,61 @@ func (s *AttendanceService) UpdateAttendanceRecord(ctx context.Context, tenantID
 	return nil
 }
 
+// ApproveAttendanceRecord approves or unapproves an attendance record. Only the overtime of
+// approved records is paid by payroll.
+func (s *AttendanceService) ApproveAttendanceRecord(ctx context.Context, tenantID, recordID, approverID uuid.UUID, approved bool) error {
+	var approvedBy *uuid.UUID
+	var approvedAt *time.Time
+	if approved {
+		now := time.Now()
+		approvedBy, approvedAt = &approverID, &now
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE attendance_records
+		SET is_approved = $1, approved_by = $2, approved_at = $3, updated_at = NOW()
+		WHERE id = $4 AND tenant_id = $5`,
+		approved, approvedBy, approvedAt, recordID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to approve attendance record: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("attendance record not found")
+	}
+
+	return nil
+}
+
+// ReviewAttendanceRecord confirms a record that was created or closed automatically. HR
+// corrects the record through UpdateAttendanceRecord before confirming it when needed.
+func (s *AttendanceService) ReviewAttendanceRecord(ctx context.Context, tenantID, recordID, reviewerID uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE attendance_records
+		SET needs_review = false, reviewed_by = $1, reviewed_at = NOW(), updated_at = NOW()
+		WHERE id = $2 AND tenant_id = $3`,
+		reviewerID, recordID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to review attendance record: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("attendance record not found")
+	}
+
+	return nil
+}
+
 // GetEmployeeIDByUserID gets the employee ID for a given user ID
 func (s *AttendanceService) GetEmployeeIDByUserID(ctx context.Context, tenantID, userID uuid.UUID) (uuid.UUID, error) {
 	var employeeID uuid.UUID
@@ -770,6 +961,33 @@ func (s *AttendanceService) GetEmployeeIDByUserID(ctx context.Context, tenantID,
 
 // CreateAttendancePolicy creates a new attendance policy or revives a soft-deleted one
 func (s *AttendanceService) CreateAttendancePolicy(ctx context.Context, tenantID uuid.UUID, req models.AttendancePolicy) (*models.AttendancePolicy, error) {
+	if req.WorkStartTime == "" {
+		req.WorkStartTime = defaultWorkStartTime
+	}
+	if _, err := time.Parse("15:04", req.WorkStartTime); err != nil {
+		return nil, fmt.Errorf("invalid work_start_time, expected HH:MM")
+	}
+	if req.DefaultCheckoutTime != nil && *req.DefaultCheckoutTime != "" {
+		if _, err := time.Parse("15:04", *req.DefaultCheckoutTime); err != nil {
+			return nil, fmt.Errorf("invalid default_checkout_time, expected HH:MM")
+		}
+	} else {
+		req.DefaultCheckoutTime = nil
+	}
+	if req.LocationEnforcement == "" {
+		req.LocationEnforcement = models.LocationEnforcementOff
+	}
+	if !validLocationEnforcement(req.LocationEnforcement) {
+		return nil, fmt.Errorf("invalid location_enforcement, expected off, flag or reject")
+	}
+	if len(req.WorkingDays) == 0 {
+		req.WorkingDays = defaultWorkingDays
+	}
+	workingDaysJSON, err := json.Marshal(req.WorkingDays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode working days: %w", err)
+	}
+
 	// Check for existing policy by name
 	var existingID string
 	var existingDeletedAt *time.Time
@@ -780,7 +998,7 @@ func (s *AttendanceService) CreateAttendancePolicy(ctx context.Context, tenantID
 		FROM attendance_policies 
 		WHERE tenant_id = $1 AND name = $2
 	`
-	err := s.db.QueryRowContext(ctx, checkQuery, tenantID, req.Name).Scan(&existingID, &existingDeletedAt)
+	err = s.db.QueryRowContext(ctx, checkQuery, tenantID, req.Name).Scan(&existingID, &existingDeletedAt)
 
 	if err == nil {
 		if existingDeletedAt == nil {
@@ -797,19 +1015,18 @@ func (s *AttendanceService) CreateAttendancePolicy(ctx context.Context, tenantID
 			    overtime_threshold_minutes = $5,
 			    is_default = $6,
 			    is_active = $7,
+			    work_start_time = $8,
+			    default_checkout_time = $9,
+			    location_enforcement = $10,
 			    deleted_at = NULL,
-			    updated_at = $8
-			WHERE id = $9
+			    updated_at = $11
+			WHERE id = $12
 		`
 
-		// Marshal working days to JSON
-		// existing code struct likely has WorkingDays as []string or similar that needs DB driver support or manual marshaling
-		// Ideally we use a helper or the model already handles it. Assuming direct pass for now but will verify model.
-
 		_, err = s.db.ExecContext(ctx, reviveQuery,
-			req.WorkingHoursPerDay, req.WorkingDays, req.GracePeriodMinutes,
+			req.WorkingHoursPerDay, workingDaysJSON, req.GracePeriodMinutes,
 			req.BreakDurationMinutes, req.OvertimeThresholdMinutes, req.IsDefault,
-			req.IsActive, time.Now(), existingID,
+			req.IsActive, req.WorkStartTime, req.DefaultCheckoutTime, req.LocationEnforcement, time.Now(), existingID,
 		)
 
 		if err != nil {
@@ -834,16 +1051,16 @@ func (s *AttendanceService) CreateAttendancePolicy(ctx context.Context, tenantID
 		INSERT INTO attendance_policies (
 			id, tenant_id, name, working_hours_per_day, working_days,
 			grace_period_minutes, break_duration_minutes, overtime_threshold_minutes,
-			is_default, is_active, created_at, updated_at
+			is_default, is_active, work_start_time, default_checkout_time, location_enforcement, created_at, updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
 		)
 	`
 
 	_, err = s.db.ExecContext(ctx, query,
-		id, tenantID, req.Name, req.WorkingHoursPerDay, req.WorkingDays,
+		id, tenantID, req.Name, req.WorkingHoursPerDay, workingDaysJSON,
 		req.GracePeriodMinutes, req.BreakDurationMinutes, req.OvertimeThresholdMinutes,
-		req.IsDefault, req.IsActive, now, now,
+		req.IsDefault, req.IsActive, req.WorkStartTime, req.DefaultCheckoutTime, req.LocationEnforcement, now, now,
 	)
 
 	if err != nil {
@@ -857,3 +1074,393 @@ func (s *AttendanceService) CreateAttendancePolicy(ctx context.Context, tenantID
 
 	return &req, nil
 }
+
+// SetEmployeeAttendancePolicy assigns an attendance policy to an employee, or clears it
+func (s *AttendanceService) SetEmployeeAttendancePolicy(ctx context.Context, tenantID, employeeID uuid.UUID, req models.AttendancePolicyAssignmentRequest) error {
+	policyID, err := s.assignablePolicy(ctx, tenantID, req)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE employees SET attendance_policy_id = $1, updated_at = NOW()
+		WHERE id = $2 AND tenant_id = $3`, policyID, employeeID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to assign attendance policy: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("employee not found")
+	}
+	return nil
+}
+
+// SetDepartmentAttendancePolicy assigns an attendance policy to a department, or clears it
+func (s *AttendanceService) SetDepartmentAttendancePolicy(ctx context.Context, tenantID, departmentID uuid.UUID, req models.AttendancePolicyAssignmentRequest) error {
+	policyID, err := s.assignablePolicy(ctx, tenantID, req)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE departments SET attendance_policy_id = $1, updated_at = NOW()
+		WHERE id = $2 AND tenant_id = $3 AND deleted_at IS NULL`, policyID, departmentID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to assign attendance policy: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("department not found")
+	}
+	return nil
+}
+
+// assignablePolicy returns the policy of an assignment request, which must be an active policy
+// of the tenant. It returns nil when the request clears the assignment.
+func (s *AttendanceService) assignablePolicy(ctx context.Context, tenantID uuid.UUID, req models.AttendancePolicyAssignmentRequest) (*uuid.UUID, error) {
+	if req.PolicyID == nil || *req.PolicyID == "" {
+		return nil, nil
+	}
+	policyID, err := uuid.Parse(*req.PolicyID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy ID")
+	}
+
+	var exists bool
+	err = s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM attendance_policies
+			WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL AND is_active = true
+		)`, policyID, tenantID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check attendance policy: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("attendance policy not found")
+	}
+	return &policyID, nil
+}
+
+// Defaults applied when a tenant has not configured an attendance policy
+const defaultWorkStartTime = "09:00"
+
+var defaultWorkingDays = []string{"monday", "tuesday", "wednesday", "thursday", "friday"}
+
+// getEffectivePolicy returns the active attendance policy of an employee along with the
+// tenant's timezone. The policy assigned to the employee applies first, then the one assigned
+// to their department, then the tenant's default policy; with uuid.Nil for employeeID only the
+// tenant default is looked up. Tenants without a policy get the same defaults as PolicyService.
+func getEffectivePolicy(ctx context.Context, db *sql.DB, tenantID, employeeID uuid.UUID) (*models.AttendancePolicy, *time.Location, error) {
+	policies, err := loadAttendancePolicies(ctx, db, tenantID, []uuid.UUID{employeeID})
+	if err != nil {
+		return nil, nil, err
+	}
+	return policies.forEmployee(employeeID), tenantLocation(ctx, db, tenantID), nil
+}
+
+// attendancePolicies holds the effective attendance policy of a set of employees, resolved
+// as getEffectivePolicy does. Employees that were not loaded get the tenant default.
+type attendancePolicies struct {
+	byEmployee    map[uuid.UUID]*models.AttendancePolicy
+	tenantDefault *models.AttendancePolicy
+}
+
+// forEmployee returns the effective policy of an employee
+func (p *attendancePolicies) forEmployee(employeeID uuid.UUID) *models.AttendancePolicy {
+	if policy, ok := p.byEmployee[employeeID]; ok {
+		return policy
+	}
+	return p.tenantDefault
+}
+
+// loadAttendancePolicies resolves the effective policy of the given employees, or of every
+// employee of the tenant when employeeIDs is nil, together with the tenant default in one query
+func loadAttendancePolicies(ctx context.Context, db *sql.DB, tenantID uuid.UUID, employeeIDs []uuid.UUID) (*attendancePolicies, error) {
+	var ids []string
+	if employeeIDs != nil {
+		ids = make([]string, 0, len(employeeIDs))
+		for _, id := range employeeIDs {
+			ids = append(ids, id.String())
+		}
+	}
+
+	// The row without an employee carries the tenant default
+	rows, err := db.QueryContext(ctx, `
+		SELECT e.id, p.id, COALESCE(p.name, ''), COALESCE(p.working_hours_per_day, 0),
+		       COALESCE(to_char(p.work_start_time, 'HH24:MI'), ''), p.working_days,
+		       COALESCE(p.grace_period_minutes, 0), COALESCE(p.break_duration_minutes, 0),
+		       COALESCE(p.overtime_threshold_minutes, 0), to_char(p.default_checkout_time, 'HH24:MI'),
+		       COALESCE(p.location_enforcement, '')
+		FROM (
+		    SELECT id, attendance_policy_id, department_id FROM employees
+		    WHERE tenant_id = $1 AND ($2::UUID[] IS NULL OR id = ANY($2::UUID[]))
+		    UNION ALL
+		    SELECT NULL, NULL, NULL
+		) e
+		LEFT JOIN departments d ON d.id = e.department_id AND d.tenant_id = $1
+		LEFT JOIN LATERAL (
+		    SELECT * FROM attendance_policies ap
+		    WHERE ap.tenant_id = $1 AND ap.deleted_at IS NULL AND ap.is_active = true
+		    ORDER BY ap.id = e.attendance_policy_id DESC NULLS LAST,
+		             ap.id = d.attendance_policy_id DESC NULLS LAST,
+		             ap.is_default DESC, ap.created_at ASC
+		    LIMIT 1
+		) p ON true`, tenantID, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance policies: %w", err)
+	}
+	defer rows.Close()
+
+	policies := &attendancePolicies{byEmployee: make(map[uuid.UUID]*models.AttendancePolicy)}
+	byID := make(map[uuid.UUID]*models.AttendancePolicy)
+	for rows.Next() {
+		var employeeID, policyID uuid.NullUUID
+		var workingDays []byte
+		var defaultCheckout sql.NullString
+		scanned := models.AttendancePolicy{}
+		if err := rows.Scan(&employeeID, &policyID, &scanned.Name, &scanned.WorkingHoursPerDay, &scanned.WorkStartTime,
+			&workingDays, &scanned.GracePeriodMinutes, &scanned.BreakDurationMinutes, &scanned.OvertimeThresholdMinutes,
+			&defaultCheckout, &scanned.LocationEnforcement); err != nil {
+			return nil, fmt.Errorf("failed to scan attendance policy: %w", err)
+		}
+
+		policy, ok := byID[policyID.UUID]
+		if !ok {
+			policy = builtInPolicy(tenantID)
+			if policyID.Valid {
+				policy.ID = policyID.UUID
+				policy.Name = scanned.Name
+				policy.WorkingHoursPerDay = scanned.WorkingHoursPerDay
+				policy.WorkStartTime = scanned.WorkStartTime
+				policy.GracePeriodMinutes = scanned.GracePeriodMinutes
+				policy.BreakDurationMinutes = scanned.BreakDurationMinutes
+				policy.OvertimeThresholdMinutes = scanned.OvertimeThresholdMinutes
+				policy.LocationEnforcement = scanned.LocationEnforcement
+				if err := json.Unmarshal(workingDays, &policy.WorkingDays); err != nil || len(policy.WorkingDays) == 0 {
+					policy.WorkingDays = defaultWorkingDays
+				}
+				if defaultCheckout.Valid {
+					policy.DefaultCheckoutTime = &defaultCheckout.String
+				}
+			}
+			byID[policyID.UUID] = policy
+		}
+
+		if employeeID.Valid {
+			policies.byEmployee[employeeID.UUID] = policy
+		} else {
+			policies.tenantDefault = policy
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get attendance policies: %w", err)
+	}
+	if policies.tenantDefault == nil {
+		policies.tenantDefault = builtInPolicy(tenantID)
+	}
+	return policies, nil
+}
+
+// builtInPolicy returns the policy of a tenant that has not configured one
+func builtInPolicy(tenantID uuid.UUID) *models.AttendancePolicy {
+	return &models.AttendancePolicy{
+		TenantID:                 tenantID,
+		Name:                     "Default",
+		WorkingHoursPerDay:       8.0,
+		WorkStartTime:            defaultWorkStartTime,
+		WorkingDays:              defaultWorkingDays,
+		GracePeriodMinutes:       15,
+		OvertimeThresholdMinutes: 480,
+		LocationEnforcement:      models.LocationEnforcementOff,
+		IsDefault:                true,
+		IsActive:                 true,
+	}
+}
+
+// tenantLocation loads the timezone configured in the tenant's organization details,
+// falling back to UTC when it is missing or invalid
+func tenantLocation(ctx context.Context, db *sql.DB, tenantID uuid.UUID) *time.Location {
+	var timezone sql.NullString
+	err := db.QueryRowContext(ctx,
+		"SELECT timezone FROM organization_details WHERE tenant_id = $1", tenantID).Scan(&timezone)
+	if err != nil || !timezone.Valid || timezone.String == "" {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(timezone.String)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// workSchedule is the working window a punch is judged against. It comes from the
+// employee's published shift when one applies, otherwise from the attendance policy.
+type workSchedule struct {
+	Date              time.Time // attendance date the punch is recorded under
+	Start             time.Time
+	End               time.Time
+	GracePeriod       time.Duration
+	OvertimeThreshold time.Duration
+	IsWorkingDay      bool
+	ShiftAssignmentID *uuid.UUID
+	Location          *time.Location
+}
+
+// overnightCheckOutSlack is how long after the end of an overnight shift a punch is
+// still attributed to the previous day's shift
+const overnightCheckOutSlack = 4 * time.Hour
+
+// resolveSchedule returns the schedule for a punch at the given time. Published shifts
+// from the previous day are considered so overnight shifts keep their start date.
+func resolveSchedule(ctx context.Context, db *sql.DB, tenantID, employeeID uuid.UUID, at time.Time) (*workSchedule, error) {
+	policy, loc, err := getEffectivePolicy(ctx, db, tenantID, employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	at = at.In(loc)
+	today := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, loc)
+	yesterday := today.AddDate(0, 0, -1)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT sa.id, sa.shift_date, to_char(st.start_time, 'HH24:MI'), to_char(st.end_time, 'HH24:MI'),
+		       st.break_duration_minutes, st.grace_period_minutes
+		FROM shift_assignments sa
+		JOIN shift_templates st ON sa.shift_template_id = st.id
+		WHERE sa.tenant_id = $1 AND sa.employee_id = $2 AND sa.status = 'published'
+		  AND sa.shift_date BETWEEN $3 AND $4`,
+		tenantID, employeeID, yesterday.Format("2006-01-02"), today.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assigned shifts: %w", err)
+	}
+	defer rows.Close()
+
+	var best *workSchedule
+	var bestDistance time.Duration
+	for rows.Next() {
+		var assignmentID uuid.UUID
+		var shiftDate time.Time
+		var startTime, endTime string
+		var breakMinutes int
+		var graceMinutes sql.NullInt64
+		if err := rows.Scan(&assignmentID, &shiftDate, &startTime, &endTime, &breakMinutes, &graceMinutes); err != nil {
+			return nil, fmt.Errorf("failed to scan assigned shift: %w", err)
+		}
+
+		day := time.Date(shiftDate.Year(), shiftDate.Month(), shiftDate.Day(), 0, 0, 0, 0, loc)
+		grace := policy.GracePeriodMinutes
+		if graceMinutes.Valid {
+			grace = int(graceMinutes.Int64)
+		}
+		schedule := shiftSchedule(day, startTime, endTime, breakMinutes, grace)
+		schedule.ShiftAssignmentID = &assignmentID
+
+		// Shifts from the previous day only apply while an overnight shift is still running
+		if day.Before(today) && (schedule.End.Before(today) || at.After(schedule.End.Add(overnightCheckOutSlack))) {
+			continue
+		}
+
+		distance := time.Duration(0)
+		if at.Before(schedule.Start) {
+			distance = schedule.Start.Sub(at)
+		} else if at.After(schedule.End) {
+			distance = at.Sub(schedule.End)
+		}
+		if best == nil || distance < bestDistance {
+			best = schedule
+			bestDistance = distance
+		}
+	}
+
+	if best != nil {
+		return best, nil
+	}
+	return policySchedule(policy, at), nil
+}
+
+// policySchedule builds the schedule for the local day of at from the attendance policy
+func policySchedule(policy *models.AttendancePolicy, at time.Time) *workSchedule {
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+
+	startClock, err := time.Parse("15:04", policy.WorkStartTime)
+	if err != nil {
+		startClock, _ = time.Parse("15:04", defaultWorkStartTime)
+	}
+	start := day.Add(time.Duration(startClock.Hour())*time.Hour + time.Duration(startClock.Minute())*time.Minute)
+	workDuration := time.Duration(policy.WorkingHoursPerDay*float64(time.Hour)) +
+		time.Duration(policy.BreakDurationMinutes)*time.Minute
+
+	return &workSchedule{
+		Date:              day,
+		Start:             start,
+		End:               start.Add(workDuration),
+		GracePeriod:       time.Duration(policy.GracePeriodMinutes) * time.Minute,
+		OvertimeThreshold: time.Duration(policy.OvertimeThresholdMinutes) * time.Minute,
+		IsWorkingDay:      containsWeekday(policy.WorkingDays, day.Weekday()),
+		Location:          at.Location(),
+	}
+}
+
+// shiftSchedule builds the schedule for a shift starting on day. A shift whose end is not
+// after its start ends on the following day. Time worked beyond the shift length is overtime.
+func shiftSchedule(day time.Time, startTime, endTime string, breakMinutes, graceMinutes int) *workSchedule {
+	startClock, _ := time.Parse("15:04", startTime)
+	endClock, _ := time.Parse("15:04", endTime)
+
+	start := day.Add(time.Duration(startClock.Hour())*time.Hour + time.Duration(startClock.Minute())*time.Minute)
+	end := day.Add(time.Duration(endClock.Hour())*time.Hour + time.Duration(endClock.Minute())*time.Minute)
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+
+	return &workSchedule{
+		Date:              day,
+		Start:             start,
+		End:               end,
+		GracePeriod:       time.Duration(graceMinutes) * time.Minute,
+		OvertimeThreshold: end.Sub(start) - time.Duration(breakMinutes)*time.Minute,
+		IsWorkingDay:      true,
+		Location:          day.Location(),
+	}
+}
+
+// evaluateCheckInStatus marks a check-in late when it falls after the scheduled start plus
+// the grace period. Lateness does not apply on non-working days.
+func evaluateCheckInStatus(schedule *workSchedule, checkIn time.Time) string {
+	if schedule.IsWorkingDay && checkIn.After(schedule.Start.Add(schedule.GracePeriod)) {
+		return "late"
+	}
+	return "present"
+}
+
+// evaluateCheckOut returns overtime hours and whether the employee left before the scheduled
+// end minus the grace period. All hours worked on a non-working day count as overtime.
+func evaluateCheckOut(schedule *workSchedule, checkIn, checkOut time.Time) (float64, bool) {
+	return evaluateWorkedDay(schedule, checkOut.Sub(checkIn), checkOut)
+}
+
+// evaluateWorkedDay is evaluateCheckOut for a day worked in several sessions: overtime comes
+// from the time worked and early departure from the last check-out
+func evaluateWorkedDay(schedule *workSchedule, worked time.Duration, checkOut time.Time) (float64, bool) {
+	if !schedule.IsWorkingDay {
+		return worked.Hours(), false
+	}
+
+	overtime := 0.0
+	if worked > schedule.OvertimeThreshold {
+		overtime = (worked - schedule.OvertimeThreshold).Hours()
+	}
+
+	earlyDeparture := checkOut.Before(schedule.End.Add(-schedule.GracePeriod))
+
+	return overtime, earlyDeparture
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+const attendanceSessionColumns = `id, tenant_id, attendance_record_id, employee_id, check_in_time, check_out_time,
+	source, device_id, biometric_log_id, created_at, updated_at`
+
+// getRecordSessions lists the check-in sessions of an attendance record in order
+func getRecordSessions(ctx context.Context, q dbExecutor, recordID uuid.UUID) ([]models.AttendanceSession, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+attendanceSessionColumns+`
+		FROM attendance_sessions
+		WHERE attendance_record_id = $1
+		ORDER BY check_in_time`, recordID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attendance sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]models.AttendanceSession, 0)
+	for rows.Next() {
+		var session models.AttendanceSession
+		err := rows.Scan(&session.ID, &session.TenantID, &session.AttendanceRecordID, &session.EmployeeID,
+			&session.CheckInTime, &session.CheckOutTime, &session.Source, &session.DeviceID,
+			&session.BiometricLogID, &session.CreatedAt, &session.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attendance session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// openSession starts a check-in session on a record
+func openSession(ctx context.Context, q dbExecutor, tenantID, recordID, employeeID uuid.UUID, at time.Time, source models.AttendanceSource, deviceID, biometricLogID *uuid.UUID) error {
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO attendance_sessions (id, tenant_id, attendance_record_id, employee_id, check_in_time,
+		                                 source, device_id, biometric_log_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())`,
+		uuid.New(), tenantID, recordID, employeeID, at, source, deviceID, biometricLogID)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return fmt.Errorf("employee already checked in today")
+		}
+		return fmt.Errorf("failed to open attendance session: %w", err)
+	}
+	return nil
+}
+
+// closeSession ends the open session of a record and returns the hours worked across all of
+// its sessions. A record checked in before sessions were kept has none; its check-in becomes
+// a single session ending now.
+func closeSession(ctx context.Context, q dbExecutor, tenantID, recordID, employeeID uuid.UUID, firstCheckIn, at time.Time, source models.AttendanceSource, deviceID *uuid.UUID) (float64, error) {
+	result, err := q.ExecContext(ctx, `
+		UPDATE attendance_sessions
+		SET check_out_time = GREATEST($1, check_in_time), updated_at = NOW()
+		WHERE attendance_record_id = $2 AND check_out_time IS NULL`, at, recordID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to close attendance session: %w", err)
+	}
+
+	if closed, _ := result.RowsAffected(); closed == 0 {
+		var sessions int
+		err := q.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM attendance_sessions WHERE attendance_record_id = $1", recordID).Scan(&sessions)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count attendance sessions: %w", err)
+		}
+		if sessions == 0 {
+			_, err = q.ExecContext(ctx, `
+				INSERT INTO attendance_sessions (id, tenant_id, attendance_record_id, employee_id, check_in_time,
+				                                 check_out_time, source, device_id, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, GREATEST($6::TIMESTAMPTZ, $5), $7, $8, NOW(), NOW())`,
+				uuid.New(), tenantID, recordID, employeeID, firstCheckIn, at, source, deviceID)
+			if err != nil {
+				return 0, fmt.Errorf("failed to record attendance session: %w", err)
+			}
+		}
+	}
+
+	return sessionHours(ctx, q, recordID)
+}
+
+// replaceSessions makes a single session from check-in to check-out the only session of a
+// record, or leaves it without sessions when there is no check-in
+func replaceSessions(ctx context.Context, q dbExecutor, tenantID, recordID, employeeID uuid.UUID, checkIn, checkOut *time.Time, source models.AttendanceSource) error {
+	if _, err := q.ExecContext(ctx, "DELETE FROM attendance_sessions WHERE attendance_record_id = $1", recordID); err != nil {
+		return fmt.Errorf("failed to clear attendance sessions: %w", err)
+	}
+	if checkIn == nil {
+		return nil
+	}
+
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO attendance_sessions (id, tenant_id, attendance_record_id, employee_id, check_in_time,
+		                                 check_out_time, source, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())`,
+		uuid.New(), tenantID, recordID, employeeID, *checkIn, checkOut, source)
+	if err != nil {
+		return fmt.Errorf("failed to record attendance session: %w", err)
+	}
+	return nil
+}
+
+// sessionHours sums the closed sessions of a record
+func sessionHours(ctx context.Context, q dbExecutor, recordID uuid.UUID) (float64, error) {
+	var hours float64
+	err := q.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(EXTRACT(EPOCH FROM check_out_time - check_in_time)), 0) / 3600
+		FROM attendance_sessions
+		WHERE attendance_record_id = $1 AND check_out_time IS NOT NULL`, recordID).Scan(&hours)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum attendance sessions: %w", err)
+	}
+	return hours, nil
+}
+
+// resumeAttendance starts another session on a record that was checked out, reopening the day.
+// The first check-in and the status it earned are kept. The session and the reopened record are
+// written together.
+func (s *AttendanceService) resumeAttendance(ctx context.Context, tenantID, employeeID, recordID uuid.UUID, now time.Time, notes string, source models.AttendanceSource, deviceID, biometricLogID *uuid.UUID, origin *checkInOrigin) (*models.AttendanceRecord, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var lastCheckOut sql.NullTime
+	err = tx.QueryRowContext(ctx,
+		"SELECT check_out_time FROM attendance_records WHERE id = $1 FOR UPDATE", recordID).Scan(&lastCheckOut)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance record: %w", err)
+	}
+	if lastCheckOut.Valid && now.Before(lastCheckOut.Time) {
+		return nil, fmt.Errorf("check-in is before the last check-out")
+	}
+
+	if err := openSession(ctx, tx, tenantID, recordID, employeeID, now, source, deviceID, biometricLogID); err != nil {
+		return nil, err
+	}
+
+	var reviewReason *string
+	if origin != nil && origin.OutsideZone {
+		reason := models.ReviewReasonOutsideZone
+		reviewReason = &reason
+	}
+
+	var record models.AttendanceRecord
+	var notesOut sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		UPDATE attendance_records
+		SET check_out_time = NULL, is_early_departure = false,
+		    notes = CONCAT_WS('; ', NULLIF(notes, ''), NULLIF($1::TEXT, '')),
+		    needs_review = needs_review OR $2, review_reason = COALESCE($3, review_reason), updated_at = $4
+		WHERE id = $5
+		RETURNING id, tenant_id, employee_id, date, check_in_time, total_hours, overtime_hours, status,
+		          notes, source, shift_assignment_id, break_duration_minutes, needs_review, review_reason,
+		          created_at, updated_at`,
+		notes, reviewReason != nil, reviewReason, now, recordID).Scan(
+		&record.ID, &record.TenantID, &record.EmployeeID, &record.Date, &record.CheckInTime,
+		&record.TotalHours, &record.OvertimeHours, &record.Status, &notesOut, &record.Source,
+		&record.ShiftAssignmentID, &record.BreakDurationMinutes, &record.NeedsReview, &record.ReviewReason,
+		&record.CreatedAt, &record.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resume attendance record: %w", err)
+	}
+	if notesOut.Valid {
+		record.Notes = &notesOut.String
+	}
+
+	record.Sessions, err = getRecordSessions(ctx, tx, recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit attendance record: %w", err)
+	}
+	return &record, nil
+}
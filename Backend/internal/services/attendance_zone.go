@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+const attendanceZoneColumns = `id, tenant_id, name, work_location, latitude, longitude, radius_meters,
+	allowed_ip_ranges, is_active, created_at, updated_at`
+
+func scanAttendanceZone(row interface{ Scan(...interface{}) error }) (*models.AttendanceZone, error) {
+	var z models.AttendanceZone
+	err := row.Scan(&z.ID, &z.TenantID, &z.Name, &z.WorkLocation, &z.Latitude, &z.Longitude,
+		&z.RadiusMeters, &z.AllowedIPRanges, &z.IsActive, &z.CreatedAt, &z.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &z, nil
+}
+
+// validateAttendanceZone checks a zone definition and normalizes its location and IP ranges
+func validateAttendanceZone(z *models.AttendanceZone) error {
+	z.Name = strings.TrimSpace(z.Name)
+	if z.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	z.WorkLocation = normalizeHolidayLocation(z.WorkLocation)
+
+	hasPoint := z.Latitude != nil || z.Longitude != nil || z.RadiusMeters != nil
+	if hasPoint {
+		if z.Latitude == nil || z.Longitude == nil || z.RadiusMeters == nil {
+			return fmt.Errorf("latitude, longitude and radius_meters must be set together")
+		}
+		if *z.Latitude < -90 || *z.Latitude > 90 || *z.Longitude < -180 || *z.Longitude > 180 {
+			return fmt.Errorf("invalid coordinates")
+		}
+		if *z.RadiusMeters <= 0 {
+			return fmt.Errorf("radius_meters must be positive")
+		}
+	}
+
+	ranges := make([]string, 0, len(z.AllowedIPRanges))
+	for _, r := range z.AllowedIPRanges {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(r); err != nil && net.ParseIP(r) == nil {
+			return fmt.Errorf("invalid IP range %q", r)
+		}
+		ranges = append(ranges, r)
+	}
+	z.AllowedIPRanges = ranges
+
+	if !hasPoint && len(z.AllowedIPRanges) == 0 {
+		return fmt.Errorf("a zone needs a geofence or at least one IP range")
+	}
+	return nil
+}
+
+// GetAttendanceZones lists the tenant's attendance zones
+func (s *AttendanceService) GetAttendanceZones(ctx context.Context, tenantID uuid.UUID) ([]models.AttendanceZone, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+attendanceZoneColumns+`
+		FROM attendance_zones
+		WHERE tenant_id = $1 AND deleted_at IS NULL
+		ORDER BY name`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attendance zones: %w", err)
+	}
+	defer rows.Close()
+
+	zones := make([]models.AttendanceZone, 0)
+	for rows.Next() {
+		z, err := scanAttendanceZone(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attendance zone: %w", err)
+		}
+		zones = append(zones, *z)
+	}
+	return zones, rows.Err()
+}
+
+// CreateAttendanceZone adds an office zone check-ins are accepted from
+func (s *AttendanceService) CreateAttendanceZone(ctx context.Context, tenantID uuid.UUID, req models.AttendanceZone) (*models.AttendanceZone, error) {
+	if err := validateAttendanceZone(&req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	req.ID = uuid.New()
+	req.TenantID = tenantID
+	req.IsActive = true
+	req.CreatedAt = now
+	req.UpdatedAt = now
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO attendance_zones (id, tenant_id, name, work_location, latitude, longitude, radius_meters,
+		                              allowed_ip_ranges, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		req.ID, req.TenantID, req.Name, req.WorkLocation, req.Latitude, req.Longitude, req.RadiusMeters,
+		req.AllowedIPRanges, req.IsActive, req.CreatedAt, req.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attendance zone: %w", err)
+	}
+
+	return &req, nil
+}
+
+// UpdateAttendanceZone replaces the definition of a zone
+func (s *AttendanceService) UpdateAttendanceZone(ctx context.Context, tenantID, zoneID uuid.UUID, req models.AttendanceZone) (*models.AttendanceZone, error) {
+	if err := validateAttendanceZone(&req); err != nil {
+		return nil, err
+	}
+
+	zone, err := scanAttendanceZone(s.db.QueryRowContext(ctx, `
+		UPDATE attendance_zones
+		SET name = $1, work_location = $2, latitude = $3, longitude = $4, radius_meters = $5,
+		    allowed_ip_ranges = $6, is_active = $7, updated_at = NOW()
+		WHERE id = $8 AND tenant_id = $9 AND deleted_at IS NULL
+		RETURNING `+attendanceZoneColumns,
+		req.Name, req.WorkLocation, req.Latitude, req.Longitude, req.RadiusMeters,
+		req.AllowedIPRanges, req.IsActive, zoneID, tenantID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("attendance zone not found")
+		}
+		return nil, fmt.Errorf("failed to update attendance zone: %w", err)
+	}
+	return zone, nil
+}
+
+// DeleteAttendanceZone removes a zone; records checked in from it keep their reference
+func (s *AttendanceService) DeleteAttendanceZone(ctx context.Context, tenantID, zoneID uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE attendance_zones SET deleted_at = NOW(), is_active = false
+		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL`, zoneID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete attendance zone: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("attendance zone not found")
+	}
+	return nil
+}
+
+// SetRemoteCheckIn allows or stops an employee checking in outside the attendance zones
+func (s *AttendanceService) SetRemoteCheckIn(ctx context.Context, tenantID, employeeID uuid.UUID, req models.RemoteCheckInRequest) error {
+	var until *string
+	if req.Allowed && req.Until != nil && *req.Until != "" {
+		if _, err := time.Parse("2006-01-02", *req.Until); err != nil {
+			return fmt.Errorf("invalid until date (use YYYY-MM-DD)")
+		}
+		until = req.Until
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE employees SET remote_check_in_allowed = $1, remote_check_in_until = $2, updated_at = NOW()
+		WHERE id = $3 AND tenant_id = $4`, req.Allowed, until, employeeID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to update remote check-in: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("employee not found")
+	}
+	return nil
+}
+
+// checkInOrigin is where a web or mobile check-in came from
+type checkInOrigin struct {
+	Latitude    *float64
+	Longitude   *float64
+	IP          *string
+	ZoneID      *uuid.UUID // the zone the check-in matched
+	OutsideZone bool       // accepted outside every zone; flagged for review
+}
+
+// CheckInFromClient records a web or mobile check-in. Its coordinates and IP address are
+// stored on the record and checked against the attendance zones of the employee's work
+// location as the attendance policy requires.
+func (s *AttendanceService) CheckInFromClient(ctx context.Context, tenantID, employeeID uuid.UUID, req models.CheckInRequest, clientIP string) (*models.AttendanceRecord, error) {
+	source := req.Source
+	if source == "" {
+		source = models.SourceWeb
+	}
+	if source != models.SourceWeb && source != models.SourceMobile {
+		return nil, fmt.Errorf("invalid source, expected web or mobile")
+	}
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		return nil, fmt.Errorf("latitude and longitude must be sent together")
+	}
+	if req.Latitude != nil && (*req.Latitude < -90 || *req.Latitude > 90 || *req.Longitude < -180 || *req.Longitude > 180) {
+		return nil, fmt.Errorf("invalid coordinates")
+	}
+
+	origin := &checkInOrigin{Latitude: req.Latitude, Longitude: req.Longitude}
+	if ip := net.ParseIP(clientIP); ip != nil {
+		address := ip.String()
+		origin.IP = &address
+	}
+
+	if err := s.matchAttendanceZone(ctx, tenantID, employeeID, origin); err != nil {
+		return nil, err
+	}
+
+	return s.checkIn(ctx, tenantID, employeeID, req.Notes, source, nil, nil, origin)
+}
+
+// matchAttendanceZone finds the zone a check-in came from and applies the policy when it
+// matches none. Nothing is enforced while the policy is off, the tenant has no zones for the
+// employee's location or the employee may check in remotely.
+func (s *AttendanceService) matchAttendanceZone(ctx context.Context, tenantID, employeeID uuid.UUID, origin *checkInOrigin) error {
+	policy, loc, err := getEffectivePolicy(ctx, s.db, tenantID, employeeID)
+	if err != nil {
+		return err
+	}
+	if policy.LocationEnforcement == "" || policy.LocationEnforcement == models.LocationEnforcementOff {
+		return nil
+	}
+
+	var remoteAllowed bool
+	var remoteUntil sql.NullTime
+	var location sql.NullString
+	err = s.db.QueryRowContext(ctx, `
+		SELECT e.remote_check_in_allowed, e.remote_check_in_until, up.work_location
+		FROM employees e
+		LEFT JOIN user_profiles up ON up.user_id = e.user_id
+		WHERE e.id = $1 AND e.tenant_id = $2`, employeeID, tenantID).Scan(&remoteAllowed, &remoteUntil, &location)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("employee not found")
+		}
+		return fmt.Errorf("failed to get employee: %w", err)
+	}
+	if remoteAllowed {
+		today := time.Now().In(loc).Format("2006-01-02")
+		if !remoteUntil.Valid || remoteUntil.Time.Format("2006-01-02") >= today {
+			return nil
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+attendanceZoneColumns+`
+		FROM attendance_zones
+		WHERE tenant_id = $1 AND deleted_at IS NULL AND is_active = true
+		  AND (work_location IS NULL OR LOWER(work_location) = LOWER($2))`,
+		tenantID, strings.TrimSpace(location.String))
+	if err != nil {
+		return fmt.Errorf("failed to query attendance zones: %w", err)
+	}
+	defer rows.Close()
+
+	zones := 0
+	for rows.Next() {
+		zone, err := scanAttendanceZone(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan attendance zone: %w", err)
+		}
+		zones++
+		if zoneContains(zone, origin) {
+			origin.ZoneID = &zone.ID
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query attendance zones: %w", err)
+	}
+	if zones == 0 {
+		return nil
+	}
+
+	if policy.LocationEnforcement == models.LocationEnforcementReject {
+		return fmt.Errorf("check-in is outside the allowed locations")
+	}
+	origin.OutsideZone = true
+	return nil
+}
+
+// zoneContains reports whether a check-in's coordinates fall inside the zone's geofence or
+// its IP address inside one of the zone's ranges
+func zoneContains(zone *models.AttendanceZone, origin *checkInOrigin) bool {
+	if origin.Latitude != nil && zone.Latitude != nil && zone.RadiusMeters != nil {
+		distance := distanceMeters(*origin.Latitude, *origin.Longitude, *zone.Latitude, *zone.Longitude)
+		if distance <= float64(*zone.RadiusMeters) {
+			return true
+		}
+	}
+
+	if origin.IP == nil {
+		return false
+	}
+	ip := net.ParseIP(*origin.IP)
+	for _, r := range zone.AllowedIPRanges {
+		if _, network, err := net.ParseCIDR(r); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if allowed := net.ParseIP(r); allowed != nil && allowed.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// distanceMeters is the great-circle distance between two coordinates
+func distanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusMeters = 6371000
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+func validLocationEnforcement(mode string) bool {
+	switch mode {
+	case models.LocationEnforcementOff, models.LocationEnforcementFlag, models.LocationEnforcementReject:
+		return true
+	}
+	return false
+}
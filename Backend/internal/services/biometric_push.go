@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/biometric"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/security"
+)
+
+const (
+	// DevicePushMaxBatch is the largest number of punches accepted in one push
+	DevicePushMaxBatch = 1000
+
+	// devicePushMaxSkew is how far the timestamp of a signed push may be from now
+	devicePushMaxSkew = 5 * time.Minute
+
+	// devicePushSecretGrace is how long the previous secret stays valid after a rotation
+	devicePushSecretGrace = 24 * time.Hour
+)
+
+// ErrInvalidDeviceCredentials is returned for any failed device authentication, so callers
+// cannot tell an unknown serial number from a wrong secret
+var ErrInvalidDeviceCredentials = errors.New("invalid device credentials")
+
+// DevicePushCredentials are the credentials a device presents with a push. Either Secret is
+// the device's push secret, or Signature is the hex HMAC-SHA256 of "<Timestamp>.<body>" keyed
+// with it and Timestamp is the Unix time of the request.
+type DevicePushCredentials struct {
+	SerialNumber string
+	Secret       string
+	Signature    string
+	Timestamp    string
+}
+
+// AuthenticateDevice returns the active device matching the push credentials
+func (s *BiometricService) AuthenticateDevice(ctx context.Context, creds DevicePushCredentials, body []byte) (*models.BiometricDevice, error) {
+	if creds.SerialNumber == "" || (creds.Secret == "" && creds.Signature == "") {
+		return nil, ErrInvalidDeviceCredentials
+	}
+
+	if creds.Signature != "" {
+		unix, err := strconv.ParseInt(creds.Timestamp, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidDeviceCredentials
+		}
+		skew := time.Since(time.Unix(unix, 0))
+		if skew > devicePushMaxSkew || skew < -devicePushMaxSkew {
+			return nil, ErrInvalidDeviceCredentials
+		}
+	}
+
+	// Serial numbers are only unique per tenant, so every device with the serial is a candidate
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, tenant_id, push_secret_encrypted,
+		        CASE WHEN previous_push_secret_expires_at > NOW() THEN previous_push_secret_encrypted END
+		 FROM biometric_devices
+		 WHERE serial_number = $1 AND is_active = true AND push_secret_encrypted IS NOT NULL`,
+		creds.SerialNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+	defer rows.Close()
+
+	var matchedID, matchedTenant uuid.UUID
+	for rows.Next() {
+		var id, tenantID uuid.UUID
+		var current string
+		var previous sql.NullString
+		if err := rows.Scan(&id, &tenantID, &current, &previous); err != nil {
+			return nil, fmt.Errorf("failed to scan device: %w", err)
+		}
+
+		for _, encrypted := range []string{current, previous.String} {
+			if encrypted == "" {
+				continue
+			}
+			secret, err := security.Decrypt(encrypted, s.encryptionKey)
+			if err != nil {
+				log.Printf("Failed to decrypt push secret of device %s: %v", id, err)
+				continue
+			}
+			if devicePushSecretMatches(secret, creds, body) {
+				matchedID, matchedTenant = id, tenantID
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+
+	if matchedID == uuid.Nil {
+		return nil, ErrInvalidDeviceCredentials
+	}
+	return s.GetDevice(ctx, matchedTenant, matchedID)
+}
+
+// devicePushSecretMatches checks the presented secret or signature in constant time
+func devicePushSecretMatches(secret string, creds DevicePushCredentials, body []byte) bool {
+	if creds.Signature != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(creds.Timestamp + "."))
+		mac.Write(body)
+		expected := hex.EncodeToString(mac.Sum(nil))
+		signature := strings.TrimPrefix(strings.ToLower(creds.Signature), "sha256=")
+		return hmac.Equal([]byte(signature), []byte(expected))
+	}
+	return subtle.ConstantTimeCompare([]byte(creds.Secret), []byte(secret)) == 1
+}
+
+// IngestDevicePunches stores a batch of punches pushed by a device. Punches already stored for
+// the device are counted as duplicates, so a device can safely re-send a batch.
+func (s *BiometricService) IngestDevicePunches(ctx context.Context, device *models.BiometricDevice, req models.DevicePushRequest) (*models.DevicePushResponse, error) {
+	if len(req.Punches) == 0 {
+		return nil, fmt.Errorf("no punches to ingest")
+	}
+	if len(req.Punches) > DevicePushMaxBatch {
+		return nil, fmt.Errorf("a batch may contain at most %d punches", DevicePushMaxBatch)
+	}
+
+	response := &models.DevicePushResponse{}
+	reject := func(punch models.DevicePunch, reason string) {
+		response.Rejected++
+		response.Errors = append(response.Errors,
+			fmt.Sprintf("%s at %s: %s", punch.EmployeeCode, punch.Timestamp.Format(time.RFC3339), reason))
+	}
+
+	for _, punch := range req.Punches {
+		entry := models.BiometricAttendanceLog{
+			TenantID:     device.TenantID,
+			DeviceID:     device.ID,
+			EmployeeCode: strings.TrimSpace(punch.EmployeeCode),
+			Timestamp:    punch.Timestamp,
+			EventType:    biometric.NormalizeEventType(punch.EventType),
+			Confidence:   punch.Confidence,
+		}
+
+		switch {
+		case entry.EmployeeCode == "":
+			reject(punch, "employee code is required")
+			continue
+		case entry.Timestamp.IsZero():
+			reject(punch, "timestamp is required")
+			continue
+		case entry.Timestamp.After(time.Now().Add(devicePushMaxSkew)):
+			reject(punch, "timestamp is in the future")
+			continue
+		}
+		switch entry.EventType {
+		case biometric.EventCheckIn, biometric.EventCheckOut, biometric.EventBreakStart, biometric.EventBreakEnd:
+		default:
+			reject(punch, "unknown event type "+punch.EventType)
+			continue
+		}
+
+		if err := s.ProcessBiometricLog(ctx, device.TenantID, entry); err != nil {
+			if errors.Is(err, ErrDuplicateBiometricLog) {
+				response.Duplicates++
+				continue
+			}
+			reject(punch, err.Error())
+			continue
+		}
+		response.Accepted++
+	}
+
+	// A push is contact from the device
+	if err := s.UpdateDeviceStatus(ctx, device.ID, models.DeviceStatusActive); err != nil {
+		log.Printf("Failed to update device status: %v", err)
+	}
+
+	return response, nil
+}
+
+// RotateDeviceSecret issues a new push secret for a device. The previous secret keeps working
+// for a grace period so the terminal can be reconfigured without losing punches.
+func (s *BiometricService) RotateDeviceSecret(ctx context.Context, tenantID, deviceID uuid.UUID) (*models.DeviceSecretResponse, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	secret := hex.EncodeToString(raw)
+
+	encrypted, err := security.Encrypt(secret, s.encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+
+	now := time.Now()
+	graceEnd := now.Add(devicePushSecretGrace)
+
+	response := &models.DeviceSecretResponse{
+		DeviceID:  deviceID,
+		Secret:    secret,
+		RotatedAt: now,
+	}
+
+	var hadSecret bool
+	err = s.db.QueryRowContext(ctx,
+		`UPDATE biometric_devices bd
+		 SET push_secret_encrypted = $1,
+		     push_secret_rotated_at = $2,
+		     previous_push_secret_encrypted = old.push_secret_encrypted,
+		     previous_push_secret_expires_at = CASE WHEN old.push_secret_encrypted IS NOT NULL THEN $3::TIMESTAMPTZ END,
+		     updated_at = NOW()
+		 FROM (SELECT id, push_secret_encrypted FROM biometric_devices WHERE id = $4 AND tenant_id = $5 FOR UPDATE) old
+		 WHERE bd.id = old.id
+		 RETURNING bd.serial_number, old.push_secret_encrypted IS NOT NULL`,
+		encrypted, now, graceEnd, deviceID, tenantID).Scan(&response.SerialNumber, &hadSecret)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrDeviceNotFound
+		}
+		return nil, fmt.Errorf("failed to rotate device secret: %w", err)
+	}
+
+	if hadSecret {
+		response.PreviousSecretExpiresAt = &graceEnd
+	}
+	return response, nil
+}
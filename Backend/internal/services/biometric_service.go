@@ -3,22 +3,32 @@ package services
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/biometric"
 	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
 )
 
 // BiometricService handles biometric device and attendance operations
 type BiometricService struct {
-	db *sql.DB
+	db            *sql.DB
+	encryptionKey string
 }
 
+// ErrDuplicateBiometricLog is returned for a punch that is already stored for the device
+var ErrDuplicateBiometricLog = errors.New("biometric log already recorded")
+
+// ErrDeviceNotFound is returned when a device does not exist in the tenant
+var ErrDeviceNotFound = errors.New("device not found")
+
 // NewBiometricService creates a new biometric service
-func NewBiometricService(db *sql.DB) *BiometricService {
-	return &BiometricService{db: db}
+func NewBiometricService(db *sql.DB, encryptionKey string) *BiometricService {
+	return &BiometricService{db: db, encryptionKey: encryptionKey}
 }
 
 // RegisterDevice registers a new biometric device
@@ -44,14 +54,19 @@ func (s *BiometricService) RegisterDevice(ctx context.Context, tenantID uuid.UUI
 		Config:       req.Config,
 	}
 
-	_, err := s.db.ExecContext(ctx,
+	config, err := json.Marshal(device.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode device config: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx,
 		`INSERT INTO biometric_devices (
 			id, tenant_id, device_name, device_type, serial_number, mac_address, 
-			ip_address, port, location, department, status, is_active, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+			ip_address, port, location, department, status, is_active, created_at, updated_at, config
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
 		device.ID, device.TenantID, device.DeviceName, device.DeviceType, device.SerialNumber,
 		device.MacAddress, device.IPAddress, device.Port, device.Location, device.Department,
-		device.Status, device.IsActive, device.CreatedAt, device.UpdatedAt)
+		device.Status, device.IsActive, device.CreatedAt, device.UpdatedAt, config)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to register device: %w", err)
@@ -60,12 +75,61 @@ func (s *BiometricService) RegisterDevice(ctx context.Context, tenantID uuid.UUI
 	return device, nil
 }
 
+// biometricDeviceColumns lists the columns read by scanBiometricDevice
+const biometricDeviceColumns = `id, tenant_id, device_name, device_type, serial_number, mac_address, 
+		        ip_address, port, location, department, status, last_sync_at, is_active, 
+		        created_at, updated_at, config`
+
+// scanBiometricDevice scans a row of biometricDeviceColumns
+func scanBiometricDevice(row interface{ Scan(...interface{}) error }) (*models.BiometricDevice, error) {
+	device := &models.BiometricDevice{}
+	var macAddress, ipAddress, location, department sql.NullString
+	var port sql.NullInt64
+	var lastSyncAt sql.NullTime
+	var config []byte
+
+	err := row.Scan(
+		&device.ID, &device.TenantID, &device.DeviceName, &device.DeviceType,
+		&device.SerialNumber, &macAddress, &ipAddress, &port, &location,
+		&department, &device.Status, &lastSyncAt, &device.IsActive,
+		&device.CreatedAt, &device.UpdatedAt, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle nullable fields
+	if macAddress.Valid {
+		device.MacAddress = &macAddress.String
+	}
+	if ipAddress.Valid {
+		device.IPAddress = &ipAddress.String
+	}
+	if port.Valid {
+		portInt := int(port.Int64)
+		device.Port = &portInt
+	}
+	if location.Valid {
+		device.Location = &location.String
+	}
+	if department.Valid {
+		device.Department = &department.String
+	}
+	if lastSyncAt.Valid {
+		device.LastSyncAt = &lastSyncAt.Time
+	}
+	if len(config) > 0 {
+		if err := json.Unmarshal(config, &device.Config); err != nil {
+			return nil, fmt.Errorf("invalid device config: %w", err)
+		}
+	}
+
+	return device, nil
+}
+
 // GetDevices retrieves all biometric devices for a tenant
 func (s *BiometricService) GetDevices(ctx context.Context, tenantID uuid.UUID) ([]*models.BiometricDevice, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, tenant_id, device_name, device_type, serial_number, mac_address, 
-		        ip_address, port, location, department, status, last_sync_at, is_active, 
-		        created_at, updated_at
+		`SELECT `+biometricDeviceColumns+`
 		 FROM biometric_devices 
 		 WHERE tenant_id = $1 AND is_active = true
 		 ORDER BY device_name`,
@@ -78,48 +142,32 @@ func (s *BiometricService) GetDevices(ctx context.Context, tenantID uuid.UUID) (
 
 	var devices []*models.BiometricDevice
 	for rows.Next() {
-		device := &models.BiometricDevice{}
-		var macAddress, ipAddress, location, department sql.NullString
-		var port sql.NullInt64
-		var lastSyncAt sql.NullTime
-
-		err := rows.Scan(
-			&device.ID, &device.TenantID, &device.DeviceName, &device.DeviceType,
-			&device.SerialNumber, &macAddress, &ipAddress, &port, &location,
-			&department, &device.Status, &lastSyncAt, &device.IsActive,
-			&device.CreatedAt, &device.UpdatedAt)
-
+		device, err := scanBiometricDevice(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan device: %w", err)
 		}
-
-		// Handle nullable fields
-		if macAddress.Valid {
-			device.MacAddress = &macAddress.String
-		}
-		if ipAddress.Valid {
-			device.IPAddress = &ipAddress.String
-		}
-		if port.Valid {
-			portInt := int(port.Int64)
-			device.Port = &portInt
-		}
-		if location.Valid {
-			device.Location = &location.String
-		}
-		if department.Valid {
-			device.Department = &department.String
-		}
-		if lastSyncAt.Valid {
-			device.LastSyncAt = &lastSyncAt.Time
-		}
-
 		devices = append(devices, device)
 	}
 
 	return devices, nil
 }
 
+// GetDevice retrieves a single biometric device of a tenant
+func (s *BiometricService) GetDevice(ctx context.Context, tenantID, deviceID uuid.UUID) (*models.BiometricDevice, error) {
+	device, err := scanBiometricDevice(s.db.QueryRowContext(ctx,
+		`SELECT `+biometricDeviceColumns+`
+		 FROM biometric_devices 
+		 WHERE tenant_id = $1 AND id = $2`,
+		tenantID, deviceID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrDeviceNotFound
+		}
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+	return device, nil
+}
+
 // UpdateDeviceStatus updates the status of a biometric device
 func (s *BiometricService) UpdateDeviceStatus(ctx context.Context, deviceID uuid.UUID, status models.BiometricDeviceStatus) error {
 	_, err := s.db.ExecContext(ctx,
@@ -152,33 +200,51 @@ func (s *BiometricService) ProcessBiometricLog(ctx context.Context, tenantID uui
 		return fmt.Errorf("failed to find employee: %w", err)
 	}
 
-	// Insert the biometric log
+	// Insert the biometric log, once per device and punch
 	logID := uuid.New()
-	_, err = s.db.ExecContext(ctx,
+	result, err := s.db.ExecContext(ctx,
 		`INSERT INTO biometric_attendance_logs (
 			id, tenant_id, device_id, employee_code, employee_id, timestamp, 
 			event_type, biometric_data, confidence, is_processed, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, false, NOW())`,
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, false, NOW())
+		ON CONFLICT (device_id, employee_code, timestamp) DO NOTHING`,
 		logID, tenantID, log.DeviceID, log.EmployeeCode, employeeID,
 		log.Timestamp, log.EventType, log.BiometricData, log.Confidence)
 
 	if err != nil {
 		return fmt.Errorf("failed to insert biometric log: %w", err)
 	}
+	if inserted, err := result.RowsAffected(); err == nil && inserted == 0 {
+		return ErrDuplicateBiometricLog
+	}
 
 	// Process the log into attendance record
 	return s.processLogToAttendance(ctx, logID, tenantID, employeeID, log)
 }
 
-// processLogToAttendance converts a biometric log to attendance record
+// processLogToAttendance converts a biometric log to attendance record. Punches are dated and
+// judged like web check-ins: against the employee's shift or attendance policy in the tenant's
+// timezone.
 func (s *BiometricService) processLogToAttendance(ctx context.Context, logID, tenantID, employeeID uuid.UUID, biometricLog models.BiometricAttendanceLog) error {
-	date := biometricLog.Timestamp.Format("2006-01-02")
+	schedule, err := resolveSchedule(ctx, s.db, tenantID, employeeID, biometricLog.Timestamp)
+	if err != nil {
+		return err
+	}
+	date := schedule.Date.Format("2006-01-02")
+
+	if biometricLog.EventType == "break_start" || biometricLog.EventType == "break_end" {
+		if err := s.processBreakLog(ctx, tenantID, employeeID, date, biometricLog); err != nil {
+			return err
+		}
+		s.markLogProcessed(ctx, logID)
+		return nil
+	}
 
 	// Check if attendance record exists for this date
 	var recordID uuid.UUID
 	var existingCheckIn, existingCheckOut sql.NullTime
 
-	err := s.db.QueryRowContext(ctx,
+	err = s.db.QueryRowContext(ctx,
 		`SELECT id, check_in_time, check_out_time 
 		 FROM attendance_records 
 		 WHERE tenant_id = $1 AND employee_id = $2 AND date = $3`,
@@ -193,11 +259,7 @@ func (s *BiometricService) processLogToAttendance(ctx context.Context, logID, te
 		recordID = uuid.New()
 		status := "present"
 		if biometricLog.EventType == "check_in" {
-			// Check if late (assuming 9:00 AM standard time)
-			standardStart := time.Date(biometricLog.Timestamp.Year(), biometricLog.Timestamp.Month(), biometricLog.Timestamp.Day(), 9, 0, 0, 0, biometricLog.Timestamp.Location())
-			if biometricLog.Timestamp.After(standardStart) {
-				status = "late"
-			}
+			status = evaluateCheckInStatus(schedule, biometricLog.Timestamp)
 		}
 
 		var checkInTime, checkOutTime *time.Time
@@ -211,32 +273,36 @@ func (s *BiometricService) processLogToAttendance(ctx context.Context, logID, te
 		_, err = s.db.ExecContext(ctx,
 			`INSERT INTO attendance_records (
 				id, tenant_id, employee_id, date, check_in_time, check_out_time, 
-				status, source, device_id, biometric_log_id, created_at, updated_at
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())`,
+				status, source, device_id, biometric_log_id, shift_assignment_id, created_at, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())`,
 			recordID, tenantID, employeeID, date, checkInTime, checkOutTime,
-			status, models.SourceBiometric, biometricLog.DeviceID, logID)
+			status, models.SourceBiometric, biometricLog.DeviceID, logID, schedule.ShiftAssignmentID)
+		if err == nil && checkInTime != nil {
+			err = openSession(ctx, s.db, tenantID, recordID, employeeID, *checkInTime, models.SourceBiometric, &biometricLog.DeviceID, &logID)
+		}
 	} else {
 		// Update existing record
-		if biometricLog.EventType == "check_in" && !existingCheckIn.Valid {
+		switch {
+		case biometricLog.EventType == "check_in" && !existingCheckIn.Valid:
 			_, err = s.db.ExecContext(ctx,
 				`UPDATE attendance_records 
-				 SET check_in_time = $1, source = $2, device_id = $3, biometric_log_id = $4, updated_at = NOW()
-				 WHERE id = $5`,
-				biometricLog.Timestamp, models.SourceBiometric, biometricLog.DeviceID, logID, recordID)
-		} else if biometricLog.EventType == "check_out" && !existingCheckOut.Valid {
-			// Calculate total hours
-			var totalHours *float64
-			if existingCheckIn.Valid {
-				duration := biometricLog.Timestamp.Sub(existingCheckIn.Time)
-				hours := duration.Hours()
-				totalHours = &hours
+				 SET check_in_time = $1, status = $2, source = $3, device_id = $4, biometric_log_id = $5,
+				     shift_assignment_id = $6, updated_at = NOW()
+				 WHERE id = $7`,
+				biometricLog.Timestamp, evaluateCheckInStatus(schedule, biometricLog.Timestamp), models.SourceBiometric,
+				biometricLog.DeviceID, logID, schedule.ShiftAssignmentID, recordID)
+			if err == nil {
+				err = openSession(ctx, s.db, tenantID, recordID, employeeID, biometricLog.Timestamp, models.SourceBiometric, &biometricLog.DeviceID, &logID)
 			}
-
-			_, err = s.db.ExecContext(ctx,
-				`UPDATE attendance_records 
-				 SET check_out_time = $1, total_hours = $2, source = $3, device_id = $4, updated_at = NOW()
-				 WHERE id = $5`,
-				biometricLog.Timestamp, totalHours, models.SourceBiometric, biometricLog.DeviceID, recordID)
+		case biometricLog.EventType == "check_in" && existingCheckOut.Valid && biometricLog.Timestamp.After(existingCheckOut.Time):
+			// Back after checking out: another session of the same day
+			err = openSession(ctx, s.db, tenantID, recordID, employeeID, biometricLog.Timestamp, models.SourceBiometric, &biometricLog.DeviceID, &logID)
+			if err == nil {
+				_, err = s.db.ExecContext(ctx,
+					`UPDATE attendance_records SET check_out_time = NULL, updated_at = NOW() WHERE id = $1`, recordID)
+			}
+		case biometricLog.EventType == "check_out" && !existingCheckOut.Valid:
+			err = s.checkOutRecord(ctx, tenantID, employeeID, recordID, existingCheckIn, schedule, biometricLog)
 		}
 	}
 
@@ -244,8 +310,55 @@ func (s *BiometricService) processLogToAttendance(ctx context.Context, logID, te
 		return fmt.Errorf("failed to update attendance record: %w", err)
 	}
 
-	// Mark log as processed
-	_, err = s.db.ExecContext(ctx,
+	s.markLogProcessed(ctx, logID)
+	return nil
+}
+
+// checkOutRecord applies a check-out punch to an open record. Total hours are the sum of the
+// day's sessions; overtime and early departure are judged against the record's schedule.
+func (s *BiometricService) checkOutRecord(ctx context.Context, tenantID, employeeID, recordID uuid.UUID, checkIn sql.NullTime, schedule *workSchedule, biometricLog models.BiometricAttendanceLog) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var totalHours *float64
+	var overtime float64
+	var earlyDeparture bool
+	if checkIn.Valid {
+		// A break still in progress ends with the day
+		allowance, err := breakAllowance(ctx, s.db, tenantID, employeeID)
+		if err != nil {
+			return err
+		}
+		if _, err := endBreak(ctx, tx, tenantID, recordID, biometricLog.Timestamp, allowance); err != nil {
+			return err
+		}
+
+		hours, err := closeSession(ctx, tx, tenantID, recordID, employeeID, checkIn.Time, biometricLog.Timestamp, models.SourceBiometric, &biometricLog.DeviceID)
+		if err != nil {
+			return err
+		}
+		totalHours = &hours
+		overtime, earlyDeparture = evaluateWorkedDay(schedule, time.Duration(hours*float64(time.Hour)), biometricLog.Timestamp)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE attendance_records 
+		 SET check_out_time = $1, total_hours = $2, overtime_hours = $3, is_early_departure = $4,
+		     source = $5, device_id = $6, updated_at = NOW()
+		 WHERE id = $7`,
+		biometricLog.Timestamp, totalHours, overtime, earlyDeparture, models.SourceBiometric, biometricLog.DeviceID, recordID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// markLogProcessed flags a biometric log as applied to attendance
+func (s *BiometricService) markLogProcessed(ctx context.Context, logID uuid.UUID) {
+	_, err := s.db.ExecContext(ctx,
 		`UPDATE biometric_attendance_logs 
 		 SET is_processed = true, processed_at = NOW() 
 		 WHERE id = $1`,
@@ -255,43 +368,226 @@ func (s *BiometricService) processLogToAttendance(ctx context.Context, logID, te
 		log.Printf("Failed to mark biometric log as processed: %v", err)
 		// Don't return error as the main operation succeeded
 	}
+}
 
-	return nil
-} // SyncDeviceData simulates syncing data from a biometric device
+// processBreakLog applies a break_start or break_end punch to the attendance record of its
+// day. Breaks punched outside an open check-in are ignored, as are repeated punches of the
+// same kind.
+func (s *BiometricService) processBreakLog(ctx context.Context, tenantID, employeeID uuid.UUID, date string, biometricLog models.BiometricAttendanceLog) error {
+	var recordID uuid.UUID
+	var checkIn, checkOut sql.NullTime
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, check_in_time, check_out_time
+		 FROM attendance_records
+		 WHERE tenant_id = $1 AND employee_id = $2 AND date = $3`,
+		tenantID, employeeID, date).Scan(&recordID, &checkIn, &checkOut)
+	if err == sql.ErrNoRows {
+		log.Printf("Ignoring %s punch of employee %s: no check-in on %s", biometricLog.EventType, employeeID, date)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check existing attendance: %w", err)
+	}
+	if !checkIn.Valid || checkOut.Valid || biometricLog.Timestamp.Before(checkIn.Time) {
+		log.Printf("Ignoring %s punch of employee %s outside the check-in on %s", biometricLog.EventType, employeeID, date)
+		return nil
+	}
+
+	if biometricLog.EventType == "break_start" {
+		_, err := startBreak(ctx, s.db, tenantID, recordID, employeeID, biometricLog.Timestamp, models.SourceBiometric, &biometricLog.DeviceID)
+		if err != nil && err.Error() != "a break is already in progress" {
+			return err
+		}
+		return nil
+	}
+
+	allowance, err := breakAllowance(ctx, s.db, tenantID, employeeID)
+	if err != nil {
+		return err
+	}
+	_, err = endBreak(ctx, s.db, tenantID, recordID, biometricLog.Timestamp, allowance)
+	return err
+}
+
+// SyncDeviceData pulls the punches a device recorded between StartDate and EndDate through the
+// driver of its protocol and processes each one as a biometric log. The window defaults to
+// the last sync (or the last day) until now. Punches already stored for the device are skipped,
+// so overlapping windows can be synced again. Unless forced, a device synced within its
+// SyncInterval is not contacted.
 func (s *BiometricService) SyncDeviceData(ctx context.Context, tenantID uuid.UUID, req models.BiometricSyncRequest) (*models.BiometricSyncResponse, error) {
 	startTime := time.Now()
 
-	// Update device sync status
-	err := s.UpdateDeviceStatus(ctx, req.DeviceID, models.DeviceStatusActive)
+	device, err := s.GetDevice(ctx, tenantID, req.DeviceID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update device status: %w", err)
+		return nil, err
+	}
+	if !device.IsActive {
+		return nil, fmt.Errorf("device is not active")
 	}
 
-	// In a real implementation, this would connect to the actual biometric device
-	// For now, we'll simulate successful sync
 	response := &models.BiometricSyncResponse{
-		DeviceID:       req.DeviceID,
-		SyncStarted:    startTime,
-		SyncCompleted:  time.Now(),
-		RecordsSynced:  0,
-		RecordsSkipped: 0,
-		Status:         "success",
+		DeviceID:    device.ID,
+		SyncStarted: startTime,
 	}
 
-	// Update last sync time
-	_, err = s.db.ExecContext(ctx,
-		`UPDATE biometric_devices 
-		 SET last_sync_at = NOW(), updated_at = NOW()
-		 WHERE id = $1`,
-		req.DeviceID)
+	if !req.Force && device.LastSyncAt != nil && device.Config.SyncInterval > 0 &&
+		startTime.Sub(*device.LastSyncAt) < time.Duration(device.Config.SyncInterval)*time.Minute {
+		response.SyncCompleted = time.Now()
+		response.Status = "skipped"
+		return response, nil
+	}
 
+	driver, err := biometric.DriverFor(device.Config)
 	if err != nil {
+		return nil, err
+	}
+
+	loc := tenantLocation(ctx, s.db, tenantID)
+	start, end, err := syncWindow(device, req, startTime)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := driver.FetchLogs(ctx, device, start.In(loc), end.In(loc))
+	if err != nil {
+		if statusErr := s.UpdateDeviceStatus(ctx, device.ID, models.DeviceStatusOffline); statusErr != nil {
+			log.Printf("Failed to update device status: %v", statusErr)
+		}
+		response.SyncCompleted = time.Now()
+		response.Errors = []string{err.Error()}
+		response.Status = "failed"
+		return response, nil
+	}
+
+	logs, repeated := dedupePunches(logs)
+	response.RecordsSkipped += repeated
+
+	for _, entry := range logs {
+		entry.TenantID = tenantID
+		entry.DeviceID = device.ID
+		if err := s.ProcessBiometricLog(ctx, tenantID, entry); err != nil {
+			if errors.Is(err, ErrDuplicateBiometricLog) {
+				response.RecordsSkipped++
+				continue
+			}
+			response.RecordsSkipped++
+			response.Errors = append(response.Errors,
+				fmt.Sprintf("%s at %s: %v", entry.EmployeeCode, entry.Timestamp.Format(time.RFC3339), err))
+			continue
+		}
+		response.RecordsSynced++
+	}
+
+	// Update device sync status and last sync time
+	if err := s.UpdateDeviceStatus(ctx, device.ID, models.DeviceStatusActive); err != nil {
 		log.Printf("Failed to update device sync time: %v", err)
 	}
 
+	response.SyncCompleted = time.Now()
+	response.Status = "success"
+	if len(response.Errors) > 0 {
+		response.Status = "partial"
+		if response.RecordsSynced == 0 {
+			response.Status = "failed"
+		}
+	}
+
 	return response, nil
 }
 
+// syncWindow returns the window of punches to fetch from a device: the requested one, or from
+// the last sync (at most a day back) until now
+func syncWindow(device *models.BiometricDevice, req models.BiometricSyncRequest, now time.Time) (time.Time, time.Time, error) {
+	end := req.EndDate
+	if end.IsZero() {
+		end = now
+	}
+	start := req.StartDate
+	if start.IsZero() {
+		start = end.Add(-24 * time.Hour)
+		if device.LastSyncAt != nil && device.LastSyncAt.Before(end) {
+			start = *device.LastSyncAt
+		}
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end date is before start date")
+	}
+	return start, end, nil
+}
+
+// dedupePunches drops punches a device reported more than once in the same fetch, which
+// terminals do when a punch is retried, and returns how many were dropped
+func dedupePunches(logs []models.BiometricAttendanceLog) ([]models.BiometricAttendanceLog, int) {
+	type punchKey struct {
+		employeeCode string
+		timestamp    int64
+		eventType    string
+	}
+	seen := make(map[punchKey]bool, len(logs))
+	unique := logs[:0]
+	for _, entry := range logs {
+		key := punchKey{entry.EmployeeCode, entry.Timestamp.UnixNano(), entry.EventType}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, entry)
+	}
+	return unique, len(logs) - len(unique)
+}
+
+// SyncDueDevices syncs every active device, across tenants, whose SyncInterval has elapsed
+// since its last sync. A failing device does not stop the others. It returns how many devices
+// were synced and how many failed.
+func (s *BiometricService) SyncDueDevices(ctx context.Context) (int, int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, tenant_id FROM biometric_devices
+		 WHERE is_active = true
+		   AND COALESCE((config->>'sync_interval')::INT, 0) > 0
+		   AND (last_sync_at IS NULL
+		        OR last_sync_at + make_interval(mins => (config->>'sync_interval')::INT) <= NOW())
+		 ORDER BY last_sync_at NULLS FIRST`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get devices due for sync: %w", err)
+	}
+
+	type dueDevice struct {
+		ID       uuid.UUID
+		TenantID uuid.UUID
+	}
+	devices := make([]dueDevice, 0)
+	for rows.Next() {
+		var d dueDevice
+		if err := rows.Scan(&d.ID, &d.TenantID); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("failed to scan device: %w", err)
+		}
+		devices = append(devices, d)
+	}
+	rows.Close()
+
+	synced, failed := 0, 0
+	for _, d := range devices {
+		if ctx.Err() != nil {
+			return synced, failed, ctx.Err()
+		}
+		response, err := s.SyncDeviceData(ctx, d.TenantID, models.BiometricSyncRequest{DeviceID: d.ID})
+		if err != nil {
+			log.Printf("Failed to sync device %s: %v", d.ID, err)
+			failed++
+			continue
+		}
+		if response.Status == "failed" {
+			log.Printf("Sync of device %s failed: %v", d.ID, response.Errors)
+			failed++
+			continue
+		}
+		synced++
+	}
+
+	return synced, failed, nil
+}
+
 // GetBiometricLogs retrieves biometric attendance logs with pagination
 func (s *BiometricService) GetBiometricLogs(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.BiometricAttendanceLog, error) {
 	if limit <= 0 {
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/biometric"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+// fetchAndDedupe runs the device side of a sync: the driver fetch and the removal of repeated
+// punches, as SyncDeviceData does before storing them
+func fetchAndDedupe(t *testing.T, device *models.BiometricDevice, start, end time.Time) ([]models.BiometricAttendanceLog, int) {
+	t.Helper()
+	driver, err := biometric.DriverFor(device.Config)
+	if err != nil {
+		t.Fatalf("DriverFor() error = %v", err)
+	}
+	logs, err := driver.FetchLogs(context.Background(), device, start, end)
+	if err != nil {
+		t.Fatalf("FetchLogs() error = %v", err)
+	}
+	return dedupePunches(logs)
+}
+
+func TestSyncDedupesRepeatedPunches(t *testing.T) {
+	loc := time.FixedZone("IST", 5*3600+1800)
+	device, err := biometric.NewFakeZKDevice("ZK-1", 1234, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer device.Close()
+
+	punch := time.Date(2026, 3, 2, 9, 0, 0, 0, loc)
+	device.AddPunch("E001", punch, biometric.EventCheckIn)
+	device.AddPunch("E001", punch, biometric.EventCheckIn) // retried by the terminal
+	device.AddPunch("E001", punch.Add(9*time.Hour), biometric.EventCheckOut)
+	device.AddPunch("E002", punch, biometric.EventCheckIn)
+
+	logs, repeated := fetchAndDedupe(t, device.Device(), punch.Add(-time.Hour), punch.Add(12*time.Hour))
+	if repeated != 1 {
+		t.Errorf("repeated = %d, want 1", repeated)
+	}
+	if len(logs) != 3 {
+		t.Fatalf("got %d punches, want 3", len(logs))
+	}
+	if !logs[0].Timestamp.Equal(punch) || logs[0].EmployeeCode != "E001" || logs[0].EventType != biometric.EventCheckIn {
+		t.Errorf("first punch = %+v", logs[0])
+	}
+}
+
+func TestSyncCursorAdvances(t *testing.T) {
+	device := biometric.NewFakeHTTPDevice("HTTP-1", "secret")
+	defer device.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	device.AddPunch("E001", now.Add(-3*time.Hour), "in")
+
+	config := device.Device()
+
+	// The first sync of a device reaches a day back
+	start, end, err := syncWindow(config, models.BiometricSyncRequest{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !start.Equal(now.Add(-24*time.Hour)) || !end.Equal(now) {
+		t.Fatalf("first window = %s..%s", start, end)
+	}
+	logs, _ := fetchAndDedupe(t, config, start, end)
+	if len(logs) != 1 {
+		t.Fatalf("first sync got %d punches, want 1", len(logs))
+	}
+
+	// The next sync starts where the last one ended
+	lastSync := now
+	config.LastSyncAt = &lastSync
+	later := now.Add(2 * time.Hour)
+	device.AddPunch("E001", now.Add(time.Hour), "out")
+
+	start, end, err = syncWindow(config, models.BiometricSyncRequest{}, later)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !start.Equal(lastSync) || !end.Equal(later) {
+		t.Fatalf("second window = %s..%s", start, end)
+	}
+	logs, _ = fetchAndDedupe(t, config, start, end)
+	if len(logs) != 1 || logs[0].EventType != biometric.EventCheckOut {
+		t.Fatalf("second sync got %+v, want only the new check-out", logs)
+	}
+
+	// An explicit window must not end before it starts
+	if _, _, err := syncWindow(config, models.BiometricSyncRequest{StartDate: later, EndDate: now}, later); err == nil {
+		t.Error("syncWindow() accepted an inverted window")
+	}
+}
+
+func TestSyncMQTTDeliversQueuedPunchesOnce(t *testing.T) {
+	device, err := biometric.NewFakeMQTTDevice("MQTT-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer device.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	device.AddPunch("E001", now.Add(-time.Hour), "check_in")
+	device.AddPunch("E002", now.Add(-time.Hour), "check_in")
+
+	logs, _ := fetchAndDedupe(t, device.Device(), now.Add(-24*time.Hour), now)
+	if len(logs) != 2 {
+		t.Fatalf("got %d punches, want 2", len(logs))
+	}
+	if pending := device.Pending(); pending != 0 {
+		t.Errorf("%d punches left unacknowledged", pending)
+	}
+
+	logs, _ = fetchAndDedupe(t, device.Device(), now.Add(-24*time.Hour), now)
+	if len(logs) != 0 {
+		t.Errorf("second sync got %d punches, want none", len(logs))
+	}
+}
+
+func TestSyncConnectionFailures(t *testing.T) {
+	zk, err := biometric.NewFakeZKDevice("ZK-2", 1234, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongKey := zk.Device()
+	key := "4321"
+	wrongKey.Config.APIKey = &key
+
+	httpDevice := biometric.NewFakeHTTPDevice("HTTP-2", "secret")
+	defer httpDevice.Close()
+	unauthorized := httpDevice.Device()
+	unauthorized.Config.APIKey = nil
+
+	offline := zk.Device()
+	offline.Config.TimeoutSeconds = 1
+	if err := zk.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	tests := []struct {
+		name   string
+		device *models.BiometricDevice
+	}{
+		{name: "wrong comm key", device: wrongKey},
+		{name: "missing API key", device: unauthorized},
+		{name: "device offline", device: offline},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, err := biometric.DriverFor(tt.device.Config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := driver.FetchLogs(context.Background(), tt.device, now.Add(-time.Hour), now); err == nil {
+				t.Error("FetchLogs() succeeded, want an error")
+			}
+		})
+	}
+}
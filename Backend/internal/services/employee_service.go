@@ -2,9 +2,10 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
 	"fmt"
-	"math/rand"
+	"math/big"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,16 +15,18 @@ import (
 )
 
 type EmployeeService struct {
-	db            *sql.DB
-	pepperSecret  string
-	encryptionKey string
+	db                  *sql.DB
+	notificationService *NotificationService
+	pepperSecret        string
+	encryptionKey       string
 }
 
-func NewEmployeeService(db *sql.DB, pepperSecret, encryptionKey string) *EmployeeService {
+func NewEmployeeService(db *sql.DB, notificationService *NotificationService, pepperSecret, encryptionKey string) *EmployeeService {
 	return &EmployeeService{
-		db:            db,
-		pepperSecret:  pepperSecret,
-		encryptionKey: encryptionKey,
+		db:                  db,
+		notificationService: notificationService,
+		pepperSecret:        pepperSecret,
+		encryptionKey:       encryptionKey,
 	}
 }
 
@@ -61,8 +64,11 @@ func (s *EmployeeService) CreateEmployee(tenantID uuid.UUID, req *CreateEmployee
 		hireDate = time.Now()
 	}
 
-	// Generate secure temporary password (consistent for admin notification)
-	tempPassword := s.generateTempPassword()
+	// Generate secure temporary password, emailed to the employee once the account is created
+	tempPassword, err := s.generateTempPassword()
+	if err != nil {
+		return nil, err
+	}
 	hashedPassword, err := auth.HashPassword(tempPassword, s.pepperSecret)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
@@ -93,7 +99,8 @@ func (s *EmployeeService) CreateEmployee(tenantID uuid.UUID, req *CreateEmployee
 		// Update user with new details and reactivate
 		updateUserQuery := `
 			UPDATE users
-			SET tenant_id = $1, password_hash = $2, role = $3, first_name = $4, last_name = $5, is_active = true, deleted_at = NULL, updated_at = $6
+			SET tenant_id = $1, password_hash = $2, role = $3, first_name = $4, last_name = $5, is_active = true, deleted_at = NULL, updated_at = $6,
+			    must_change_password = true
 			WHERE id = $7`
 
 		_, err = tx.Exec(updateUserQuery, tenantID, string(hashedPassword), role, req.FirstName, req.LastName, time.Now(), userID)
@@ -102,11 +109,12 @@ func (s *EmployeeService) CreateEmployee(tenantID uuid.UUID, req *CreateEmployee
 		}
 
 	case err == sql.ErrNoRows:
-		// Create new user
+		// Create new user; the temporary password must be changed at the first sign-in
 		userID = uuid.New()
 		userQuery := `
-			INSERT INTO users (id, tenant_id, email, password_hash, role, first_name, last_name, is_active, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+			INSERT INTO users (id, tenant_id, email, password_hash, role, first_name, last_name, is_active,
+			                   must_change_password, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, true, $9, $10)`
 
 		role := req.Role
 		if role == "" {
@@ -203,6 +211,12 @@ func (s *EmployeeService) CreateEmployee(tenantID uuid.UUID, req *CreateEmployee
 		return nil, fmt.Errorf("failed to create employee record: %w", err)
 	}
 
+	// Send the sign-in details to the employee
+	err = s.notificationService.QueueAccountCreated(context.Background(), tx, tenantID, req.Email, req.FirstName, tempPassword)
+	if err != nil {
+		return nil, err
+	}
+
 	// Commit transaction
 	if err = tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
@@ -780,6 +794,13 @@ func (s *EmployeeService) UpdateEmployeeStatus(tenantID, employeeID uuid.UUID, u
 		if err != nil {
 			return nil, fmt.Errorf("failed to update user status: %w", err)
 		}
+
+		// A deactivated user is signed out of every session
+		if isActive == false {
+			if err := auth.RevokeUserRefreshTokens(context.Background(), tx, userID.String(), auth.RevokeReasonUserInactive); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// Update employees table if employment_status is provided
@@ -804,13 +825,17 @@ func (s *EmployeeService) UpdateEmployeeStatus(tenantID, employeeID uuid.UUID, u
 }
 
 // generateTempPassword generates a secure temporary password
-func (s *EmployeeService) generateTempPassword() string {
+func (s *EmployeeService) generateTempPassword() (string, error) {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	const length = 8
+	const length = 12
 
 	password := make([]byte, length)
 	for i := range password {
-		password[i] = charset[rand.Intn(len(charset))]
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate temporary password: %w", err)
+		}
+		password[i] = charset[n.Int64()]
 	}
-	return string(password)
+	return string(password), nil
 }
@@ -0,0 +1,466 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+type HolidayService struct {
+	db *sql.DB
+}
+
+func NewHolidayService(db *sql.DB) *HolidayService {
+	return &HolidayService{db: db}
+}
+
+// GetHolidays lists the tenant's holidays for a year, optionally limited to holidays
+// that apply to a location (including tenant-wide holidays)
+func (s *HolidayService) GetHolidays(ctx context.Context, tenantID uuid.UUID, year int, location string) ([]models.Holiday, error) {
+	query := `
+		SELECT id, tenant_id, name, holiday_date, location, description, source, external_uid, created_at, updated_at
+		FROM holidays
+		WHERE tenant_id = $1 AND EXTRACT(YEAR FROM holiday_date) = $2`
+	args := []interface{}{tenantID, year}
+
+	if location != "" {
+		query += " AND (location IS NULL OR LOWER(location) = LOWER($3))"
+		args = append(args, location)
+	}
+	query += " ORDER BY holiday_date, name"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query holidays: %w", err)
+	}
+	defer rows.Close()
+
+	holidays := make([]models.Holiday, 0)
+	for rows.Next() {
+		h, err := scanHoliday(rows)
+		if err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, *h)
+	}
+
+	return holidays, nil
+}
+
+// CreateHoliday adds a holiday to the tenant's calendar
+func (s *HolidayService) CreateHoliday(ctx context.Context, tenantID uuid.UUID, req models.CreateHolidayRequest) (*models.Holiday, error) {
+	date, err := validateHoliday(req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	h := models.Holiday{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		Name:        strings.TrimSpace(req.Name),
+		Date:        date,
+		Location:    normalizeHolidayLocation(req.Location),
+		Description: req.Description,
+		Source:      "manual",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO holidays (id, tenant_id, name, holiday_date, location, description, source, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		h.ID, h.TenantID, h.Name, h.Date, h.Location, h.Description, h.Source, h.CreatedAt, h.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, fmt.Errorf("a holiday already exists on this date for this location")
+		}
+		return nil, fmt.Errorf("failed to create holiday: %w", err)
+	}
+
+	return &h, nil
+}
+
+// UpdateHoliday replaces the definition of an existing holiday
+func (s *HolidayService) UpdateHoliday(ctx context.Context, tenantID, holidayID uuid.UUID, req models.CreateHolidayRequest) (*models.Holiday, error) {
+	date, err := validateHoliday(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE holidays
+		SET name = $1, holiday_date = $2, location = $3, description = $4, updated_at = $5
+		WHERE id = $6 AND tenant_id = $7`,
+		strings.TrimSpace(req.Name), date, normalizeHolidayLocation(req.Location), req.Description,
+		time.Now(), holidayID, tenantID)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, fmt.Errorf("a holiday already exists on this date for this location")
+		}
+		return nil, fmt.Errorf("failed to update holiday: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, fmt.Errorf("holiday not found")
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, tenant_id, name, holiday_date, location, description, source, external_uid, created_at, updated_at
+		FROM holidays
+		WHERE id = $1 AND tenant_id = $2`, holidayID, tenantID)
+	return scanHoliday(row)
+}
+
+// DeleteHoliday removes a holiday from the tenant's calendar
+func (s *HolidayService) DeleteHoliday(ctx context.Context, tenantID, holidayID uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM holidays WHERE id = $1 AND tenant_id = $2", holidayID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete holiday: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("holiday not found")
+	}
+
+	return nil
+}
+
+// ImportICS imports the all-day events of an iCalendar file as holidays. Events that fall
+// on a date that already has a holiday for the location replace its name and description.
+func (s *HolidayService) ImportICS(ctx context.Context, tenantID uuid.UUID, r io.Reader, location *string) (*models.HolidayImportResult, error) {
+	events, err := parseICSEvents(r)
+	if err != nil {
+		return nil, err
+	}
+
+	location = normalizeHolidayLocation(location)
+	result := &models.HolidayImportResult{}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for _, event := range events {
+		if event.Summary == "" || len(event.Dates) == 0 {
+			result.Skipped++
+			if event.Err != "" {
+				result.Errors = append(result.Errors, event.Err)
+			}
+			continue
+		}
+
+		for _, date := range event.Dates {
+			var inserted bool
+			err := tx.QueryRowContext(ctx, `
+				INSERT INTO holidays (id, tenant_id, name, holiday_date, location, description, source,
+				                      external_uid, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, 'ics', $7, $8, $8)
+				ON CONFLICT (tenant_id, holiday_date, (COALESCE(location, ''))) DO UPDATE
+				SET name = EXCLUDED.name, description = EXCLUDED.description, source = EXCLUDED.source,
+				    external_uid = EXCLUDED.external_uid, updated_at = EXCLUDED.updated_at
+				RETURNING (xmax = 0)`,
+				uuid.New(), tenantID, event.Summary, date, location, nullIfEmpty(event.Description),
+				nullIfEmpty(event.UID), now).Scan(&inserted)
+			if err != nil {
+				return nil, fmt.Errorf("failed to import holiday %q: %w", event.Summary, err)
+			}
+
+			if inserted {
+				result.Imported++
+			} else {
+				result.Updated++
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit holiday import: %w", err)
+	}
+
+	return result, nil
+}
+
+// holidayDates returns the dates between from and to (inclusive) that are holidays for the
+// given location, keyed by YYYY-MM-DD. An empty location only matches tenant-wide holidays.
+func holidayDates(ctx context.Context, q dbExecutor, tenantID uuid.UUID, location string, from, to time.Time) (map[string]bool, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT holiday_date
+		FROM holidays
+		WHERE tenant_id = $1 AND holiday_date BETWEEN $2 AND $3
+		  AND (location IS NULL OR LOWER(location) = LOWER($4))`,
+		tenantID, from.Format("2006-01-02"), to.Format("2006-01-02"), location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query holidays: %w", err)
+	}
+	defer rows.Close()
+
+	dates := make(map[string]bool)
+	for rows.Next() {
+		var date time.Time
+		if err := rows.Scan(&date); err != nil {
+			return nil, fmt.Errorf("failed to scan holiday: %w", err)
+		}
+		dates[date.Format("2006-01-02")] = true
+	}
+
+	return dates, nil
+}
+
+// employeeWorkLocation returns the work location from the employee's user profile, or an
+// empty string when none is set
+func employeeWorkLocation(ctx context.Context, q dbExecutor, tenantID, employeeID uuid.UUID) (string, error) {
+	var location sql.NullString
+	err := q.QueryRowContext(ctx, `
+		SELECT up.work_location
+		FROM employees e
+		LEFT JOIN user_profiles up ON up.user_id = e.user_id
+		WHERE e.id = $1 AND e.tenant_id = $2
+		LIMIT 1`, employeeID, tenantID).Scan(&location)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to get employee work location: %w", err)
+	}
+
+	return strings.TrimSpace(location.String), nil
+}
+
+// countLeaveDays counts the working days between start and end (inclusive) that are not
+// holidays. A half-day start or end counts the first or last working day as half a day;
+// for a single-day leave either flag makes it a half day.
+func countLeaveDays(start, end time.Time, startHalfDay, endHalfDay bool, workingDays []string, holidays map[string]bool) float64 {
+	isLeaveDay := func(day time.Time) bool {
+		return containsWeekday(workingDays, day.Weekday()) && !holidays[day.Format("2006-01-02")]
+	}
+
+	days := 0.0
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if isLeaveDay(day) {
+			days++
+		}
+	}
+
+	if start.Equal(end) {
+		if days > 0 && (startHalfDay || endHalfDay) {
+			return 0.5
+		}
+		return days
+	}
+
+	if startHalfDay && isLeaveDay(start) {
+		days -= 0.5
+	}
+	if endHalfDay && isLeaveDay(end) {
+		days -= 0.5
+	}
+
+	return days
+}
+
+// icsEvent is an all-day event read from an iCalendar file
+type icsEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Dates       []time.Time
+	Err         string
+}
+
+// maxICSEventDays bounds how many holiday dates a single multi-day event can expand into
+const maxICSEventDays = 31
+
+// parseICSEvents reads the VEVENT components of an iCalendar stream. DTEND is exclusive,
+// as in RFC 5545, so a one-day event usually has DTEND on the following day.
+func parseICSEvents(r io.Reader) ([]icsEvent, error) {
+	lines, err := unfoldICSLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []icsEvent
+	var current *icsEvent
+	var start, end time.Time
+	sawCalendar := false
+
+	for _, line := range lines {
+		name, value := splitICSLine(line)
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VCALENDAR"):
+			sawCalendar = true
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			current = &icsEvent{}
+			start, end = time.Time{}, time.Time{}
+		case name == "END" && strings.EqualFold(value, "VEVENT") && current != nil:
+			if start.IsZero() {
+				current.Err = fmt.Sprintf("event %q has no valid DTSTART", current.Summary)
+			} else {
+				if end.IsZero() || !end.After(start) {
+					end = start.AddDate(0, 0, 1)
+				}
+				for day := start; day.Before(end) && len(current.Dates) < maxICSEventDays; day = day.AddDate(0, 0, 1) {
+					current.Dates = append(current.Dates, day)
+				}
+			}
+			events = append(events, *current)
+			current = nil
+		case current == nil:
+			continue
+		case name == "UID":
+			current.UID = value
+		case name == "SUMMARY":
+			current.Summary = strings.TrimSpace(unescapeICSText(value))
+		case name == "DESCRIPTION":
+			current.Description = strings.TrimSpace(unescapeICSText(value))
+		case name == "DTSTART":
+			start = parseICSDate(value)
+		case name == "DTEND":
+			end = parseICSDate(value)
+		}
+	}
+
+	if !sawCalendar {
+		return nil, fmt.Errorf("invalid iCalendar file: missing VCALENDAR")
+	}
+
+	return events, nil
+}
+
+// unfoldICSLines joins folded content lines, which continue with a leading space or tab
+func unfoldICSLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read iCalendar file: %w", err)
+	}
+
+	return lines, nil
+}
+
+// splitICSLine splits "NAME;PARAM=X:value" into its upper-cased name and value
+func splitICSLine(line string) (string, string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return strings.ToUpper(line), ""
+	}
+
+	name, value := line[:colon], line[colon+1:]
+	if semi := strings.Index(name, ";"); semi >= 0 {
+		name = name[:semi]
+	}
+
+	return strings.ToUpper(name), value
+}
+
+// parseICSDate parses a DATE or DATE-TIME value and keeps only the calendar date.
+// Floating and TZID times are taken at face value; holidays are whole days.
+func parseICSDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if len(value) < 8 {
+		return time.Time{}
+	}
+
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}
+	}
+	return date
+}
+
+// unescapeICSText reverses the TEXT escaping of RFC 5545
+func unescapeICSText(value string) string {
+	replacer := strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return replacer.Replace(value)
+}
+
+func validateHoliday(req models.CreateHolidayRequest) (time.Time, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return time.Time{}, fmt.Errorf("holiday name is required")
+	}
+
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid holiday date format, expected YYYY-MM-DD")
+	}
+
+	return date, nil
+}
+
+// normalizeHolidayLocation treats a blank location as tenant-wide
+func normalizeHolidayLocation(location *string) *string {
+	if location == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*location)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+func nullIfEmpty(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+type holidayScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanHoliday(row holidayScanner) (*models.Holiday, error) {
+	var h models.Holiday
+	var location, description, externalUID sql.NullString
+	err := row.Scan(&h.ID, &h.TenantID, &h.Name, &h.Date, &location, &description, &h.Source,
+		&externalUID, &h.CreatedAt, &h.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("holiday not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan holiday: %w", err)
+	}
+
+	if location.Valid {
+		h.Location = &location.String
+	}
+	if description.Valid {
+		h.Description = &description.String
+	}
+	if externalUID.Valid {
+		h.ExternalUID = &externalUID.String
+	}
+
+	return &h, nil
+}
@@ -14,11 +14,12 @@ import (
 )
 
 type InvoiceService struct {
-	db *sql.DB
+	db                  *sql.DB
+	notificationService *NotificationService
 }
 
-func NewInvoiceService(db *sql.DB) *InvoiceService {
-	return &InvoiceService{db: db}
+func NewInvoiceService(db *sql.DB, notificationService *NotificationService) *InvoiceService {
+	return &InvoiceService{db: db, notificationService: notificationService}
 }
 
 // CreateInvoice creates a new invoice
@@ -55,7 +56,13 @@ func (s *InvoiceService) CreateInvoice(ctx context.Context, invoice *models.Invo
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		RETURNING id`
 
-	err = s.db.QueryRowContext(ctx, query,
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query,
 		invoice.ID, invoice.InvoiceNumber, invoice.TenantID, invoice.SubscriptionID,
 		invoice.Subtotal, invoice.TaxRate, invoice.TaxAmount, invoice.DiscountAmount, invoice.TotalAmount, invoice.Currency,
 		invoice.Status, invoice.IssueDate, invoice.DueDate,
@@ -67,9 +74,27 @@ func (s *InvoiceService) CreateInvoice(ctx context.Context, invoice *models.Invo
 		return nil, fmt.Errorf("failed to create invoice: %w", err)
 	}
 
+	var notifications []models.Notification
+	if isIssuedInvoiceStatus(invoice.Status) {
+		notifications, err = s.notificationService.QueueInvoiceIssued(ctx, tx, invoice.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit invoice: %w", err)
+	}
+	s.notificationService.Publish(notifications)
+
 	return s.GetInvoiceByID(ctx, invoice.ID)
 }
 
+// isIssuedInvoiceStatus reports whether an invoice in a status has been issued to the customer
+func isIssuedInvoiceStatus(status string) bool {
+	return status == "pending" || status == "overdue"
+}
+
 // GetInvoiceByID retrieves an invoice by ID
 func (s *InvoiceService) GetInvoiceByID(ctx context.Context, id uuid.UUID) (*models.Invoice, error) {
 	query := `
@@ -227,7 +252,13 @@ func (s *InvoiceService) UpdateInvoice(ctx context.Context, id uuid.UUID, update
 			billing_details = $10, line_items = $11, notes = $12, updated_at = $13
 		WHERE id = $14`
 
-	_, err = s.db.ExecContext(ctx, query,
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, query,
 		updates.Subtotal, updates.TaxRate, updates.TaxAmount, updates.DiscountAmount,
 		updates.TotalAmount, updates.Currency, updates.Status, updates.IssueDate, updates.DueDate,
 		billingDetailsJSON, lineItemsJSON, updates.Notes, time.Now(), id,
@@ -237,6 +268,20 @@ func (s *InvoiceService) UpdateInvoice(ctx context.Context, id uuid.UUID, update
 		return nil, fmt.Errorf("failed to update invoice: %w", err)
 	}
 
+	// A draft invoice is sent when it is issued
+	var notifications []models.Notification
+	if current.Status == "draft" && isIssuedInvoiceStatus(updates.Status) {
+		notifications, err = s.notificationService.QueueInvoiceIssued(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit invoice: %w", err)
+	}
+	s.notificationService.Publish(notifications)
+
 	return s.GetInvoiceByID(ctx, id)
 }
 
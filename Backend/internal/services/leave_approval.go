@@ -0,0 +1,765 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+// approvalChainStep is a step of the workflow selected for a leave request
+type approvalChainStep struct {
+	ApproverType   models.ApproverType
+	ApproverUserID *uuid.UUID
+	MinDays        *float64
+}
+
+// defaultApprovalChain is used when no workflow matches a request. A request whose chain
+// resolves to no approver at all falls back to a single HR step.
+var defaultApprovalChain = []approvalChainStep{{ApproverType: models.ApproverManager}}
+
+// GetApprovalWorkflows lists the tenant's leave approval workflows with their steps
+func (s *LeaveService) GetApprovalWorkflows(ctx context.Context, tenantID uuid.UUID) ([]models.LeaveApprovalWorkflow, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, tenant_id, name, leave_type, department_id, min_days, priority, is_active, created_at, updated_at
+		FROM leave_approval_workflows
+		WHERE tenant_id = $1
+		ORDER BY priority DESC, name`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query approval workflows: %w", err)
+	}
+	defer rows.Close()
+
+	workflows := make([]models.LeaveApprovalWorkflow, 0)
+	index := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var w models.LeaveApprovalWorkflow
+		var leaveType sql.NullString
+		var minDays sql.NullFloat64
+		if err := rows.Scan(&w.ID, &w.TenantID, &w.Name, &leaveType, &w.DepartmentID, &minDays,
+			&w.Priority, &w.IsActive, &w.CreatedAt, &w.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan approval workflow: %w", err)
+		}
+		if leaveType.Valid {
+			w.LeaveType = &leaveType.String
+		}
+		if minDays.Valid {
+			w.MinDays = &minDays.Float64
+		}
+		w.Steps = make([]models.LeaveApprovalWorkflowStep, 0)
+		index[w.ID] = len(workflows)
+		workflows = append(workflows, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	stepRows, err := s.db.QueryContext(ctx, `
+		SELECT workflow_id, id, step_order, approver_type, approver_user_id, min_days
+		FROM leave_approval_workflow_steps
+		WHERE tenant_id = $1
+		ORDER BY workflow_id, step_order`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query approval workflow steps: %w", err)
+	}
+	defer stepRows.Close()
+
+	for stepRows.Next() {
+		var workflowID uuid.UUID
+		var step models.LeaveApprovalWorkflowStep
+		var minDays sql.NullFloat64
+		if err := stepRows.Scan(&workflowID, &step.ID, &step.StepOrder, &step.ApproverType,
+			&step.ApproverUserID, &minDays); err != nil {
+			return nil, fmt.Errorf("failed to scan approval workflow step: %w", err)
+		}
+		if minDays.Valid {
+			step.MinDays = &minDays.Float64
+		}
+		if i, ok := index[workflowID]; ok {
+			workflows[i].Steps = append(workflows[i].Steps, step)
+		}
+	}
+
+	return workflows, nil
+}
+
+// CreateApprovalWorkflow creates a leave approval workflow
+func (s *LeaveService) CreateApprovalWorkflow(ctx context.Context, tenantID uuid.UUID, req models.CreateLeaveApprovalWorkflowRequest) (*models.LeaveApprovalWorkflow, error) {
+	return s.saveApprovalWorkflow(ctx, tenantID, nil, req)
+}
+
+// UpdateApprovalWorkflow replaces the definition and steps of a leave approval workflow.
+// Requests already in approval keep the trail they were created with.
+func (s *LeaveService) UpdateApprovalWorkflow(ctx context.Context, tenantID, workflowID uuid.UUID, req models.CreateLeaveApprovalWorkflowRequest) (*models.LeaveApprovalWorkflow, error) {
+	return s.saveApprovalWorkflow(ctx, tenantID, &workflowID, req)
+}
+
+// DeleteApprovalWorkflow deletes a leave approval workflow and its steps
+func (s *LeaveService) DeleteApprovalWorkflow(ctx context.Context, tenantID, workflowID uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM leave_approval_workflows WHERE id = $1 AND tenant_id = $2", workflowID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete approval workflow: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("approval workflow not found")
+	}
+
+	return nil
+}
+
+func (s *LeaveService) saveApprovalWorkflow(ctx context.Context, tenantID uuid.UUID, workflowID *uuid.UUID, req models.CreateLeaveApprovalWorkflowRequest) (*models.LeaveApprovalWorkflow, error) {
+	workflow, err := validateApprovalWorkflow(tenantID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkWorkflowReferences(ctx, tx, tenantID, workflow); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	workflow.UpdatedAt = now
+	if workflowID == nil {
+		workflow.ID = uuid.New()
+		workflow.CreatedAt = now
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO leave_approval_workflows (id, tenant_id, name, leave_type, department_id, min_days,
+			                                      priority, is_active, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			workflow.ID, tenantID, workflow.Name, workflow.LeaveType, workflow.DepartmentID, workflow.MinDays,
+			workflow.Priority, workflow.IsActive, workflow.CreatedAt, workflow.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create approval workflow: %w", err)
+		}
+	} else {
+		workflow.ID = *workflowID
+		err = tx.QueryRowContext(ctx, `
+			UPDATE leave_approval_workflows
+			SET name = $1, leave_type = $2, department_id = $3, min_days = $4, priority = $5,
+			    is_active = $6, updated_at = $7
+			WHERE id = $8 AND tenant_id = $9
+			RETURNING created_at`,
+			workflow.Name, workflow.LeaveType, workflow.DepartmentID, workflow.MinDays, workflow.Priority,
+			workflow.IsActive, now, workflow.ID, tenantID).Scan(&workflow.CreatedAt)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("approval workflow not found")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update approval workflow: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			"DELETE FROM leave_approval_workflow_steps WHERE workflow_id = $1", workflow.ID); err != nil {
+			return nil, fmt.Errorf("failed to replace approval workflow steps: %w", err)
+		}
+	}
+
+	for i := range workflow.Steps {
+		step := &workflow.Steps[i]
+		step.ID = uuid.New()
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO leave_approval_workflow_steps (id, tenant_id, workflow_id, step_order, approver_type,
+			                                           approver_user_id, min_days, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			step.ID, tenantID, workflow.ID, step.StepOrder, step.ApproverType, step.ApproverUserID,
+			step.MinDays, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create approval workflow step: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit approval workflow: %w", err)
+	}
+
+	return workflow, nil
+}
+
+func validateApprovalWorkflow(tenantID uuid.UUID, req models.CreateLeaveApprovalWorkflowRequest) (*models.LeaveApprovalWorkflow, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, fmt.Errorf("workflow name is required")
+	}
+	if len(req.Steps) == 0 {
+		return nil, fmt.Errorf("workflow must have at least one step")
+	}
+	if req.MinDays != nil && *req.MinDays < 0 {
+		return nil, fmt.Errorf("min_days cannot be negative")
+	}
+
+	workflow := &models.LeaveApprovalWorkflow{
+		TenantID: tenantID,
+		Name:     strings.TrimSpace(req.Name),
+		MinDays:  req.MinDays,
+		Priority: req.Priority,
+		IsActive: true,
+		Steps:    make([]models.LeaveApprovalWorkflowStep, 0, len(req.Steps)),
+	}
+	if req.IsActive != nil {
+		workflow.IsActive = *req.IsActive
+	}
+	if req.LeaveType != nil && strings.TrimSpace(*req.LeaveType) != "" {
+		leaveType := strings.TrimSpace(*req.LeaveType)
+		workflow.LeaveType = &leaveType
+	}
+	if req.DepartmentID != nil && *req.DepartmentID != "" {
+		departmentID, err := uuid.Parse(*req.DepartmentID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid department ID")
+		}
+		workflow.DepartmentID = &departmentID
+	}
+
+	for i, stepReq := range req.Steps {
+		step := models.LeaveApprovalWorkflowStep{
+			StepOrder:    i + 1,
+			ApproverType: stepReq.ApproverType,
+			MinDays:      stepReq.MinDays,
+		}
+
+		switch stepReq.ApproverType {
+		case models.ApproverTeamLead, models.ApproverManager, models.ApproverDepartmentHead, models.ApproverHR:
+		case models.ApproverUser:
+			userID, err := uuid.Parse(stepReq.ApproverUserID)
+			if err != nil {
+				return nil, fmt.Errorf("step %d: approver_user_id is required for approver type user", i+1)
+			}
+			step.ApproverUserID = &userID
+		default:
+			return nil, fmt.Errorf("step %d: invalid approver type %q", i+1, stepReq.ApproverType)
+		}
+
+		if stepReq.MinDays != nil && *stepReq.MinDays < 0 {
+			return nil, fmt.Errorf("step %d: min_days cannot be negative", i+1)
+		}
+		workflow.Steps = append(workflow.Steps, step)
+	}
+
+	return workflow, nil
+}
+
+// checkWorkflowReferences rejects a workflow whose department or approver users are not of the tenant
+func checkWorkflowReferences(ctx context.Context, q dbExecutor, tenantID uuid.UUID, workflow *models.LeaveApprovalWorkflow) error {
+	if workflow.DepartmentID != nil {
+		var exists bool
+		err := q.QueryRowContext(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM departments WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+			)`, *workflow.DepartmentID, tenantID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check department: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("department not found")
+		}
+	}
+
+	for _, step := range workflow.Steps {
+		if step.ApproverUserID == nil {
+			continue
+		}
+		var exists bool
+		err := q.QueryRowContext(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM users WHERE id = $1 AND tenant_id = $2 AND is_active = true AND deleted_at IS NULL
+			)`, *step.ApproverUserID, tenantID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check approver: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("step %d: approver user not found", step.StepOrder)
+		}
+	}
+
+	return nil
+}
+
+// selectApprovalChain returns the steps of the most specific active workflow matching the request
+func (s *LeaveService) selectApprovalChain(ctx context.Context, q dbExecutor, tenantID, employeeID uuid.UUID, leaveType models.LeaveType, days float64) ([]approvalChainStep, error) {
+	aliases := []string{strings.ToLower(string(leaveType))}
+	policy, err := s.findLeaveTypePolicy(ctx, q, tenantID, leaveType)
+	if err != nil {
+		return nil, err
+	}
+	if policy != nil {
+		aliases = policy.Aliases
+	}
+
+	var workflowID uuid.UUID
+	err = q.QueryRowContext(ctx, `
+		SELECT w.id
+		FROM leave_approval_workflows w
+		WHERE w.tenant_id = $1 AND w.is_active = true
+		  AND (w.leave_type IS NULL OR LOWER(w.leave_type) = ANY($2))
+		  AND (w.department_id IS NULL OR w.department_id = (SELECT department_id FROM employees WHERE id = $3))
+		  AND (w.min_days IS NULL OR w.min_days <= $4)
+		ORDER BY w.priority DESC, (w.department_id IS NOT NULL) DESC, (w.leave_type IS NOT NULL) DESC,
+		         COALESCE(w.min_days, 0) DESC, w.created_at ASC
+		LIMIT 1`,
+		tenantID, aliases, employeeID, days).Scan(&workflowID)
+	if err == sql.ErrNoRows {
+		return defaultApprovalChain, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to select approval workflow: %w", err)
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT approver_type, approver_user_id, min_days
+		FROM leave_approval_workflow_steps
+		WHERE workflow_id = $1
+		ORDER BY step_order`, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query approval workflow steps: %w", err)
+	}
+	defer rows.Close()
+
+	var chain []approvalChainStep
+	for rows.Next() {
+		var step approvalChainStep
+		var minDays sql.NullFloat64
+		if err := rows.Scan(&step.ApproverType, &step.ApproverUserID, &minDays); err != nil {
+			return nil, fmt.Errorf("failed to scan approval workflow step: %w", err)
+		}
+		if minDays.Valid {
+			step.MinDays = &minDays.Float64
+		}
+		chain = append(chain, step)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return chain, nil
+}
+
+// resolveApprover returns the user who approves a step for an employee, or the role whose
+// members may approve it. Both are nil when the employee has no such approver.
+func resolveApprover(ctx context.Context, q dbExecutor, tenantID, employeeID uuid.UUID, step approvalChainStep) (*uuid.UUID, *string, error) {
+	var approver uuid.NullUUID
+	var err error
+
+	switch step.ApproverType {
+	case models.ApproverHR:
+		role := "hr"
+		return nil, &role, nil
+	case models.ApproverUser:
+		return step.ApproverUserID, nil, nil
+	case models.ApproverTeamLead:
+		err = q.QueryRowContext(ctx, `
+			SELECT tl.id
+			FROM employees e
+			JOIN users u ON e.user_id = u.id
+			JOIN users tl ON tl.team_id = u.team_id AND tl.tenant_id = u.tenant_id
+			WHERE e.id = $1 AND e.tenant_id = $2 AND u.team_id IS NOT NULL
+			  AND tl.role = 'team_lead' AND tl.is_active = true AND tl.deleted_at IS NULL AND tl.id <> u.id
+			ORDER BY tl.created_at
+			LIMIT 1`, employeeID, tenantID).Scan(&approver)
+	case models.ApproverManager:
+		err = q.QueryRowContext(ctx, `
+			SELECT COALESCE(e.manager_id, d.head_id)
+			FROM employees e
+			LEFT JOIN departments d ON d.id = e.department_id
+			WHERE e.id = $1 AND e.tenant_id = $2`, employeeID, tenantID).Scan(&approver)
+	case models.ApproverDepartmentHead:
+		err = q.QueryRowContext(ctx, `
+			SELECT d.head_id
+			FROM employees e
+			JOIN departments d ON d.id = e.department_id
+			WHERE e.id = $1 AND e.tenant_id = $2`, employeeID, tenantID).Scan(&approver)
+	default:
+		return nil, nil, fmt.Errorf("invalid approver type %q", step.ApproverType)
+	}
+
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, fmt.Errorf("failed to resolve %s approver: %w", step.ApproverType, err)
+	}
+	if !approver.Valid {
+		return nil, nil, nil
+	}
+	return &approver.UUID, nil, nil
+}
+
+// createApprovalTrail builds the approval steps of a new leave request. Steps whose approver
+// cannot be resolved, or who is the requester, are skipped; the first remaining step is pending.
+func (s *LeaveService) createApprovalTrail(ctx context.Context, tx *sql.Tx, tenantID, leaveID, employeeID uuid.UUID, leaveType models.LeaveType, days float64, now time.Time) error {
+	chain, err := s.selectApprovalChain(ctx, tx, tenantID, employeeID, leaveType, days)
+	if err != nil {
+		return err
+	}
+
+	var requester uuid.NullUUID
+	if err := tx.QueryRowContext(ctx,
+		"SELECT user_id FROM employees WHERE id = $1 AND tenant_id = $2", employeeID, tenantID).Scan(&requester); err != nil {
+		return fmt.Errorf("failed to get requesting employee: %w", err)
+	}
+
+	type trailStep struct {
+		approverType models.ApproverType
+		approverID   *uuid.UUID
+		role         *string
+		status       models.ApprovalStepStatus
+	}
+
+	var trail []trailStep
+	hasPending := false
+	for _, step := range chain {
+		if step.MinDays != nil && days < *step.MinDays {
+			continue
+		}
+
+		approverID, role, err := resolveApprover(ctx, tx, tenantID, employeeID, step)
+		if err != nil {
+			return err
+		}
+
+		status := models.ApprovalStepWaiting
+		if (approverID == nil && role == nil) || (approverID != nil && requester.Valid && *approverID == requester.UUID) {
+			status = models.ApprovalStepSkipped
+		} else if !hasPending {
+			status = models.ApprovalStepPending
+			hasPending = true
+		}
+		trail = append(trail, trailStep{step.ApproverType, approverID, role, status})
+	}
+
+	if !hasPending {
+		role := "hr"
+		trail = append(trail, trailStep{models.ApproverHR, nil, &role, models.ApprovalStepPending})
+	}
+
+	for i, step := range trail {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO leave_approval_steps (id, tenant_id, leave_request_id, step_order, approver_type,
+			                                  approver_user_id, approver_role, status, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)`,
+			uuid.New(), tenantID, leaveID, i+1, step.approverType, step.approverID, step.role, step.status, now)
+		if err != nil {
+			return fmt.Errorf("failed to create approval step: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// getApprovalTrails loads the approval steps of the given leave requests, keyed by request ID
+func getApprovalTrails(ctx context.Context, q dbExecutor, tenantID uuid.UUID, leaveIDs []uuid.UUID) (map[uuid.UUID][]models.LeaveApprovalStep, error) {
+	trails := make(map[uuid.UUID][]models.LeaveApprovalStep)
+	if len(leaveIDs) == 0 {
+		return trails, nil
+	}
+
+	ids := make([]string, len(leaveIDs))
+	for i, id := range leaveIDs {
+		ids[i] = id.String()
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT s.id, s.leave_request_id, s.step_order, s.approver_type, s.approver_user_id, s.approver_role,
+		       s.status, s.comment, s.acted_by, s.delegated_from, s.acted_at, s.created_at,
+		       COALESCE(au.first_name || ' ' || au.last_name, ''),
+		       COALESCE(bu.first_name || ' ' || bu.last_name, '')
+		FROM leave_approval_steps s
+		LEFT JOIN users au ON au.id = s.approver_user_id
+		LEFT JOIN users bu ON bu.id = s.acted_by
+		WHERE s.tenant_id = $1 AND s.leave_request_id = ANY($2::uuid[])
+		ORDER BY s.leave_request_id, s.step_order`, tenantID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query approval steps: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var step models.LeaveApprovalStep
+		var role, comment sql.NullString
+		var actedAt sql.NullTime
+		if err := rows.Scan(&step.ID, &step.LeaveRequestID, &step.StepOrder, &step.ApproverType,
+			&step.ApproverUserID, &role, &step.Status, &comment, &step.ActedBy, &step.DelegatedFrom,
+			&actedAt, &step.CreatedAt, &step.ApproverName, &step.ActedByName); err != nil {
+			return nil, fmt.Errorf("failed to scan approval step: %w", err)
+		}
+		if role.Valid {
+			step.ApproverRole = &role.String
+		}
+		if comment.Valid {
+			step.Comment = &comment.String
+		}
+		if actedAt.Valid {
+			step.ActedAt = &actedAt.Time
+		}
+		trails[step.LeaveRequestID] = append(trails[step.LeaveRequestID], step)
+	}
+
+	return trails, rows.Err()
+}
+
+// pendingApprovalStep is the step of a leave request currently awaiting a decision
+type pendingApprovalStep struct {
+	ID             uuid.UUID
+	ApproverUserID *uuid.UUID
+	ApproverRole   *string
+}
+
+// authorizeApprovalStep checks whether a user may act on a pending step. It returns the
+// approver the user acts on behalf of when acting as a delegate. Admins may act on any step.
+// When the assigned approver is on approved leave and has not delegated, HR may act for them.
+// Both are checked for the date of today, which is in the tenant's timezone.
+func authorizeApprovalStep(ctx context.Context, q dbExecutor, tenantID uuid.UUID, step *pendingApprovalStep, actorID uuid.UUID, actorRole string, today time.Time) (*uuid.UUID, error) {
+	notAuthorized := fmt.Errorf("not authorized to act on this leave request")
+
+	if step.ApproverUserID == nil {
+		if step.ApproverRole != nil && *step.ApproverRole == actorRole {
+			return nil, nil
+		}
+		if isAdminRole(actorRole) {
+			return nil, nil
+		}
+		return nil, notAuthorized
+	}
+
+	approverID := *step.ApproverUserID
+	if approverID == actorID {
+		return nil, nil
+	}
+
+	var delegated bool
+	err := q.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM approval_delegations
+			WHERE tenant_id = $1 AND delegator_id = $2 AND delegate_id = $3
+			  AND revoked_at IS NULL AND $4 BETWEEN start_date AND end_date
+		)`, tenantID, approverID, actorID, today.Format("2006-01-02")).Scan(&delegated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check approval delegation: %w", err)
+	}
+	if delegated {
+		return &approverID, nil
+	}
+
+	if isAdminRole(actorRole) {
+		return nil, nil
+	}
+
+	if actorRole == "hr" {
+		var onLeave bool
+		err := q.QueryRowContext(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM leave_requests lr
+				JOIN employees e ON e.id = lr.employee_id
+				WHERE lr.tenant_id = $1 AND e.user_id = $2 AND lr.status = 'approved'
+				  AND $3 BETWEEN lr.start_date AND lr.end_date
+			) AND NOT EXISTS (
+				SELECT 1 FROM approval_delegations
+				WHERE tenant_id = $1 AND delegator_id = $2
+				  AND revoked_at IS NULL AND $3 BETWEEN start_date AND end_date
+			)`, tenantID, approverID, today.Format("2006-01-02")).Scan(&onLeave)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check approver availability: %w", err)
+		}
+		if onLeave {
+			return &approverID, nil
+		}
+	}
+
+	return nil, notAuthorized
+}
+
+func isAdminRole(role string) bool {
+	return role == "admin" || role == "super_admin"
+}
+
+// employeeIDForUser returns the employee record of a user, or nil when the user has none
+func employeeIDForUser(ctx context.Context, q dbExecutor, tenantID, userID uuid.UUID) (*uuid.UUID, error) {
+	var employeeID uuid.UUID
+	err := q.QueryRowContext(ctx,
+		"SELECT id FROM employees WHERE user_id = $1 AND tenant_id = $2 LIMIT 1", userID, tenantID).Scan(&employeeID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get approver employee record: %w", err)
+	}
+	return &employeeID, nil
+}
+
+// GetPendingApprovals lists the pending leave requests whose current approval step the user
+// may act on, directly, through their role or as a delegate. Delegations apply on their dates
+// in the tenant's timezone.
+func (s *LeaveService) GetPendingApprovals(ctx context.Context, tenantID, userID uuid.UUID, role string) ([]models.LeaveRequest, error) {
+	today := time.Now().In(tenantLocation(ctx, s.db, tenantID)).Format("2006-01-02")
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT s.leave_request_id
+		FROM leave_approval_steps s
+		JOIN leave_requests lr ON lr.id = s.leave_request_id
+		JOIN employees e ON e.id = lr.employee_id
+		WHERE s.tenant_id = $1 AND s.status = 'pending' AND lr.status = 'pending'
+		  AND (e.user_id IS NULL OR e.user_id <> $2)
+		  AND (
+		      s.approver_user_id = $2
+		      OR (s.approver_user_id IS NULL AND s.approver_role = $3)
+		      OR s.approver_user_id IN (
+		          SELECT delegator_id FROM approval_delegations
+		          WHERE tenant_id = $1 AND delegate_id = $2 AND revoked_at IS NULL
+		            AND $4 BETWEEN start_date AND end_date
+		      )
+		  )
+		ORDER BY lr.created_at`, tenantID, userID, role, today)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending approvals: %w", err)
+	}
+	defer rows.Close()
+
+	var leaveIDs []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan pending approval: %w", err)
+		}
+		leaveIDs = append(leaveIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	rows.Close()
+
+	leaves := make([]models.LeaveRequest, 0, len(leaveIDs))
+	for _, id := range leaveIDs {
+		leave, err := s.GetLeaveRequestByID(ctx, tenantID, id)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, *leave)
+	}
+
+	return leaves, nil
+}
+
+// GetApprovalDelegations lists the delegations a user has given or received
+func (s *LeaveService) GetApprovalDelegations(ctx context.Context, tenantID, userID uuid.UUID) ([]models.ApprovalDelegation, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT d.id, d.tenant_id, d.delegator_id, d.delegate_id, d.start_date, d.end_date, d.reason,
+		       d.created_by, d.revoked_at, d.created_at, (u.first_name || ' ' || u.last_name)
+		FROM approval_delegations d
+		JOIN users u ON u.id = d.delegate_id
+		WHERE d.tenant_id = $1 AND (d.delegator_id = $2 OR d.delegate_id = $2)
+		ORDER BY d.start_date DESC`, tenantID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query approval delegations: %w", err)
+	}
+	defer rows.Close()
+
+	delegations := make([]models.ApprovalDelegation, 0)
+	for rows.Next() {
+		var d models.ApprovalDelegation
+		var reason sql.NullString
+		var revokedAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.TenantID, &d.DelegatorID, &d.DelegateID, &d.StartDate, &d.EndDate,
+			&reason, &d.CreatedBy, &revokedAt, &d.CreatedAt, &d.DelegateName); err != nil {
+			return nil, fmt.Errorf("failed to scan approval delegation: %w", err)
+		}
+		if reason.Valid {
+			d.Reason = &reason.String
+		}
+		if revokedAt.Valid {
+			d.RevokedAt = &revokedAt.Time
+		}
+		delegations = append(delegations, d)
+	}
+
+	return delegations, nil
+}
+
+// CreateApprovalDelegation lets another user act on the delegator's approval steps between two dates
+func (s *LeaveService) CreateApprovalDelegation(ctx context.Context, tenantID, delegatorID uuid.UUID, req models.CreateApprovalDelegationRequest) (*models.ApprovalDelegation, error) {
+	delegateID, err := uuid.Parse(req.DelegateID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid delegate ID")
+	}
+	if delegateID == delegatorID {
+		return nil, fmt.Errorf("cannot delegate approvals to yourself")
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date format: %w", err)
+	}
+	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date format: %w", err)
+	}
+	if endDate.Before(startDate) {
+		return nil, fmt.Errorf("end date cannot be before start date")
+	}
+
+	var exists bool
+	err = s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM users WHERE id = $1 AND tenant_id = $2 AND is_active = true AND deleted_at IS NULL
+		)`, delegateID, tenantID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check delegate: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("delegate not found")
+	}
+
+	d := models.ApprovalDelegation{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		DelegatorID: delegatorID,
+		DelegateID:  delegateID,
+		StartDate:   startDate,
+		EndDate:     endDate,
+		Reason:      req.Reason,
+		CreatedBy:   &delegatorID,
+		CreatedAt:   time.Now(),
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO approval_delegations (id, tenant_id, delegator_id, delegate_id, start_date, end_date,
+		                                  reason, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		d.ID, d.TenantID, d.DelegatorID, d.DelegateID, d.StartDate, d.EndDate, d.Reason, d.CreatedBy, d.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create approval delegation: %w", err)
+	}
+
+	return &d, nil
+}
+
+// RevokeApprovalDelegation ends a delegation given by the user
+func (s *LeaveService) RevokeApprovalDelegation(ctx context.Context, tenantID, delegationID, delegatorID uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE approval_delegations SET revoked_at = $1
+		WHERE id = $2 AND tenant_id = $3 AND delegator_id = $4 AND revoked_at IS NULL`,
+		time.Now(), delegationID, tenantID, delegatorID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke approval delegation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("approval delegation not found")
+	}
+
+	return nil
+}
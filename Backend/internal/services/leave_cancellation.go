@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+// CancelLeaveRequest withdraws a pending leave request or cancels an approved one that has not
+// started yet. Days debited on approval are refunded to the ledger and the "on leave" attendance
+// records are removed. Approved leave that has already started can only be shortened through
+// ModifyLeaveRequest.
+func (s *LeaveService) CancelLeaveRequest(ctx context.Context, tenantID, employeeID, leaveID uuid.UUID, reason string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status models.LeaveStatus
+	var startDate time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT status, start_date FROM leave_requests
+		WHERE id = $1 AND tenant_id = $2 AND employee_id = $3
+		FOR UPDATE`,
+		leaveID, tenantID, employeeID).Scan(&status, &startDate)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("leave request not found")
+		}
+		return fmt.Errorf("failed to get leave request: %w", err)
+	}
+
+	now := time.Now()
+	switch status {
+	case models.LeaveStatusPending:
+		if err := s.closeLeaveRequest(ctx, tx, tenantID, leaveID, reason, now); err != nil {
+			return err
+		}
+	case models.LeaveStatusApproved:
+		today := now.In(tenantLocation(ctx, s.db, tenantID))
+		if !startDate.After(time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)) {
+			return fmt.Errorf("leave has already started, submit a modification to shorten it")
+		}
+		if err := s.cancelApprovedLeave(ctx, tx, tenantID, leaveID, uuid.Nil, reason, now); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("leave request is already %s", status)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit leave cancellation: %w", err)
+	}
+
+	return nil
+}
+
+// ModifyLeaveRequest submits new dates for an approved leave request. The modification is a new
+// request that goes through approval; once approved it replaces the original, whose days are
+// refunded. For leave that has already started only the end of the leave can change.
+func (s *LeaveService) ModifyLeaveRequest(ctx context.Context, tenantID, employeeID, leaveID uuid.UUID, req models.ModifyLeaveRequest) (*models.LeaveRequest, error) {
+	original, err := s.GetLeaveRequestByID(ctx, tenantID, leaveID)
+	if err != nil {
+		return nil, err
+	}
+	if original.EmployeeID != employeeID {
+		return nil, fmt.Errorf("leave request not found")
+	}
+	if original.Status != models.LeaveStatusApproved {
+		return nil, fmt.Errorf("only approved leave can be modified, cancel the %s request instead", original.Status)
+	}
+
+	var hasPending bool
+	err = s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM leave_requests
+			WHERE tenant_id = $1 AND modifies_request_id = $2 AND status = 'pending'
+		)`, tenantID, leaveID).Scan(&hasPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check pending modifications: %w", err)
+	}
+	if hasPending {
+		return nil, fmt.Errorf("a modification of this leave request is already pending")
+	}
+
+	today := time.Now().In(tenantLocation(ctx, s.db, tenantID))
+	started := !original.StartDate.After(time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC))
+	if started && (req.StartDate != original.StartDate.Format("2006-01-02") || req.StartHalfDay != original.StartHalfDay) {
+		return nil, fmt.Errorf("leave has already started, only the end date can be changed")
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		reason = original.Reason
+	}
+
+	return s.createLeaveRequest(ctx, tenantID, employeeID, models.CreateLeaveRequest{
+		LeaveType:    original.LeaveType,
+		StartDate:    req.StartDate,
+		EndDate:      req.EndDate,
+		StartHalfDay: req.StartHalfDay,
+		EndHalfDay:   req.EndHalfDay,
+		Reason:       reason,
+	}, &leaveID)
+}
+
+// cancelApprovedLeave cancels an approved request: its days are refunded, its "on leave"
+// attendance records removed and its pending modifications, other than replacementID, withdrawn
+func (s *LeaveService) cancelApprovedLeave(ctx context.Context, tx *sql.Tx, tenantID, leaveID, replacementID uuid.UUID, reason string, now time.Time) error {
+	if err := s.refundLeaveBalance(ctx, tx, tenantID, leaveID, now); err != nil {
+		return err
+	}
+
+	if err := unmarkLeaveAttendance(ctx, tx, tenantID, leaveID); err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM leave_requests
+		WHERE tenant_id = $1 AND modifies_request_id = $2 AND status = 'pending' AND id <> $3
+		FOR UPDATE`, tenantID, leaveID, replacementID)
+	if err != nil {
+		return fmt.Errorf("failed to get pending modifications: %w", err)
+	}
+	var modifications []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan pending modification: %w", err)
+		}
+		modifications = append(modifications, id)
+	}
+	rows.Close()
+
+	for _, id := range modifications {
+		if err := s.closeLeaveRequest(ctx, tx, tenantID, id, "Original leave request was cancelled", now); err != nil {
+			return err
+		}
+	}
+
+	return s.closeLeaveRequest(ctx, tx, tenantID, leaveID, reason, now)
+}
+
+// closeLeaveRequest marks a request as cancelled and skips its undecided approval steps
+func (s *LeaveService) closeLeaveRequest(ctx context.Context, tx *sql.Tx, tenantID, leaveID uuid.UUID, reason string, now time.Time) error {
+	var cancellationReason *string
+	if reason = strings.TrimSpace(reason); reason != "" {
+		cancellationReason = &reason
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		UPDATE leave_requests
+		SET status = $1, cancelled_at = $2, cancellation_reason = $3, updated_at = $2
+		WHERE id = $4 AND tenant_id = $5`,
+		models.LeaveStatusCancelled, now, cancellationReason, leaveID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel leave request: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE leave_approval_steps SET status = 'skipped', updated_at = $1
+		WHERE leave_request_id = $2 AND status IN ('waiting', 'pending')`, now, leaveID)
+	if err != nil {
+		return fmt.Errorf("failed to close approval steps: %w", err)
+	}
+
+	return nil
+}
+
+// markLeaveAttendance records the full working days of an approved leave as "on leave".
+// Half days are left unmarked so the employee can still check in, and days that already
+// have an attendance record are not touched.
+func (s *LeaveService) markLeaveAttendance(ctx context.Context, tx *sql.Tx, tenantID, employeeID, leaveID uuid.UUID, startDate, endDate time.Time, startHalfDay, endHalfDay bool, now time.Time) error {
+	policy, _, err := getEffectivePolicy(ctx, s.db, tenantID, employeeID)
+	if err != nil {
+		return err
+	}
+
+	location, err := employeeWorkLocation(ctx, tx, tenantID, employeeID)
+	if err != nil {
+		return err
+	}
+
+	holidays, err := holidayDates(ctx, tx, tenantID, location, startDate, endDate)
+	if err != nil {
+		return err
+	}
+
+	for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
+		if !containsWeekday(policy.WorkingDays, day.Weekday()) || holidays[day.Format("2006-01-02")] {
+			continue
+		}
+		if (startHalfDay && day.Equal(startDate)) || (endHalfDay && day.Equal(endDate)) {
+			continue
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO attendance_records (id, tenant_id, employee_id, date, status, leave_request_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, 'on_leave', $5, $6, $6)
+			ON CONFLICT (tenant_id, employee_id, date) DO NOTHING`,
+			uuid.New(), tenantID, employeeID, day.Format("2006-01-02"), leaveID, now)
+		if err != nil {
+			return fmt.Errorf("failed to mark leave attendance: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// unmarkLeaveAttendance removes the "on leave" records of a leave request. Records the
+// employee has since checked in on are kept.
+func unmarkLeaveAttendance(ctx context.Context, q dbExecutor, tenantID, leaveID uuid.UUID) error {
+	_, err := q.ExecContext(ctx, `
+		DELETE FROM attendance_records
+		WHERE tenant_id = $1 AND leave_request_id = $2 AND status = 'on_leave' AND check_in_time IS NULL`,
+		tenantID, leaveID)
+	if err != nil {
+		return fmt.Errorf("failed to remove leave attendance: %w", err)
+	}
+	return nil
+}
+
+// requestDebitedDays returns the days currently debited from the ledger for a leave request
+func requestDebitedDays(ctx context.Context, q dbExecutor, tenantID, leaveID uuid.UUID, leaveType string, year int) (float64, error) {
+	var days float64
+	err := q.QueryRowContext(ctx, `
+		SELECT COALESCE(-SUM(days), 0)
+		FROM leave_ledger_entries
+		WHERE tenant_id = $1 AND leave_request_id = $2 AND entry_type IN ('debit', 'refund')
+		  AND leave_type = $3 AND year = $4`,
+		tenantID, leaveID, leaveType, year).Scan(&days)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get debited leave days: %w", err)
+	}
+	return days, nil
+}
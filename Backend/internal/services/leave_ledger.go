@@ -0,0 +1,704 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+// dbExecutor is satisfied by both *sql.DB and *sql.Tx
+type dbExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// leaveTypePolicy is the part of a leave type configuration the ledger works with.
+// Key is the canonical ledger key: the lowercased short code, or the name when no code is set.
+// Leave types configured through PolicyService only carry accrual_rate, which is treated as
+// a monthly accrual amount.
+type leaveTypePolicy struct {
+	Key               string
+	Aliases           []string
+	AnnualLimit       float64
+	AccrualType       string
+	AccrualAmount     *float64
+	CarryForwardLimit float64
+	IsPaid            bool
+}
+
+const leaveTypePolicyColumns = `
+	LOWER(COALESCE(NULLIF(short_code, ''), name)), LOWER(COALESCE(short_code, '')), LOWER(name),
+	COALESCE(annual_limit, 0),
+	CASE WHEN accrual_amount IS NULL AND COALESCE(accrual_rate, 0) > 0 THEN 'monthly' ELSE COALESCE(accrual_type, 'yearly') END,
+	COALESCE(accrual_amount, NULLIF(accrual_rate, 0)),
+	COALESCE(carry_forward_limit, 0), COALESCE(is_paid, true)`
+
+func scanLeaveTypePolicy(scan func(dest ...interface{}) error) (*leaveTypePolicy, error) {
+	var p leaveTypePolicy
+	var shortCode, name string
+	var accrualAmount sql.NullFloat64
+	if err := scan(&p.Key, &shortCode, &name, &p.AnnualLimit, &p.AccrualType, &accrualAmount,
+		&p.CarryForwardLimit, &p.IsPaid); err != nil {
+		return nil, err
+	}
+	if accrualAmount.Valid {
+		p.AccrualAmount = &accrualAmount.Float64
+	}
+	p.Aliases = []string{name}
+	if shortCode != "" && shortCode != name {
+		p.Aliases = append(p.Aliases, shortCode)
+	}
+	return &p, nil
+}
+
+// findLeaveTypePolicy resolves a leave type string (short code or name) to its configuration.
+// It returns nil when the tenant has not configured the leave type, in which case no balance is tracked.
+func (s *LeaveService) findLeaveTypePolicy(ctx context.Context, q dbExecutor, tenantID uuid.UUID, leaveType models.LeaveType) (*leaveTypePolicy, error) {
+	key := strings.ToLower(strings.TrimSpace(string(leaveType)))
+	row := q.QueryRowContext(ctx, `
+		SELECT `+leaveTypePolicyColumns+`
+		FROM leave_types
+		WHERE tenant_id = $1 AND deleted_at IS NULL AND is_active = true
+		  AND (LOWER(short_code) = $2 OR LOWER(name) = $2)
+		ORDER BY (LOWER(short_code) = $2) DESC NULLS LAST
+		LIMIT 1`, tenantID, key)
+
+	policy, err := scanLeaveTypePolicy(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leave type: %w", err)
+	}
+	return policy, nil
+}
+
+// getLeaveTypePolicies lists every active leave type of a tenant
+func (s *LeaveService) getLeaveTypePolicies(ctx context.Context, q dbExecutor, tenantID uuid.UUID) ([]*leaveTypePolicy, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+leaveTypePolicyColumns+`
+		FROM leave_types
+		WHERE tenant_id = $1 AND deleted_at IS NULL AND is_active = true`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leave types: %w", err)
+	}
+	defer rows.Close()
+
+	policies := make([]*leaveTypePolicy, 0)
+	for rows.Next() {
+		policy, err := scanLeaveTypePolicy(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan leave type: %w", err)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, rows.Err()
+}
+
+// ledgerBalance returns the available days for an employee, leave type and year
+func (s *LeaveService) ledgerBalance(ctx context.Context, q dbExecutor, tenantID, employeeID uuid.UUID, key string, year int) (float64, error) {
+	var balance float64
+	err := q.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(days), 0)
+		FROM leave_ledger_entries
+		WHERE tenant_id = $1 AND employee_id = $2 AND leave_type = $3 AND year = $4`,
+		tenantID, employeeID, key, year).Scan(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get leave balance: %w", err)
+	}
+	return balance, nil
+}
+
+// pendingLeaveDays returns the days already requested but not yet decided for a leave type and year.
+// Requests that cross into another year only count the days that fall in year.
+func (s *LeaveService) pendingLeaveDays(ctx context.Context, q dbExecutor, tenantID, employeeID uuid.UUID, policy *leaveTypePolicy, year int) (float64, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT start_date, end_date, start_half_day, end_half_day, days_requested
+		FROM leave_requests
+		WHERE tenant_id = $1 AND employee_id = $2 AND status = 'pending'
+		  AND LOWER(leave_type) = ANY($3)
+		  AND EXTRACT(YEAR FROM start_date) <= $4 AND EXTRACT(YEAR FROM end_date) >= $4`,
+		tenantID, employeeID, policy.Aliases, year)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending leave days: %w", err)
+	}
+
+	type pendingRequest struct {
+		StartDate, EndDate       time.Time
+		StartHalfDay, EndHalfDay bool
+		Days                     float64
+	}
+	requests := make([]pendingRequest, 0)
+	for rows.Next() {
+		var r pendingRequest
+		if err := rows.Scan(&r.StartDate, &r.EndDate, &r.StartHalfDay, &r.EndHalfDay, &r.Days); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan pending leave request: %w", err)
+		}
+		requests = append(requests, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get pending leave days: %w", err)
+	}
+
+	var pending float64
+	for _, r := range requests {
+		split, err := s.splitLeaveDays(ctx, tenantID, employeeID, r.StartDate, r.EndDate, r.StartHalfDay, r.EndHalfDay, r.Days)
+		if err != nil {
+			return 0, err
+		}
+		for _, part := range split {
+			if part.Year == year {
+				pending += part.Days
+			}
+		}
+	}
+	return pending, nil
+}
+
+// leaveYearDays is the part of a leave request that falls in one calendar year
+type leaveYearDays struct {
+	Year int
+	Days float64
+}
+
+// splitLeaveDays splits the days of a leave request by calendar year, since each year has its own
+// balance. A request within one year keeps its days; one that crosses into the next year is
+// split by the working days in each year, and the last year takes whatever of days is left, so
+// the parts always add up to the request.
+func (s *LeaveService) splitLeaveDays(ctx context.Context, tenantID, employeeID uuid.UUID, startDate, endDate time.Time, startHalfDay, endHalfDay bool, days float64) ([]leaveYearDays, error) {
+	if startDate.Year() == endDate.Year() {
+		return []leaveYearDays{{Year: startDate.Year(), Days: days}}, nil
+	}
+
+	split := make([]leaveYearDays, 0, endDate.Year()-startDate.Year()+1)
+	remaining := days
+	for year := startDate.Year(); year <= endDate.Year(); year++ {
+		if year == endDate.Year() {
+			split = append(split, leaveYearDays{Year: year, Days: roundDays(math.Max(remaining, 0))})
+			break
+		}
+
+		from := startDate
+		if year > startDate.Year() {
+			from = time.Date(year, time.January, 1, 0, 0, 0, 0, startDate.Location())
+		}
+		to := time.Date(year, time.December, 31, 0, 0, 0, 0, startDate.Location())
+		yearDays, err := s.CalculateLeaveDays(ctx, tenantID, employeeID, from, to, startHalfDay && year == startDate.Year(), false)
+		if err != nil {
+			return nil, err
+		}
+		yearDays = math.Min(yearDays, math.Max(remaining, 0))
+		remaining -= yearDays
+		split = append(split, leaveYearDays{Year: year, Days: yearDays})
+	}
+	return split, nil
+}
+
+// insertLedgerEntry appends an entry to the leave ledger. Period-keyed entries (accruals and
+// rollovers) are skipped when they already exist; the returned bool reports whether a row was written.
+// An entry posted to a year that has been rolled over, such as a refund of leave taken in that
+// year, brings the rollover up to date so the days are not lost.
+func insertLedgerEntry(ctx context.Context, q dbExecutor, entry models.LeaveLedgerEntry) (bool, error) {
+	query := `
+		INSERT INTO leave_ledger_entries (
+			id, tenant_id, employee_id, leave_type, year, entry_type, days, period,
+			leave_request_id, notes, created_by, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	if entry.Period != nil {
+		query += `
+		ON CONFLICT (tenant_id, employee_id, leave_type, entry_type, period)
+		WHERE entry_type IN ('accrual', 'carry_forward', 'expiry') DO NOTHING`
+	}
+
+	result, err := q.ExecContext(ctx, query,
+		entry.ID, entry.TenantID, entry.EmployeeID, entry.LeaveType, entry.Year, entry.EntryType,
+		roundDays(entry.Days), entry.Period, entry.LeaveRequestID, entry.Notes, entry.CreatedBy, entry.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to write leave ledger entry: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	if entry.EntryType != models.LeaveEntryExpiry && entry.EntryType != models.LeaveEntryCarryForward {
+		closed, err := leaveYearClosed(ctx, q, entry.TenantID, entry.Year)
+		if err != nil {
+			return false, err
+		}
+		if closed {
+			carryLimit, err := carryForwardLimit(ctx, q, entry.TenantID, string(entry.LeaveType))
+			if err != nil {
+				return false, err
+			}
+			if _, err := settleRollover(ctx, q, entry.TenantID, entry.EmployeeID, string(entry.LeaveType),
+				entry.Year, carryLimit, entry.CreatedAt); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// GetLeaveBalances returns an employee's balances for a year, aggregated from the ledger
+func (s *LeaveService) GetLeaveBalances(ctx context.Context, tenantID, employeeID uuid.UUID, year int) ([]models.LeaveBalance, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT leave_type,
+		       COALESCE(SUM(days) FILTER (WHERE entry_type IN ('opening', 'accrual', 'carry_forward', 'adjustment')), 0),
+		       COALESCE(-SUM(days) FILTER (WHERE entry_type IN ('debit', 'refund')), 0),
+		       COALESCE(SUM(days), 0),
+		       COALESCE(SUM(days) FILTER (WHERE entry_type = 'carry_forward'), 0),
+		       MIN(created_at), MAX(created_at)
+		FROM leave_ledger_entries
+		WHERE tenant_id = $1 AND employee_id = $2 AND year = $3
+		GROUP BY leave_type
+		ORDER BY leave_type`, tenantID, employeeID, year)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leave balances: %w", err)
+	}
+	defer rows.Close()
+
+	balances := make([]models.LeaveBalance, 0)
+	for rows.Next() {
+		b := models.LeaveBalance{TenantID: tenantID, EmployeeID: employeeID, Year: year}
+		if err := rows.Scan(&b.LeaveType, &b.TotalDays, &b.UsedDays, &b.AvailableDays,
+			&b.CarriedForwardDays, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan leave balance: %w", err)
+		}
+		balances = append(balances, b)
+	}
+
+	return balances, rows.Err()
+}
+
+// GetLeaveLedger lists an employee's ledger entries for a year, oldest first
+func (s *LeaveService) GetLeaveLedger(ctx context.Context, tenantID, employeeID uuid.UUID, year int) ([]models.LeaveLedgerEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, tenant_id, employee_id, leave_type, year, entry_type, days, period,
+		       leave_request_id, notes, created_by, created_at
+		FROM leave_ledger_entries
+		WHERE tenant_id = $1 AND employee_id = $2 AND year = $3
+		ORDER BY created_at, id`, tenantID, employeeID, year)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leave ledger: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]models.LeaveLedgerEntry, 0)
+	for rows.Next() {
+		var e models.LeaveLedgerEntry
+		if err := rows.Scan(&e.ID, &e.TenantID, &e.EmployeeID, &e.LeaveType, &e.Year, &e.EntryType,
+			&e.Days, &e.Period, &e.LeaveRequestID, &e.Notes, &e.CreatedBy, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan leave ledger entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// AdjustLeaveBalance records a manual credit (positive days) or debit (negative days)
+func (s *LeaveService) AdjustLeaveBalance(ctx context.Context, tenantID, employeeID, adjustedBy uuid.UUID, req models.LeaveAdjustmentRequest) (*models.LeaveLedgerEntry, error) {
+	if req.Days == 0 {
+		return nil, fmt.Errorf("adjustment days cannot be zero")
+	}
+	if strings.TrimSpace(req.Notes) == "" {
+		return nil, fmt.Errorf("a note explaining the adjustment is required")
+	}
+
+	policy, err := s.findLeaveTypePolicy(ctx, s.db, tenantID, req.LeaveType)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, fmt.Errorf("leave type not found: %s", req.LeaveType)
+	}
+
+	year := req.Year
+	if year == 0 {
+		year = time.Now().Year()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	entry := models.LeaveLedgerEntry{
+		ID:         uuid.New(),
+		TenantID:   tenantID,
+		EmployeeID: employeeID,
+		LeaveType:  models.LeaveType(policy.Key),
+		Year:       year,
+		EntryType:  models.LeaveEntryAdjustment,
+		Days:       req.Days,
+		Notes:      &req.Notes,
+		CreatedBy:  &adjustedBy,
+		CreatedAt:  time.Now(),
+	}
+	if _, err := insertLedgerEntry(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit leave adjustment: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// RunLeaveAccrual credits every active employee with the accruals due between the start of
+// asOf's year and asOf. Periods already credited are skipped, so the run can be repeated safely,
+// as are years seeded with an opening balance.
+// It returns the number of ledger entries written.
+func (s *LeaveService) RunLeaveAccrual(ctx context.Context, tenantID uuid.UUID, asOf time.Time) (int, error) {
+	policies, err := s.getLeaveTypePolicies(ctx, s.db, tenantID)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, date_of_joining
+		FROM employees
+		WHERE tenant_id = $1 AND employment_status = 'active'`, tenantID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get employees: %w", err)
+	}
+
+	type accrualEmployee struct {
+		ID            uuid.UUID
+		DateOfJoining sql.NullTime
+	}
+	employees := make([]accrualEmployee, 0)
+	for rows.Next() {
+		var e accrualEmployee
+		if err := rows.Scan(&e.ID, &e.DateOfJoining); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan employee: %w", err)
+		}
+		employees = append(employees, e)
+	}
+	rows.Close()
+
+	written := 0
+	now := time.Now()
+	for _, policy := range policies {
+		for _, emp := range employees {
+			var accrued float64
+			var opened bool
+			err := s.db.QueryRowContext(ctx, `
+				SELECT COALESCE(SUM(days) FILTER (WHERE entry_type = 'accrual'), 0),
+				       COUNT(*) FILTER (WHERE entry_type = 'opening') > 0
+				FROM leave_ledger_entries
+				WHERE tenant_id = $1 AND employee_id = $2 AND leave_type = $3 AND year = $4`,
+				tenantID, emp.ID, policy.Key, asOf.Year()).Scan(&accrued, &opened)
+			if err != nil {
+				return written, fmt.Errorf("failed to get accrued leave: %w", err)
+			}
+			if opened {
+				// The year's entitlement was carried over from leave_balances
+				continue
+			}
+
+			for _, period := range accrualPeriods(policy, asOf) {
+				if emp.DateOfJoining.Valid && period.End.Before(emp.DateOfJoining.Time) {
+					continue
+				}
+
+				days := period.Days
+				if policy.AnnualLimit > 0 {
+					days = math.Min(days, policy.AnnualLimit-accrued)
+				}
+				if days <= 0 {
+					continue
+				}
+
+				label := period.Label
+				ok, err := insertLedgerEntry(ctx, s.db, models.LeaveLedgerEntry{
+					ID:         uuid.New(),
+					TenantID:   tenantID,
+					EmployeeID: emp.ID,
+					LeaveType:  models.LeaveType(policy.Key),
+					Year:       asOf.Year(),
+					EntryType:  models.LeaveEntryAccrual,
+					Days:       days,
+					Period:     &label,
+					CreatedAt:  now,
+				})
+				if err != nil {
+					return written, err
+				}
+				if ok {
+					accrued += days
+					written++
+				}
+			}
+		}
+	}
+
+	return written, nil
+}
+
+// RunYearEndRollover closes a leave year: the remaining balance of each employee and leave type
+// expires, and up to the leave type's carry-forward limit is credited to the following year.
+// Running it again brings the rollover up to date with entries posted since, as does any entry
+// posted to the closed year later on. It returns the number of ledger entries written.
+func (s *LeaveService) RunYearEndRollover(ctx context.Context, tenantID uuid.UUID, year int) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Late entries to the year lock its closing row too, so they wait for the rollover
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO leave_year_closings (tenant_id, year, closed_at) VALUES ($1, $2, NOW())
+		ON CONFLICT (tenant_id, year) DO NOTHING`, tenantID, year)
+	if err != nil {
+		return 0, fmt.Errorf("failed to close leave year: %w", err)
+	}
+	if _, err := leaveYearClosed(ctx, tx, tenantID, year); err != nil {
+		return 0, err
+	}
+
+	policies, err := s.getLeaveTypePolicies(ctx, tx, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	carryLimits := make(map[string]float64, len(policies))
+	for _, p := range policies {
+		carryLimits[p.Key] = p.CarryForwardLimit
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT employee_id, leave_type
+		FROM leave_ledger_entries
+		WHERE tenant_id = $1 AND year = $2`, tenantID, year)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get closing balances: %w", err)
+	}
+
+	type ledgerKey struct {
+		EmployeeID uuid.UUID
+		LeaveType  string
+	}
+	keys := make([]ledgerKey, 0)
+	for rows.Next() {
+		var k ledgerKey
+		if err := rows.Scan(&k.EmployeeID, &k.LeaveType); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan closing balance: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+
+	written := 0
+	now := time.Now()
+	for _, k := range keys {
+		n, err := settleRollover(ctx, tx, tenantID, k.EmployeeID, k.LeaveType, year, carryLimits[k.LeaveType], now)
+		if err != nil {
+			return 0, err
+		}
+		written += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit rollover: %w", err)
+	}
+
+	return written, nil
+}
+
+// leaveYearClosed reports whether a leave year has been rolled over. The closing row is locked
+// so entries settling the same year are applied one after another.
+func leaveYearClosed(ctx context.Context, q dbExecutor, tenantID uuid.UUID, year int) (bool, error) {
+	var closed bool
+	err := q.QueryRowContext(ctx, `
+		SELECT true FROM leave_year_closings
+		WHERE tenant_id = $1 AND year = $2
+		FOR UPDATE`, tenantID, year).Scan(&closed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check leave year closing: %w", err)
+	}
+	return closed, nil
+}
+
+// carryForwardLimit returns the carry-forward limit of a leave type by its ledger key. Leave
+// types that are no longer active carry nothing forward.
+func carryForwardLimit(ctx context.Context, q dbExecutor, tenantID uuid.UUID, key string) (float64, error) {
+	var limit float64
+	err := q.QueryRowContext(ctx, `
+		SELECT COALESCE(carry_forward_limit, 0)
+		FROM leave_types
+		WHERE tenant_id = $1 AND deleted_at IS NULL AND is_active = true
+		  AND LOWER(COALESCE(NULLIF(short_code, ''), name)) = $2
+		LIMIT 1`, tenantID, key).Scan(&limit)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get carry-forward limit: %w", err)
+	}
+	return limit, nil
+}
+
+// settleRollover brings the rollover of a closed year up to date for one employee and leave
+// type, posting what its expiry and carry-forward are short of. A change to the carry-forward
+// is settled into the next year too when that year is closed as well. It returns the number of
+// ledger entries written.
+func settleRollover(ctx context.Context, q dbExecutor, tenantID, employeeID uuid.UUID, leaveType string, year int, carryLimit float64, now time.Time) (int, error) {
+	written := 0
+	for {
+		var closing, expired, carried float64
+		err := q.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(days) FILTER (WHERE year = $4 AND entry_type <> 'expiry'), 0),
+			       COALESCE(-SUM(days) FILTER (WHERE year = $4 AND entry_type = 'expiry'), 0),
+			       COALESCE(SUM(days) FILTER (WHERE year = $4 + 1 AND entry_type = 'carry_forward'), 0)
+			FROM leave_ledger_entries
+			WHERE tenant_id = $1 AND employee_id = $2 AND leave_type = $3 AND year IN ($4, $4 + 1)`,
+			tenantID, employeeID, leaveType, year).Scan(&closing, &expired, &carried)
+		if err != nil {
+			return written, fmt.Errorf("failed to get closing balance: %w", err)
+		}
+
+		expiry, carry := rolloverCorrection(closing, expired, carried, carryLimit)
+		for _, e := range []struct {
+			entryType models.LeaveEntryType
+			year      int
+			days      float64
+		}{
+			{models.LeaveEntryExpiry, year, expiry},
+			{models.LeaveEntryCarryForward, year + 1, carry},
+		} {
+			if e.days == 0 {
+				continue
+			}
+			if err := postRolloverEntry(ctx, q, models.LeaveLedgerEntry{
+				TenantID:   tenantID,
+				EmployeeID: employeeID,
+				LeaveType:  models.LeaveType(leaveType),
+				Year:       e.year,
+				EntryType:  e.entryType,
+				Days:       e.days,
+				CreatedAt:  now,
+			}, year); err != nil {
+				return written, err
+			}
+			written++
+		}
+
+		if carry == 0 {
+			return written, nil
+		}
+		year++
+		closed, err := leaveYearClosed(ctx, q, tenantID, year)
+		if err != nil || !closed {
+			return written, err
+		}
+	}
+}
+
+// postRolloverEntry writes an expiry or carry-forward entry of the rollover of closedYear. The
+// first is keyed by the year; later corrections are posted alongside it.
+func postRolloverEntry(ctx context.Context, q dbExecutor, entry models.LeaveLedgerEntry, closedYear int) error {
+	period := fmt.Sprintf("%d", closedYear)
+	entry.ID = uuid.New()
+	entry.Period = &period
+	ok, err := insertLedgerEntry(ctx, q, entry)
+	if err != nil || ok {
+		return err
+	}
+
+	notes := fmt.Sprintf("Correction to the %d rollover", closedYear)
+	entry.ID = uuid.New()
+	entry.Period = nil
+	entry.Notes = &notes
+	_, err = insertLedgerEntry(ctx, q, entry)
+	return err
+}
+
+// rolloverCorrection returns the expiry and carry-forward days still to post for a closed year,
+// given its closing balance and what has already expired and been carried. The whole closing
+// balance expires and up to carryLimit of it carries forward; an overdrawn year has nothing to
+// expire or carry.
+func rolloverCorrection(closing, expired, carried, carryLimit float64) (expiry, carry float64) {
+	wantExpired, wantCarried := 0.0, 0.0
+	if closing > 0 {
+		wantExpired = closing
+		wantCarried = math.Max(math.Min(closing, carryLimit), 0)
+	}
+	return roundDays(expired - wantExpired), roundDays(wantCarried - carried)
+}
+
+// accrualPeriod is a single accrual slot of a leave type within a year
+type accrualPeriod struct {
+	Label string
+	End   time.Time
+	Days  float64
+}
+
+// accrualPeriods lists the accrual periods of a leave type that have started by asOf
+func accrualPeriods(policy *leaveTypePolicy, asOf time.Time) []accrualPeriod {
+	year := asOf.Year()
+	periods := make([]accrualPeriod, 0)
+
+	perPeriod := func(divisor float64) float64 {
+		if policy.AccrualAmount != nil {
+			return *policy.AccrualAmount
+		}
+		return policy.AnnualLimit / divisor
+	}
+
+	switch policy.AccrualType {
+	case "monthly":
+		for m := time.January; m <= asOf.Month(); m++ {
+			start := time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+			periods = append(periods, accrualPeriod{
+				Label: start.Format("2006-01"),
+				End:   start.AddDate(0, 1, -1),
+				Days:  perPeriod(12),
+			})
+		}
+	case "quarterly":
+		for q := 1; q <= (int(asOf.Month())+2)/3; q++ {
+			start := time.Date(year, time.Month(3*q-2), 1, 0, 0, 0, 0, time.UTC)
+			periods = append(periods, accrualPeriod{
+				Label: fmt.Sprintf("%d-Q%d", year, q),
+				End:   start.AddDate(0, 3, -1),
+				Days:  perPeriod(4),
+			})
+		}
+	default:
+		// yearly and fixed leave types are credited once at the start of the year
+		periods = append(periods, accrualPeriod{
+			Label: fmt.Sprintf("%d", year),
+			End:   time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC),
+			Days:  perPeriod(1),
+		})
+	}
+
+	return periods
+}
+
+func roundDays(days float64) float64 {
+	return math.Round(days*100) / 100
+}
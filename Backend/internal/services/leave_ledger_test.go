@@ -0,0 +1,94 @@
+package services
+
+import "testing"
+
+func TestRolloverCorrection(t *testing.T) {
+	tests := []struct {
+		name                      string
+		closing, expired, carried float64
+		carryLimit                float64
+		wantExpiry, wantCarry     float64
+	}{
+		{name: "balance within the carry limit", closing: 4, carryLimit: 5, wantExpiry: -4, wantCarry: 4},
+		{name: "balance over the carry limit", closing: 8, carryLimit: 5, wantExpiry: -8, wantCarry: 5},
+		{name: "no carry forward", closing: 3, wantExpiry: -3},
+		{name: "overdrawn year", closing: -2, carryLimit: 5},
+		{name: "rollover already posted", closing: 4, expired: 4, carried: 4, carryLimit: 5},
+		{name: "refund within the carry limit", closing: 6, expired: 4, carried: 4, carryLimit: 5, wantExpiry: -2, wantCarry: 1},
+		{name: "refund beyond the carry limit", closing: 10, expired: 8, carried: 5, carryLimit: 5, wantExpiry: -2},
+		{name: "debit adjustment", closing: 2, expired: 4, carried: 4, carryLimit: 5, wantExpiry: 2, wantCarry: -2},
+		{name: "half days", closing: 2.5, expired: 2, carried: 2, carryLimit: 5, wantExpiry: -0.5, wantCarry: 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expiry, carry := rolloverCorrection(tt.closing, tt.expired, tt.carried, tt.carryLimit)
+			if expiry != tt.wantExpiry || carry != tt.wantCarry {
+				t.Errorf("rolloverCorrection() = %v, %v; want %v, %v", expiry, carry, tt.wantExpiry, tt.wantCarry)
+			}
+		})
+	}
+}
+
+// testLedger sums ledger days per year and entry type, the way settleRollover reads them
+type testLedger map[int]map[string]float64
+
+func (l testLedger) post(year int, entryType string, days float64) {
+	if l[year] == nil {
+		l[year] = make(map[string]float64)
+	}
+	l[year][entryType] += days
+}
+
+func (l testLedger) balance(year int) float64 {
+	total := 0.0
+	for _, days := range l[year] {
+		total += days
+	}
+	return total
+}
+
+// settle applies the correction settleRollover posts for a closed year
+func (l testLedger) settle(year int, carryLimit float64) {
+	closing := l.balance(year) - l[year]["expiry"]
+	expiry, carry := rolloverCorrection(closing, -l[year]["expiry"], l[year+1]["carry_forward"], carryLimit)
+	l.post(year, "expiry", expiry)
+	l.post(year+1, "carry_forward", carry)
+}
+
+func TestRefundAfterRollover(t *testing.T) {
+	ledger := testLedger{}
+	ledger.post(2025, "accrual", 12)
+	ledger.post(2025, "debit", -10)
+	ledger.settle(2025, 5)
+	ledger.post(2026, "accrual", 12)
+
+	if got := ledger.balance(2025); got != 0 {
+		t.Fatalf("2025 balance after rollover = %v, want 0", got)
+	}
+	if got := ledger.balance(2026); got != 14 {
+		t.Fatalf("2026 balance after rollover = %v, want 14", got)
+	}
+
+	// Leave taken in 2025 is cancelled after the rollover: the refund reaches 2026
+	ledger.post(2025, "refund", 3)
+	ledger.settle(2025, 5)
+	if got := ledger.balance(2025); got != 0 {
+		t.Errorf("2025 balance after refund = %v, want 0", got)
+	}
+	if got := ledger.balance(2026); got != 17 {
+		t.Errorf("2026 balance after refund = %v, want 17", got)
+	}
+
+	// Beyond the carry limit the refund expires with the year
+	ledger.post(2025, "refund", 2)
+	ledger.settle(2025, 5)
+	if got := ledger.balance(2026); got != 17 {
+		t.Errorf("2026 balance after a refund over the limit = %v, want 17", got)
+	}
+
+	// Settling again changes nothing
+	ledger.settle(2025, 5)
+	if got := ledger[2026]["carry_forward"]; got != 5 {
+		t.Errorf("carried forward = %v, want 5", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
@@ -12,15 +13,22 @@ import (
 )
 
 type LeaveService struct {
-	db *sql.DB
+	db                  *sql.DB
+	notificationService *NotificationService
 }
 
-func NewLeaveService(db *sql.DB) *LeaveService {
-	return &LeaveService{db: db}
+func NewLeaveService(db *sql.DB, notificationService *NotificationService) *LeaveService {
+	return &LeaveService{db: db, notificationService: notificationService}
 }
 
 // CreateLeaveRequest creates a new leave request
 func (s *LeaveService) CreateLeaveRequest(ctx context.Context, tenantID, employeeID uuid.UUID, req models.CreateLeaveRequest) (*models.LeaveRequest, error) {
+	return s.createLeaveRequest(ctx, tenantID, employeeID, req, nil)
+}
+
+// createLeaveRequest creates a leave request and its approval trail. A request that modifies an
+// approved request may use the days debited for that request, since they are refunded when it is replaced.
+func (s *LeaveService) createLeaveRequest(ctx context.Context, tenantID, employeeID uuid.UUID, req models.CreateLeaveRequest, modifiesID *uuid.UUID) (*models.LeaveRequest, error) {
 	// Parse dates
 	startDate, err := time.Parse("2006-01-02", req.StartDate)
 	if err != nil {
@@ -37,50 +45,146 @@ func (s *LeaveService) CreateLeaveRequest(ctx context.Context, tenantID, employe
 		return nil, fmt.Errorf("end date cannot be before start date")
 	}
 
+	daysRequested, err := s.CalculateLeaveDays(ctx, tenantID, employeeID, startDate, endDate, req.StartHalfDay, req.EndHalfDay)
+	if err != nil {
+		return nil, err
+	}
+	if daysRequested <= 0 {
+		return nil, fmt.Errorf("leave period contains no working days")
+	}
+
+	// Paid leave types with a configuration are limited by the ledger balance, less days already pending
+	policy, err := s.findLeaveTypePolicy(ctx, s.db, tenantID, req.LeaveType)
+	if err != nil {
+		return nil, err
+	}
+	if policy != nil && policy.IsPaid {
+		split, err := s.splitLeaveDays(ctx, tenantID, employeeID, startDate, endDate, req.StartHalfDay, req.EndHalfDay, daysRequested)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range split {
+			balance, err := s.ledgerBalance(ctx, s.db, tenantID, employeeID, policy.Key, part.Year)
+			if err != nil {
+				return nil, err
+			}
+			pending, err := s.pendingLeaveDays(ctx, s.db, tenantID, employeeID, policy, part.Year)
+			if err != nil {
+				return nil, err
+			}
+			if modifiesID != nil {
+				debited, err := requestDebitedDays(ctx, s.db, tenantID, *modifiesID, policy.Key, part.Year)
+				if err != nil {
+					return nil, err
+				}
+				balance += debited
+			}
+			if available := balance - pending; part.Days > available {
+				if len(split) > 1 {
+					return nil, fmt.Errorf("insufficient leave balance for %d: %.2f days available", part.Year, math.Max(available, 0))
+				}
+				return nil, fmt.Errorf("insufficient leave balance: %.2f days available", math.Max(available, 0))
+			}
+		}
+	}
+
 	// Create leave request
 	leaveRequest := models.LeaveRequest{
-		ID:            uuid.New(),
-		TenantID:      tenantID,
-		EmployeeID:    employeeID,
-		LeaveType:     req.LeaveType,
-		StartDate:     startDate,
-		EndDate:       endDate,
-		DaysRequested: req.DaysRequested,
-		Reason:        req.Reason,
-		Status:        models.LeaveStatusPending,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+		ID:                uuid.New(),
+		TenantID:          tenantID,
+		EmployeeID:        employeeID,
+		LeaveType:         req.LeaveType,
+		StartDate:         startDate,
+		EndDate:           endDate,
+		DaysRequested:     daysRequested,
+		StartHalfDay:      req.StartHalfDay,
+		EndHalfDay:        req.EndHalfDay,
+		Reason:            req.Reason,
+		Status:            models.LeaveStatusPending,
+		ModifiesRequestID: modifiesID,
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
 	}
 
 	query := `
 		INSERT INTO leave_requests (
 			id, tenant_id, employee_id, leave_type, start_date, end_date, 
-			days_requested, reason, status, created_at, updated_at
+			days_requested, start_half_day, end_half_day, reason, status, modifies_request_id,
+			created_at, updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
 		)
 	`
 
-	_, err = s.db.ExecContext(ctx, query,
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, query,
 		leaveRequest.ID, leaveRequest.TenantID, leaveRequest.EmployeeID,
 		leaveRequest.LeaveType, leaveRequest.StartDate, leaveRequest.EndDate,
-		leaveRequest.DaysRequested, leaveRequest.Reason, leaveRequest.Status,
+		leaveRequest.DaysRequested, leaveRequest.StartHalfDay, leaveRequest.EndHalfDay,
+		leaveRequest.Reason, leaveRequest.Status, leaveRequest.ModifiesRequestID,
 		leaveRequest.CreatedAt, leaveRequest.UpdatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create leave request: %w", err)
 	}
 
+	if err := s.createApprovalTrail(ctx, tx, tenantID, leaveRequest.ID, employeeID, req.LeaveType, daysRequested, leaveRequest.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	trails, err := getApprovalTrails(ctx, tx, tenantID, []uuid.UUID{leaveRequest.ID})
+	if err != nil {
+		return nil, err
+	}
+	leaveRequest.ApprovalSteps = trails[leaveRequest.ID]
+
+	notifications, err := s.notificationService.QueueLeaveSubmitted(ctx, tx, tenantID, leaveRequest.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit leave request: %w", err)
+	}
+	s.notificationService.Publish(notifications)
+
 	return &leaveRequest, nil
 }
 
+// CalculateLeaveDays returns the number of leave days between two dates for an employee,
+// skipping non-working days of the attendance policy and holidays at the employee's location
+func (s *LeaveService) CalculateLeaveDays(ctx context.Context, tenantID, employeeID uuid.UUID, startDate, endDate time.Time, startHalfDay, endHalfDay bool) (float64, error) {
+	policy, _, err := getEffectivePolicy(ctx, s.db, tenantID, employeeID)
+	if err != nil {
+		return 0, err
+	}
+
+	location, err := employeeWorkLocation(ctx, s.db, tenantID, employeeID)
+	if err != nil {
+		return 0, err
+	}
+
+	holidays, err := holidayDates(ctx, s.db, tenantID, location, startDate, endDate)
+	if err != nil {
+		return 0, err
+	}
+
+	return countLeaveDays(startDate, endDate, startHalfDay, endHalfDay, policy.WorkingDays, holidays), nil
+}
+
 // GetLeaveRequests retrieves leave requests with optional filters
 func (s *LeaveService) GetLeaveRequests(ctx context.Context, tenantID uuid.UUID, employeeID *uuid.UUID, departmentID *uuid.UUID, status *models.LeaveStatus) ([]models.LeaveRequest, error) {
 	query := `
 		SELECT 
 			lr.id, lr.tenant_id, lr.employee_id, lr.leave_type, lr.start_date, lr.end_date,
-			lr.days_requested, lr.reason, lr.status, lr.approved_by, lr.approved_at,
-			lr.rejection_reason, lr.created_at, lr.updated_at,
+			lr.days_requested, lr.start_half_day, lr.end_half_day, lr.reason, lr.status, lr.approved_by, lr.approved_at,
+			lr.rejection_reason, lr.modifies_request_id, lr.cancelled_at, lr.cancellation_reason,
+			lr.created_at, lr.updated_at,
 			(u.first_name || ' ' || u.last_name) as employee_name, 
 			e.employee_code as employee_code, u.role,
 			COALESCE((a_user.first_name || ' ' || a_user.last_name), '') as approver_name
@@ -126,8 +230,9 @@ func (s *LeaveService) GetLeaveRequests(ctx context.Context, tenantID uuid.UUID,
 		var leave models.LeaveRequest
 		err := rows.Scan(
 			&leave.ID, &leave.TenantID, &leave.EmployeeID, &leave.LeaveType,
-			&leave.StartDate, &leave.EndDate, &leave.DaysRequested, &leave.Reason,
+			&leave.StartDate, &leave.EndDate, &leave.DaysRequested, &leave.StartHalfDay, &leave.EndHalfDay, &leave.Reason,
 			&leave.Status, &leave.ApprovedBy, &leave.ApprovedAt, &leave.RejectionReason,
+			&leave.ModifiesRequestID, &leave.CancelledAt, &leave.CancellationReason,
 			&leave.CreatedAt, &leave.UpdatedAt, &leave.EmployeeName, &leave.EmployeeCode, &leave.Role,
 			&leave.ApproverName,
 		)
@@ -141,6 +246,18 @@ func (s *LeaveService) GetLeaveRequests(ctx context.Context, tenantID uuid.UUID,
 		return nil, fmt.Errorf("rows iteration error: %w", err)
 	}
 
+	leaveIDs := make([]uuid.UUID, len(leaves))
+	for i := range leaves {
+		leaveIDs[i] = leaves[i].ID
+	}
+	trails, err := getApprovalTrails(ctx, s.db, tenantID, leaveIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range leaves {
+		leaves[i].ApprovalSteps = trails[leaves[i].ID]
+	}
+
 	return leaves, nil
 }
 
@@ -149,8 +266,9 @@ func (s *LeaveService) GetLeaveRequestByID(ctx context.Context, tenantID, leaveI
 	query := `
 		SELECT 
 			lr.id, lr.tenant_id, lr.employee_id, lr.leave_type, lr.start_date, lr.end_date,
-			lr.days_requested, lr.reason, lr.status, lr.approved_by, lr.approved_at,
-			lr.rejection_reason, lr.created_at, lr.updated_at,
+			lr.days_requested, lr.start_half_day, lr.end_half_day, lr.reason, lr.status, lr.approved_by, lr.approved_at,
+			lr.rejection_reason, lr.modifies_request_id, lr.cancelled_at, lr.cancellation_reason,
+			lr.created_at, lr.updated_at,
 			(u.first_name || ' ' || u.last_name) as employee_name, 
 			e.employee_code as employee_code, u.role,
 			COALESCE((a_user.first_name || ' ' || a_user.last_name), '') as approver_name
@@ -165,8 +283,9 @@ func (s *LeaveService) GetLeaveRequestByID(ctx context.Context, tenantID, leaveI
 	var leave models.LeaveRequest
 	err := s.db.QueryRowContext(ctx, query, leaveID, tenantID).Scan(
 		&leave.ID, &leave.TenantID, &leave.EmployeeID, &leave.LeaveType,
-		&leave.StartDate, &leave.EndDate, &leave.DaysRequested, &leave.Reason,
+		&leave.StartDate, &leave.EndDate, &leave.DaysRequested, &leave.StartHalfDay, &leave.EndHalfDay, &leave.Reason,
 		&leave.Status, &leave.ApprovedBy, &leave.ApprovedAt, &leave.RejectionReason,
+		&leave.ModifiesRequestID, &leave.CancelledAt, &leave.CancellationReason,
 		&leave.CreatedAt, &leave.UpdatedAt, &leave.EmployeeName, &leave.EmployeeCode, &leave.Role,
 		&leave.ApproverName,
 	)
@@ -177,56 +296,317 @@ func (s *LeaveService) GetLeaveRequestByID(ctx context.Context, tenantID, leaveI
 		return nil, fmt.Errorf("failed to get leave request: %w", err)
 	}
 
+	trails, err := getApprovalTrails(ctx, s.db, tenantID, []uuid.UUID{leave.ID})
+	if err != nil {
+		return nil, err
+	}
+	leave.ApprovalSteps = trails[leave.ID]
+
 	return &leave, nil
 }
 
-// ApproveLeaveRequest approves a leave request
-func (s *LeaveService) ApproveLeaveRequest(ctx context.Context, tenantID, leaveID, approverID uuid.UUID) error {
+// ApproveLeaveRequest records the approval of the current step of a leave request by a user.
+// The request is approved, and the employee's leave balance debited, once the last step is approved.
+// It returns the status of the request after the approval.
+func (s *LeaveService) ApproveLeaveRequest(ctx context.Context, tenantID, leaveID, actorID uuid.UUID, actorRole, comment string) (models.LeaveStatus, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	// Get leave request first
+	var employeeID uuid.UUID
+	var requesterID, modifiesID uuid.NullUUID
+	var leaveType models.LeaveType
+	var startDate, endDate time.Time
+	var startHalfDay, endHalfDay bool
+	var days float64
 	var status models.LeaveStatus
 	query := `
-		SELECT status
-		FROM leave_requests
-		WHERE id = $1 AND tenant_id = $2
+		SELECT lr.employee_id, e.user_id, lr.leave_type, lr.start_date, lr.end_date, lr.start_half_day,
+		       lr.end_half_day, lr.days_requested, lr.status, lr.modifies_request_id
+		FROM leave_requests lr
+		JOIN employees e ON e.id = lr.employee_id
+		WHERE lr.id = $1 AND lr.tenant_id = $2
+		FOR UPDATE OF lr
 	`
-	err := s.db.QueryRowContext(ctx, query, leaveID, tenantID).Scan(&status)
+	err = tx.QueryRowContext(ctx, query, leaveID, tenantID).Scan(&employeeID, &requesterID, &leaveType,
+		&startDate, &endDate, &startHalfDay, &endHalfDay, &days, &status, &modifiesID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("leave request not found")
+			return "", fmt.Errorf("leave request not found")
 		}
-		return fmt.Errorf("failed to get leave request: %w", err)
+		return "", fmt.Errorf("failed to get leave request: %w", err)
 	}
 
 	// Check if already approved/rejected
 	if status != models.LeaveStatusPending {
-		return fmt.Errorf("leave request is already %s", status)
+		return "", fmt.Errorf("leave request is already %s", status)
+	}
+	if requesterID.Valid && requesterID.UUID == actorID {
+		return "", fmt.Errorf("not authorized to act on this leave request")
 	}
 
-	// Update leave request status
 	now := time.Now()
+	final, err := s.recordApprovalDecision(ctx, tx, tenantID, leaveID, actorID, actorRole, models.ApprovalStepApproved, comment, now)
+	if err != nil {
+		return "", err
+	}
+	if !final {
+		// The request moved on to the next approver
+		notifications, err := s.notificationService.QueueLeaveSubmitted(ctx, tx, tenantID, leaveID)
+		if err != nil {
+			return "", err
+		}
+		if err := tx.Commit(); err != nil {
+			return "", fmt.Errorf("failed to commit leave approval: %w", err)
+		}
+		s.notificationService.Publish(notifications)
+		return models.LeaveStatusPending, nil
+	}
+
+	// An approved modification replaces the request it modifies, returning its days first
+	if modifiesID.Valid {
+		reason := fmt.Sprintf("Replaced by modified leave request %s", leaveID)
+		if err := s.cancelApprovedLeave(ctx, tx, tenantID, modifiesID.UUID, leaveID, reason, now); err != nil {
+			return "", err
+		}
+	}
+
+	if err := s.debitLeaveBalance(ctx, tx, tenantID, employeeID, leaveID, leaveType, startDate, endDate, startHalfDay, endHalfDay, days, now); err != nil {
+		return "", err
+	}
+
+	if err := s.markLeaveAttendance(ctx, tx, tenantID, employeeID, leaveID, startDate, endDate, startHalfDay, endHalfDay, now); err != nil {
+		return "", err
+	}
+
+	approverEmployeeID, err := employeeIDForUser(ctx, tx, tenantID, actorID)
+	if err != nil {
+		return "", err
+	}
+
+	// Update leave request status
 	updateQuery := `
 		UPDATE leave_requests
 		SET status = $1, approved_by = $2, approved_at = $3, updated_at = $4
 		WHERE id = $5
 	`
-	_, err = s.db.ExecContext(ctx, updateQuery, models.LeaveStatusApproved, approverID, now, now, leaveID)
+	_, err = tx.ExecContext(ctx, updateQuery, models.LeaveStatusApproved, approverEmployeeID, now, now, leaveID)
 	if err != nil {
-		return fmt.Errorf("failed to approve leave request: %w", err)
+		return "", fmt.Errorf("failed to approve leave request: %w", err)
+	}
+
+	notifications, err := s.notificationService.QueueLeaveDecision(ctx, tx, tenantID, leaveID, true, comment)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit leave approval: %w", err)
+	}
+	s.notificationService.Publish(notifications)
+
+	return models.LeaveStatusApproved, nil
+}
+
+// recordApprovalDecision applies a user's decision to the pending approval step of a request.
+// Approving moves the chain to its next step; it reports true when no step is left. Rejecting
+// ends the chain and skips the remaining steps. Requests created before approval chains existed
+// have no steps and are decided in one step by managers, HR and admins.
+func (s *LeaveService) recordApprovalDecision(ctx context.Context, tx *sql.Tx, tenantID, leaveID, actorID uuid.UUID, actorRole string, decision models.ApprovalStepStatus, comment string, now time.Time) (bool, error) {
+	var step pendingApprovalStep
+	var role sql.NullString
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, approver_user_id, approver_role
+		FROM leave_approval_steps
+		WHERE leave_request_id = $1 AND tenant_id = $2 AND status = 'pending'
+		ORDER BY step_order
+		LIMIT 1
+		FOR UPDATE`, leaveID, tenantID).Scan(&step.ID, &step.ApproverUserID, &role)
+	if err == sql.ErrNoRows {
+		var steps int
+		if err := tx.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM leave_approval_steps WHERE leave_request_id = $1", leaveID).Scan(&steps); err != nil {
+			return false, fmt.Errorf("failed to get approval steps: %w", err)
+		}
+		if steps == 0 && (actorRole == "manager" || actorRole == "hr" || isAdminRole(actorRole)) {
+			return true, nil
+		}
+		return false, fmt.Errorf("not authorized to act on this leave request")
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get pending approval step: %w", err)
+	}
+	if role.Valid {
+		step.ApproverRole = &role.String
+	}
+
+	// Delegations and approver leave are dated in the tenant's timezone
+	today := now.In(tenantLocation(ctx, s.db, tenantID))
+	delegatedFrom, err := authorizeApprovalStep(ctx, tx, tenantID, &step, actorID, actorRole, today)
+	if err != nil {
+		return false, err
+	}
+
+	var stepComment *string
+	if comment != "" {
+		stepComment = &comment
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE leave_approval_steps
+		SET status = $1, comment = $2, acted_by = $3, delegated_from = $4, acted_at = $5, updated_at = $5
+		WHERE id = $6`,
+		decision, stepComment, actorID, delegatedFrom, now, step.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update approval step: %w", err)
+	}
+
+	if decision == models.ApprovalStepRejected {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE leave_approval_steps SET status = 'skipped', updated_at = $1
+			WHERE leave_request_id = $2 AND status = 'waiting'`, now, leaveID)
+		if err != nil {
+			return false, fmt.Errorf("failed to close approval steps: %w", err)
+		}
+		return true, nil
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE leave_approval_steps SET status = 'pending', updated_at = $1
+		WHERE id = (
+			SELECT id FROM leave_approval_steps
+			WHERE leave_request_id = $2 AND status = 'waiting'
+			ORDER BY step_order
+			LIMIT 1
+		)`, now, leaveID)
+	if err != nil {
+		return false, fmt.Errorf("failed to advance approval chain: %w", err)
+	}
+
+	advanced, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return advanced == 0, nil
+}
+
+// debitLeaveBalance writes the ledger debits for an approved leave request, one for each year the
+// leave falls in. Leave types that are unpaid or not configured are not balance-tracked.
+func (s *LeaveService) debitLeaveBalance(ctx context.Context, tx *sql.Tx, tenantID, employeeID, leaveID uuid.UUID, leaveType models.LeaveType, startDate, endDate time.Time, startHalfDay, endHalfDay bool, days float64, now time.Time) error {
+	policy, err := s.findLeaveTypePolicy(ctx, tx, tenantID, leaveType)
+	if err != nil {
+		return err
+	}
+	if policy == nil || !policy.IsPaid {
+		return nil
+	}
+
+	split, err := s.splitLeaveDays(ctx, tenantID, employeeID, startDate, endDate, startHalfDay, endHalfDay, days)
+	if err != nil {
+		return err
+	}
+
+	// Serialise balance changes per employee
+	if _, err := tx.ExecContext(ctx, "SELECT id FROM employees WHERE id = $1 FOR UPDATE", employeeID); err != nil {
+		return fmt.Errorf("failed to lock employee: %w", err)
+	}
+
+	for _, part := range split {
+		if part.Days <= 0 {
+			continue
+		}
+
+		balance, err := s.ledgerBalance(ctx, tx, tenantID, employeeID, policy.Key, part.Year)
+		if err != nil {
+			return err
+		}
+		if part.Days > balance {
+			if len(split) > 1 {
+				return fmt.Errorf("insufficient leave balance for %d: %.2f days available", part.Year, math.Max(balance, 0))
+			}
+			return fmt.Errorf("insufficient leave balance: %.2f days available", math.Max(balance, 0))
+		}
+
+		_, err = insertLedgerEntry(ctx, tx, models.LeaveLedgerEntry{
+			ID:             uuid.New(),
+			TenantID:       tenantID,
+			EmployeeID:     employeeID,
+			LeaveType:      models.LeaveType(policy.Key),
+			Year:           part.Year,
+			EntryType:      models.LeaveEntryDebit,
+			Days:           -part.Days,
+			LeaveRequestID: &leaveID,
+			CreatedAt:      now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refundLeaveBalance reverses whatever is still debited for a leave request
+func (s *LeaveService) refundLeaveBalance(ctx context.Context, tx *sql.Tx, tenantID, leaveID uuid.UUID, now time.Time) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT employee_id, leave_type, year, SUM(days)
+		FROM leave_ledger_entries
+		WHERE tenant_id = $1 AND leave_request_id = $2 AND entry_type IN ('debit', 'refund')
+		GROUP BY employee_id, leave_type, year
+		HAVING SUM(days) < 0`, tenantID, leaveID)
+	if err != nil {
+		return fmt.Errorf("failed to get leave debits: %w", err)
+	}
+
+	refunds := make([]models.LeaveLedgerEntry, 0)
+	for rows.Next() {
+		entry := models.LeaveLedgerEntry{
+			ID:             uuid.New(),
+			TenantID:       tenantID,
+			EntryType:      models.LeaveEntryRefund,
+			LeaveRequestID: &leaveID,
+			CreatedAt:      now,
+		}
+		if err := rows.Scan(&entry.EmployeeID, &entry.LeaveType, &entry.Year, &entry.Days); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan leave debit: %w", err)
+		}
+		entry.Days = -entry.Days
+		refunds = append(refunds, entry)
+	}
+	rows.Close()
+
+	for _, entry := range refunds {
+		if _, err := insertLedgerEntry(ctx, tx, entry); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// RejectLeaveRequest rejects a leave request
-func (s *LeaveService) RejectLeaveRequest(ctx context.Context, tenantID, leaveID, approverID uuid.UUID, reason string) error {
+// RejectLeaveRequest rejects a leave request at its current approval step
+func (s *LeaveService) RejectLeaveRequest(ctx context.Context, tenantID, leaveID, actorID uuid.UUID, actorRole, reason string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	// Get leave request first
 	var status models.LeaveStatus
+	var requesterID uuid.NullUUID
 	query := `
-		SELECT status
-		FROM leave_requests
-		WHERE id = $1 AND tenant_id = $2
+		SELECT lr.status, e.user_id
+		FROM leave_requests lr
+		JOIN employees e ON e.id = lr.employee_id
+		WHERE lr.id = $1 AND lr.tenant_id = $2
+		FOR UPDATE OF lr
 	`
-	err := s.db.QueryRowContext(ctx, query, leaveID, tenantID).Scan(&status)
+	err = tx.QueryRowContext(ctx, query, leaveID, tenantID).Scan(&status, &requesterID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("leave request not found")
@@ -238,21 +618,43 @@ func (s *LeaveService) RejectLeaveRequest(ctx context.Context, tenantID, leaveID
 	if status != models.LeaveStatusPending {
 		return fmt.Errorf("leave request is already %s", status)
 	}
+	if requesterID.Valid && requesterID.UUID == actorID {
+		return fmt.Errorf("not authorized to act on this leave request")
+	}
 
-	// Update leave request status
 	now := time.Now()
+	if _, err := s.recordApprovalDecision(ctx, tx, tenantID, leaveID, actorID, actorRole, models.ApprovalStepRejected, reason, now); err != nil {
+		return err
+	}
+
+	approverEmployeeID, err := employeeIDForUser(ctx, tx, tenantID, actorID)
+	if err != nil {
+		return err
+	}
+
+	// Update leave request status
 	updateQuery := `
 		UPDATE leave_requests
 		SET status = $1, approved_by = $2, approved_at = $3, rejection_reason = $4, updated_at = $5
 		WHERE id = $6
 	`
-	_, err = s.db.ExecContext(ctx, updateQuery,
-		models.LeaveStatusRejected, approverID, now, reason, now, leaveID,
+	_, err = tx.ExecContext(ctx, updateQuery,
+		models.LeaveStatusRejected, approverEmployeeID, now, reason, now, leaveID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to reject leave request: %w", err)
 	}
 
+	notifications, err := s.notificationService.QueueLeaveDecision(ctx, tx, tenantID, leaveID, false, reason)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit leave rejection: %w", err)
+	}
+	s.notificationService.Publish(notifications)
+
 	return nil
 }
 
@@ -306,7 +708,7 @@ func (s *LeaveService) CreateLeaveType(ctx context.Context, tenantID uuid.UUID,
 			    annual_limit = $2, 
 			    accrual_type = $3, 
 			    accrual_amount = $4, 
-			    carryforward_limit = $5, 
+			    carry_forward_limit = $5, 
 			    is_paid = $6, 
 			    requires_approval = $7,
 			    deleted_at = NULL,
@@ -337,7 +739,7 @@ func (s *LeaveService) CreateLeaveType(ctx context.Context, tenantID uuid.UUID,
 	query := `
 		INSERT INTO leave_types (
 			id, tenant_id, name, short_code, annual_limit, accrual_type, 
-			accrual_amount, carryforward_limit, is_paid, requires_approval, 
+			accrual_amount, carry_forward_limit, is_paid, requires_approval, 
 			is_active, created_at, updated_at
 		) VALUES (
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
@@ -366,7 +768,7 @@ func (s *LeaveService) CreateLeaveType(ctx context.Context, tenantID uuid.UUID,
 func (s *LeaveService) GetLeaveTypeByID(ctx context.Context, tenantID, id uuid.UUID) (*models.LeaveTypeConfig, error) {
 	query := `
 		SELECT id, tenant_id, name, short_code, annual_limit, accrual_type,
-		       accrual_amount, carryforward_limit, is_paid, requires_approval,
+		       accrual_amount, carry_forward_limit, is_paid, requires_approval,
 		       is_active, created_at, updated_at
 		FROM leave_types
 		WHERE id = $1 AND tenant_id = $2
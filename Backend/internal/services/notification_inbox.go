@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+const notificationColumns = `id, tenant_id, user_id, category, type, title, body, link, resource_type, resource_id,
+	read_at, created_at`
+
+// notificationPreferenceKeys maps each category to the user preference that turns it off.
+// Users without the preference receive the category.
+var notificationPreferenceKeys = map[models.NotificationCategory]string{
+	models.NotificationCategoryLeave:      "leave_approval_notifications",
+	models.NotificationCategoryAttendance: "attendance_notifications",
+	models.NotificationCategoryPayslip:    "payslip_notifications",
+	models.NotificationCategoryBilling:    "billing_notifications",
+}
+
+func scanNotification(row interface{ Scan(...interface{}) error }) (*models.Notification, error) {
+	var n models.Notification
+	err := row.Scan(&n.ID, &n.TenantID, &n.UserID, &n.Category, &n.Type, &n.Title, &n.Body, &n.Link,
+		&n.ResourceType, &n.ResourceID, &n.ReadAt, &n.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// inboxItem is the content of an in-app notification
+type inboxItem struct {
+	category     models.NotificationCategory
+	kind         string
+	title        string
+	body         string
+	link         string
+	resourceType string
+	resourceID   *uuid.UUID
+}
+
+// addToInbox writes a notification for a user through q unless the user turned its category
+// off. It returns nil when the notification was not written.
+func addToInbox(ctx context.Context, q dbExecutor, tenantID, userID uuid.UUID, item inboxItem) (*models.Notification, error) {
+	n, err := scanNotification(q.QueryRowContext(ctx, `
+		INSERT INTO notifications (id, tenant_id, user_id, category, type, title, body, link,
+		                           resource_type, resource_id, created_at, updated_at)
+		SELECT $1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), $10,
+		       clock_timestamp(), clock_timestamp()
+		WHERE NOT EXISTS (
+		    SELECT 1 FROM user_preferences
+		    WHERE user_id = $3 AND preference_key = $11 AND preference_value = 'false'
+		)
+		RETURNING `+notificationColumns,
+		uuid.New(), tenantID, userID, item.category, item.kind, item.title, item.body, item.link,
+		item.resourceType, item.resourceID, notificationPreferenceKeys[item.category]))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+	return n, nil
+}
+
+// notificationHub wakes the open notification streams of users in this process
+type notificationHub struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan struct{}]struct{}
+}
+
+func newNotificationHub() *notificationHub {
+	return &notificationHub{subscribers: make(map[uuid.UUID]map[chan struct{}]struct{})}
+}
+
+func (h *notificationHub) subscribe(userID uuid.UUID) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	h.mu.Lock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan struct{}]struct{})
+	}
+	h.subscribers[userID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subscribers[userID], ch)
+		if len(h.subscribers[userID]) == 0 {
+			delete(h.subscribers, userID)
+		}
+		h.mu.Unlock()
+	}
+}
+
+func (h *notificationHub) wake(userID uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[userID] {
+		select {
+		case ch <- struct{}{}:
+		default: // already woken
+		}
+	}
+}
+
+// Publish wakes the streams of the recipients of notifications. Call it once the transaction
+// that wrote them has committed. Streams served by other API instances pick them up on their
+// next poll.
+func (s *NotificationService) Publish(notifications []models.Notification) {
+	for _, n := range notifications {
+		s.hub.wake(n.UserID)
+	}
+}
+
+// Subscribe returns a channel that receives a value when notifications for the user were
+// published, and the function that ends the subscription
+func (s *NotificationService) Subscribe(userID uuid.UUID) (<-chan struct{}, func()) {
+	return s.hub.subscribe(userID)
+}
+
+// GetNotifications lists a page of a user's inbox, newest first, with the total count and the
+// unread count
+func (s *NotificationService) GetNotifications(ctx context.Context, tenantID, userID uuid.UUID, unreadOnly bool, page, limit int) ([]models.Notification, int, int, error) {
+	var total, unread int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FILTER (WHERE NOT $3 OR read_at IS NULL), COUNT(*) FILTER (WHERE read_at IS NULL)
+		FROM notifications
+		WHERE tenant_id = $1 AND user_id = $2`, tenantID, userID, unreadOnly).Scan(&total, &unread)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+notificationColumns+`
+		FROM notifications
+		WHERE tenant_id = $1 AND user_id = $2 AND (NOT $3 OR read_at IS NULL)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4 OFFSET $5`, tenantID, userID, unreadOnly, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to get notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := make([]models.Notification, 0)
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, *n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to get notifications: %w", err)
+	}
+	return notifications, total, unread, nil
+}
+
+// GetUnreadNotificationCount returns the number of unread notifications of a user
+func (s *NotificationService) GetUnreadNotificationCount(ctx context.Context, tenantID, userID uuid.UUID) (int, error) {
+	var unread int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM notifications
+		WHERE tenant_id = $1 AND user_id = $2 AND read_at IS NULL`, tenantID, userID).Scan(&unread)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return unread, nil
+}
+
+// MarkNotificationRead marks one of a user's notifications read
+func (s *NotificationService) MarkNotificationRead(ctx context.Context, tenantID, userID, notificationID uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE notifications SET read_at = COALESCE(read_at, NOW()), updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2 AND user_id = $3`, notificationID, tenantID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("notification not found")
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead marks every unread notification of a user read and returns how many
+// there were
+func (s *NotificationService) MarkAllNotificationsRead(ctx context.Context, tenantID, userID uuid.UUID) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE notifications SET read_at = NOW(), updated_at = NOW()
+		WHERE tenant_id = $1 AND user_id = $2 AND read_at IS NULL`, tenantID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// NotificationCursor is a position in a user's inbox; streams send what was created after it
+type NotificationCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// GetNotificationCursor returns the position of a notification, or of the user's newest
+// notification when notificationID is nil. A user without notifications starts at the zero
+// cursor.
+func (s *NotificationService) GetNotificationCursor(ctx context.Context, tenantID, userID uuid.UUID, notificationID *uuid.UUID) (NotificationCursor, error) {
+	var cursor NotificationCursor
+	err := s.db.QueryRowContext(ctx, `
+		SELECT created_at, id FROM notifications
+		WHERE tenant_id = $1 AND user_id = $2 AND ($3::UUID IS NULL OR id = $3)
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1`, tenantID, userID, notificationID).Scan(&cursor.CreatedAt, &cursor.ID)
+	if err == sql.ErrNoRows {
+		if notificationID != nil {
+			return s.GetNotificationCursor(ctx, tenantID, userID, nil)
+		}
+		return NotificationCursor{}, nil
+	}
+	if err != nil {
+		return NotificationCursor{}, fmt.Errorf("failed to get notification cursor: %w", err)
+	}
+	return cursor, nil
+}
+
+// GetNotificationsAfter lists a user's notifications created after a cursor, oldest first
+func (s *NotificationService) GetNotificationsAfter(ctx context.Context, tenantID, userID uuid.UUID, cursor NotificationCursor) ([]models.Notification, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+notificationColumns+`
+		FROM notifications
+		WHERE tenant_id = $1 AND user_id = $2 AND (created_at, id) > ($3::TIMESTAMPTZ, $4::UUID)
+		ORDER BY created_at, id
+		LIMIT 100`, tenantID, userID, cursor.CreatedAt, cursor.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get new notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, *n)
+	}
+	return notifications, rows.Err()
+}
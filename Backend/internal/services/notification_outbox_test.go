@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/mail"
+)
+
+// recordingExecutor stands in for the caller's transaction and records the statements run on it
+type recordingExecutor struct {
+	statements []string
+	args       [][]interface{}
+}
+
+func (e *recordingExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	e.statements = append(e.statements, query)
+	e.args = append(e.args, args)
+	return nil, nil
+}
+
+func (e *recordingExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("unexpected query")
+}
+
+func (e *recordingExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	panic("unexpected query: " + query)
+}
+
+func TestQueueEmailUsesCallerTransaction(t *testing.T) {
+	// Without a database of its own, the service can only reach the outbox through q
+	s := NewNotificationService(nil, mail.LogSender{}, "https://app.example.com/")
+	tx := &recordingExecutor{}
+
+	err := s.QueuePasswordReset(context.Background(), tx, nil, " ana@example.com ", "Ana", "tok123", time.Hour)
+	if err != nil {
+		t.Fatalf("QueuePasswordReset() error = %v", err)
+	}
+	if len(tx.statements) != 1 || !strings.Contains(tx.statements[0], "INSERT INTO email_outbox") {
+		t.Fatalf("statements = %q, want one outbox insert", tx.statements)
+	}
+	args := tx.args[0]
+	if args[2] != "ana@example.com" || args[3] != mail.TemplatePasswordReset {
+		t.Errorf("recipient, template = %v, %v", args[2], args[3])
+	}
+	if body, _ := args[5].(string); !strings.Contains(body, "https://app.example.com/reset-password?token=tok123") {
+		t.Errorf("body does not carry the reset link: %q", body)
+	}
+
+	// No recipient, nothing queued
+	tx = &recordingExecutor{}
+	if err := s.QueuePasswordReset(context.Background(), tx, nil, " ", "Ana", "tok", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.statements) != 0 {
+		t.Errorf("queued %d emails without a recipient", len(tx.statements))
+	}
+}
+
+func TestEmailRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Minute},
+		{attempts: 2, want: 2 * time.Minute},
+		{attempts: 3, want: 4 * time.Minute},
+		{attempts: 9, want: 256 * time.Minute},
+		{attempts: 10, want: emailMaxRetryDelay},
+		{attempts: 50, want: emailMaxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := emailRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("emailRetryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestDeliveryOutcome(t *testing.T) {
+	now := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	sendErr := errors.New("connection refused")
+	tests := []struct {
+		name        string
+		attempts    int
+		sendErr     error
+		wantStatus  string
+		wantAttempt time.Time
+	}{
+		{name: "sent", attempts: 1, wantStatus: "sent"},
+		{name: "sent on the last attempt", attempts: 5, wantStatus: "sent"},
+		{name: "first failure retries after a minute", attempts: 1, sendErr: sendErr, wantStatus: "pending", wantAttempt: now.Add(time.Minute)},
+		{name: "third failure backs off", attempts: 3, sendErr: sendErr, wantStatus: "pending", wantAttempt: now.Add(4 * time.Minute)},
+		{name: "gives up after max attempts", attempts: 5, sendErr: sendErr, wantStatus: "failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, next := deliveryOutcome(outboxEmail{attempts: tt.attempts, maxAttempts: 5}, tt.sendErr, now)
+			if status != tt.wantStatus || !next.Equal(tt.wantAttempt) {
+				t.Errorf("deliveryOutcome() = %s, %s; want %s, %s", status, next, tt.wantStatus, tt.wantAttempt)
+			}
+		})
+	}
+}
+
+func TestDeliveryThroughFakeServer(t *testing.T) {
+	server, err := mail.StartFakeServer("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, portText, _ := net.SplitHostPort(server.Addr())
+	port, _ := strconv.Atoi(portText)
+	sender := mail.NewSender(host, port, "", "", "PeopleOS <no-reply@example.com>")
+
+	msg, err := mail.Render(mail.TemplatePasswordReset, "ana@example.com", map[string]interface{}{
+		"Name": "Ana", "ResetURL": "https://app.example.com/reset-password?token=tok", "ExpiresIn": "1 hour",
+		"OrganizationName": "",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := outboxEmail{id: uuid.New(), template: mail.TemplatePasswordReset, msg: *msg, attempts: 1, maxAttempts: 3}
+
+	sendErr := sender.Send(context.Background(), email.msg)
+	if status, _ := deliveryOutcome(email, sendErr, time.Now()); status != "sent" {
+		t.Fatalf("first delivery: status %s, error %v", status, sendErr)
+	}
+	received := server.Messages()
+	if len(received) != 1 || received[0].Subject != msg.Subject || received[0].To[0] != "ana@example.com" {
+		t.Fatalf("received = %+v", received)
+	}
+
+	// The relay goes away: the email is retried until its attempts run out
+	server.Close()
+	for attempt, want := range []string{"pending", "pending", "failed"} {
+		email.attempts = attempt + 1
+		sendErr := sender.Send(context.Background(), email.msg)
+		if sendErr == nil {
+			t.Fatal("Send() succeeded without a server")
+		}
+		if status, _ := deliveryOutcome(email, sendErr, time.Now()); status != want {
+			t.Errorf("attempt %d: status %s, want %s", email.attempts, status, want)
+		}
+	}
+}
@@ -0,0 +1,804 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/auth"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/mail"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+const (
+	// emailSendTimeout bounds one delivery attempt
+	emailSendTimeout = 30 * time.Second
+	// emailLease is how long a claimed email stays with a delivery run before another run may
+	// retry it, in case the first one died mid-send
+	emailLease = 10 * time.Minute
+	// emailMaxRetryDelay caps the exponential delay between attempts
+	emailMaxRetryDelay = 6 * time.Hour
+)
+
+// sensitiveEmailTemplates carry credentials; their bodies are cleared once delivery ends
+var sensitiveEmailTemplates = map[string]bool{
+	mail.TemplateAccountCreated: true,
+	mail.TemplatePasswordReset:  true,
+}
+
+// NotificationService tells users about changes that concern them, by email through the
+// outbox and in their in-app inbox
+type NotificationService struct {
+	db     *sql.DB
+	sender mail.Sender
+	appURL string
+	hub    *notificationHub
+}
+
+func NewNotificationService(db *sql.DB, sender mail.Sender, appURL string) *NotificationService {
+	return &NotificationService{
+		db:     db,
+		sender: sender,
+		appURL: strings.TrimRight(appURL, "/"),
+		hub:    newNotificationHub(),
+	}
+}
+
+// queueEmail renders a template and adds it to the outbox through q. Callers pass the
+// transaction of the change the email reports, so the email is only sent if it commits.
+func (s *NotificationService) queueEmail(ctx context.Context, q dbExecutor, tenantID *uuid.UUID, to, template string, data map[string]interface{}) error {
+	to = strings.TrimSpace(to)
+	if to == "" {
+		return nil
+	}
+
+	if _, ok := data["OrganizationName"]; !ok {
+		data["OrganizationName"] = ""
+		if tenantID != nil {
+			var name string
+			err := q.QueryRowContext(ctx, "SELECT name FROM tenants WHERE id = $1", *tenantID).Scan(&name)
+			if err != nil && err != sql.ErrNoRows {
+				return fmt.Errorf("failed to get organization name: %w", err)
+			}
+			data["OrganizationName"] = name
+		}
+	}
+
+	msg, err := mail.Render(template, to, data)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.ExecContext(ctx, `
+		INSERT INTO email_outbox (id, tenant_id, recipient, template, subject, body_text, body_html,
+		                          status, next_attempt_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, 'pending', NOW(), NOW(), NOW())`,
+		uuid.New(), tenantID, msg.To, template, msg.Subject, msg.Text, msg.HTML)
+	if err != nil {
+		return fmt.Errorf("failed to queue email: %w", err)
+	}
+	return nil
+}
+
+// link returns an absolute URL of a frontend page
+func (s *NotificationService) link(path string) string {
+	return s.appURL + path
+}
+
+// QueueAccountCreated tells a new user their sign-in email and temporary password
+func (s *NotificationService) QueueAccountCreated(ctx context.Context, q dbExecutor, tenantID uuid.UUID, email, name, tempPassword string) error {
+	return s.queueEmail(ctx, q, &tenantID, email, mail.TemplateAccountCreated, map[string]interface{}{
+		"Name":         name,
+		"Email":        email,
+		"TempPassword": tempPassword,
+		"LoginURL":     s.link("/login"),
+	})
+}
+
+// QueuePasswordReset sends a user the link to reset their password with token
+func (s *NotificationService) QueuePasswordReset(ctx context.Context, q dbExecutor, tenantID *uuid.UUID, email, name, token string, expiresIn time.Duration) error {
+	return s.queueEmail(ctx, q, tenantID, email, mail.TemplatePasswordReset, map[string]interface{}{
+		"Name":      name,
+		"ResetURL":  s.link("/reset-password?token=" + token),
+		"ExpiresIn": formatEmailDuration(expiresIn),
+	})
+}
+
+// QueuePasswordResetEmail sends a user who asked to reset their password the reset link
+func (s *NotificationService) QueuePasswordResetEmail(ctx context.Context, tx *sql.Tx, user *auth.User, token string, expiresIn time.Duration) error {
+	tenantID, err := userTenantID(user)
+	if err != nil {
+		return err
+	}
+	return s.QueuePasswordReset(ctx, tx, tenantID, user.Email, user.FirstName, token, expiresIn)
+}
+
+// userTenantID returns the organization of a signed-in user, nil for platform users
+func userTenantID(user *auth.User) (*uuid.UUID, error) {
+	if user.TenantID == nil {
+		return nil, nil
+	}
+	id, err := uuid.Parse(*user.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant ID: %w", err)
+	}
+	return &id, nil
+}
+
+// leaveEmail holds the details of a leave request used by the leave templates
+type leaveEmail struct {
+	id           uuid.UUID
+	requesterID  uuid.NullUUID
+	email        string
+	firstName    string
+	employeeName string
+	data         map[string]interface{}
+}
+
+func (s *NotificationService) getLeaveEmail(ctx context.Context, q dbExecutor, tenantID, leaveID uuid.UUID) (*leaveEmail, error) {
+	var le leaveEmail
+	var leaveType string
+	var startDate, endDate time.Time
+	var days float64
+	var reason string
+	err := q.QueryRowContext(ctx, `
+		SELECT e.user_id, u.email, u.first_name, u.first_name || ' ' || u.last_name,
+		       lr.leave_type, lr.start_date, lr.end_date, lr.days_requested, COALESCE(lr.reason, '')
+		FROM leave_requests lr
+		JOIN employees e ON e.id = lr.employee_id
+		JOIN users u ON u.id = e.user_id
+		WHERE lr.id = $1 AND lr.tenant_id = $2`, leaveID, tenantID).Scan(
+		&le.requesterID, &le.email, &le.firstName, &le.employeeName,
+		&leaveType, &startDate, &endDate, &days, &reason)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("leave request not found")
+		}
+		return nil, fmt.Errorf("failed to get leave request: %w", err)
+	}
+
+	le.id = leaveID
+	le.data = map[string]interface{}{
+		"EmployeeName": le.employeeName,
+		"LeaveType":    strings.ReplaceAll(leaveType, "_", " "),
+		"StartDate":    startDate.Format("02 Jan 2006"),
+		"EndDate":      endDate.Format("02 Jan 2006"),
+		"Days":         strconv.FormatFloat(days, 'f', -1, 64),
+		"Reason":       reason,
+	}
+	return &le, nil
+}
+
+// QueueLeaveSubmitted tells the approvers of the pending step of a leave request that it is
+// waiting for them: the approver user of the step, or every active user of its role. It
+// returns the in-app notifications to publish once the transaction commits.
+func (s *NotificationService) QueueLeaveSubmitted(ctx context.Context, q dbExecutor, tenantID, leaveID uuid.UUID) ([]models.Notification, error) {
+	le, err := s.getLeaveEmail(ctx, q, tenantID, leaveID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT u.id, u.email, u.first_name, u.role
+		FROM leave_approval_steps s
+		JOIN users u ON u.tenant_id = s.tenant_id
+		            AND (u.id = s.approver_user_id OR (s.approver_user_id IS NULL AND u.role = s.approver_role))
+		WHERE s.leave_request_id = $1 AND s.tenant_id = $2 AND s.status = 'pending'
+		  AND u.is_active = true AND u.deleted_at IS NULL
+		  AND ($3::UUID IS NULL OR u.id <> $3)`,
+		leaveID, tenantID, le.requesterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leave approvers: %w", err)
+	}
+	approvers, err := scanRecipients(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leave approvers: %w", err)
+	}
+
+	var notifications []models.Notification
+	for _, a := range approvers {
+		data := map[string]interface{}{
+			"Name":      a.name,
+			"ReviewURL": s.link(leaveReviewPath(a.role)),
+		}
+		for k, v := range le.data {
+			data[k] = v
+		}
+		if err := s.queueEmail(ctx, q, &tenantID, a.email, mail.TemplateLeaveSubmitted, data); err != nil {
+			return nil, err
+		}
+
+		n, err := addToInbox(ctx, q, tenantID, a.userID, inboxItem{
+			category:     models.NotificationCategoryLeave,
+			kind:         models.NotificationLeaveSubmitted,
+			title:        fmt.Sprintf("Leave request from %s", le.employeeName),
+			body:         fmt.Sprintf("%s day(s) of %s leave from %s to %s", le.data["Days"], le.data["LeaveType"], le.data["StartDate"], le.data["EndDate"]),
+			link:         leaveReviewPath(a.role),
+			resourceType: "leave_request",
+			resourceID:   &le.id,
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifications = appendNotification(notifications, n)
+	}
+	return notifications, nil
+}
+
+// QueueLeaveDecision tells an employee that their leave request was approved or rejected.
+// note is the approver's comment or the rejection reason.
+func (s *NotificationService) QueueLeaveDecision(ctx context.Context, q dbExecutor, tenantID, leaveID uuid.UUID, approved bool, note string) ([]models.Notification, error) {
+	le, err := s.getLeaveEmail(ctx, q, tenantID, leaveID)
+	if err != nil {
+		return nil, err
+	}
+
+	item := inboxItem{
+		category:     models.NotificationCategoryLeave,
+		kind:         models.NotificationLeaveRejected,
+		title:        "Leave request rejected",
+		body:         fmt.Sprintf("Your %s leave from %s to %s was rejected", le.data["LeaveType"], le.data["StartDate"], le.data["EndDate"]),
+		link:         "/employee/leaves",
+		resourceType: "leave_request",
+		resourceID:   &le.id,
+	}
+
+	data := le.data
+	data["Name"] = le.firstName
+	data["LeavesURL"] = s.link("/employee/leaves")
+	template := mail.TemplateLeaveRejected
+	if approved {
+		template = mail.TemplateLeaveApproved
+		data["Comment"] = note
+		item.kind = models.NotificationLeaveApproved
+		item.title = "Leave request approved"
+		item.body = fmt.Sprintf("Your %s leave from %s to %s was approved", le.data["LeaveType"], le.data["StartDate"], le.data["EndDate"])
+	} else {
+		data["Reason"] = note
+		if note != "" {
+			item.body += ": " + note
+		}
+	}
+	if err := s.queueEmail(ctx, q, &tenantID, le.email, template, data); err != nil {
+		return nil, err
+	}
+
+	if !le.requesterID.Valid {
+		return nil, nil
+	}
+	n, err := addToInbox(ctx, q, tenantID, le.requesterID.UUID, item)
+	if err != nil {
+		return nil, err
+	}
+	return appendNotification(nil, n), nil
+}
+
+// QueuePayslipsPublished tells every employee with a payslip in a payroll run that it is available
+func (s *NotificationService) QueuePayslipsPublished(ctx context.Context, q dbExecutor, tenantID, runID uuid.UUID) ([]models.Notification, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT p.id, u.id, u.email, u.first_name, pr.period_year, pr.period_month, pr.payment_date
+		FROM payslips p
+		JOIN payroll_runs pr ON pr.id = p.payroll_run_id
+		JOIN employees e ON e.id = p.employee_id
+		JOIN users u ON u.id = e.user_id
+		WHERE p.payroll_run_id = $1 AND p.tenant_id = $2 AND u.deleted_at IS NULL`, runID, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payslip recipients: %w", err)
+	}
+
+	type payslipRecipient struct {
+		payslipID, userID uuid.UUID
+		email             string
+		data              map[string]interface{}
+	}
+	var recipients []payslipRecipient
+	for rows.Next() {
+		var r payslipRecipient
+		var name string
+		var year, month int
+		var paymentDate sql.NullTime
+		if err := rows.Scan(&r.payslipID, &r.userID, &r.email, &name, &year, &month, &paymentDate); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan payslip recipient: %w", err)
+		}
+		data := map[string]interface{}{
+			"Name":        name,
+			"Period":      fmt.Sprintf("%s %d", time.Month(month), year),
+			"PaymentDate": "",
+			"PayslipsURL": s.link("/employee/payslips"),
+		}
+		if paymentDate.Valid {
+			data["PaymentDate"] = paymentDate.Time.Format("02 Jan 2006")
+		}
+		r.data = data
+		recipients = append(recipients, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get payslip recipients: %w", err)
+	}
+
+	var notifications []models.Notification
+	for _, r := range recipients {
+		if err := s.queueEmail(ctx, q, &tenantID, r.email, mail.TemplatePayslipPublished, r.data); err != nil {
+			return nil, err
+		}
+
+		payslipID := r.payslipID
+		n, err := addToInbox(ctx, q, tenantID, r.userID, inboxItem{
+			category:     models.NotificationCategoryPayslip,
+			kind:         models.NotificationPayslipPublished,
+			title:        fmt.Sprintf("Payslip for %s", r.data["Period"]),
+			body:         "Your payslip is available to view and download",
+			link:         "/employee/payslips",
+			resourceType: "payslip",
+			resourceID:   &payslipID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifications = appendNotification(notifications, n)
+	}
+	return notifications, nil
+}
+
+// QueueInvoiceIssued sends an invoice to the billing email of its organization, or to the
+// organization's admin email when the invoice has none, and notifies the organization's admins
+func (s *NotificationService) QueueInvoiceIssued(ctx context.Context, q dbExecutor, invoiceID uuid.UUID) ([]models.Notification, error) {
+	var tenantID uuid.UUID
+	var number, currency, recipient, organization string
+	var total float64
+	var issueDate, dueDate time.Time
+	err := q.QueryRowContext(ctx, `
+		SELECT i.tenant_id, i.invoice_number, i.total_amount, i.currency, i.issue_date, i.due_date,
+		       COALESCE(NULLIF(i.billing_details->>'email', ''), t.admin_email, ''), t.name
+		FROM invoices i
+		JOIN tenants t ON t.id = i.tenant_id
+		WHERE i.id = $1`, invoiceID).Scan(
+		&tenantID, &number, &total, &currency, &issueDate, &dueDate, &recipient, &organization)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("invoice not found")
+		}
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+
+	amount := strconv.FormatFloat(total, 'f', 2, 64)
+	err = s.queueEmail(ctx, q, &tenantID, recipient, mail.TemplateInvoiceIssued, map[string]interface{}{
+		"OrganizationName": organization,
+		"InvoiceNumber":    number,
+		"Amount":           amount,
+		"Currency":         currency,
+		"IssueDate":        issueDate.Format("02 Jan 2006"),
+		"DueDate":          dueDate.Format("02 Jan 2006"),
+		"LoginURL":         s.link("/login"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, email, first_name, role FROM users
+		WHERE tenant_id = $1 AND role = 'admin' AND is_active = true AND deleted_at IS NULL`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization admins: %w", err)
+	}
+	admins, err := scanRecipients(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization admins: %w", err)
+	}
+
+	var notifications []models.Notification
+	for _, admin := range admins {
+		n, err := addToInbox(ctx, q, tenantID, admin.userID, inboxItem{
+			category:     models.NotificationCategoryBilling,
+			kind:         models.NotificationInvoiceIssued,
+			title:        fmt.Sprintf("Invoice %s issued", number),
+			body:         fmt.Sprintf("%s %s due on %s", amount, currency, dueDate.Format("02 Jan 2006")),
+			resourceType: "invoice",
+			resourceID:   &invoiceID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifications = appendNotification(notifications, n)
+	}
+	return notifications, nil
+}
+
+// QueueRegularizationSubmitted notifies the approvers of an attendance regularization request:
+// its approver user, or every active user of its approver role
+func (s *NotificationService) QueueRegularizationSubmitted(ctx context.Context, q dbExecutor, tenantID, requestID uuid.UUID) ([]models.Notification, error) {
+	var employeeName, kind string
+	var day time.Time
+	var requesterID uuid.NullUUID
+	err := q.QueryRowContext(ctx, `
+		SELECT u.first_name || ' ' || u.last_name, e.user_id, r.date, r.type
+		FROM attendance_regularization_requests r
+		JOIN employees e ON e.id = r.employee_id
+		JOIN users u ON u.id = e.user_id
+		WHERE r.id = $1 AND r.tenant_id = $2`, requestID, tenantID).Scan(&employeeName, &requesterID, &day, &kind)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("regularization request not found")
+		}
+		return nil, fmt.Errorf("failed to get regularization request: %w", err)
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT u.id, u.email, u.first_name, u.role
+		FROM attendance_regularization_requests r
+		JOIN users u ON u.tenant_id = r.tenant_id
+		            AND (u.id = r.approver_user_id OR (r.approver_user_id IS NULL AND u.role = r.approver_role))
+		WHERE r.id = $1 AND u.is_active = true AND u.deleted_at IS NULL
+		  AND ($2::UUID IS NULL OR u.id <> $2)`, requestID, requesterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get regularization approvers: %w", err)
+	}
+	approvers, err := scanRecipients(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get regularization approvers: %w", err)
+	}
+
+	var notifications []models.Notification
+	for _, a := range approvers {
+		n, err := addToInbox(ctx, q, tenantID, a.userID, inboxItem{
+			category:     models.NotificationCategoryAttendance,
+			kind:         models.NotificationRegularizationSubmitted,
+			title:        fmt.Sprintf("Attendance correction from %s", employeeName),
+			body:         fmt.Sprintf("%s for %s", strings.ReplaceAll(kind, "_", " "), day.Format("02 Jan 2006")),
+			link:         attendanceReviewPath(a.role),
+			resourceType: "attendance_regularization",
+			resourceID:   &requestID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifications = appendNotification(notifications, n)
+	}
+	return notifications, nil
+}
+
+// QueueRegularizationDecision tells an employee that their regularization request was
+// approved or rejected
+func (s *NotificationService) QueueRegularizationDecision(ctx context.Context, q dbExecutor, tenantID, requestID uuid.UUID, approved bool, comment string) ([]models.Notification, error) {
+	var userID uuid.NullUUID
+	var day time.Time
+	err := q.QueryRowContext(ctx, `
+		SELECT e.user_id, r.date
+		FROM attendance_regularization_requests r
+		JOIN employees e ON e.id = r.employee_id
+		WHERE r.id = $1 AND r.tenant_id = $2`, requestID, tenantID).Scan(&userID, &day)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("regularization request not found")
+		}
+		return nil, fmt.Errorf("failed to get regularization request: %w", err)
+	}
+	if !userID.Valid {
+		return nil, nil
+	}
+
+	item := inboxItem{
+		category:     models.NotificationCategoryAttendance,
+		kind:         models.NotificationRegularizationRejected,
+		title:        "Attendance correction rejected",
+		body:         fmt.Sprintf("Your correction for %s was rejected", day.Format("02 Jan 2006")),
+		link:         "/employee/attendance",
+		resourceType: "attendance_regularization",
+		resourceID:   &requestID,
+	}
+	if approved {
+		item.kind = models.NotificationRegularizationApproved
+		item.title = "Attendance correction approved"
+		item.body = fmt.Sprintf("Your correction for %s was approved", day.Format("02 Jan 2006"))
+	}
+	if comment = strings.TrimSpace(comment); comment != "" {
+		item.body += ": " + comment
+	}
+
+	n, err := addToInbox(ctx, q, tenantID, userID.UUID, item)
+	if err != nil {
+		return nil, err
+	}
+	return appendNotification(nil, n), nil
+}
+
+// NotifyNewDeviceLogin tells a user their account was signed in to from a device they had not
+// used before, by email and in their inbox. It runs after the sign-in committed, so it uses
+// its own transaction.
+func (s *NotificationService) NotifyNewDeviceLogin(ctx context.Context, user *auth.User, session *auth.Session) error {
+	tenantID, err := userTenantID(user)
+	if err != nil {
+		return err
+	}
+	userID, err := uuid.Parse(user.ID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	location, ipAddress := "", ""
+	if session.Location != nil {
+		location = *session.Location
+	}
+	if session.IPAddress != nil {
+		ipAddress = *session.IPAddress
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = s.queueEmail(ctx, tx, tenantID, user.Email, mail.TemplateNewDeviceLogin, map[string]interface{}{
+		"Name":        user.FirstName,
+		"Device":      session.DeviceName,
+		"Time":        session.CreatedAt.UTC().Format("02 Jan 2006 15:04 MST"),
+		"Location":    location,
+		"IPAddress":   ipAddress,
+		"SessionsURL": s.link(securitySettingsPath(user.Role)),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Users without an organization have no inbox
+	var notifications []models.Notification
+	if tenantID != nil {
+		body := fmt.Sprintf("%s signed in to your account", session.DeviceName)
+		if location != "" {
+			body += " from " + location
+		}
+		sessionID, _ := uuid.Parse(session.ID)
+		n, err := addToInbox(ctx, tx, *tenantID, userID, inboxItem{
+			category:     models.NotificationCategorySecurity,
+			kind:         models.NotificationNewDeviceLogin,
+			title:        "New sign-in from an unrecognized device",
+			body:         body,
+			link:         securitySettingsPath(user.Role),
+			resourceType: "session",
+			resourceID:   &sessionID,
+		})
+		if err != nil {
+			return err
+		}
+		notifications = appendNotification(notifications, n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Publish(notifications)
+	return nil
+}
+
+// recipient is a user a notification is addressed to
+type recipient struct {
+	userID uuid.UUID
+	email  string
+	name   string
+	role   string
+}
+
+// scanRecipients reads rows of user ID, email, first name and role, and closes them
+func scanRecipients(rows *sql.Rows) ([]recipient, error) {
+	defer rows.Close()
+	var recipients []recipient
+	for rows.Next() {
+		var r recipient
+		if err := rows.Scan(&r.userID, &r.email, &r.name, &r.role); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, rows.Err()
+}
+
+// appendNotification appends n unless the recipient turned its category off
+func appendNotification(notifications []models.Notification, n *models.Notification) []models.Notification {
+	if n == nil {
+		return notifications
+	}
+	return append(notifications, *n)
+}
+
+// outboxEmail is an email claimed for delivery
+type outboxEmail struct {
+	id          uuid.UUID
+	template    string
+	msg         mail.Message
+	attempts    int
+	maxAttempts int
+}
+
+// DeliverPending sends the emails that are due, batch at a time until none are left. Failed
+// emails are retried with a delay that doubles with each attempt; an email that failed
+// max_attempts times is marked failed.
+func (s *NotificationService) DeliverPending(ctx context.Context, batch int) (sent, failed int, err error) {
+	// Emails claimed by a delivery run that died after the last attempt have nothing left to try
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE email_outbox
+		SET status = 'failed', last_error = COALESCE(last_error, 'delivery attempt did not complete'), updated_at = NOW()
+		WHERE status = 'sending' AND next_attempt_at <= NOW() AND attempts >= max_attempts`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to expire email deliveries: %w", err)
+	}
+
+	for ctx.Err() == nil {
+		emails, err := s.claimEmails(ctx, batch)
+		if err != nil {
+			return sent, failed, err
+		}
+
+		for _, email := range emails {
+			sendCtx, cancel := context.WithTimeout(ctx, emailSendTimeout)
+			sendErr := s.sender.Send(sendCtx, email.msg)
+			cancel()
+
+			if err := s.recordDelivery(ctx, email, sendErr); err != nil {
+				return sent, failed, err
+			}
+			if sendErr != nil {
+				log.Printf("Failed to send email %s to %s (attempt %d of %d): %v",
+					email.id, email.msg.To, email.attempts, email.maxAttempts, sendErr)
+				failed++
+			} else {
+				sent++
+			}
+		}
+
+		if len(emails) < batch {
+			break
+		}
+	}
+	return sent, failed, ctx.Err()
+}
+
+// claimEmails takes due emails for delivery. Each claim counts as an attempt and holds the
+// email for emailLease, so concurrent runs skip it and a lost run's emails are retried later.
+func (s *NotificationService) claimEmails(ctx context.Context, batch int) ([]outboxEmail, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE email_outbox
+		SET status = 'sending', attempts = attempts + 1, next_attempt_at = $2, updated_at = NOW()
+		WHERE id IN (
+		    SELECT id FROM email_outbox
+		    WHERE status IN ('pending', 'sending') AND next_attempt_at <= NOW() AND attempts < max_attempts
+		    ORDER BY next_attempt_at
+		    LIMIT $1
+		    FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, template, recipient, subject, body_text, COALESCE(body_html, ''), attempts, max_attempts`,
+		batch, time.Now().Add(emailLease))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim emails: %w", err)
+	}
+	defer rows.Close()
+
+	var emails []outboxEmail
+	for rows.Next() {
+		var e outboxEmail
+		err := rows.Scan(&e.id, &e.template, &e.msg.To, &e.msg.Subject, &e.msg.Text, &e.msg.HTML,
+			&e.attempts, &e.maxAttempts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan email: %w", err)
+		}
+		emails = append(emails, e)
+	}
+	return emails, rows.Err()
+}
+
+// deliveryOutcome decides what becomes of an email after a delivery attempt: it is sent,
+// pending another attempt at nextAttempt, or failed for good once it used all its attempts
+func deliveryOutcome(email outboxEmail, sendErr error, now time.Time) (status string, nextAttempt time.Time) {
+	switch {
+	case sendErr == nil:
+		return "sent", time.Time{}
+	case email.attempts >= email.maxAttempts:
+		return "failed", time.Time{}
+	default:
+		return "pending", now.Add(emailRetryDelay(email.attempts))
+	}
+}
+
+// recordDelivery stores the outcome of a delivery attempt
+func (s *NotificationService) recordDelivery(ctx context.Context, email outboxEmail, sendErr error) error {
+	status, nextAttempt := deliveryOutcome(email, sendErr, time.Now())
+
+	var err error
+	switch status {
+	case "sent":
+		_, err = s.db.ExecContext(ctx, `
+			UPDATE email_outbox
+			SET status = 'sent', sent_at = NOW(), last_error = NULL, updated_at = NOW()
+			WHERE id = $1`, email.id)
+
+	case "failed":
+		_, err = s.db.ExecContext(ctx, `
+			UPDATE email_outbox SET status = 'failed', last_error = $2, updated_at = NOW()
+			WHERE id = $1`, email.id, sendErr.Error())
+
+	default:
+		_, err = s.db.ExecContext(ctx, `
+			UPDATE email_outbox SET status = 'pending', next_attempt_at = $2, last_error = $3, updated_at = NOW()
+			WHERE id = $1`, email.id, nextAttempt, sendErr.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record email delivery: %w", err)
+	}
+
+	if sensitiveEmailTemplates[email.template] && status != "pending" {
+		_, err = s.db.ExecContext(ctx,
+			"UPDATE email_outbox SET body_text = '', body_html = NULL WHERE id = $1", email.id)
+		if err != nil {
+			return fmt.Errorf("failed to clear email body: %w", err)
+		}
+	}
+	return nil
+}
+
+// emailRetryDelay is the wait after a failed attempt: a minute after the first, doubling up to
+// emailMaxRetryDelay
+func emailRetryDelay(attempts int) time.Duration {
+	delay := time.Minute
+	for i := 1; i < attempts && delay < emailMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > emailMaxRetryDelay {
+		delay = emailMaxRetryDelay
+	}
+	return delay
+}
+
+// leaveReviewPath is the frontend page where users of a role review leave requests
+func leaveReviewPath(role string) string {
+	switch role {
+	case "admin":
+		return "/admin/leaves"
+	case "hr":
+		return "/hr/leaves"
+	default:
+		return "/manager/leaves"
+	}
+}
+
+// attendanceReviewPath is the frontend page where users of a role review attendance
+func attendanceReviewPath(role string) string {
+	switch role {
+	case "admin":
+		return "/admin/attendance"
+	case "hr":
+		return "/hr/attendance"
+	default:
+		return "/manager/attendance"
+	}
+}
+
+// securitySettingsPath is the frontend page where users of a role manage their sessions
+func securitySettingsPath(role string) string {
+	switch role {
+	case "super_admin":
+		return "/super-admin/settings"
+	case "admin":
+		return "/admin/settings"
+	default:
+		return "/employee/settings"
+	}
+}
+
+// formatEmailDuration writes a duration in words, such as "1 hour" or "30 minutes"
+func formatEmailDuration(d time.Duration) string {
+	unit, n := "minute", int(d.Minutes())
+	if d >= time.Hour && d%time.Hour == 0 {
+		unit, n = "hour", int(d.Hours())
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
@@ -405,6 +405,7 @@ func (s *OrganizationService) GetOrganizationByID(ctx context.Context, tenantID
 			t.id, t.name, t.subdomain, t.domain, t.status, t.country, t.admin_email,
 			t.storage_used_mb, t.api_requests_count, t.last_activity_at, t.settings,
 			t.created_at, t.updated_at,
+			t.default_ip_restrictions, t.default_session_timeout, t.default_password_expiry_days,
 			od.admin_name, od.contact_number, od.website, od.address_line1, od.address_line2,
 			od.city, od.state, od.postal_code, od.currency, od.timezone,
 			s.id as sub_id, s.plan_id, s.status as sub_status, s.billing_cycle, s.amount,
@@ -420,6 +421,7 @@ func (s *OrganizationService) GetOrganizationByID(ctx context.Context, tenantID
 	tenant := &models.Tenant{
 		OrganizationDetail: &models.OrganizationDetail{},
 		Subscription:       &models.Subscription{Plan: &models.SubscriptionPlan{}},
+		SecurityDefaults:   &models.SecurityDefaults{},
 	}
 
 	var subID, planID *uuid.UUID
@@ -432,6 +434,8 @@ func (s *OrganizationService) GetOrganizationByID(ctx context.Context, tenantID
 		&tenant.ID, &tenant.Name, &tenant.Subdomain, &tenant.Domain, &tenant.Status,
 		&tenant.Country, &tenant.AdminEmail, &tenant.StorageUsedMB, &tenant.APIRequestsCount,
 		&tenant.LastActivityAt, &settingsBytes, &tenant.CreatedAt, &tenant.UpdatedAt,
+		&tenant.SecurityDefaults.IPRestrictions, &tenant.SecurityDefaults.SessionTimeout,
+		&tenant.SecurityDefaults.PasswordExpiryDays,
 		&tenant.OrganizationDetail.AdminName, &tenant.OrganizationDetail.ContactNumber,
 		&tenant.OrganizationDetail.Website, &tenant.OrganizationDetail.AddressLine1,
 		&tenant.OrganizationDetail.AddressLine2, &tenant.OrganizationDetail.City,
@@ -543,14 +547,46 @@ func (s *OrganizationService) GetAllOrganizations(ctx context.Context, filters m
 	return tenants, nil
 }
 
+// GetActiveTenantIDs returns the IDs of all active, non-deleted organizations
+func (s *OrganizationService) GetActiveTenantIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id FROM tenants WHERE status = 'active' AND deleted_at IS NULL ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active organizations: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 // BlockOrganization blocks/suspends an organization
 func (s *OrganizationService) BlockOrganization(ctx context.Context, tenantID uuid.UUID) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `UPDATE tenants SET status = 'suspended', updated_at = $1 WHERE id = $2`
-	_, err := s.db.ExecContext(ctx, query, time.Now(), tenantID)
+	_, err = tx.ExecContext(ctx, query, time.Now(), tenantID)
 	if err != nil {
 		return fmt.Errorf("failed to block organization: %w", err)
 	}
-	return nil
+
+	// Users of a blocked organization are signed out of every session
+	if err := auth.RevokeTenantRefreshTokens(ctx, tx, tenantID.String(), auth.RevokeReasonTenantBlocked); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // UnblockOrganization unblocks/activates an organization
@@ -782,6 +818,30 @@ func (s *OrganizationService) UpdateOrganization(ctx context.Context, tenantID u
 	return s.GetOrganizationByID(ctx, tenantID)
 }
 
+// UpdateSecurityDefaults sets the security policy of the organization. It applies to every user;
+// a user's own settings can only make it stricter. clientIP is the address of the admin making
+// the change, which new IP restrictions must allow.
+func (s *OrganizationService) UpdateSecurityDefaults(ctx context.Context, tenantID uuid.UUID, defaults models.SecurityDefaults, clientIP string) error {
+	restrictions, err := validateSecurityPolicy(defaults.IPRestrictions, defaults.SessionTimeout, defaults.PasswordExpiryDays, clientIP, nil)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE tenants
+		SET default_ip_restrictions = $2, default_session_timeout = $3,
+		    default_password_expiry_days = $4, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL`,
+		tenantID, restrictions, defaults.SessionTimeout, defaults.PasswordExpiryDays)
+	if err != nil {
+		return fmt.Errorf("failed to update security defaults: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("organization not found")
+	}
+	return nil
+}
+
 // Helper function to join strings
 func joinStringsOrg(parts []string, sep string) string {
 	if len(parts) == 0 {
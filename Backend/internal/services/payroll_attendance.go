@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// getPayrollAttendance collects the attendance and leave inputs of an employee's pay period.
+// Working days follow the attendance policy and the holiday calendar of the employee's location.
+// A working day is:
+//   - unpaid leave when approved leave of an unpaid type covers it (half a day for half-day leave)
+//   - paid when it is covered by paid leave or has an attendance record other than "absent"
+//   - paid and projected when it is still to come, since it cannot have been missed yet
+//   - an absence otherwise
+//
+// Working days before joining or after leaving are neither paid nor loss of pay. Only overtime
+// on approved attendance records counts. A period that has not started yet cannot be paid.
+func (s *PayslipService) getPayrollAttendance(ctx context.Context, tenantID, employeeID uuid.UUID, periodStart, periodEnd time.Time) (*PayrollAttendance, error) {
+	start := time.Date(periodStart.Year(), periodStart.Month(), periodStart.Day(), 0, 0, 0, 0, time.UTC)
+	end := time.Date(periodEnd.Year(), periodEnd.Month(), periodEnd.Day(), 0, 0, 0, 0, time.UTC)
+	if end.Before(start) {
+		return nil, fmt.Errorf("pay period end is before its start")
+	}
+
+	policy, loc, err := getEffectivePolicy(ctx, s.DB.DB, tenantID, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	today := localToday(loc, time.Now())
+	if start.After(today) {
+		return nil, errPayPeriodNotStarted
+	}
+
+	location, err := employeeWorkLocation(ctx, s.DB, tenantID, employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	holidays, err := holidayDates(ctx, s.DB, tenantID, location, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var joined, left sql.NullTime
+	err = s.DB.QueryRowContext(ctx, `
+		SELECT date_of_joining, date_of_leaving FROM employees
+		WHERE id = $1 AND tenant_id = $2`, employeeID, tenantID).Scan(&joined, &left)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("employee not found")
+		}
+		return nil, fmt.Errorf("failed to get employment dates: %w", err)
+	}
+
+	statuses, err := s.attendanceStatuses(ctx, tenantID, employeeID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	paidLeave, unpaidLeave, err := s.approvedLeaveDays(ctx, tenantID, employeeID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	attendance := &PayrollAttendance{HoursPerDay: policy.WorkingHoursPerDay}
+	err = s.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(overtime_hours), 0) FROM attendance_records
+		WHERE tenant_id = $1 AND employee_id = $2 AND date BETWEEN $3 AND $4 AND is_approved = true`,
+		tenantID, employeeID, start.Format("2006-01-02"), end.Format("2006-01-02")).Scan(&attendance.OvertimeHours)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overtime hours: %w", err)
+	}
+
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		if !containsWeekday(policy.WorkingDays, day.Weekday()) || holidays[key] {
+			continue
+		}
+		attendance.WorkingDays++
+
+		if joined.Valid && day.Before(dateOnly(joined.Time)) {
+			continue
+		}
+		if left.Valid && day.After(dateOnly(left.Time)) {
+			continue
+		}
+
+		if unpaid := unpaidLeave[key]; unpaid > 0 {
+			attendance.UnpaidLeaveDays += unpaid
+			attendance.PaidDays += 1 - unpaid
+			continue
+		}
+
+		status, recorded := statuses[key]
+		switch {
+		case paidLeave[key], recorded && status != "absent":
+			attendance.PaidDays++
+		case day.After(today):
+			attendance.PaidDays++
+			attendance.ProjectedDays++
+		default:
+			attendance.AbsentDays++
+		}
+	}
+
+	return attendance, nil
+}
+
+// errPayPeriodNotStarted is returned when payroll would be calculated for a period in the future
+var errPayPeriodNotStarted = errors.New("pay period has not started yet")
+
+// localToday returns the current date in the tenant's time zone, as a DATE column value
+func localToday(loc *time.Location, now time.Time) time.Time {
+	return dateOnly(now.In(loc))
+}
+
+// attendanceStatuses returns the attendance status of each recorded day in the period
+func (s *PayslipService) attendanceStatuses(ctx context.Context, tenantID, employeeID uuid.UUID, start, end time.Time) (map[string]string, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT date, status FROM attendance_records
+		WHERE tenant_id = $1 AND employee_id = $2 AND date BETWEEN $3 AND $4`,
+		tenantID, employeeID, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance records: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := make(map[string]string)
+	for rows.Next() {
+		var date time.Time
+		var status sql.NullString
+		if err := rows.Scan(&date, &status); err != nil {
+			return nil, fmt.Errorf("failed to scan attendance record: %w", err)
+		}
+		statuses[date.Format("2006-01-02")] = status.String
+	}
+	return statuses, rows.Err()
+}
+
+// approvedLeaveDays maps the days in the period covered by approved leave. Unpaid days carry
+// the unpaid portion of the day. A leave type is unpaid when its configuration says so or,
+// without a configuration, when it is the built-in unpaid type.
+func (s *PayslipService) approvedLeaveDays(ctx context.Context, tenantID, employeeID uuid.UUID, start, end time.Time) (map[string]bool, map[string]float64, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT lr.start_date, lr.end_date, lr.start_half_day, lr.end_half_day,
+			COALESCE((
+				SELECT lt.is_paid FROM leave_types lt
+				WHERE lt.tenant_id = lr.tenant_id AND lt.deleted_at IS NULL
+				  AND (LOWER(lt.short_code) = LOWER(lr.leave_type) OR LOWER(lt.name) = LOWER(lr.leave_type))
+				ORDER BY (LOWER(lt.short_code) = LOWER(lr.leave_type)) DESC NULLS LAST
+				LIMIT 1
+			), LOWER(lr.leave_type) <> $5) AS is_paid
+		FROM leave_requests lr
+		WHERE lr.tenant_id = $1 AND lr.employee_id = $2 AND lr.status = 'approved'
+		  AND lr.start_date <= $4 AND lr.end_date >= $3`,
+		tenantID, employeeID, start.Format("2006-01-02"), end.Format("2006-01-02"), "unpaid")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get approved leave: %w", err)
+	}
+	defer rows.Close()
+
+	paid := make(map[string]bool)
+	unpaid := make(map[string]float64)
+	for rows.Next() {
+		var leaveStart, leaveEnd time.Time
+		var startHalfDay, endHalfDay, isPaid bool
+		if err := rows.Scan(&leaveStart, &leaveEnd, &startHalfDay, &endHalfDay, &isPaid); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan approved leave: %w", err)
+		}
+
+		leaveStart, leaveEnd = dateOnly(leaveStart), dateOnly(leaveEnd)
+		for day := leaveStart; !day.After(leaveEnd); day = day.AddDate(0, 0, 1) {
+			key := day.Format("2006-01-02")
+			if isPaid {
+				paid[key] = true
+				continue
+			}
+			portion := 1.0
+			if (startHalfDay && day.Equal(leaveStart)) || (endHalfDay && day.Equal(leaveEnd)) {
+				portion = 0.5
+			}
+			unpaid[key] += portion
+			if unpaid[key] > 1 {
+				unpaid[key] = 1
+			}
+		}
+	}
+	return paid, unpaid, rows.Err()
+}
+
+// dateOnly strips the time and zone of a DATE column value
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocalToday(t *testing.T) {
+	ist := time.FixedZone("IST", 5*3600+1800)
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{name: "mid day", now: time.Date(2026, 3, 15, 12, 0, 0, 0, ist), want: time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "last minute of the day", now: time.Date(2026, 3, 31, 23, 59, 0, 0, ist), want: time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)},
+		// 20:00 UTC on the 31st is already the 1st in IST
+		{name: "tenant time zone decides", now: time.Date(2026, 3, 31, 20, 0, 0, 0, time.UTC), want: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := localToday(ist, tt.now); !got.Equal(tt.want) {
+				t.Errorf("localToday() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
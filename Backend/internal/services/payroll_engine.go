@@ -0,0 +1,594 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+// BasicComponentCode is the code of the component that carries the structure's basic salary
+const BasicComponentCode = "BASIC"
+
+// OvertimeComponentCode is the code of the component that pays approved overtime
+const OvertimeComponentCode = "OVERTIME"
+
+// Attendance inputs available to formulas alongside component codes
+var payrollVariables = map[string]bool{
+	"WORKING_DAYS":      true,
+	"PAID_DAYS":         true,
+	"LOP_DAYS":          true,
+	"UNPAID_LEAVE_DAYS": true,
+	"ABSENT_DAYS":       true,
+	"OVERTIME_HOURS":    true,
+	"HOURLY_RATE":       true,
+}
+
+// PayrollAttendance carries the attendance and leave inputs of a pay period. Loss-of-pay days are
+// the unpaid leave days plus the absences; working days outside the employment are simply unpaid.
+// Projected days are the paid working days that had not happened yet when payroll was calculated.
+type PayrollAttendance struct {
+	WorkingDays     float64
+	PaidDays        float64
+	ProjectedDays   float64
+	UnpaidLeaveDays float64
+	AbsentDays      float64
+	OvertimeHours   float64
+	HoursPerDay     float64
+}
+
+// LOPDays returns the loss-of-pay days of the period
+func (a *PayrollAttendance) LOPDays() float64 {
+	return a.UnpaidLeaveDays + a.AbsentDays
+}
+
+// prorationFactor returns the share of the period's working days that is paid
+func (a *PayrollAttendance) prorationFactor() float64 {
+	if a == nil || a.WorkingDays <= 0 {
+		return 1
+	}
+	return math.Min(math.Max(a.PaidDays/a.WorkingDays, 0), 1)
+}
+
+// PayrollResult is the outcome of evaluating an employee salary structure
+type PayrollResult struct {
+	GrossSalary     float64
+	TaxableEarnings float64
+	TotalDeductions float64
+	NetSalary       float64
+	Components      []models.PayslipComponent
+}
+
+// payrollRule is a salary structure component ready for evaluation
+type payrollRule struct {
+	component models.SalaryComponent
+	code      string
+	formula   *formula
+	prorate   bool
+}
+
+// CalculatePayroll evaluates a salary structure into payslip components. The basic salary is
+// booked against the basic component; every other component is evaluated in dependency order,
+// ties broken by calculation order, so formulas can reference any component by its code.
+// Amounts are rounded to two decimals and clamped to the component's min/max caps.
+//
+// With attendance inputs the basic salary and fixed earnings are prorated by paid days over
+// working days. Percentage and formula components follow from the prorated values they
+// reference, and formulas can use the inputs themselves (PAID_DAYS, OVERTIME_HOURS, ...).
+// Without attendance inputs the full structure is paid.
+// It has no side effects and does not touch the database.
+func CalculatePayroll(structure *models.EmployeeSalaryStructure, basic *models.SalaryComponent, attendance *PayrollAttendance) (*PayrollResult, error) {
+	if structure == nil {
+		return nil, fmt.Errorf("salary structure is required")
+	}
+	if basic == nil {
+		return nil, fmt.Errorf("basic salary component is required")
+	}
+
+	factor := attendance.prorationFactor()
+	basicSalary := roundAmount(structure.BasicSalary * factor)
+	values := map[string]float64{BasicComponentCode: basicSalary}
+	if attendance != nil {
+		values["WORKING_DAYS"] = attendance.WorkingDays
+		values["PAID_DAYS"] = attendance.PaidDays
+		values["LOP_DAYS"] = attendance.LOPDays()
+		values["UNPAID_LEAVE_DAYS"] = attendance.UnpaidLeaveDays
+		values["ABSENT_DAYS"] = attendance.AbsentDays
+		values["OVERTIME_HOURS"] = attendance.OvertimeHours
+		values["HOURLY_RATE"] = 0
+		if attendance.WorkingDays > 0 && attendance.HoursPerDay > 0 {
+			values["HOURLY_RATE"] = structure.BasicSalary / attendance.WorkingDays / attendance.HoursPerDay
+		}
+	}
+
+	result := &PayrollResult{}
+	result.add(*basic, BasicComponentCode, basicSalary)
+
+	rules, err := payrollRules(structure)
+	if err != nil {
+		return nil, err
+	}
+
+	ordered, err := orderPayrollRules(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rule := range ordered {
+		amount, err := rule.formula.eval(values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate %s: %w", rule.component.Name, err)
+		}
+		if rule.prorate {
+			amount *= factor
+		}
+		if rule.component.MinAmount != nil && amount < *rule.component.MinAmount {
+			amount = *rule.component.MinAmount
+		}
+		if rule.component.MaxAmount != nil && amount > *rule.component.MaxAmount {
+			amount = *rule.component.MaxAmount
+		}
+		amount = roundAmount(math.Max(amount, 0))
+
+		if rule.code != "" {
+			values[rule.code] = amount
+		}
+		result.add(rule.component, rule.code, amount)
+	}
+
+	result.GrossSalary = roundAmount(result.GrossSalary)
+	result.TaxableEarnings = roundAmount(result.TaxableEarnings)
+	result.TotalDeductions = roundAmount(result.TotalDeductions)
+	result.NetSalary = roundAmount(result.GrossSalary - result.TotalDeductions)
+
+	return result, nil
+}
+
+func (r *PayrollResult) add(component models.SalaryComponent, code string, amount float64) {
+	line := models.PayslipComponent{
+		ComponentID:   component.ID,
+		ComponentName: component.Name,
+		ComponentType: component.Type,
+		Amount:        amount,
+		IsTaxable:     component.IsTaxable,
+	}
+	if code != "" {
+		line.ComponentCode = &code
+	}
+	r.Components = append(r.Components, line)
+
+	switch component.Type {
+	case "earning":
+		r.GrossSalary += amount
+		if component.IsTaxable {
+			r.TaxableEarnings += amount
+		}
+	case "deduction":
+		r.TotalDeductions += amount
+	}
+}
+
+// payrollRules resolves how each structure component is calculated. An amount or formula set
+// on the structure overrides the component default; percentages are taken of basic salary.
+func payrollRules(structure *models.EmployeeSalaryStructure) ([]payrollRule, error) {
+	var rules []payrollRule
+	codes := make(map[string]bool)
+
+	for _, structComp := range structure.Components {
+		if structComp.Component == nil {
+			continue
+		}
+		component := *structComp.Component
+		code := strings.ToUpper(strings.TrimSpace(component.Code))
+		if code == BasicComponentCode {
+			continue
+		}
+		if payrollVariables[code] {
+			return nil, fmt.Errorf("salary component code %s is reserved", code)
+		}
+		if code != "" {
+			if codes[code] {
+				return nil, fmt.Errorf("duplicate salary component code %s", code)
+			}
+			codes[code] = true
+		}
+
+		var f *formula
+		var err error
+		constant := false
+		switch {
+		case structComp.Formula != nil && strings.TrimSpace(*structComp.Formula) != "":
+			f, err = parseFormula(*structComp.Formula)
+		case structComp.Amount != nil:
+			f, constant = constantFormula(*structComp.Amount), true
+		case structComp.Percentage != nil:
+			f = percentOfBasicFormula(*structComp.Percentage)
+		case strings.TrimSpace(component.Formula) != "":
+			f, err = parseFormula(component.Formula)
+		case component.IsPercentage:
+			f = percentOfBasicFormula(component.Percentage)
+		default:
+			f, constant = constantFormula(component.Amount), true
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid formula for %s: %w", component.Name, err)
+		}
+
+		rules = append(rules, payrollRule{
+			component: component,
+			code:      code,
+			formula:   f,
+			prorate:   constant && component.Type == "earning",
+		})
+	}
+
+	for _, rule := range rules {
+		for _, ref := range rule.formula.refs {
+			if ref != BasicComponentCode && !codes[ref] && !payrollVariables[ref] {
+				return nil, fmt.Errorf("%s references unknown component %s", rule.component.Name, ref)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+// orderPayrollRules sorts rules so every component comes after the components its formula
+// references. Among components whose references are satisfied, the lowest calculation order
+// goes first, then the name.
+func orderPayrollRules(rules []payrollRule) ([]payrollRule, error) {
+	pending := make([]payrollRule, len(rules))
+	copy(pending, rules)
+	sort.SliceStable(pending, func(i, j int) bool {
+		if pending[i].component.CalculationOrder != pending[j].component.CalculationOrder {
+			return pending[i].component.CalculationOrder < pending[j].component.CalculationOrder
+		}
+		return pending[i].component.Name < pending[j].component.Name
+	})
+
+	unresolved := make(map[string]bool)
+	for _, rule := range pending {
+		if rule.code != "" {
+			unresolved[rule.code] = true
+		}
+	}
+
+	ordered := make([]payrollRule, 0, len(pending))
+	for len(pending) > 0 {
+		next := -1
+		for i, rule := range pending {
+			ready := true
+			for _, ref := range rule.formula.refs {
+				if unresolved[ref] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			names := make([]string, 0, len(pending))
+			for _, rule := range pending {
+				names = append(names, rule.component.Name)
+			}
+			return nil, fmt.Errorf("circular reference between salary components: %s", strings.Join(names, ", "))
+		}
+
+		rule := pending[next]
+		ordered = append(ordered, rule)
+		delete(unresolved, rule.code)
+		pending = append(pending[:next], pending[next+1:]...)
+	}
+
+	return ordered, nil
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// formula is a parsed component formula. Formulas are arithmetic expressions over numbers,
+// percentages (40%), component codes and the functions min, max and round, e.g.
+// "min(12% * BASIC, 1800)".
+type formula struct {
+	root formulaNode
+	refs []string
+}
+
+func (f *formula) eval(values map[string]float64) (float64, error) {
+	return f.root.eval(values)
+}
+
+func constantFormula(value float64) *formula {
+	return &formula{root: numberNode(value)}
+}
+
+func percentOfBasicFormula(percentage float64) *formula {
+	return &formula{
+		root: binaryNode{op: '*', left: refNode(BasicComponentCode), right: numberNode(percentage / 100)},
+		refs: []string{BasicComponentCode},
+	}
+}
+
+type formulaNode interface {
+	eval(values map[string]float64) (float64, error)
+}
+
+type numberNode float64
+
+func (n numberNode) eval(map[string]float64) (float64, error) {
+	return float64(n), nil
+}
+
+type refNode string
+
+func (n refNode) eval(values map[string]float64) (float64, error) {
+	value, ok := values[string(n)]
+	if !ok {
+		return 0, fmt.Errorf("%s is not available", string(n))
+	}
+	return value, nil
+}
+
+type negateNode struct {
+	operand formulaNode
+}
+
+func (n negateNode) eval(values map[string]float64) (float64, error) {
+	value, err := n.operand.eval(values)
+	return -value, err
+}
+
+type binaryNode struct {
+	op          byte
+	left, right formulaNode
+}
+
+func (n binaryNode) eval(values map[string]float64) (float64, error) {
+	left, err := n.left.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	right, err := n.right.eval(values)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case '+':
+		return left + right, nil
+	case '-':
+		return left - right, nil
+	case '*':
+		return left * right, nil
+	case '/':
+		if right == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return left / right, nil
+	}
+	return 0, fmt.Errorf("unknown operator %c", n.op)
+}
+
+type callNode struct {
+	name string
+	args []formulaNode
+}
+
+func (n callNode) eval(values map[string]float64) (float64, error) {
+	args := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		value, err := arg.eval(values)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = value
+	}
+
+	switch n.name {
+	case "min":
+		result := args[0]
+		for _, value := range args[1:] {
+			result = math.Min(result, value)
+		}
+		return result, nil
+	case "max":
+		result := args[0]
+		for _, value := range args[1:] {
+			result = math.Max(result, value)
+		}
+		return result, nil
+	case "round":
+		digits := 0.0
+		if len(args) == 2 {
+			digits = args[1]
+		}
+		scale := math.Pow(10, digits)
+		return math.Round(args[0]*scale) / scale, nil
+	}
+	return 0, fmt.Errorf("unknown function %s", n.name)
+}
+
+// formulaParser is a recursive descent parser over the grammar
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/") unary }
+//	unary   = "-" unary | primary
+//	primary = number [ "%" ] | code | function "(" expr { "," expr } ")" | "(" expr ")"
+type formulaParser struct {
+	src  string
+	pos  int
+	refs []string
+}
+
+// parseFormula parses a component formula, returning the component codes it references
+func parseFormula(src string) (*formula, error) {
+	p := &formulaParser{src: src}
+	if p.peek() == 0 {
+		return nil, fmt.Errorf("formula is empty")
+	}
+
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != 0 {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.src[p.pos], p.pos+1)
+	}
+
+	return &formula{root: root, refs: p.refs}, nil
+}
+
+// peek skips whitespace and returns the next character, or 0 at the end of the formula
+func (p *formulaParser) peek() byte {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *formulaParser) parseExpr() (formulaNode, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *formulaParser) parseTerm() (formulaNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *formulaParser) parseUnary() (formulaNode, error) {
+	if p.peek() == '-' {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negateNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *formulaParser) parsePrimary() (formulaNode, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of formula")
+	case c == '(':
+		p.pos++
+		node, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return node, nil
+	case c == '.' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	case c == '_' || unicode.IsLetter(rune(c)):
+		return p.parseIdentifier()
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", c, p.pos+1)
+}
+
+func (p *formulaParser) parseNumber() (formulaNode, error) {
+	start := p.pos
+	for p.pos < len(p.src) && (p.src[p.pos] == '.' || (p.src[p.pos] >= '0' && p.src[p.pos] <= '9')) {
+		p.pos++
+	}
+	value, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", p.src[start:p.pos])
+	}
+	if p.peek() == '%' {
+		p.pos++
+		value /= 100
+	}
+	return numberNode(value), nil
+}
+
+func (p *formulaParser) parseIdentifier() (formulaNode, error) {
+	start := p.pos
+	for p.pos < len(p.src) && (p.src[p.pos] == '_' || unicode.IsLetter(rune(p.src[p.pos])) || unicode.IsDigit(rune(p.src[p.pos]))) {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+
+	if p.peek() != '(' {
+		code := strings.ToUpper(name)
+		p.refs = append(p.refs, code)
+		return refNode(code), nil
+	}
+
+	function := strings.ToLower(name)
+	if function != "min" && function != "max" && function != "round" {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	p.pos++
+
+	var args []formulaNode
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	if p.peek() != ')' {
+		return nil, fmt.Errorf("missing closing parenthesis after %s arguments", name)
+	}
+	p.pos++
+
+	switch {
+	case function == "round" && len(args) > 2:
+		return nil, fmt.Errorf("round takes a value and optional digits")
+	case function != "round" && len(args) < 2:
+		return nil, fmt.Errorf("%s needs at least two arguments", function)
+	}
+
+	return callNode{name: function, args: args}, nil
+}
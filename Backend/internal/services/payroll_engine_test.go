@@ -0,0 +1,261 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+// testComponent is a structure component with a code and a formula, as the formula editor saves it
+type testComponent struct {
+	code, name, typ, formula string
+	order                    int
+	min, max                 *float64
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func testStructure(basicSalary float64, components ...testComponent) *models.EmployeeSalaryStructure {
+	structure := &models.EmployeeSalaryStructure{BasicSalary: basicSalary}
+	for _, c := range components {
+		typ := c.typ
+		if typ == "" {
+			typ = "earning"
+		}
+		name := c.name
+		if name == "" {
+			name = c.code
+		}
+		structure.Components = append(structure.Components, models.SalaryStructureComponent{
+			Component: &models.SalaryComponent{
+				Name:             name,
+				Code:             c.code,
+				Type:             typ,
+				Formula:          c.formula,
+				CalculationOrder: c.order,
+				MinAmount:        c.min,
+				MaxAmount:        c.max,
+				IsTaxable:        true,
+			},
+		})
+	}
+	return structure
+}
+
+func componentAmounts(result *PayrollResult) map[string]float64 {
+	amounts := make(map[string]float64)
+	for _, c := range result.Components {
+		amounts[c.ComponentName] = c.Amount
+	}
+	return amounts
+}
+
+func componentNames(result *PayrollResult) []string {
+	names := make([]string, 0, len(result.Components))
+	for _, c := range result.Components {
+		names = append(names, c.ComponentName)
+	}
+	return names
+}
+
+var testBasic = &models.SalaryComponent{Name: "Basic", Code: BasicComponentCode, Type: "earning", IsTaxable: true}
+
+func TestCalculatePayroll(t *testing.T) {
+	tests := []struct {
+		name       string
+		structure  *models.EmployeeSalaryStructure
+		attendance *PayrollAttendance
+		amounts    map[string]float64
+		order      []string
+		gross, net float64
+	}{
+		{
+			name:      "HRA is 40% of basic",
+			structure: testStructure(50000, testComponent{code: "HRA", formula: "40% * BASIC"}),
+			amounts:   map[string]float64{"Basic": 50000, "HRA": 20000},
+			gross:     70000,
+			net:       70000,
+		},
+		{
+			name: "PF is capped at its maximum",
+			structure: testStructure(50000,
+				testComponent{code: "PF", typ: "deduction", formula: "12% * BASIC", max: floatPtr(1800)}),
+			amounts: map[string]float64{"PF": 1800},
+			gross:   50000,
+			net:     48200,
+		},
+		{
+			name: "minimum lifts a small amount",
+			structure: testStructure(10000,
+				testComponent{code: "CONV", formula: "BASIC * 0.01", min: floatPtr(500)}),
+			amounts: map[string]float64{"CONV": 500},
+			gross:   10500,
+			net:     10500,
+		},
+		{
+			name: "components follow the components they reference",
+			structure: testStructure(40000,
+				testComponent{code: "GROSS_BONUS", formula: "round((BASIC + HRA + SPECIAL) * 10%)", order: 1},
+				testComponent{code: "SPECIAL", formula: "HRA / 2", order: 2},
+				testComponent{code: "HRA", formula: "BASIC * 40%", order: 3}),
+			amounts: map[string]float64{"HRA": 16000, "SPECIAL": 8000, "GROSS_BONUS": 6400},
+			order:   []string{"Basic", "HRA", "SPECIAL", "GROSS_BONUS"},
+			gross:   70400,
+			net:     70400,
+		},
+		{
+			name: "calculation order breaks ties",
+			structure: testStructure(1000,
+				testComponent{code: "B", formula: "10", order: 2},
+				testComponent{code: "A", formula: "20", order: 1}),
+			order: []string{"Basic", "A", "B"},
+			gross: 1030,
+			net:   1030,
+		},
+		{
+			name: "attendance prorates basic and formulas follow",
+			structure: testStructure(30000,
+				testComponent{code: "HRA", formula: "BASIC * 40%"},
+				testComponent{code: "LOP_NOTE", formula: "LOP_DAYS * 0"}),
+			attendance: &PayrollAttendance{WorkingDays: 20, PaidDays: 15, UnpaidLeaveDays: 5},
+			amounts:    map[string]float64{"Basic": 22500, "HRA": 9000},
+			gross:      31500,
+			net:        31500,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := CalculatePayroll(tt.structure, testBasic, tt.attendance)
+			if err != nil {
+				t.Fatalf("CalculatePayroll() error = %v", err)
+			}
+			amounts := componentAmounts(result)
+			for name, want := range tt.amounts {
+				if amounts[name] != want {
+					t.Errorf("%s = %v, want %v", name, amounts[name], want)
+				}
+			}
+			if tt.order != nil {
+				if got := strings.Join(componentNames(result), ","); got != strings.Join(tt.order, ",") {
+					t.Errorf("order = %s, want %s", got, strings.Join(tt.order, ","))
+				}
+			}
+			if result.GrossSalary != tt.gross {
+				t.Errorf("GrossSalary = %v, want %v", result.GrossSalary, tt.gross)
+			}
+			if result.NetSalary != tt.net {
+				t.Errorf("NetSalary = %v, want %v", result.NetSalary, tt.net)
+			}
+		})
+	}
+}
+
+func TestCalculatePayrollErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		structure *models.EmployeeSalaryStructure
+		wantErr   string
+	}{
+		{
+			name: "cyclic formulas",
+			structure: testStructure(1000,
+				testComponent{code: "A", formula: "B + 1"},
+				testComponent{code: "B", formula: "C + 1"},
+				testComponent{code: "C", formula: "A + 1"}),
+			wantErr: "circular reference",
+		},
+		{
+			name:      "self reference",
+			structure: testStructure(1000, testComponent{code: "A", formula: "A * 2"}),
+			wantErr:   "circular reference",
+		},
+		{
+			name:      "unknown code",
+			structure: testStructure(1000, testComponent{code: "HRA", formula: "BASIK * 40%"}),
+			wantErr:   "unknown component BASIK",
+		},
+		{
+			name:      "divide by zero",
+			structure: testStructure(1000, testComponent{code: "A", formula: "BASIC / (LOP_DAYS - LOP_DAYS)"}),
+			wantErr:   "division by zero",
+		},
+		{
+			name:      "malformed expression",
+			structure: testStructure(1000, testComponent{code: "A", formula: "BASIC * (40% +"}),
+			wantErr:   "invalid formula",
+		},
+		{
+			name:      "reserved code",
+			structure: testStructure(1000, testComponent{code: "PAID_DAYS", formula: "1"}),
+			wantErr:   "reserved",
+		},
+		{
+			name: "duplicate code",
+			structure: testStructure(1000,
+				testComponent{code: "A", name: "First", formula: "1"},
+				testComponent{code: "a", name: "Second", formula: "2"}),
+			wantErr: "duplicate salary component code A",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CalculatePayroll(tt.structure, testBasic, &PayrollAttendance{WorkingDays: 20, PaidDays: 20})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("CalculatePayroll() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseFormula(t *testing.T) {
+	values := map[string]float64{"BASIC": 1000, "HRA": 400}
+	tests := []struct {
+		src     string
+		want    float64
+		wantErr bool
+	}{
+		{src: "40% * BASIC", want: 400},
+		{src: "BASIC * 12.5%", want: 125},
+		{src: "min(12% * BASIC, 100)", want: 100},
+		{src: "max(BASIC - HRA, 700, 650)", want: 700},
+		{src: "round(BASIC / 3, 2)", want: 333.33},
+		{src: "-HRA + 2 * (3 + 4)", want: -386},
+		{src: "basic + hra", want: 1400},
+		{src: "", wantErr: true},
+		{src: "BASIC +", wantErr: true},
+		{src: "(BASIC", wantErr: true},
+		{src: "BASIC HRA", wantErr: true},
+		{src: "1.2.3", wantErr: true},
+		{src: "pow(BASIC, 2)", wantErr: true},
+		{src: "min(BASIC)", wantErr: true},
+		{src: "round(BASIC, 1, 2)", wantErr: true},
+		{src: "BASIC $ 2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			f, err := parseFormula(tt.src)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseFormula(%q) succeeded, want an error", tt.src)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFormula(%q) error = %v", tt.src, err)
+			}
+			got, err := f.eval(values)
+			if err != nil {
+				t.Fatalf("eval(%q) error = %v", tt.src, err)
+			}
+			if got != tt.want {
+				t.Errorf("eval(%q) = %v, want %v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
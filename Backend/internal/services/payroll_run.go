@@ -0,0 +1,517 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+// errPayrollRunLocked is returned when a run that is no longer in draft would be regenerated
+var errPayrollRunLocked = errors.New("payroll run is locked, reopen it before re-running")
+
+const payrollRunColumns = `pr.id, pr.tenant_id, pr.period_year, pr.period_month, pr.period_start, pr.period_end,
+		pr.payment_date, pr.status, pr.notes, pr.created_by, pr.locked_at, pr.locked_by,
+		pr.published_at, pr.published_by, pr.reopened_at, pr.reopened_by, pr.reopen_reason,
+		pr.created_at, pr.updated_at,
+		COALESCE(ps.payslips, 0), COALESCE(pe.errors, 0),
+		COALESCE(ps.gross, 0), COALESCE(ps.deductions, 0), COALESCE(ps.net, 0)`
+
+const payrollRunJoins = `
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS payslips, SUM(gross_salary) AS gross,
+			       SUM(total_deductions) AS deductions, SUM(net_salary) AS net
+			FROM payslips WHERE payroll_run_id = pr.id
+		) ps ON true
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS errors FROM payroll_run_errors WHERE payroll_run_id = pr.id
+		) pe ON true`
+
+func scanPayrollRun(scan func(dest ...interface{}) error) (*models.PayrollRun, error) {
+	var run models.PayrollRun
+	err := scan(
+		&run.ID, &run.TenantID, &run.PeriodYear, &run.PeriodMonth, &run.PeriodStart, &run.PeriodEnd,
+		&run.PaymentDate, &run.Status, &run.Notes, &run.CreatedBy, &run.LockedAt, &run.LockedBy,
+		&run.PublishedAt, &run.PublishedBy, &run.ReopenedAt, &run.ReopenedBy, &run.ReopenReason,
+		&run.CreatedAt, &run.UpdatedAt,
+		&run.PayslipCount, &run.ErrorCount,
+		&run.TotalGross, &run.TotalDeductions, &run.TotalNet,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetPayrollRuns lists the payroll runs of a tenant, optionally for one year
+func (s *PayslipService) GetPayrollRuns(ctx context.Context, tenantID uuid.UUID, year int) ([]models.PayrollRun, error) {
+	query := `SELECT ` + payrollRunColumns + ` FROM payroll_runs pr` + payrollRunJoins + `
+		WHERE pr.tenant_id = $1`
+	args := []interface{}{tenantID}
+	if year > 0 {
+		query += " AND pr.period_year = $2"
+		args = append(args, year)
+	}
+	query += " ORDER BY pr.period_start DESC"
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payroll runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := make([]models.PayrollRun, 0)
+	for rows.Next() {
+		run, err := scanPayrollRun(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payroll run: %w", err)
+		}
+		runs = append(runs, *run)
+	}
+
+	return runs, rows.Err()
+}
+
+// GetPayrollRun gets a payroll run with its summary and generation errors
+func (s *PayslipService) GetPayrollRun(ctx context.Context, tenantID, runID uuid.UUID) (*models.PayrollRun, error) {
+	row := s.DB.QueryRowContext(ctx, `SELECT `+payrollRunColumns+` FROM payroll_runs pr`+payrollRunJoins+`
+		WHERE pr.tenant_id = $1 AND pr.id = $2`, tenantID, runID)
+
+	run, err := scanPayrollRun(row.Scan)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("payroll run not found")
+		}
+		return nil, fmt.Errorf("failed to get payroll run: %w", err)
+	}
+
+	run.Errors, err = s.getPayrollRunErrors(ctx, tenantID, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// CreatePayrollRun starts the payroll run of a month and generates its payslips. The run stays
+// in draft so HR can review the figures and re-run it before locking.
+func (s *PayslipService) CreatePayrollRun(ctx context.Context, tenantID, userID uuid.UUID, req models.CreatePayrollRunRequest) (*models.PayrollRunGeneration, error) {
+	if req.Month < 1 || req.Month > 12 {
+		return nil, fmt.Errorf("month must be between 1 and 12")
+	}
+	if req.Year < 2000 || req.Year > 9999 {
+		return nil, fmt.Errorf("invalid year")
+	}
+
+	var paymentDate *time.Time
+	if req.PaymentDate != nil && *req.PaymentDate != "" {
+		date, err := time.Parse("2006-01-02", *req.PaymentDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid payment date format, expected YYYY-MM-DD")
+		}
+		paymentDate = &date
+	}
+
+	periodStart := time.Date(req.Year, time.Month(req.Month), 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.AddDate(0, 1, -1)
+
+	_, loc, err := getEffectivePolicy(ctx, s.DB.DB, tenantID, uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
+	if periodStart.After(localToday(loc, time.Now())) {
+		return nil, errPayPeriodNotStarted
+	}
+
+	var exists bool
+	err = s.DB.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM payroll_runs WHERE tenant_id = $1 AND period_year = $2 AND period_month = $3
+		)`, tenantID, req.Year, req.Month).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing payroll runs: %w", err)
+	}
+	if exists {
+		return nil, fmt.Errorf("payroll run already exists for this period")
+	}
+
+	runID := uuid.New()
+	_, err = s.DB.ExecContext(ctx, `
+		INSERT INTO payroll_runs (id, tenant_id, period_year, period_month, period_start, period_end,
+			payment_date, status, notes, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		runID, tenantID, req.Year, req.Month, periodStart.Format("2006-01-02"), periodEnd.Format("2006-01-02"),
+		paymentDate, models.PayrollRunStatusDraft, req.Notes, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payroll run: %w", err)
+	}
+
+	return s.GeneratePayrollRun(ctx, tenantID, runID, req.PayrollRunFilter)
+}
+
+// GeneratePayrollRun (re)generates the payslips of a draft run for the filtered employees.
+// Employees whose payslip cannot be generated are reported and their previous payslip in
+// the run is removed; the other employees are not affected.
+func (s *PayslipService) GeneratePayrollRun(ctx context.Context, tenantID, runID uuid.UUID, filter models.PayrollRunFilter) (*models.PayrollRunGeneration, error) {
+	run, err := s.GetPayrollRun(ctx, tenantID, runID)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != models.PayrollRunStatusDraft {
+		return nil, errPayrollRunLocked
+	}
+
+	employeeIDs, err := s.getPayrollRunEmployees(ctx, tenantID, run, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.PayrollRunGeneration{Errors: make([]models.PayrollRunError, 0)}
+	for _, employeeID := range employeeIDs {
+		err := s.generateRunPayslip(ctx, tenantID, run, employeeID)
+		if err == nil {
+			result.Generated++
+			continue
+		}
+		if errors.Is(err, errPayrollRunLocked) {
+			return nil, err
+		}
+
+		runError, err := s.recordPayrollRunError(ctx, tenantID, run.ID, employeeID, err)
+		if err != nil {
+			return nil, err
+		}
+		result.Failed++
+		result.Errors = append(result.Errors, *runError)
+	}
+
+	result.Run, err = s.GetPayrollRun(ctx, tenantID, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// LockPayrollRun freezes the payslips of a draft run
+func (s *PayslipService) LockPayrollRun(ctx context.Context, tenantID, runID, userID uuid.UUID) (*models.PayrollRun, error) {
+	return s.transitionPayrollRun(ctx, tenantID, runID, userID, models.PayrollRunStatusLocked, "")
+}
+
+// PublishPayrollRun makes the payslips of a locked run visible to employees
+func (s *PayslipService) PublishPayrollRun(ctx context.Context, tenantID, runID, userID uuid.UUID) (*models.PayrollRun, error) {
+	return s.transitionPayrollRun(ctx, tenantID, runID, userID, models.PayrollRunStatusPublished, "")
+}
+
+// ReopenPayrollRun returns a locked or published run to draft so it can be re-run. Published
+// payslips are hidden from employees again until the run is republished.
+func (s *PayslipService) ReopenPayrollRun(ctx context.Context, tenantID, runID, userID uuid.UUID, reason string) (*models.PayrollRun, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("a reason is required to reopen a payroll run")
+	}
+	return s.transitionPayrollRun(ctx, tenantID, runID, userID, models.PayrollRunStatusDraft, reason)
+}
+
+// transitionPayrollRun moves a run to the next lifecycle status and records the change in the
+// audit log
+func (s *PayslipService) transitionPayrollRun(ctx context.Context, tenantID, runID, userID uuid.UUID, to models.PayrollRunStatus, reason string) (*models.PayrollRun, error) {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var from models.PayrollRunStatus
+	err = tx.QueryRowContext(ctx, `
+		SELECT status FROM payroll_runs WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+		runID, tenantID).Scan(&from)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("payroll run not found")
+		}
+		return nil, fmt.Errorf("failed to get payroll run: %w", err)
+	}
+
+	now := time.Now()
+	var action string
+	var notifications []models.Notification
+	switch to {
+	case models.PayrollRunStatusLocked:
+		if from != models.PayrollRunStatusDraft {
+			return nil, fmt.Errorf("only a draft payroll run can be locked")
+		}
+		var payslips int
+		err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM payslips WHERE payroll_run_id = $1`, runID).Scan(&payslips)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count payslips: %w", err)
+		}
+		if payslips == 0 {
+			return nil, fmt.Errorf("payroll run has no payslips")
+		}
+		action = "LOCK"
+		_, err = tx.ExecContext(ctx, `
+			UPDATE payroll_runs SET status = $1, locked_at = $2, locked_by = $3
+			WHERE id = $4`, to, now, userID, runID)
+
+	case models.PayrollRunStatusPublished:
+		if from != models.PayrollRunStatusLocked {
+			return nil, fmt.Errorf("only a locked payroll run can be published")
+		}
+		action = "PUBLISH"
+		_, err = tx.ExecContext(ctx, `
+			UPDATE payroll_runs SET status = $1, published_at = $2, published_by = $3
+			WHERE id = $4`, to, now, userID, runID)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `UPDATE payslips SET published_at = $1 WHERE payroll_run_id = $2`, now, runID)
+		}
+		if err == nil {
+			notifications, err = s.notificationService.QueuePayslipsPublished(ctx, tx, tenantID, runID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+	case models.PayrollRunStatusDraft:
+		if from == models.PayrollRunStatusDraft {
+			return nil, fmt.Errorf("payroll run is already in draft")
+		}
+		action = "REOPEN"
+		_, err = tx.ExecContext(ctx, `
+			UPDATE payroll_runs
+			SET status = $1, locked_at = NULL, locked_by = NULL, published_at = NULL, published_by = NULL,
+			    reopened_at = $2, reopened_by = $3, reopen_reason = $4
+			WHERE id = $5`, to, now, userID, reason, runID)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `UPDATE payslips SET published_at = NULL WHERE payroll_run_id = $1`, runID)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update payroll run: %w", err)
+	}
+
+	oldValues, _ := json.Marshal(map[string]interface{}{"status": from})
+	newValues := map[string]interface{}{"status": to}
+	if reason != "" {
+		newValues["reason"] = reason
+	}
+	newValuesJSON, _ := json.Marshal(newValues)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_logs (tenant_id, user_id, action, resource_type, resource_id, old_values, new_values)
+		VALUES ($1, $2, $3, 'payroll_runs', $4, $5, $6)`,
+		tenantID, userID, action, runID, oldValues, newValuesJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit payroll run: %w", err)
+	}
+	s.notificationService.Publish(notifications)
+
+	return s.GetPayrollRun(ctx, tenantID, runID)
+}
+
+// generateRunPayslip replaces an employee's payslip in a draft run
+func (s *PayslipService) generateRunPayslip(ctx context.Context, tenantID uuid.UUID, run *models.PayrollRun, employeeID uuid.UUID) error {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locking the run waits for a concurrent lock or publish to finish
+	var status models.PayrollRunStatus
+	err = tx.QueryRowContext(ctx, `SELECT status FROM payroll_runs WHERE id = $1 FOR SHARE`, run.ID).Scan(&status)
+	if err != nil {
+		return fmt.Errorf("failed to get payroll run: %w", err)
+	}
+	if status != models.PayrollRunStatusDraft {
+		return errPayrollRunLocked
+	}
+
+	// A payslip created outside the run would pay the employee twice
+	var hasPayslip bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM payslips
+			WHERE tenant_id = $1 AND employee_id = $2 AND payroll_run_id IS NULL AND status <> 'cancelled'
+			  AND pay_period_start <= $4 AND pay_period_end >= $3
+		)`, tenantID, employeeID, run.PeriodStart, run.PeriodEnd).Scan(&hasPayslip)
+	if err != nil {
+		return fmt.Errorf("failed to check existing payslips: %w", err)
+	}
+	if hasPayslip {
+		return fmt.Errorf("employee already has a payslip for this period outside the payroll run")
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM payslips WHERE payroll_run_id = $1 AND employee_id = $2`, run.ID, employeeID); err != nil {
+		return fmt.Errorf("failed to remove previous payslip: %w", err)
+	}
+
+	_, err = s.generatePayslip(ctx, tx, tenantID, &models.PayslipCreateRequest{
+		EmployeeID:     employeeID,
+		PayPeriodStart: run.PeriodStart,
+		PayPeriodEnd:   run.PeriodEnd,
+		PaymentDate:    run.PaymentDate,
+	}, &run.ID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM payroll_run_errors WHERE payroll_run_id = $1 AND employee_id = $2`, run.ID, employeeID); err != nil {
+		return fmt.Errorf("failed to clear payroll run error: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// recordPayrollRunError stores why an employee's payslip failed and removes the stale payslip
+// from an earlier generation
+func (s *PayslipService) recordPayrollRunError(ctx context.Context, tenantID, runID, employeeID uuid.UUID, cause error) (*models.PayrollRunError, error) {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM payslips WHERE payroll_run_id = $1 AND employee_id = $2`, runID, employeeID); err != nil {
+		return nil, fmt.Errorf("failed to remove previous payslip: %w", err)
+	}
+
+	runError := models.PayrollRunError{EmployeeID: employeeID, Error: cause.Error(), CreatedAt: time.Now()}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO payroll_run_errors (tenant_id, payroll_run_id, employee_id, error, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (payroll_run_id, employee_id) DO UPDATE SET error = EXCLUDED.error, created_at = EXCLUDED.created_at`,
+		tenantID, runID, employeeID, runError.Error, runError.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record payroll run error: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit payroll run error: %w", err)
+	}
+
+	return &runError, nil
+}
+
+// getPayrollRunEmployees returns the employees employed during the run's period that match the filter
+func (s *PayslipService) getPayrollRunEmployees(ctx context.Context, tenantID uuid.UUID, run *models.PayrollRun, filter models.PayrollRunFilter) ([]uuid.UUID, error) {
+	query := `
+		SELECT e.id FROM employees e
+		WHERE e.tenant_id = $1 AND e.deleted_at IS NULL
+		  AND (e.employment_status = 'active' OR e.date_of_leaving >= $2)
+		  AND (e.date_of_joining IS NULL OR e.date_of_joining <= $3)
+		  AND (e.date_of_leaving IS NULL OR e.date_of_leaving >= $2)`
+	args := []interface{}{tenantID, run.PeriodStart, run.PeriodEnd}
+
+	if filter.DepartmentID != nil {
+		args = append(args, *filter.DepartmentID)
+		query += fmt.Sprintf(" AND e.department_id = $%d", len(args))
+	}
+	if len(filter.EmployeeIDs) > 0 {
+		ids := make([]string, len(filter.EmployeeIDs))
+		for i, id := range filter.EmployeeIDs {
+			ids[i] = id.String()
+		}
+		args = append(args, ids)
+		query += fmt.Sprintf(" AND e.id = ANY($%d::uuid[])", len(args))
+	}
+	query += " ORDER BY e.employee_code"
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get employees: %w", err)
+	}
+	defer rows.Close()
+
+	var employeeIDs []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan employee: %w", err)
+		}
+		employeeIDs = append(employeeIDs, id)
+	}
+
+	return employeeIDs, rows.Err()
+}
+
+func (s *PayslipService) getPayrollRunErrors(ctx context.Context, tenantID, runID uuid.UUID) ([]models.PayrollRunError, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT re.employee_id, COALESCE(u.first_name || ' ' || u.last_name, ''), COALESCE(e.employee_code, ''),
+		       re.error, re.created_at
+		FROM payroll_run_errors re
+		JOIN employees e ON e.id = re.employee_id
+		LEFT JOIN users u ON u.id = e.user_id
+		WHERE re.tenant_id = $1 AND re.payroll_run_id = $2
+		ORDER BY e.employee_code`, tenantID, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payroll run errors: %w", err)
+	}
+	defer rows.Close()
+
+	runErrors := make([]models.PayrollRunError, 0)
+	for rows.Next() {
+		var runError models.PayrollRunError
+		if err := rows.Scan(&runError.EmployeeID, &runError.EmployeeName, &runError.EmployeeCode,
+			&runError.Error, &runError.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan payroll run error: %w", err)
+		}
+		runErrors = append(runErrors, runError)
+	}
+
+	return runErrors, rows.Err()
+}
+
+// checkPayPeriodOpen rejects a payslip created outside a payroll run when the period's run is
+// locked or published, or when the employee's payslip for the period belongs to a run
+func (s *PayslipService) checkPayPeriodOpen(ctx context.Context, tenantID, employeeID uuid.UUID, periodStart, periodEnd time.Time) error {
+	var status sql.NullString
+	err := s.DB.QueryRowContext(ctx, `
+		SELECT status FROM payroll_runs
+		WHERE tenant_id = $1 AND status <> 'draft' AND period_start <= $3 AND period_end >= $2
+		LIMIT 1`, tenantID, periodStart, periodEnd).Scan(&status)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check payroll runs: %w", err)
+	}
+	if status.Valid {
+		return fmt.Errorf("payroll for this period is %s, reopen the payroll run first", status.String)
+	}
+
+	var inRun bool
+	err = s.DB.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM payslips
+			WHERE tenant_id = $1 AND employee_id = $2 AND payroll_run_id IS NOT NULL
+			  AND pay_period_start <= $4 AND pay_period_end >= $3
+		)`, tenantID, employeeID, periodStart, periodEnd).Scan(&inRun)
+	if err != nil {
+		return fmt.Errorf("failed to check existing payslips: %w", err)
+	}
+	if inRun {
+		return fmt.Errorf("employee's payslip for this period is generated by a payroll run")
+	}
+
+	return nil
+}
+
+// GetEmployeeIDByUserID gets the employee record of a user
+func (s *PayslipService) GetEmployeeIDByUserID(ctx context.Context, tenantID, userID uuid.UUID) (uuid.UUID, error) {
+	employeeID, err := employeeIDForUser(ctx, s.DB, tenantID, userID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if employeeID == nil {
+		return uuid.Nil, fmt.Errorf("employee not found")
+	}
+	return *employeeID, nil
+}
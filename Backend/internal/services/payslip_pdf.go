@@ -0,0 +1,419 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+// maxLogoSize limits the organization logo downloaded into a payslip
+const maxLogoSize = 2 << 20
+
+// payslipOrganization holds the organization details printed on a payslip
+type payslipOrganization struct {
+	Name     string
+	Address  []string
+	Currency string
+	Logo     []byte
+	LogoType string
+	TaxID    string
+	Contact  string
+	Website  string
+}
+
+// GeneratePayslipPDF renders a single payslip, as loaded by GetPayslipByID, as a PDF document
+func (s *PayslipService) GeneratePayslipPDF(ctx context.Context, payslip *models.Payslip) ([]byte, error) {
+	org, err := s.getPayslipOrganization(ctx, payslip.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return renderPayslipPDF(org, payslip)
+}
+
+// GeneratePayslipsZip renders every payslip matching the filter and bundles them in a zip archive.
+// It returns the archive and the number of payslips it contains.
+func (s *PayslipService) GeneratePayslipsZip(ctx context.Context, tenantID uuid.UUID, filter *models.PayslipFilter) ([]byte, int, error) {
+	payslips, _, err := s.GetPayslipsByTenant(tenantID, filter, 1, 0)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get payslips: %w", err)
+	}
+	if len(payslips) == 0 {
+		return nil, 0, fmt.Errorf("no payslips found for the pay period")
+	}
+
+	org, err := s.getPayslipOrganization(ctx, tenantID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	names := make(map[string]int)
+
+	for i := range payslips {
+		payslip := &payslips[i]
+		content, err := renderPayslipPDF(org, payslip)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		name := PayslipFileName(payslip)
+		names[name]++
+		if n := names[name]; n > 1 {
+			name = fmt.Sprintf("%s-%d.pdf", strings.TrimSuffix(name, ".pdf"), n)
+		}
+
+		file, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to add payslip to archive: %w", err)
+		}
+		if _, err := file.Write(content); err != nil {
+			return nil, 0, fmt.Errorf("failed to add payslip to archive: %w", err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, 0, fmt.Errorf("failed to create archive: %w", err)
+	}
+
+	return buf.Bytes(), len(payslips), nil
+}
+
+// PayslipFileName returns the download name of a payslip, e.g. payslip-EMP001-2025-03.pdf
+func PayslipFileName(payslip *models.Payslip) string {
+	code := payslip.EmployeeID.String()
+	if payslip.Employee != nil && payslip.Employee.EmployeeCode != "" {
+		code = payslip.Employee.EmployeeCode
+	}
+	code = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, code)
+	return fmt.Sprintf("payslip-%s-%s.pdf", code, payslip.PayPeriodStart.Format("2006-01"))
+}
+
+// getPayslipOrganization loads the tenant's name, address, currency and logo.
+// A logo that cannot be downloaded is left out rather than failing the payslip.
+func (s *PayslipService) getPayslipOrganization(ctx context.Context, tenantID uuid.UUID) (*payslipOrganization, error) {
+	var name string
+	var line1, line2, city, state, country, postalCode, currency, logoURL, taxID, contact, website sql.NullString
+
+	err := s.DB.QueryRowContext(ctx, `
+		SELECT t.name, od.address_line1, od.address_line2, od.city, od.state, od.country, od.postal_code,
+			od.currency, od.logo_url, od.tax_id, od.contact_number, od.website
+		FROM tenants t
+		LEFT JOIN organization_details od ON od.tenant_id = t.id
+		WHERE t.id = $1`, tenantID).Scan(
+		&name, &line1, &line2, &city, &state, &country, &postalCode,
+		&currency, &logoURL, &taxID, &contact, &website)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("organization not found")
+		}
+		return nil, fmt.Errorf("failed to get organization details: %w", err)
+	}
+
+	org := &payslipOrganization{
+		Name:     name,
+		Currency: "USD",
+		TaxID:    strings.TrimSpace(taxID.String),
+		Contact:  strings.TrimSpace(contact.String),
+		Website:  strings.TrimSpace(website.String),
+	}
+	if c := strings.TrimSpace(currency.String); c != "" {
+		org.Currency = strings.ToUpper(c)
+	}
+
+	for _, line := range []string{line1.String, line2.String} {
+		if line = strings.TrimSpace(line); line != "" {
+			org.Address = append(org.Address, line)
+		}
+	}
+	var locality []string
+	for _, part := range []string{city.String, state.String, postalCode.String} {
+		if part = strings.TrimSpace(part); part != "" {
+			locality = append(locality, part)
+		}
+	}
+	if len(locality) > 0 {
+		org.Address = append(org.Address, strings.Join(locality, ", "))
+	}
+	if c := strings.TrimSpace(country.String); c != "" {
+		org.Address = append(org.Address, c)
+	}
+
+	if url := strings.TrimSpace(logoURL.String); url != "" {
+		org.Logo, org.LogoType, err = fetchLogo(ctx, url)
+		if err != nil {
+			log.Printf("Error loading organization logo for tenant %s: %v", tenantID, err)
+		}
+	}
+
+	return org, nil
+}
+
+// fetchLogo downloads an organization logo and checks that gofpdf can embed it
+func fetchLogo(ctx context.Context, url string) ([]byte, string, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, "", fmt.Errorf("unsupported logo URL")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("logo download returned %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxLogoSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxLogoSize {
+		return nil, "", fmt.Errorf("logo is larger than %d bytes", maxLogoSize)
+	}
+
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("unsupported logo image: %w", err)
+	}
+
+	switch format {
+	case "png":
+		return data, "PNG", nil
+	case "jpeg":
+		return data, "JPG", nil
+	case "gif":
+		return data, "GIF", nil
+	}
+	return nil, "", fmt.Errorf("unsupported logo format %s", format)
+}
+
+// renderPayslipPDF lays out a payslip using gofpdf
+func renderPayslipPDF(org *payslipOrganization, payslip *models.Payslip) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	// Colors
+	primaryColor := []int{37, 99, 235} // Blue-600
+	grayColor := []int{107, 114, 128}  // Gray-500
+	lightGray := []int{243, 244, 246}  // Gray-100
+
+	money := func(amount float64) string {
+		return fmt.Sprintf("%s %.2f", org.Currency, amount)
+	}
+
+	// Logo or organization name (Left)
+	logoShown := false
+	if len(org.Logo) > 0 {
+		options := gofpdf.ImageOptions{ImageType: org.LogoType}
+		pdf.RegisterImageOptionsReader("logo", options, bytes.NewReader(org.Logo))
+		if pdf.Ok() {
+			pdf.ImageOptions("logo", 10, 10, 0, 15, false, options, 0, "")
+			pdf.SetXY(10, 27)
+			logoShown = true
+		} else {
+			pdf.ClearError()
+		}
+	}
+
+	pdf.SetFont("Arial", "B", 16)
+	if !logoShown {
+		pdf.SetFont("Arial", "B", 20)
+		pdf.SetTextColor(primaryColor[0], primaryColor[1], primaryColor[2])
+	}
+	pdf.SetX(10)
+	pdf.Cell(110, 9, tr(org.Name))
+	pdf.Ln(9)
+
+	pdf.SetFont("Arial", "", 9)
+	pdf.SetTextColor(grayColor[0], grayColor[1], grayColor[2])
+	for _, line := range org.Address {
+		pdf.Cell(110, 4, tr(line))
+		pdf.Ln(4)
+	}
+	if org.Contact != "" || org.Website != "" {
+		pdf.Cell(110, 4, tr(strings.Trim(org.Contact+"  "+org.Website, " ")))
+		pdf.Ln(4)
+	}
+	if org.TaxID != "" {
+		pdf.Cell(110, 4, tr("Tax ID: "+org.TaxID))
+		pdf.Ln(4)
+	}
+	headerBottom := pdf.GetY()
+
+	// PAYSLIP Label (Right)
+	pdf.SetXY(120, 10)
+	pdf.SetFont("Arial", "B", 20)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(80, 10, "PAYSLIP", "", 1, "R", false, 0, "")
+	pdf.SetX(120)
+	pdf.SetFont("Arial", "", 10)
+	pdf.SetTextColor(grayColor[0], grayColor[1], grayColor[2])
+	pdf.CellFormat(80, 6, payslip.PayPeriodStart.Format("January 2006"), "", 1, "R", false, 0, "")
+
+	if headerBottom < 40 {
+		headerBottom = 40
+	}
+	pdf.SetXY(10, headerBottom+4)
+
+	// Store Y position for two columns
+	yPos := pdf.GetY()
+	pdf.SetTextColor(0, 0, 0)
+
+	detail := func(x, y float64, label, value string) {
+		pdf.SetXY(x, y)
+		pdf.SetFont("Arial", "B", 10)
+		pdf.Cell(35, 5, label)
+		pdf.SetFont("Arial", "", 10)
+		pdf.Cell(55, 5, tr(value))
+	}
+
+	// Column 1: Employee (Left)
+	employeeName, employeeCode, department := "", "", ""
+	if payslip.Employee != nil {
+		employeeName = strings.TrimSpace(payslip.Employee.FirstName + " " + payslip.Employee.LastName)
+		employeeCode = payslip.Employee.EmployeeCode
+		if payslip.Employee.Department != nil {
+			department = payslip.Employee.Department.Name
+		}
+	}
+	detail(10, yPos, "Employee:", employeeName)
+	detail(10, yPos+6, "Employee Code:", employeeCode)
+	detail(10, yPos+12, "Department:", department)
+
+	// Column 2: Pay Period (Right)
+	paymentDate := "-"
+	if payslip.PaymentDate != nil {
+		paymentDate = payslip.PaymentDate.Format("Jan 02, 2006")
+	}
+	detail(110, yPos, "Pay Period:", fmt.Sprintf("%s - %s",
+		payslip.PayPeriodStart.Format("Jan 02, 2006"), payslip.PayPeriodEnd.Format("Jan 02, 2006")))
+	detail(110, yPos+6, "Payment Date:", paymentDate)
+	detail(110, yPos+12, "Payslip #:", strings.ToUpper(payslip.ID.String()[:8]))
+
+	// Attendance
+	pdf.SetXY(10, yPos+22)
+	days := func(value *float64) string {
+		if value == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%g", *value)
+	}
+	pdf.SetFillColor(lightGray[0], lightGray[1], lightGray[2])
+	pdf.SetFont("Arial", "B", 9)
+	for _, label := range []string{"Working Days", "Paid Days", "Unpaid Leave", "Absent Days", "Overtime Hours"} {
+		pdf.CellFormat(38, 6, label, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(6)
+	paidDays := days(payslip.PaidDays)
+	if payslip.ProjectedDays > 0 {
+		paidDays += fmt.Sprintf(" (%g projected)", payslip.ProjectedDays)
+	}
+	pdf.SetFont("Arial", "", 9)
+	for _, value := range []string{
+		days(payslip.WorkingDays), paidDays,
+		fmt.Sprintf("%g", payslip.UnpaidLeaveDays), fmt.Sprintf("%g", payslip.AbsentDays),
+		fmt.Sprintf("%g", payslip.OvertimeHours),
+	} {
+		pdf.CellFormat(38, 6, value, "1", 0, "C", false, 0, "")
+	}
+	pdf.Ln(12)
+
+	// Earnings and deductions side by side
+	var earnings, deductions []models.PayslipComponent
+	for _, component := range payslip.Components {
+		if component.ComponentType == "deduction" {
+			deductions = append(deductions, component)
+		} else {
+			earnings = append(earnings, component)
+		}
+	}
+
+	pdf.SetFont("Arial", "B", 10)
+	pdf.CellFormat(60, 7, "Earnings", "1", 0, "", true, 0, "")
+	pdf.CellFormat(35, 7, "Amount", "1", 0, "R", true, 0, "")
+	pdf.CellFormat(60, 7, "Deductions", "1", 0, "", true, 0, "")
+	pdf.CellFormat(35, 7, "Amount", "1", 1, "R", true, 0, "")
+
+	pdf.SetFont("Arial", "", 10)
+	rows := len(earnings)
+	if len(deductions) > rows {
+		rows = len(deductions)
+	}
+	for i := 0; i < rows; i++ {
+		if i < len(earnings) {
+			pdf.CellFormat(60, 7, tr(earnings[i].ComponentName), "1", 0, "", false, 0, "")
+			pdf.CellFormat(35, 7, money(earnings[i].Amount), "1", 0, "R", false, 0, "")
+		} else {
+			pdf.CellFormat(95, 7, "", "1", 0, "", false, 0, "")
+		}
+		if i < len(deductions) {
+			pdf.CellFormat(60, 7, tr(deductions[i].ComponentName), "1", 0, "", false, 0, "")
+			pdf.CellFormat(35, 7, money(deductions[i].Amount), "1", 1, "R", false, 0, "")
+		} else {
+			pdf.CellFormat(95, 7, "", "1", 1, "", false, 0, "")
+		}
+	}
+
+	pdf.SetFont("Arial", "B", 10)
+	pdf.CellFormat(60, 7, "Gross Earnings", "1", 0, "", true, 0, "")
+	pdf.CellFormat(35, 7, money(payslip.GrossSalary), "1", 0, "R", true, 0, "")
+	pdf.CellFormat(60, 7, "Total Deductions", "1", 0, "", true, 0, "")
+	pdf.CellFormat(35, 7, money(payslip.TotalDeductions), "1", 1, "R", true, 0, "")
+
+	// Net pay
+	pdf.Ln(5)
+	pdf.SetFont("Arial", "B", 12)
+	pdf.Cell(120, 8, "")
+	pdf.Cell(35, 8, "Net Pay:")
+	pdf.SetTextColor(primaryColor[0], primaryColor[1], primaryColor[2])
+	pdf.CellFormat(35, 8, money(payslip.NetSalary), "", 1, "R", false, 0, "")
+
+	// Footer
+	pdf.Ln(10)
+	pdf.SetFont("Arial", "I", 8)
+	pdf.SetTextColor(grayColor[0], grayColor[1], grayColor[2])
+	pdf.CellFormat(0, 5, "This is a computer-generated payslip and does not require a signature.", "", 1, "C", false, 0, "")
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to generate PDF: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
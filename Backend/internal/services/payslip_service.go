@@ -1,10 +1,11 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
-	"math"
+	"strings"
 	"time"
 
 	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
@@ -14,11 +15,12 @@ import (
 )
 
 type PayslipService struct {
-	DB *sqlx.DB
+	DB                  *sqlx.DB
+	notificationService *NotificationService
 }
 
-func NewPayslipService(db *sqlx.DB) *PayslipService {
-	return &PayslipService{DB: db}
+func NewPayslipService(db *sqlx.DB, notificationService *NotificationService) *PayslipService {
+	return &PayslipService{DB: db, notificationService: notificationService}
 }
 
 // GetPayslipsByTenant gets all payslips for a tenant with optional filtering
@@ -30,7 +32,9 @@ func (s *PayslipService) GetPayslipsByTenant(tenantID uuid.UUID, filter *models.
 			p.id, p.tenant_id, p.employee_id, p.salary_structure_id, 
 			p.pay_period_start, p.pay_period_end, p.payment_date, 
 			p.basic_salary, p.gross_salary, p.total_deductions, 
-			p.net_salary, p.status, p.notes, p.created_at, p.updated_at,
+			p.net_salary, p.status, p.notes, p.working_days, p.paid_days, p.projected_days,
+			p.unpaid_leave_days, p.absent_days, p.overtime_hours, p.payroll_run_id, p.published_at,
+			p.created_at, p.updated_at,
 			u.first_name, u.last_name, e.employee_code as emp_code, u.email, u.role, d.name as department_name
 		FROM payslips p
 		JOIN employees e ON p.employee_id = e.id
@@ -67,6 +71,14 @@ func (s *PayslipService) GetPayslipsByTenant(tenantID uuid.UUID, filter *models.
 			query += fmt.Sprintf(" AND p.pay_period_end <= $%d", argIndex)
 			args = append(args, *filter.PayPeriodEnd)
 		}
+		if filter.PayrollRunID != nil {
+			argIndex++
+			query += fmt.Sprintf(" AND p.payroll_run_id = $%d", argIndex)
+			args = append(args, *filter.PayrollRunID)
+		}
+		if filter.PublishedOnly {
+			query += " AND p.published_at IS NOT NULL"
+		}
 		if filter.PaymentMonth != nil && filter.PaymentYear != nil {
 			argIndex++
 			query += fmt.Sprintf(" AND EXTRACT(MONTH FROM p.payment_date) = $%d", argIndex)
@@ -122,7 +134,9 @@ func (s *PayslipService) GetPayslipsByTenant(tenantID uuid.UUID, filter *models.
 			&p.ID, &p.TenantID, &p.EmployeeID, &salaryStructureID,
 			&p.PayPeriodStart, &p.PayPeriodEnd, &paymentDate,
 			&p.BasicSalary, &p.GrossSalary, &p.TotalDeductions,
-			&p.NetSalary, &p.Status, &notes, &p.CreatedAt, &p.UpdatedAt,
+			&p.NetSalary, &p.Status, &notes, &p.WorkingDays, &p.PaidDays, &p.ProjectedDays,
+			&p.UnpaidLeaveDays, &p.AbsentDays, &p.OvertimeHours, &p.PayrollRunID, &p.PublishedAt,
+			&p.CreatedAt, &p.UpdatedAt,
 			&emp.FirstName, &emp.LastName, &emp.EmployeeCode, &emp.Email, &p.Role, &deptName,
 		)
 		if err != nil {
@@ -176,7 +190,9 @@ func (s *PayslipService) GetPayslipByID(tenantID, payslipID uuid.UUID) (*models.
 			p.id, p.tenant_id, p.employee_id, p.salary_structure_id, 
 			p.pay_period_start, p.pay_period_end, p.payment_date, 
 			p.basic_salary, p.gross_salary, p.total_deductions, 
-			p.net_salary, p.status, p.notes, p.created_at, p.updated_at,
+			p.net_salary, p.status, p.notes, p.working_days, p.paid_days, p.projected_days,
+			p.unpaid_leave_days, p.absent_days, p.overtime_hours, p.payroll_run_id, p.published_at,
+			p.created_at, p.updated_at,
 			u.first_name, u.last_name, e.employee_code as emp_code, u.email, u.role, d.name as department_name
 		FROM payslips p
 		JOIN employees e ON p.employee_id = e.id
@@ -196,7 +212,9 @@ func (s *PayslipService) GetPayslipByID(tenantID, payslipID uuid.UUID) (*models.
 		&p.ID, &p.TenantID, &p.EmployeeID, &salaryStructureID,
 		&p.PayPeriodStart, &p.PayPeriodEnd, &paymentDate,
 		&p.BasicSalary, &p.GrossSalary, &p.TotalDeductions,
-		&p.NetSalary, &p.Status, &notes, &p.CreatedAt, &p.UpdatedAt,
+		&p.NetSalary, &p.Status, &notes, &p.WorkingDays, &p.PaidDays, &p.ProjectedDays,
+		&p.UnpaidLeaveDays, &p.AbsentDays, &p.OvertimeHours, &p.PayrollRunID, &p.PublishedAt,
+		&p.CreatedAt, &p.UpdatedAt,
 		&emp.FirstName, &emp.LastName, &emp.EmployeeCode, &emp.Email, &p.Role, &deptName,
 	)
 	if err != nil {
@@ -233,6 +251,32 @@ func (s *PayslipService) GetPayslipByID(tenantID, payslipID uuid.UUID) (*models.
 
 // CreatePayslip creates a new payslip
 func (s *PayslipService) CreatePayslip(tenantID uuid.UUID, req *models.PayslipCreateRequest) (*models.Payslip, error) {
+	ctx := context.Background()
+	if err := s.checkPayPeriodOpen(ctx, tenantID, req.EmployeeID, req.PayPeriodStart, req.PayPeriodEnd); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	payslip, err := s.generatePayslip(ctx, tx, tenantID, req, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return payslip, nil
+}
+
+// generatePayslip calculates an employee's payslip for a pay period and inserts it within tx
+func (s *PayslipService) generatePayslip(ctx context.Context, tx *sqlx.Tx, tenantID uuid.UUID, req *models.PayslipCreateRequest, payrollRunID *uuid.UUID) (*models.Payslip, error) {
 	// Get employee's current salary structure
 	salaryStructure, err := s.getCurrentSalaryStructure(tenantID, req.EmployeeID)
 	if err != nil {
@@ -243,11 +287,35 @@ func (s *PayslipService) CreatePayslip(tenantID uuid.UUID, req *models.PayslipCr
 		return nil, fmt.Errorf("no active salary structure found for employee")
 	}
 
+	basicComponent, err := s.getSystemComponent(tenantID, BasicComponentCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get basic salary component: %v", err)
+	}
+
+	attendance, err := s.getPayrollAttendance(ctx, tenantID, req.EmployeeID, req.PayPeriodStart, req.PayPeriodEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance for pay period: %v", err)
+	}
+
+	// Approved overtime is paid through the tenant's overtime component unless the structure has its own
+	if attendance.OvertimeHours > 0 && !hasComponentCode(salaryStructure, OvertimeComponentCode) {
+		overtimeComponent, err := s.getSystemComponent(tenantID, OvertimeComponentCode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get overtime component: %v", err)
+		}
+		salaryStructure.Components = append(salaryStructure.Components, models.SalaryStructureComponent{
+			SalaryStructureID: salaryStructure.ID,
+			ComponentID:       overtimeComponent.ID,
+			Component:         overtimeComponent,
+		})
+	}
+
 	// Calculate payslip amounts
-	grossSalary, totalDeductions, netSalary, components, err := s.calculatePayslip(salaryStructure)
+	result, err := CalculatePayroll(salaryStructure, basicComponent, attendance)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate payslip: %v", err)
 	}
+	components := result.Components
 
 	// Create payslip
 	payslipID := uuid.New()
@@ -260,54 +328,53 @@ func (s *PayslipService) CreatePayslip(tenantID uuid.UUID, req *models.PayslipCr
 		PayPeriodEnd:      req.PayPeriodEnd,
 		PaymentDate:       req.PaymentDate,
 		BasicSalary:       salaryStructure.BasicSalary,
-		GrossSalary:       grossSalary,
-		TotalDeductions:   totalDeductions,
-		NetSalary:         netSalary,
+		GrossSalary:       result.GrossSalary,
+		TotalDeductions:   result.TotalDeductions,
+		NetSalary:         result.NetSalary,
 		Status:            "draft",
 		Notes:             req.Notes,
+		WorkingDays:       &attendance.WorkingDays,
+		PaidDays:          &attendance.PaidDays,
+		ProjectedDays:     attendance.ProjectedDays,
+		UnpaidLeaveDays:   attendance.UnpaidLeaveDays,
+		AbsentDays:        attendance.AbsentDays,
+		OvertimeHours:     attendance.OvertimeHours,
+		PayrollRunID:      payrollRunID,
 		CreatedAt:         time.Now(),
 		UpdatedAt:         time.Now(),
 	}
 
-	tx, err := s.DB.Beginx()
-	if err != nil {
-		return nil, err
-	}
-	defer tx.Rollback()
-
 	// Insert payslip
 	_, err = tx.NamedExec(`
 		INSERT INTO payslips (id, tenant_id, employee_id, salary_structure_id, 
 			pay_period_start, pay_period_end, payment_date, basic_salary, 
-			gross_salary, total_deductions, net_salary, status, notes)
+			gross_salary, total_deductions, net_salary, status, notes,
+			working_days, paid_days, projected_days, unpaid_leave_days, absent_days, overtime_hours, payroll_run_id)
 		VALUES (:id, :tenant_id, :employee_id, :salary_structure_id, 
 			:pay_period_start, :pay_period_end, :payment_date, :basic_salary, 
-			:gross_salary, :total_deductions, :net_salary, :status, :notes)`,
+			:gross_salary, :total_deductions, :net_salary, :status, :notes,
+			:working_days, :paid_days, :projected_days, :unpaid_leave_days, :absent_days, :overtime_hours, :payroll_run_id)`,
 		payslip)
 	if err != nil {
 		return nil, err
 	}
 
 	// Insert payslip components
-	for _, component := range components {
-		component.PayslipID = payslipID
-		component.ID = uuid.New()
+	for i := range components {
+		components[i].PayslipID = payslipID
+		components[i].ID = uuid.New()
+		components[i].CreatedAt = payslip.CreatedAt
 		_, err = tx.NamedExec(`
 			INSERT INTO payslip_components (id, payslip_id, component_id, 
-				component_name, component_type, amount)
+				component_name, component_code, component_type, amount, is_taxable)
 			VALUES (:id, :payslip_id, :component_id, :component_name, 
-				:component_type, :amount)`,
-			component)
+				:component_code, :component_type, :amount, :is_taxable)`,
+			components[i])
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return nil, err
-	}
-
 	payslip.Components = components
 	return payslip, nil
 }
@@ -328,6 +395,11 @@ func (s *PayslipService) UpdatePayslip(tenantID, payslipID uuid.UUID, req *model
 		argIndex++
 		setParts = append(setParts, fmt.Sprintf("status = $%d", argIndex))
 		args = append(args, *req.Status)
+
+		// Payslips outside a payroll run become visible to the employee once approved
+		if *req.Status == "approved" || *req.Status == "paid" {
+			setParts = append(setParts, "published_at = CASE WHEN payroll_run_id IS NULL THEN COALESCE(published_at, NOW()) ELSE published_at END")
+		}
 	}
 
 	if req.Notes != nil {
@@ -345,16 +417,28 @@ func (s *PayslipService) UpdatePayslip(tenantID, payslipID uuid.UUID, req *model
 	args = append(args, time.Now())
 
 	query := fmt.Sprintf("UPDATE payslips SET %s WHERE tenant_id = $%d AND id = $%d",
-		fmt.Sprintf("%s", setParts), argIndex+1, argIndex+2)
+		strings.Join(setParts, ", "), argIndex+1, argIndex+2)
 	args = append(args, tenantID, payslipID)
 
 	_, err := s.DB.Exec(query, args...)
 	return err
 }
 
-// DeletePayslip deletes a payslip
+// DeletePayslip deletes a payslip. Payslips of a locked or published payroll run cannot be deleted.
 func (s *PayslipService) DeletePayslip(tenantID, payslipID uuid.UUID) error {
-	_, err := s.DB.Exec("DELETE FROM payslips WHERE tenant_id = $1 AND id = $2", tenantID, payslipID)
+	var runStatus sql.NullString
+	err := s.DB.QueryRow(`
+		SELECT pr.status FROM payslips p
+		LEFT JOIN payroll_runs pr ON pr.id = p.payroll_run_id
+		WHERE p.tenant_id = $1 AND p.id = $2`, tenantID, payslipID).Scan(&runStatus)
+	if err != nil {
+		return err
+	}
+	if runStatus.Valid && runStatus.String != string(models.PayrollRunStatusDraft) {
+		return fmt.Errorf("payslip belongs to a %s payroll run", runStatus.String)
+	}
+
+	_, err = s.DB.Exec("DELETE FROM payslips WHERE tenant_id = $1 AND id = $2", tenantID, payslipID)
 	return err
 }
 
@@ -386,7 +470,7 @@ func (s *PayslipService) GetPayslipStats(tenantID uuid.UUID) (*models.PayslipSta
 
 func (s *PayslipService) getPayslipComponents(payslipID uuid.UUID) ([]models.PayslipComponent, error) {
 	query := `
-		SELECT id, payslip_id, component_id, component_name, component_type, amount, created_at
+		SELECT id, payslip_id, component_id, component_name, component_code, component_type, amount, is_taxable, created_at
 		FROM payslip_components
 		WHERE payslip_id = $1
 		ORDER BY component_type, component_name`
@@ -408,106 +492,118 @@ func (s *PayslipService) getCurrentSalaryStructure(tenantID, employeeID uuid.UUI
 
 	var structure models.EmployeeSalaryStructure
 	err := s.DB.Get(&structure, query, tenantID, employeeID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	// Load components
 	componentQuery := `
-		SELECT ssc.id, ssc.salary_structure_id, ssc.component_id, ssc.amount, ssc.percentage, ssc.created_at,
-			sc.name, sc.type, sc.is_percentage, sc.is_taxable
+		SELECT ssc.id, ssc.salary_structure_id, ssc.component_id, ssc.amount, ssc.percentage, ssc.formula, ssc.created_at,
+			sc.name, sc.type, COALESCE(sc.amount, 0), COALESCE(sc.percentage, 0), sc.is_percentage, sc.is_taxable,
+			sc.code, sc.formula, sc.calculation_order, sc.min_amount, sc.max_amount
 		FROM salary_structure_components ssc
 		JOIN salary_components sc ON ssc.component_id = sc.id
 		WHERE ssc.salary_structure_id = $1
-		ORDER BY sc.type, sc.name`
+		ORDER BY sc.calculation_order, sc.type, sc.name`
 
+	// A partially loaded structure would produce a wrong payslip, so any failure is returned
 	rows, err := s.DB.Query(componentQuery, structure.ID)
 	if err != nil {
-		return &structure, nil // Return structure without components if query fails
+		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var comp models.SalaryStructureComponent
 		var sc models.SalaryComponent
+		var code, formula sql.NullString
 
 		err := rows.Scan(
 			&comp.ID, &comp.SalaryStructureID, &comp.ComponentID,
-			&comp.Amount, &comp.Percentage, &comp.CreatedAt,
-			&sc.Name, &sc.Type, &sc.IsPercentage, &sc.IsTaxable,
+			&comp.Amount, &comp.Percentage, &comp.Formula, &comp.CreatedAt,
+			&sc.Name, &sc.Type, &sc.Amount, &sc.Percentage, &sc.IsPercentage, &sc.IsTaxable,
+			&code, &formula, &sc.CalculationOrder, &sc.MinAmount, &sc.MaxAmount,
 		)
 		if err != nil {
-			continue
+			return nil, err
 		}
 
 		sc.ID = comp.ComponentID
+		sc.Code = code.String
+		sc.Formula = formula.String
 		comp.Component = &sc
 		structure.Components = append(structure.Components, comp)
 	}
 
-	return &structure, nil
+	return &structure, rows.Err()
 }
 
-func (s *PayslipService) calculatePayslip(structure *models.EmployeeSalaryStructure) (float64, float64, float64, []models.PayslipComponent, error) {
-	basicSalary := structure.BasicSalary
-	totalEarnings := basicSalary
-	totalDeductions := 0.0
-	var components []models.PayslipComponent
+// systemSalaryComponent is a salary component every tenant has, looked up by its code
+type systemSalaryComponent struct {
+	name             string
+	calculationOrder int
+	formula          string
+	description      string
+}
 
-	// Add basic salary component
-	components = append(components, models.PayslipComponent{
-		ComponentID:   uuid.New(), // This should be a predefined basic salary component ID
-		ComponentName: "Basic Salary",
-		ComponentType: "earning",
-		Amount:        basicSalary,
-		CreatedAt:     time.Now(),
-	})
-
-	// Calculate other components
-	for _, structComp := range structure.Components {
-		if structComp.Component == nil {
-			continue
-		}
+var systemSalaryComponents = map[string]systemSalaryComponent{
+	BasicComponentCode: {
+		name:        "Basic Salary",
+		description: "Basic salary from the employee salary structure",
+	},
+	OvertimeComponentCode: {
+		name:             "Overtime Pay",
+		calculationOrder: 900,
+		formula:          "HOURLY_RATE * 1.5 * OVERTIME_HOURS",
+		description:      "Approved overtime hours paid at 1.5x the hourly basic rate",
+	},
+}
 
-		var amount float64
-		if structComp.Component.IsPercentage {
-			if structComp.Percentage != nil {
-				amount = basicSalary * (*structComp.Percentage / 100)
-			}
-		} else {
-			if structComp.Amount != nil {
-				amount = *structComp.Amount
-			}
-		}
+// getSystemComponent returns one of the tenant's system components, creating it for tenants
+// set up after it was seeded
+func (s *PayslipService) getSystemComponent(tenantID uuid.UUID, code string) (*models.SalaryComponent, error) {
+	defaults, ok := systemSalaryComponents[code]
+	if !ok {
+		return nil, fmt.Errorf("unknown system salary component %s", code)
+	}
 
-		// Round to 2 decimal places
-		amount = math.Round(amount*100) / 100
+	_, err := s.DB.Exec(`
+		INSERT INTO salary_components (tenant_id, name, code, type, is_fixed, is_percentage, is_taxable, calculation_order, formula, description)
+		VALUES ($1, $2, $3, 'earning', $4, false, true, $5, $6, $7)
+		ON CONFLICT (tenant_id, code) WHERE code IS NOT NULL AND deleted_at IS NULL DO NOTHING`,
+		tenantID, defaults.name, code, defaults.formula == "", defaults.calculationOrder,
+		nullIfEmpty(defaults.formula), defaults.description)
+	if err != nil {
+		return nil, err
+	}
 
-		component := models.PayslipComponent{
-			ComponentID:   structComp.ComponentID,
-			ComponentName: structComp.Component.Name,
-			ComponentType: structComp.Component.Type,
-			Amount:        amount,
-			CreatedAt:     time.Now(),
-		}
+	component := models.SalaryComponent{Code: code}
+	var formula sql.NullString
+	err = s.DB.QueryRow(`
+		SELECT id, tenant_id, name, type, is_taxable, calculation_order, formula, min_amount, max_amount
+		FROM salary_components
+		WHERE tenant_id = $1 AND code = $2 AND deleted_at IS NULL`,
+		tenantID, code).Scan(
+		&component.ID, &component.TenantID, &component.Name, &component.Type,
+		&component.IsTaxable, &component.CalculationOrder, &formula,
+		&component.MinAmount, &component.MaxAmount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	component.Formula = formula.String
 
-		components = append(components, component)
+	return &component, nil
+}
 
-		switch structComp.Component.Type {
-		case "earning":
-			totalEarnings += amount
-		case "deduction":
-			totalDeductions += amount
+func hasComponentCode(structure *models.EmployeeSalaryStructure, code string) bool {
+	for _, comp := range structure.Components {
+		if comp.Component != nil && strings.EqualFold(comp.Component.Code, code) {
+			return true
 		}
 	}
-
-	grossSalary := totalEarnings
-	netSalary := grossSalary - totalDeductions
-
-	// Round final amounts
-	grossSalary = math.Round(grossSalary*100) / 100
-	totalDeductions = math.Round(totalDeductions*100) / 100
-	netSalary = math.Round(netSalary*100) / 100
-
-	return grossSalary, totalDeductions, netSalary, components, nil
+	return false
 }
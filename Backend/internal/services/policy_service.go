@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
@@ -20,20 +22,31 @@ func NewPolicyService(db *sql.DB) *PolicyService {
 // Request structs
 type AttendancePolicyRequest struct {
 	WorkingHoursPerDay   *float64 `json:"working_hours_per_day"`
+	WorkStartTime        *string  `json:"work_start_time"`
+	WorkingDays          []string `json:"working_days"`
 	GracePeriodMinutes   *int     `json:"grace_period_minutes"`
 	OvertimeThreshold    *int     `json:"overtime_threshold_minutes"`
+	DefaultCheckoutTime  *string  `json:"default_checkout_time"` // "" removes it
+	LocationEnforcement  *string  `json:"location_enforcement"`
 	RequiredHoursPerWeek *float64 `json:"required_hours_per_week"`
 	RequiredDaysPerMonth *int     `json:"required_days_per_month"`
 	LateFinePerMinute    *float64 `json:"late_fine_per_minute"`
 }
 
 type SalaryComponentRequest struct {
-	Name        string  `json:"name"`
-	Type        string  `json:"type"`
-	IsFixed     bool    `json:"is_fixed"`
-	Amount      float64 `json:"amount"`
-	Percentage  float64 `json:"percentage"`
-	Description string  `json:"description"`
+	Name             string   `json:"name"`
+	Type             string   `json:"type"`
+	IsFixed          bool     `json:"is_fixed"`
+	Amount           float64  `json:"amount"`
+	Percentage       float64  `json:"percentage"`
+	IsPercentage     bool     `json:"is_percentage"`
+	IsTaxable        *bool    `json:"is_taxable"`
+	Code             string   `json:"code"`
+	Formula          string   `json:"formula"`
+	CalculationOrder *int     `json:"calculation_order"`
+	MinAmount        *float64 `json:"min_amount"`
+	MaxAmount        *float64 `json:"max_amount"`
+	Description      string   `json:"description"`
 }
 
 type LeaveTypeRequest struct {
@@ -51,8 +64,12 @@ type AttendancePolicy struct {
 	ID                   uuid.UUID `json:"id"`
 	TenantID             uuid.UUID `json:"tenant_id"`
 	WorkingHoursPerDay   float64   `json:"working_hours_per_day"`
+	WorkStartTime        string    `json:"work_start_time"`
+	WorkingDays          []string  `json:"working_days"`
 	GracePeriodMinutes   int       `json:"grace_period_minutes"`
 	OvertimeThreshold    int       `json:"overtime_threshold_minutes"`
+	DefaultCheckoutTime  *string   `json:"default_checkout_time,omitempty"`
+	LocationEnforcement  string    `json:"location_enforcement"`
 	RequiredHoursPerWeek float64   `json:"required_hours_per_week"`
 	RequiredDaysPerMonth int       `json:"required_days_per_month"`
 	LateFinePerMinute    float64   `json:"late_fine_per_minute"`
@@ -62,10 +79,13 @@ type AttendancePolicy struct {
 // If no policy exists, it returns default values
 func (s *PolicyService) GetAttendancePolicy(tenantID uuid.UUID) (*AttendancePolicy, error) {
 	var policy AttendancePolicy
+	var workingDays []byte
+	var defaultCheckout sql.NullString
 
 	err := s.db.QueryRow(`
-		SELECT id, tenant_id, working_hours_per_day, grace_period_minutes, 
-		       overtime_threshold_minutes, required_hours_per_week, required_days_per_month
+		SELECT id, tenant_id, working_hours_per_day, to_char(work_start_time, 'HH24:MI'), working_days,
+		       grace_period_minutes, overtime_threshold_minutes, required_hours_per_week, required_days_per_month,
+		       to_char(default_checkout_time, 'HH24:MI'), location_enforcement
 		FROM attendance_policies
 		WHERE tenant_id = $1 AND deleted_at IS NULL
 		LIMIT 1
@@ -73,10 +93,14 @@ func (s *PolicyService) GetAttendancePolicy(tenantID uuid.UUID) (*AttendancePoli
 		&policy.ID,
 		&policy.TenantID,
 		&policy.WorkingHoursPerDay,
+		&policy.WorkStartTime,
+		&workingDays,
 		&policy.GracePeriodMinutes,
 		&policy.OvertimeThreshold,
 		&policy.RequiredHoursPerWeek,
 		&policy.RequiredDaysPerMonth,
+		&defaultCheckout,
+		&policy.LocationEnforcement,
 	)
 
 	if err == sql.ErrNoRows {
@@ -85,11 +109,14 @@ func (s *PolicyService) GetAttendancePolicy(tenantID uuid.UUID) (*AttendancePoli
 			ID:                   uuid.New(),
 			TenantID:             tenantID,
 			WorkingHoursPerDay:   8.0,
+			WorkStartTime:        defaultWorkStartTime,
+			WorkingDays:          defaultWorkingDays,
 			GracePeriodMinutes:   15,
 			OvertimeThreshold:    480, // 8 hours in minutes
 			RequiredHoursPerWeek: 40.0,
 			RequiredDaysPerMonth: 22,
 			LateFinePerMinute:    0.0,
+			LocationEnforcement:  models.LocationEnforcementOff,
 		}, nil
 	}
 
@@ -97,6 +124,13 @@ func (s *PolicyService) GetAttendancePolicy(tenantID uuid.UUID) (*AttendancePoli
 		return nil, fmt.Errorf("failed to get attendance policy: %w", err)
 	}
 
+	if err := json.Unmarshal(workingDays, &policy.WorkingDays); err != nil || len(policy.WorkingDays) == 0 {
+		policy.WorkingDays = defaultWorkingDays
+	}
+	if defaultCheckout.Valid {
+		policy.DefaultCheckoutTime = &defaultCheckout.String
+	}
+
 	// Get late fine from tenant settings
 	var settingsJSON sql.NullString
 	err = s.db.QueryRow(`
@@ -149,6 +183,33 @@ func (s *PolicyService) UpdateAttendancePolicy(tenantID uuid.UUID, req Attendanc
 		}
 	}
 
+	if req.WorkStartTime != nil {
+		if _, err := time.Parse("15:04", *req.WorkStartTime); err != nil {
+			return nil, fmt.Errorf("invalid work_start_time, expected HH:MM")
+		}
+		_, err = s.db.Exec(`
+			UPDATE attendance_policies SET work_start_time = $1 WHERE id = $2
+		`, *req.WorkStartTime, policyID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(req.WorkingDays) > 0 {
+		for _, day := range req.WorkingDays {
+			if !isWeekdayName(day) {
+				return nil, fmt.Errorf("invalid working day: %s", day)
+			}
+		}
+		workingDaysJSON, _ := json.Marshal(req.WorkingDays)
+		_, err = s.db.Exec(`
+			UPDATE attendance_policies SET working_days = $1 WHERE id = $2
+		`, workingDaysJSON, policyID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if req.GracePeriodMinutes != nil {
 		_, err = s.db.Exec(`
 			UPDATE attendance_policies SET grace_period_minutes = $1 WHERE id = $2
@@ -167,6 +228,34 @@ func (s *PolicyService) UpdateAttendancePolicy(tenantID uuid.UUID, req Attendanc
 		}
 	}
 
+	if req.DefaultCheckoutTime != nil {
+		var checkout interface{}
+		if *req.DefaultCheckoutTime != "" {
+			if _, err := time.Parse("15:04", *req.DefaultCheckoutTime); err != nil {
+				return nil, fmt.Errorf("invalid default_checkout_time, expected HH:MM")
+			}
+			checkout = *req.DefaultCheckoutTime
+		}
+		_, err = s.db.Exec(`
+			UPDATE attendance_policies SET default_checkout_time = $1 WHERE id = $2
+		`, checkout, policyID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.LocationEnforcement != nil {
+		if !validLocationEnforcement(*req.LocationEnforcement) {
+			return nil, fmt.Errorf("invalid location_enforcement, expected off, flag or reject")
+		}
+		_, err = s.db.Exec(`
+			UPDATE attendance_policies SET location_enforcement = $1 WHERE id = $2
+		`, *req.LocationEnforcement, policyID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if req.RequiredHoursPerWeek != nil {
 		_, err = s.db.Exec(`
 			UPDATE attendance_policies SET required_hours_per_week = $1 WHERE id = $2
@@ -211,13 +300,23 @@ func (s *PolicyService) UpdateAttendancePolicy(tenantID uuid.UUID, req Attendanc
 	return s.GetAttendancePolicy(tenantID)
 }
 
+// isWeekdayName reports whether s is a lowercase English weekday name
+func isWeekdayName(s string) bool {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.ToLower(d.String()) == s {
+			return true
+		}
+	}
+	return false
+}
+
 // GetSalaryComponents retrieves all salary components for a tenant
 func (s *PolicyService) GetSalaryComponents(tenantID uuid.UUID) ([]*models.SalaryComponent, error) {
 	rows, err := s.db.Query(`
-		SELECT id, tenant_id, name, type, is_fixed, amount, percentage, description, created_at, updated_at
+		SELECT `+salaryComponentColumns+`
 		FROM salary_components
 		WHERE tenant_id = $1 AND deleted_at IS NULL
-		ORDER BY type, name
+		ORDER BY calculation_order, type, name
 	`, tenantID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get salary components: %w", err)
@@ -226,23 +325,11 @@ func (s *PolicyService) GetSalaryComponents(tenantID uuid.UUID) ([]*models.Salar
 
 	var components []*models.SalaryComponent
 	for rows.Next() {
-		var comp models.SalaryComponent
-		err := rows.Scan(
-			&comp.ID,
-			&comp.TenantID,
-			&comp.Name,
-			&comp.Type,
-			&comp.IsFixed,
-			&comp.Amount,
-			&comp.Percentage,
-			&comp.Description,
-			&comp.CreatedAt,
-			&comp.UpdatedAt,
-		)
+		comp, err := scanSalaryComponent(rows)
 		if err != nil {
 			return nil, err
 		}
-		components = append(components, &comp)
+		components = append(components, comp)
 	}
 
 	return components, nil
@@ -250,25 +337,73 @@ func (s *PolicyService) GetSalaryComponents(tenantID uuid.UUID) ([]*models.Salar
 
 // CreateSalaryComponent creates a new salary component
 func (s *PolicyService) CreateSalaryComponent(tenantID uuid.UUID, req SalaryComponentRequest) (*models.SalaryComponent, error) {
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if code != "" {
+		if _, system := systemSalaryComponents[code]; system || payrollVariables[code] {
+			return nil, fmt.Errorf("code %s is reserved", code)
+		}
+		for i, r := range code {
+			if !(r == '_' || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9')) {
+				return nil, fmt.Errorf("code must start with a letter and contain only letters, digits and underscores")
+			}
+		}
+	}
+
+	formula := strings.TrimSpace(req.Formula)
+	if formula != "" {
+		parsed, err := parseFormula(formula)
+		if err != nil {
+			return nil, fmt.Errorf("invalid formula: %w", err)
+		}
+		for _, ref := range parsed.refs {
+			if ref == code {
+				return nil, fmt.Errorf("formula cannot reference its own component")
+			}
+		}
+	}
+
+	if req.MinAmount != nil && req.MaxAmount != nil && *req.MinAmount > *req.MaxAmount {
+		return nil, fmt.Errorf("min_amount cannot be greater than max_amount")
+	}
+
+	isTaxable := true
+	if req.IsTaxable != nil {
+		isTaxable = *req.IsTaxable
+	}
+	calculationOrder := 100
+	if req.CalculationOrder != nil {
+		calculationOrder = *req.CalculationOrder
+	}
+
 	componentID := uuid.New()
 
 	_, err := s.db.Exec(`
 		INSERT INTO salary_components (
-			id, tenant_id, name, type, is_fixed, amount, percentage, description
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-	`, componentID, tenantID, req.Name, req.Type, req.IsFixed, req.Amount, req.Percentage, req.Description)
+			id, tenant_id, name, type, is_fixed, amount, percentage, description,
+			is_percentage, is_taxable, code, formula, calculation_order, min_amount, max_amount
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`, componentID, tenantID, req.Name, req.Type, req.IsFixed, req.Amount, req.Percentage, req.Description,
+		req.IsPercentage, isTaxable, nullIfEmpty(code), nullIfEmpty(formula), calculationOrder, req.MinAmount, req.MaxAmount)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create salary component: %w", err)
 	}
 
 	// Fetch and return the created component
-	var comp models.SalaryComponent
-	err = s.db.QueryRow(`
-		SELECT id, tenant_id, name, type, is_fixed, amount, percentage, description, created_at, updated_at
+	return scanSalaryComponent(s.db.QueryRow(`
+		SELECT `+salaryComponentColumns+`
 		FROM salary_components
 		WHERE id = $1
-	`, componentID).Scan(
+	`, componentID))
+}
+
+const salaryComponentColumns = `id, tenant_id, name, type, is_fixed, amount, percentage, description,
+		is_percentage, is_taxable, code, formula, calculation_order, min_amount, max_amount, created_at, updated_at`
+
+func scanSalaryComponent(row interface{ Scan(...interface{}) error }) (*models.SalaryComponent, error) {
+	var comp models.SalaryComponent
+	var description, code, formula sql.NullString
+	err := row.Scan(
 		&comp.ID,
 		&comp.TenantID,
 		&comp.Name,
@@ -276,15 +411,23 @@ func (s *PolicyService) CreateSalaryComponent(tenantID uuid.UUID, req SalaryComp
 		&comp.IsFixed,
 		&comp.Amount,
 		&comp.Percentage,
-		&comp.Description,
+		&description,
+		&comp.IsPercentage,
+		&comp.IsTaxable,
+		&code,
+		&formula,
+		&comp.CalculationOrder,
+		&comp.MinAmount,
+		&comp.MaxAmount,
 		&comp.CreatedAt,
 		&comp.UpdatedAt,
 	)
-
 	if err != nil {
 		return nil, err
 	}
-
+	comp.Description = description.String
+	comp.Code = code.String
+	comp.Formula = formula.String
 	return &comp, nil
 }
 
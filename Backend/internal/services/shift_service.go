@@ -0,0 +1,523 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
+)
+
+type ShiftService struct {
+	db *sql.DB
+}
+
+func NewShiftService(db *sql.DB) *ShiftService {
+	return &ShiftService{db: db}
+}
+
+// GetShiftTemplates lists the tenant's shift templates
+func (s *ShiftService) GetShiftTemplates(ctx context.Context, tenantID uuid.UUID) ([]models.ShiftTemplate, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, tenant_id, name, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI'),
+		       break_duration_minutes, grace_period_minutes, color, is_active, created_at, updated_at
+		FROM shift_templates
+		WHERE tenant_id = $1 AND deleted_at IS NULL
+		ORDER BY start_time, name`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shift templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := make([]models.ShiftTemplate, 0)
+	for rows.Next() {
+		var t models.ShiftTemplate
+		var grace sql.NullInt64
+		var color sql.NullString
+		if err := rows.Scan(&t.ID, &t.TenantID, &t.Name, &t.StartTime, &t.EndTime,
+			&t.BreakDurationMinutes, &grace, &color, &t.IsActive, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan shift template: %w", err)
+		}
+		if grace.Valid {
+			g := int(grace.Int64)
+			t.GracePeriodMinutes = &g
+		}
+		if color.Valid {
+			t.Color = &color.String
+		}
+		t.CrossesMidnight = t.EndTime <= t.StartTime
+		templates = append(templates, t)
+	}
+
+	return templates, nil
+}
+
+// CreateShiftTemplate creates a new shift template
+func (s *ShiftService) CreateShiftTemplate(ctx context.Context, tenantID uuid.UUID, req models.CreateShiftTemplateRequest) (*models.ShiftTemplate, error) {
+	if err := validateShiftTemplate(req); err != nil {
+		return nil, err
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	now := time.Now()
+	t := models.ShiftTemplate{
+		ID:                   uuid.New(),
+		TenantID:             tenantID,
+		Name:                 req.Name,
+		StartTime:            req.StartTime,
+		EndTime:              req.EndTime,
+		BreakDurationMinutes: req.BreakDurationMinutes,
+		GracePeriodMinutes:   req.GracePeriodMinutes,
+		Color:                req.Color,
+		IsActive:             isActive,
+		CrossesMidnight:      req.EndTime <= req.StartTime,
+		CreatedAt:            now,
+		UpdatedAt:            now,
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO shift_templates (id, tenant_id, name, start_time, end_time, break_duration_minutes,
+		                             grace_period_minutes, color, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		t.ID, t.TenantID, t.Name, t.StartTime, t.EndTime, t.BreakDurationMinutes,
+		t.GracePeriodMinutes, t.Color, t.IsActive, t.CreatedAt, t.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shift template: %w", err)
+	}
+
+	return &t, nil
+}
+
+// UpdateShiftTemplate replaces the definition of an existing shift template
+func (s *ShiftService) UpdateShiftTemplate(ctx context.Context, tenantID, templateID uuid.UUID, req models.CreateShiftTemplateRequest) (*models.ShiftTemplate, error) {
+	if err := validateShiftTemplate(req); err != nil {
+		return nil, err
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE shift_templates
+		SET name = $1, start_time = $2, end_time = $3, break_duration_minutes = $4,
+		    grace_period_minutes = $5, color = $6, is_active = $7, updated_at = $8
+		WHERE id = $9 AND tenant_id = $10 AND deleted_at IS NULL`,
+		req.Name, req.StartTime, req.EndTime, req.BreakDurationMinutes,
+		req.GracePeriodMinutes, req.Color, isActive, time.Now(), templateID, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update shift template: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, fmt.Errorf("shift template not found")
+	}
+
+	return s.getShiftTemplate(ctx, tenantID, templateID)
+}
+
+// DeleteShiftTemplate soft deletes a shift template. Existing roster entries keep referencing it.
+func (s *ShiftService) DeleteShiftTemplate(ctx context.Context, tenantID, templateID uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE shift_templates SET deleted_at = $1, is_active = false
+		WHERE id = $2 AND tenant_id = $3 AND deleted_at IS NULL`,
+		time.Now(), templateID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete shift template: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("shift template not found")
+	}
+
+	return nil
+}
+
+func (s *ShiftService) getShiftTemplate(ctx context.Context, tenantID, templateID uuid.UUID) (*models.ShiftTemplate, error) {
+	var t models.ShiftTemplate
+	var grace sql.NullInt64
+	var color sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, tenant_id, name, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI'),
+		       break_duration_minutes, grace_period_minutes, color, is_active, created_at, updated_at
+		FROM shift_templates
+		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL`, templateID, tenantID).Scan(
+		&t.ID, &t.TenantID, &t.Name, &t.StartTime, &t.EndTime,
+		&t.BreakDurationMinutes, &grace, &color, &t.IsActive, &t.CreatedAt, &t.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("shift template not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shift template: %w", err)
+	}
+
+	if grace.Valid {
+		g := int(grace.Int64)
+		t.GracePeriodMinutes = &g
+	}
+	if color.Valid {
+		t.Color = &color.String
+	}
+	t.CrossesMidnight = t.EndTime <= t.StartTime
+
+	return &t, nil
+}
+
+// CreateRoster writes draft roster entries for the week starting at req.WeekStart.
+// Existing draft entries of the affected employees in that week are replaced;
+// published entries are left untouched.
+func (s *ShiftService) CreateRoster(ctx context.Context, tenantID, createdBy uuid.UUID, req models.CreateRosterRequest) ([]models.ShiftAssignment, error) {
+	weekStart, err := time.Parse("2006-01-02", req.WeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid week_start, expected YYYY-MM-DD")
+	}
+	weekEnd := weekStart.AddDate(0, 0, 6)
+
+	if len(req.Entries) == 0 {
+		return nil, fmt.Errorf("roster must contain at least one entry")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	assignments := make([]models.ShiftAssignment, 0)
+	cleared := make(map[uuid.UUID]bool)
+	now := time.Now()
+
+	for _, entry := range req.Entries {
+		templateID, err := uuid.Parse(entry.ShiftTemplateID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid shift_template_id: %s", entry.ShiftTemplateID)
+		}
+
+		var exists bool
+		err = tx.QueryRowContext(ctx, `
+			SELECT EXISTS(SELECT 1 FROM shift_templates WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL AND is_active = true)`,
+			templateID, tenantID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check shift template: %w", err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("shift template not found: %s", templateID)
+		}
+
+		employeeIDs, err := s.resolveRosterEmployees(ctx, tx, tenantID, entry)
+		if err != nil {
+			return nil, err
+		}
+
+		weekdays := entry.Weekdays
+		if len(weekdays) == 0 {
+			weekdays = defaultWorkingDays
+		}
+
+		var notes *string
+		if entry.Notes != "" {
+			notes = &entry.Notes
+		}
+
+		for _, employeeID := range employeeIDs {
+			if !cleared[employeeID] {
+				_, err = tx.ExecContext(ctx, `
+					DELETE FROM shift_assignments
+					WHERE tenant_id = $1 AND employee_id = $2 AND shift_date BETWEEN $3 AND $4 AND status = 'draft'`,
+					tenantID, employeeID, weekStart, weekEnd)
+				if err != nil {
+					return nil, fmt.Errorf("failed to clear draft roster: %w", err)
+				}
+				cleared[employeeID] = true
+			}
+
+			for d := weekStart; !d.After(weekEnd); d = d.AddDate(0, 0, 1) {
+				if !containsWeekday(weekdays, d.Weekday()) {
+					continue
+				}
+
+				a := models.ShiftAssignment{
+					ID:              uuid.New(),
+					TenantID:        tenantID,
+					EmployeeID:      employeeID,
+					ShiftTemplateID: templateID,
+					ShiftDate:       d,
+					Status:          models.ShiftStatusDraft,
+					Notes:           notes,
+					CreatedBy:       &createdBy,
+					CreatedAt:       now,
+					UpdatedAt:       now,
+				}
+
+				result, err := tx.ExecContext(ctx, `
+					INSERT INTO shift_assignments (id, tenant_id, employee_id, shift_template_id, shift_date, status, notes, created_by, created_at, updated_at)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+					ON CONFLICT (tenant_id, employee_id, shift_date, shift_template_id) DO NOTHING`,
+					a.ID, a.TenantID, a.EmployeeID, a.ShiftTemplateID, d.Format("2006-01-02"),
+					a.Status, a.Notes, a.CreatedBy, a.CreatedAt, a.UpdatedAt)
+				if err != nil {
+					return nil, fmt.Errorf("failed to create roster entry: %w", err)
+				}
+				if n, _ := result.RowsAffected(); n > 0 {
+					assignments = append(assignments, a)
+				}
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit roster: %w", err)
+	}
+
+	return assignments, nil
+}
+
+// resolveRosterEmployees expands a roster entry to the employees it applies to
+func (s *ShiftService) resolveRosterEmployees(ctx context.Context, tx *sql.Tx, tenantID uuid.UUID, entry models.RosterEntryRequest) ([]uuid.UUID, error) {
+	if entry.EmployeeID != "" {
+		employeeID, err := uuid.Parse(entry.EmployeeID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid employee_id: %s", entry.EmployeeID)
+		}
+
+		var exists bool
+		err = tx.QueryRowContext(ctx,
+			"SELECT EXISTS(SELECT 1 FROM employees WHERE id = $1 AND tenant_id = $2)",
+			employeeID, tenantID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check employee: %w", err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("employee not found: %s", employeeID)
+		}
+		return []uuid.UUID{employeeID}, nil
+	}
+
+	if entry.TeamID == "" {
+		return nil, fmt.Errorf("each roster entry needs an employee_id or team_id")
+	}
+
+	teamID, err := uuid.Parse(entry.TeamID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid team_id: %s", entry.TeamID)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT e.id
+		FROM employees e
+		JOIN users u ON e.user_id = u.id
+		WHERE e.tenant_id = $1 AND u.team_id = $2 AND e.employment_status = 'active'`,
+		tenantID, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query team members: %w", err)
+	}
+	defer rows.Close()
+
+	employeeIDs := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan team member: %w", err)
+		}
+		employeeIDs = append(employeeIDs, id)
+	}
+	if len(employeeIDs) == 0 {
+		return nil, fmt.Errorf("team has no active members: %s", teamID)
+	}
+
+	return employeeIDs, nil
+}
+
+// PublishRoster publishes every draft entry in the week starting at weekStart
+func (s *ShiftService) PublishRoster(ctx context.Context, tenantID uuid.UUID, weekStart time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE shift_assignments
+		SET status = 'published', published_at = $1, updated_at = $1
+		WHERE tenant_id = $2 AND shift_date BETWEEN $3 AND $4 AND status = 'draft'`,
+		time.Now(), tenantID, weekStart, weekStart.AddDate(0, 0, 6))
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish roster: %w", err)
+	}
+
+	published, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return published, nil
+}
+
+// SwapShifts exchanges the employees assigned to two roster entries
+func (s *ShiftService) SwapShifts(ctx context.Context, tenantID, assignmentID, otherAssignmentID uuid.UUID) error {
+	if assignmentID == otherAssignmentID {
+		return fmt.Errorf("cannot swap a shift with itself")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var firstEmployee, secondEmployee uuid.UUID
+	err = tx.QueryRowContext(ctx,
+		"SELECT employee_id FROM shift_assignments WHERE id = $1 AND tenant_id = $2 FOR UPDATE",
+		assignmentID, tenantID).Scan(&firstEmployee)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("shift assignment not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get shift assignment: %w", err)
+	}
+
+	err = tx.QueryRowContext(ctx,
+		"SELECT employee_id FROM shift_assignments WHERE id = $1 AND tenant_id = $2 FOR UPDATE",
+		otherAssignmentID, tenantID).Scan(&secondEmployee)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("shift assignment not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get shift assignment: %w", err)
+	}
+
+	if firstEmployee == secondEmployee {
+		return fmt.Errorf("both shifts are assigned to the same employee")
+	}
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx,
+		"UPDATE shift_assignments SET employee_id = $1, updated_at = $2 WHERE id = $3",
+		secondEmployee, now, assignmentID)
+	if err != nil {
+		return fmt.Errorf("failed to swap shift: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		"UPDATE shift_assignments SET employee_id = $1, updated_at = $2 WHERE id = $3",
+		firstEmployee, now, otherAssignmentID)
+	if err != nil {
+		return fmt.Errorf("failed to swap shift: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit shift swap: %w", err)
+	}
+
+	return nil
+}
+
+// GetRoster lists roster entries between startDate and endDate, optionally for a single
+// employee and restricted to published entries
+func (s *ShiftService) GetRoster(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time, employeeID *uuid.UUID, publishedOnly bool) ([]models.ShiftAssignment, error) {
+	query := `
+		SELECT sa.id, sa.tenant_id, sa.employee_id, sa.shift_template_id, sa.shift_date, sa.status,
+		       sa.notes, sa.created_by, sa.published_at, sa.created_at, sa.updated_at,
+		       CONCAT(u.first_name, ' ', u.last_name) as employee_name,
+		       st.name, to_char(st.start_time, 'HH24:MI'), to_char(st.end_time, 'HH24:MI')
+		FROM shift_assignments sa
+		JOIN shift_templates st ON sa.shift_template_id = st.id
+		JOIN employees e ON sa.employee_id = e.id
+		JOIN users u ON e.user_id = u.id
+		WHERE sa.tenant_id = $1 AND sa.shift_date BETWEEN $2 AND $3`
+	args := []interface{}{tenantID, startDate, endDate}
+
+	if employeeID != nil {
+		args = append(args, *employeeID)
+		query += fmt.Sprintf(" AND sa.employee_id = $%d", len(args))
+	}
+	if publishedOnly {
+		query += " AND sa.status = 'published'"
+	}
+	query += " ORDER BY sa.shift_date, st.start_time, employee_name"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query roster: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := make([]models.ShiftAssignment, 0)
+	for rows.Next() {
+		var a models.ShiftAssignment
+		var notes sql.NullString
+		var createdBy uuid.NullUUID
+		var publishedAt sql.NullTime
+		if err := rows.Scan(&a.ID, &a.TenantID, &a.EmployeeID, &a.ShiftTemplateID, &a.ShiftDate, &a.Status,
+			&notes, &createdBy, &publishedAt, &a.CreatedAt, &a.UpdatedAt,
+			&a.EmployeeName, &a.ShiftName, &a.StartTime, &a.EndTime); err != nil {
+			return nil, fmt.Errorf("failed to scan roster entry: %w", err)
+		}
+		if notes.Valid {
+			a.Notes = &notes.String
+		}
+		if createdBy.Valid {
+			a.CreatedBy = &createdBy.UUID
+		}
+		if publishedAt.Valid {
+			a.PublishedAt = &publishedAt.Time
+		}
+		assignments = append(assignments, a)
+	}
+
+	return assignments, nil
+}
+
+// GetEmployeeIDByUserID gets the employee ID for a given user ID
+func (s *ShiftService) GetEmployeeIDByUserID(ctx context.Context, tenantID, userID uuid.UUID) (uuid.UUID, error) {
+	var employeeID uuid.UUID
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id FROM employees WHERE tenant_id = $1 AND user_id = $2",
+		tenantID, userID).Scan(&employeeID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get employee ID for user: %w", err)
+	}
+
+	return employeeID, nil
+}
+
+func validateShiftTemplate(req models.CreateShiftTemplateRequest) error {
+	if strings.TrimSpace(req.Name) == "" {
+		return fmt.Errorf("shift name is required")
+	}
+	start, err := time.Parse("15:04", req.StartTime)
+	if err != nil {
+		return fmt.Errorf("invalid start_time, expected HH:MM")
+	}
+	end, err := time.Parse("15:04", req.EndTime)
+	if err != nil {
+		return fmt.Errorf("invalid end_time, expected HH:MM")
+	}
+	if start.Equal(end) {
+		return fmt.Errorf("start_time and end_time must differ")
+	}
+	if req.BreakDurationMinutes < 0 {
+		return fmt.Errorf("break_duration_minutes cannot be negative")
+	}
+	return nil
+}
+
+func containsWeekday(days []string, weekday time.Weekday) bool {
+	name := strings.ToLower(weekday.String())
+	for _, d := range days {
+		if strings.ToLower(d) == name {
+			return true
+		}
+	}
+	return false
+}
@@ -399,6 +399,37 @@ func (s *SubscriptionService) CancelSubscription(ctx context.Context, tenantID u
 	return nil
 }
 
+// subscriptionGracePeriod is how long a subscription stays past due before it expires
+const subscriptionGracePeriod = 7 * 24 * time.Hour
+
+// ExpireSubscriptions moves subscriptions whose period has ended to their next status. Trials
+// past trial_ends_at and periods ending without auto-renewal expire; an auto-renewing period
+// that was not renewed becomes past due and expires after a grace period. It returns the
+// number of subscriptions changed.
+func (s *SubscriptionService) ExpireSubscriptions(ctx context.Context, now time.Time) (int, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE subscriptions
+		SET status = CASE
+				WHEN status = 'trial' THEN 'expired'
+				WHEN status = 'active' AND COALESCE(auto_renew, false) THEN 'past_due'
+				ELSE 'expired'
+			END,
+			updated_at = $1
+		WHERE (status = 'trial' AND COALESCE(trial_ends_at, current_period_end) <= $1)
+		   OR (status = 'active' AND current_period_end <= $1)
+		   OR (status = 'past_due' AND current_period_end <= $2)`,
+		now, now.Add(-subscriptionGracePeriod))
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire subscriptions: %w", err)
+	}
+
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire subscriptions: %w", err)
+	}
+	return int(changed), nil
+}
+
 // Helper function to join strings
 func joinStrings(parts []string, sep string) string {
 	if len(parts) == 0 {
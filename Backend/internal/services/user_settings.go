@@ -3,11 +3,15 @@ package services
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/auth"
+	"github.com/rajanprasaila/PeopleOS/backend/peopleos-api/internal/models"
 )
 
 // UserProfile represents a user's profile information
@@ -43,18 +47,29 @@ type UserPreference struct {
 	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// ErrInvalidSecurityPolicy is returned for IP restrictions, session timeouts and password
+// expiries that cannot be saved
+var ErrInvalidSecurityPolicy = errors.New("invalid security settings")
+
+// Limits of the security policy of a user or organization
+const (
+	minSessionTimeoutSeconds = 5 * 60
+	maxSessionTimeoutSeconds = 90 * 24 * 60 * 60
+	maxPasswordExpiryDays    = 3650
+)
+
 // SecuritySettings represents user security preferences
 type SecuritySettings struct {
 	ID                 uuid.UUID      `json:"id" db:"id"`
 	UserID             uuid.UUID      `json:"user_id" db:"user_id"`
 	TenantID           uuid.UUID      `json:"tenant_id" db:"tenant_id"`
 	TwoFactorEnabled   bool           `json:"two_factor_enabled" db:"two_factor_enabled"`
-	SessionTimeout     int            `json:"session_timeout" db:"session_timeout"`
+	SessionTimeout     *int           `json:"session_timeout" db:"session_timeout"` // seconds; nil leaves the organization default
 	LoginNotifications bool           `json:"login_notifications" db:"login_notifications"`
 	DeviceTracking     bool           `json:"device_tracking" db:"device_tracking"`
 	IPRestrictions     pq.StringArray `json:"ip_restrictions" db:"ip_restrictions"`
 	LastPasswordChange *time.Time     `json:"last_password_change" db:"last_password_change"`
-	PasswordExpiryDays int            `json:"password_expiry_days" db:"password_expiry_days"`
+	PasswordExpiryDays *int           `json:"password_expiry_days" db:"password_expiry_days"` // nil leaves the organization default
 	CreatedAt          time.Time      `json:"created_at" db:"created_at"`
 	UpdatedAt          time.Time      `json:"updated_at" db:"updated_at"`
 }
@@ -273,16 +288,35 @@ func (s *UserSettingsService) GetSecuritySettings(userID uuid.UUID) (*SecuritySe
 	return &settings, nil
 }
 
-// UpdateSecuritySettings updates user security settings
-func (s *UserSettingsService) UpdateSecuritySettings(userID uuid.UUID, settings SecuritySettings) error {
+// UpdateSecuritySettings updates user security settings. TwoFactorEnabled is ignored: it
+// follows the user's enrolment under /settings/security/2fa. The settings may only tighten
+// the security defaults of the user's organization. clientIP is the address of the request,
+// which new IP restrictions must allow so users cannot lock themselves out.
+func (s *UserSettingsService) UpdateSecuritySettings(userID uuid.UUID, settings SecuritySettings, clientIP string) error {
+	org := &models.SecurityDefaults{}
+	err := s.db.QueryRow(`
+		SELECT COALESCE(t.default_ip_restrictions, '{}'), t.default_session_timeout, t.default_password_expiry_days
+		FROM users u
+		LEFT JOIN tenants t ON t.id = u.tenant_id
+		WHERE u.id = $1`, userID).Scan(&org.IPRestrictions, &org.SessionTimeout, &org.PasswordExpiryDays)
+	if err != nil {
+		return fmt.Errorf("failed to get security defaults: %w", err)
+	}
+
+	restrictions, err := validateSecurityPolicy(settings.IPRestrictions, settings.SessionTimeout, settings.PasswordExpiryDays, clientIP, org)
+	if err != nil {
+		return err
+	}
+	settings.IPRestrictions = restrictions
+
 	query := `
 		UPDATE security_settings 
-		SET two_factor_enabled = $1, session_timeout = $2, login_notifications = $3,
-		    device_tracking = $4, ip_restrictions = $5, password_expiry_days = $6,
+		SET session_timeout = $1, login_notifications = $2,
+		    device_tracking = $3, ip_restrictions = $4, password_expiry_days = $5,
 		    updated_at = CURRENT_TIMESTAMP
-		WHERE user_id = $7`
+		WHERE user_id = $6`
 
-	_, err := s.db.Exec(query, settings.TwoFactorEnabled, settings.SessionTimeout,
+	_, err = s.db.Exec(query, settings.SessionTimeout,
 		settings.LoginNotifications, settings.DeviceTracking, settings.IPRestrictions,
 		settings.PasswordExpiryDays, userID)
 
@@ -293,6 +327,67 @@ func (s *UserSettingsService) UpdateSecuritySettings(userID uuid.UUID, settings
 	return nil
 }
 
+// validateSecurityPolicy checks the IP restrictions, session timeout and password expiry of a
+// user or organization, and returns the IP restrictions without blank entries. A user's
+// settings are checked against the defaults of their organization, org, which they may only
+// tighten; org is nil for the defaults themselves.
+func validateSecurityPolicy(ipRestrictions []string, sessionTimeout, passwordExpiryDays *int, clientIP string, org *models.SecurityDefaults) (pq.StringArray, error) {
+	restrictions := pq.StringArray{}
+	for _, entry := range ipRestrictions {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			restrictions = append(restrictions, entry)
+		}
+	}
+	networks, err := auth.ParseIPRestrictions(restrictions)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSecurityPolicy, err)
+	}
+	if !auth.IPAllowed(networks, clientIP) {
+		return nil, fmt.Errorf("%w: IP restrictions must include your current address %s", ErrInvalidSecurityPolicy, clientIP)
+	}
+
+	if sessionTimeout != nil && *sessionTimeout != 0 &&
+		(*sessionTimeout < minSessionTimeoutSeconds || *sessionTimeout > maxSessionTimeoutSeconds) {
+		return nil, fmt.Errorf("%w: session timeout must be 0 or between %d and %d seconds",
+			ErrInvalidSecurityPolicy, minSessionTimeoutSeconds, maxSessionTimeoutSeconds)
+	}
+	if passwordExpiryDays != nil && (*passwordExpiryDays < 0 || *passwordExpiryDays > maxPasswordExpiryDays) {
+		return nil, fmt.Errorf("%w: password expiry must be between 0 and %d days",
+			ErrInvalidSecurityPolicy, maxPasswordExpiryDays)
+	}
+
+	if org != nil {
+		orgNetworks, err := auth.ParseIPRestrictions(org.IPRestrictions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse organization IP restrictions: %w", err)
+		}
+		if !auth.NetworksWithin(networks, orgNetworks) {
+			return nil, fmt.Errorf("%w: IP restrictions must fall within your organization's (%s)",
+				ErrInvalidSecurityPolicy, strings.Join(org.IPRestrictions, ", "))
+		}
+		// Unset values follow the organization; set ones must not be looser, and zero is no limit
+		if limit := intValue(org.SessionTimeout); limit > 0 && sessionTimeout != nil &&
+			(*sessionTimeout == 0 || *sessionTimeout > limit) {
+			return nil, fmt.Errorf("%w: session timeout cannot exceed your organization's %d seconds",
+				ErrInvalidSecurityPolicy, limit)
+		}
+		if limit := intValue(org.PasswordExpiryDays); limit > 0 && passwordExpiryDays != nil &&
+			(*passwordExpiryDays == 0 || *passwordExpiryDays > limit) {
+			return nil, fmt.Errorf("%w: password expiry cannot exceed your organization's %d days",
+				ErrInvalidSecurityPolicy, limit)
+		}
+	}
+	return restrictions, nil
+}
+
+// intValue dereferences an optional limit, where nil means no limit
+func intValue(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
 // ===== THEME SETTINGS METHODS =====
 
 // GetUserTheme retrieves user theme settings
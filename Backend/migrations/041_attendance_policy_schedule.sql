@@ -0,0 +1,13 @@
+-- Migration: 041_attendance_policy_schedule.sql
+-- Description: Add schedule columns to attendance policies and an early-departure flag to attendance records
+
+-- Attendance policies recreated in 037 lost the columns used by CreateAttendancePolicy
+ALTER TABLE attendance_policies ADD COLUMN IF NOT EXISTS name VARCHAR(255) NOT NULL DEFAULT 'Default';
+ALTER TABLE attendance_policies ADD COLUMN IF NOT EXISTS work_start_time TIME NOT NULL DEFAULT '09:00';
+ALTER TABLE attendance_policies ADD COLUMN IF NOT EXISTS working_days JSONB NOT NULL DEFAULT '["monday","tuesday","wednesday","thursday","friday"]'::jsonb;
+ALTER TABLE attendance_policies ADD COLUMN IF NOT EXISTS break_duration_minutes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE attendance_policies ADD COLUMN IF NOT EXISTS is_default BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE attendance_policies ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT true;
+
+-- Early departure is tracked separately from status so that 'late' is preserved
+ALTER TABLE attendance_records ADD COLUMN IF NOT EXISTS is_early_departure BOOLEAN NOT NULL DEFAULT false;
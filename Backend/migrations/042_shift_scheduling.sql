@@ -0,0 +1,69 @@
+-- Migration: 042_shift_scheduling.sql
+-- Description: Shift templates and weekly rosters; attendance records reference the shift they were judged against
+
+-- Shift Templates Table
+-- A shift whose end_time is not after start_time crosses midnight
+CREATE TABLE IF NOT EXISTS shift_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    break_duration_minutes INTEGER NOT NULL DEFAULT 0,
+    grace_period_minutes INTEGER,
+    color VARCHAR(20),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Shift Assignments Table (roster entries, one per employee per shift per day)
+CREATE TABLE IF NOT EXISTS shift_assignments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    employee_id UUID NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    shift_template_id UUID NOT NULL REFERENCES shift_templates(id) ON DELETE CASCADE,
+    shift_date DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'published')),
+    notes TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    published_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, employee_id, shift_date, shift_template_id)
+);
+
+ALTER TABLE attendance_records ADD COLUMN IF NOT EXISTS shift_assignment_id UUID REFERENCES shift_assignments(id) ON DELETE SET NULL;
+
+-- Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_shift_templates_tenant ON shift_templates(tenant_id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_shift_assignments_employee_date ON shift_assignments(tenant_id, employee_id, shift_date);
+CREATE INDEX IF NOT EXISTS idx_shift_assignments_tenant_date ON shift_assignments(tenant_id, shift_date);
+
+-- Enable RLS
+ALTER TABLE shift_templates ENABLE ROW LEVEL SECURITY;
+ALTER TABLE shift_assignments ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS shift_templates_tenant_isolation ON shift_templates;
+CREATE POLICY shift_templates_tenant_isolation ON shift_templates
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
+
+DROP POLICY IF EXISTS shift_assignments_tenant_isolation ON shift_assignments;
+CREATE POLICY shift_assignments_tenant_isolation ON shift_assignments
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
+
+-- Update triggers for updated_at
+DROP TRIGGER IF EXISTS update_shift_templates_updated_at ON shift_templates;
+CREATE TRIGGER update_shift_templates_updated_at
+    BEFORE UPDATE ON shift_templates
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_shift_assignments_updated_at ON shift_assignments;
+CREATE TRIGGER update_shift_assignments_updated_at
+    BEFORE UPDATE ON shift_assignments
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
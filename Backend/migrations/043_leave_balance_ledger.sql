@@ -0,0 +1,66 @@
+-- Migration: 043_leave_balance_ledger.sql
+-- Description: Append-only leave balance ledger with accrual and year-end rollover entries
+
+-- Leave types recreated in 037 lost the accrual columns used by LeaveTypeConfig
+ALTER TABLE leave_types ADD COLUMN IF NOT EXISTS short_code VARCHAR(50);
+ALTER TABLE leave_types ADD COLUMN IF NOT EXISTS accrual_type VARCHAR(20) DEFAULT 'yearly';
+ALTER TABLE leave_types ADD COLUMN IF NOT EXISTS accrual_amount DECIMAL(5,2);
+ALTER TABLE leave_types ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT true;
+
+ALTER TABLE leave_types DROP CONSTRAINT IF EXISTS leave_types_accrual_type_check;
+ALTER TABLE leave_types ADD CONSTRAINT leave_types_accrual_type_check
+    CHECK (accrual_type IN ('monthly', 'quarterly', 'yearly', 'fixed'));
+
+-- Leave Ledger Entries Table
+-- Balances are the sum of entries per employee, leave type and year. Entries are never updated or deleted.
+CREATE TABLE IF NOT EXISTS leave_ledger_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    employee_id UUID NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    leave_type VARCHAR(50) NOT NULL,
+    year INTEGER NOT NULL,
+    entry_type VARCHAR(20) NOT NULL CHECK (entry_type IN ('accrual', 'debit', 'refund', 'carry_forward', 'expiry', 'adjustment')),
+    days DECIMAL(6,2) NOT NULL,
+    period VARCHAR(10),
+    leave_request_id UUID REFERENCES leave_requests(id) ON DELETE SET NULL,
+    notes TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Accrual and rollover runs are idempotent per period
+CREATE UNIQUE INDEX IF NOT EXISTS idx_leave_ledger_period_unique
+    ON leave_ledger_entries(tenant_id, employee_id, leave_type, entry_type, period)
+    WHERE entry_type IN ('accrual', 'carry_forward', 'expiry');
+
+CREATE INDEX IF NOT EXISTS idx_leave_ledger_balance ON leave_ledger_entries(tenant_id, employee_id, year, leave_type);
+CREATE INDEX IF NOT EXISTS idx_leave_ledger_request ON leave_ledger_entries(leave_request_id);
+
+-- Enable RLS
+ALTER TABLE leave_ledger_entries ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS leave_ledger_entries_tenant_isolation ON leave_ledger_entries;
+CREATE POLICY leave_ledger_entries_tenant_isolation ON leave_ledger_entries
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
+
+-- Reject direct updates and deletes so the ledger stays append-only.
+-- Changes cascaded from tenants, employees or leave_requests run at a deeper trigger depth and are allowed.
+CREATE OR REPLACE FUNCTION prevent_leave_ledger_mutation()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF pg_trigger_depth() > 1 THEN
+        IF TG_OP = 'DELETE' THEN
+            RETURN OLD;
+        END IF;
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'leave_ledger_entries is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS leave_ledger_entries_append_only ON leave_ledger_entries;
+CREATE TRIGGER leave_ledger_entries_append_only
+    BEFORE UPDATE OR DELETE ON leave_ledger_entries
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_leave_ledger_mutation();
@@ -0,0 +1,41 @@
+-- Migration: 044_holiday_calendar.sql
+-- Description: Per-tenant public holiday calendar and half-day leave requests
+
+-- Holidays Table
+-- A holiday without a location applies to every employee of the tenant; otherwise it only
+-- applies to employees whose profile work_location matches.
+CREATE TABLE IF NOT EXISTS holidays (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    holiday_date DATE NOT NULL,
+    location VARCHAR(100),
+    description TEXT,
+    source VARCHAR(20) NOT NULL DEFAULT 'manual' CHECK (source IN ('manual', 'ics')),
+    external_uid VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_holidays_unique_date
+    ON holidays(tenant_id, holiday_date, COALESCE(location, ''));
+CREATE INDEX IF NOT EXISTS idx_holidays_tenant_date ON holidays(tenant_id, holiday_date);
+
+-- Enable RLS
+ALTER TABLE holidays ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS holidays_tenant_isolation ON holidays;
+CREATE POLICY holidays_tenant_isolation ON holidays
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
+
+-- Update trigger
+DROP TRIGGER IF EXISTS update_holidays_updated_at ON holidays;
+CREATE TRIGGER update_holidays_updated_at
+    BEFORE UPDATE ON holidays
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Half-day flags: the leave starts at midday on start_date and/or ends at midday on end_date
+ALTER TABLE leave_requests ADD COLUMN IF NOT EXISTS start_half_day BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE leave_requests ADD COLUMN IF NOT EXISTS end_half_day BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,119 @@
+-- Migration: 045_leave_approval_workflows.sql
+-- Description: Configurable multi-level leave approval chains, per-request approval trail and approver delegation
+
+-- Leave Approval Workflows Table
+-- A workflow applies to requests matching its leave type and department (NULL matches any)
+-- that are at least min_days long. The most specific active workflow wins.
+CREATE TABLE IF NOT EXISTS leave_approval_workflows (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    leave_type VARCHAR(50),
+    department_id UUID REFERENCES departments(id) ON DELETE CASCADE,
+    min_days DECIMAL(5,2),
+    priority INTEGER NOT NULL DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_leave_approval_workflows_tenant ON leave_approval_workflows(tenant_id, is_active);
+
+-- Leave Approval Workflow Steps Table
+-- A step with min_days is only part of the chain for requests of at least that many days
+CREATE TABLE IF NOT EXISTS leave_approval_workflow_steps (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    workflow_id UUID NOT NULL REFERENCES leave_approval_workflows(id) ON DELETE CASCADE,
+    step_order INTEGER NOT NULL,
+    approver_type VARCHAR(20) NOT NULL CHECK (approver_type IN ('team_lead', 'manager', 'department_head', 'hr', 'user')),
+    approver_user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    min_days DECIMAL(5,2),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(workflow_id, step_order)
+);
+
+-- Leave Approval Steps Table
+-- The approval trail of a leave request. Steps become pending one at a time in step_order.
+CREATE TABLE IF NOT EXISTS leave_approval_steps (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    leave_request_id UUID NOT NULL REFERENCES leave_requests(id) ON DELETE CASCADE,
+    step_order INTEGER NOT NULL,
+    approver_type VARCHAR(20) NOT NULL,
+    approver_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    approver_role VARCHAR(50),
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting' CHECK (status IN ('waiting', 'pending', 'approved', 'rejected', 'skipped')),
+    comment TEXT,
+    acted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    delegated_from UUID REFERENCES users(id) ON DELETE SET NULL,
+    acted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(leave_request_id, step_order)
+);
+
+CREATE INDEX IF NOT EXISTS idx_leave_approval_steps_request ON leave_approval_steps(leave_request_id);
+CREATE INDEX IF NOT EXISTS idx_leave_approval_steps_pending ON leave_approval_steps(tenant_id, status, approver_user_id);
+
+-- Approval Delegations Table
+-- While a delegation is active the delegate may act on the delegator's approval steps
+CREATE TABLE IF NOT EXISTS approval_delegations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    delegator_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    delegate_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    reason TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CHECK (end_date >= start_date),
+    CHECK (delegator_id <> delegate_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_approval_delegations_delegator ON approval_delegations(tenant_id, delegator_id, start_date, end_date);
+CREATE INDEX IF NOT EXISTS idx_approval_delegations_delegate ON approval_delegations(tenant_id, delegate_id, start_date, end_date);
+
+-- Enable RLS
+ALTER TABLE leave_approval_workflows ENABLE ROW LEVEL SECURITY;
+ALTER TABLE leave_approval_workflow_steps ENABLE ROW LEVEL SECURITY;
+ALTER TABLE leave_approval_steps ENABLE ROW LEVEL SECURITY;
+ALTER TABLE approval_delegations ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS leave_approval_workflows_tenant_isolation ON leave_approval_workflows;
+CREATE POLICY leave_approval_workflows_tenant_isolation ON leave_approval_workflows
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
+
+DROP POLICY IF EXISTS leave_approval_workflow_steps_tenant_isolation ON leave_approval_workflow_steps;
+CREATE POLICY leave_approval_workflow_steps_tenant_isolation ON leave_approval_workflow_steps
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
+
+DROP POLICY IF EXISTS leave_approval_steps_tenant_isolation ON leave_approval_steps;
+CREATE POLICY leave_approval_steps_tenant_isolation ON leave_approval_steps
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
+
+DROP POLICY IF EXISTS approval_delegations_tenant_isolation ON approval_delegations;
+CREATE POLICY approval_delegations_tenant_isolation ON approval_delegations
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
+
+-- Update triggers
+DROP TRIGGER IF EXISTS update_leave_approval_workflows_updated_at ON leave_approval_workflows;
+CREATE TRIGGER update_leave_approval_workflows_updated_at
+    BEFORE UPDATE ON leave_approval_workflows
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_leave_approval_steps_updated_at ON leave_approval_steps;
+CREATE TRIGGER update_leave_approval_steps_updated_at
+    BEFORE UPDATE ON leave_approval_steps
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
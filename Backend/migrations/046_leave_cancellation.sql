@@ -0,0 +1,18 @@
+-- Migration: 046_leave_cancellation.sql
+-- Description: Leave cancellation and modification, and "on leave" attendance marking for approved leave
+
+-- A modification is a new leave request that replaces the approved request it modifies once approved
+ALTER TABLE leave_requests ADD COLUMN IF NOT EXISTS modifies_request_id UUID REFERENCES leave_requests(id) ON DELETE SET NULL;
+ALTER TABLE leave_requests ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE leave_requests ADD COLUMN IF NOT EXISTS cancellation_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_leave_requests_modifies ON leave_requests(modifies_request_id) WHERE modifies_request_id IS NOT NULL;
+
+-- Attendance records created for approved leave days point back to their leave request
+ALTER TABLE attendance_records ADD COLUMN IF NOT EXISTS leave_request_id UUID REFERENCES leave_requests(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_attendance_records_leave_request ON attendance_records(leave_request_id) WHERE leave_request_id IS NOT NULL;
+
+ALTER TABLE attendance_records DROP CONSTRAINT IF EXISTS attendance_records_status_check;
+ALTER TABLE attendance_records ADD CONSTRAINT attendance_records_status_check
+    CHECK (status IN ('present', 'absent', 'partial', 'late', 'holiday', 'on_leave'));
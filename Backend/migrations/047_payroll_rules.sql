@@ -0,0 +1,28 @@
+-- Migration: 047_payroll_rules.sql
+-- Description: Formula-based salary components with evaluation order, min/max caps and a per-tenant Basic Salary component
+
+-- Components are referenced from formulas by their code, e.g. HRA = "40% * BASIC"
+ALTER TABLE salary_components ADD COLUMN IF NOT EXISTS code VARCHAR(50);
+ALTER TABLE salary_components ADD COLUMN IF NOT EXISTS formula TEXT;
+ALTER TABLE salary_components ADD COLUMN IF NOT EXISTS calculation_order INTEGER NOT NULL DEFAULT 100;
+ALTER TABLE salary_components ADD COLUMN IF NOT EXISTS min_amount DECIMAL(15,2);
+ALTER TABLE salary_components ADD COLUMN IF NOT EXISTS max_amount DECIMAL(15,2);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_salary_components_tenant_code
+    ON salary_components(tenant_id, code) WHERE code IS NOT NULL AND deleted_at IS NULL;
+
+-- Structure-level formula overrides the component formula for a single employee
+ALTER TABLE salary_structure_components ADD COLUMN IF NOT EXISTS formula TEXT;
+
+-- Payslip lines keep the code and tax treatment they were calculated with
+ALTER TABLE payslip_components ADD COLUMN IF NOT EXISTS component_code VARCHAR(50);
+ALTER TABLE payslip_components ADD COLUMN IF NOT EXISTS is_taxable BOOLEAN NOT NULL DEFAULT true;
+
+-- Every tenant gets a Basic Salary component so payslip lines reference a real component
+INSERT INTO salary_components (tenant_id, name, code, type, is_fixed, is_percentage, is_taxable, calculation_order, description)
+SELECT t.id, 'Basic Salary', 'BASIC', 'earning', true, false, true, 0, 'Basic salary from the employee salary structure'
+FROM tenants t
+WHERE NOT EXISTS (
+    SELECT 1 FROM salary_components sc
+    WHERE sc.tenant_id = t.id AND sc.code = 'BASIC' AND sc.deleted_at IS NULL
+);
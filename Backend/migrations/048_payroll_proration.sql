@@ -0,0 +1,18 @@
+-- Migration: 048_payroll_proration.sql
+-- Description: Attendance and leave inputs recorded on payslips, and a per-tenant Overtime Pay component
+
+-- Inputs the payslip was prorated with, kept for audit
+ALTER TABLE payslips ADD COLUMN IF NOT EXISTS working_days DECIMAL(5,1);
+ALTER TABLE payslips ADD COLUMN IF NOT EXISTS paid_days DECIMAL(5,1);
+ALTER TABLE payslips ADD COLUMN IF NOT EXISTS unpaid_leave_days DECIMAL(5,1) NOT NULL DEFAULT 0;
+ALTER TABLE payslips ADD COLUMN IF NOT EXISTS absent_days DECIMAL(5,1) NOT NULL DEFAULT 0;
+ALTER TABLE payslips ADD COLUMN IF NOT EXISTS overtime_hours DECIMAL(7,2) NOT NULL DEFAULT 0;
+
+-- Overtime is paid through a regular formula component so tenants can change the rate
+INSERT INTO salary_components (tenant_id, name, code, type, is_fixed, is_percentage, is_taxable, calculation_order, formula, description)
+SELECT t.id, 'Overtime Pay', 'OVERTIME', 'earning', false, false, true, 900, 'HOURLY_RATE * 1.5 * OVERTIME_HOURS', 'Approved overtime hours paid at 1.5x the hourly basic rate'
+FROM tenants t
+WHERE NOT EXISTS (
+    SELECT 1 FROM salary_components sc
+    WHERE sc.tenant_id = t.id AND sc.code = 'OVERTIME' AND sc.deleted_at IS NULL
+);
@@ -0,0 +1,75 @@
+-- Migration: 049_payroll_runs.sql
+-- Description: Monthly payroll runs with a draft/locked/published lifecycle and per-employee generation errors
+
+-- Payroll Runs Table
+-- One run per tenant and month. Payslips of a draft run can be regenerated; a locked run freezes
+-- them and a published run makes them visible to employees. Reopening returns the run to draft.
+CREATE TABLE IF NOT EXISTS payroll_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    period_year INTEGER NOT NULL,
+    period_month INTEGER NOT NULL CHECK (period_month BETWEEN 1 AND 12),
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    payment_date DATE,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'locked', 'published')),
+    notes TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    locked_at TIMESTAMP WITH TIME ZONE,
+    locked_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    published_at TIMESTAMP WITH TIME ZONE,
+    published_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reopened_at TIMESTAMP WITH TIME ZONE,
+    reopened_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reopen_reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(tenant_id, period_year, period_month)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payroll_runs_tenant ON payroll_runs(tenant_id, period_start, period_end);
+
+-- Payroll Run Errors Table
+-- Employees whose payslip could not be generated in the latest generation of a run
+CREATE TABLE IF NOT EXISTS payroll_run_errors (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    payroll_run_id UUID NOT NULL REFERENCES payroll_runs(id) ON DELETE CASCADE,
+    employee_id UUID NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    error TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(payroll_run_id, employee_id)
+);
+
+-- Payslips belong to at most one run and are only visible to employees once published
+ALTER TABLE payslips ADD COLUMN IF NOT EXISTS payroll_run_id UUID REFERENCES payroll_runs(id) ON DELETE SET NULL;
+ALTER TABLE payslips ADD COLUMN IF NOT EXISTS published_at TIMESTAMP WITH TIME ZONE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payslips_run_employee ON payslips(payroll_run_id, employee_id) WHERE payroll_run_id IS NOT NULL;
+
+-- Payslips that employees could already see stay visible
+UPDATE payslips SET published_at = COALESCE(updated_at, created_at)
+WHERE published_at IS NULL AND status IN ('approved', 'paid');
+
+-- Enable RLS
+ALTER TABLE payroll_runs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE payroll_run_errors ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS payroll_runs_tenant_isolation ON payroll_runs;
+CREATE POLICY payroll_runs_tenant_isolation ON payroll_runs
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
+
+DROP POLICY IF EXISTS payroll_run_errors_tenant_isolation ON payroll_run_errors;
+CREATE POLICY payroll_run_errors_tenant_isolation ON payroll_run_errors
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
+
+-- Update triggers
+DROP TRIGGER IF EXISTS update_payroll_runs_updated_at ON payroll_runs;
+CREATE TRIGGER update_payroll_runs_updated_at
+    BEFORE UPDATE ON payroll_runs
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,5 @@
+-- Migration: 050_biometric_device_config.sql
+-- Description: Store the protocol configuration of biometric devices so they can be synced
+
+-- Protocol, vendor, endpoint, credentials and timeouts used by the device drivers
+ALTER TABLE biometric_devices ADD COLUMN IF NOT EXISTS config JSONB NOT NULL DEFAULT '{}';
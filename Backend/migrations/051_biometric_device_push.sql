@@ -0,0 +1,23 @@
+-- Migration: 051_biometric_device_push.sql
+-- Description: Per-device push credentials and idempotent biometric log ingestion
+
+-- Devices authenticate pushes with a secret, stored encrypted. After a rotation the previous
+-- secret keeps working until previous_push_secret_expires_at so terminals can be reconfigured.
+ALTER TABLE biometric_devices ADD COLUMN IF NOT EXISTS push_secret_encrypted TEXT;
+ALTER TABLE biometric_devices ADD COLUMN IF NOT EXISTS push_secret_rotated_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE biometric_devices ADD COLUMN IF NOT EXISTS previous_push_secret_encrypted TEXT;
+ALTER TABLE biometric_devices ADD COLUMN IF NOT EXISTS previous_push_secret_expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_biometric_devices_serial_number ON biometric_devices(serial_number);
+
+-- Keep the first copy of punches that were stored more than once
+DELETE FROM biometric_attendance_logs bl
+USING biometric_attendance_logs dup
+WHERE bl.device_id = dup.device_id
+  AND bl.employee_code = dup.employee_code
+  AND bl.timestamp = dup.timestamp
+  AND (bl.created_at, bl.id) > (dup.created_at, dup.id);
+
+-- A punch is stored once per device, so re-sent batches are ignored
+CREATE UNIQUE INDEX IF NOT EXISTS idx_biometric_logs_device_punch
+    ON biometric_attendance_logs(device_id, employee_code, timestamp);
@@ -0,0 +1,24 @@
+-- Migration: 053_attendance_day_close.sql
+-- Description: End-of-day attendance closing: default checkout time and HR review flags
+
+-- Open records are closed at this time when the employee forgets to check out. When it is not
+-- set the end of the employee's shift or policy working day is used.
+ALTER TABLE attendance_policies ADD COLUMN IF NOT EXISTS default_checkout_time TIME;
+
+-- Records created or closed automatically wait for HR to confirm them
+ALTER TABLE attendance_records ADD COLUMN IF NOT EXISTS needs_review BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE attendance_records ADD COLUMN IF NOT EXISTS review_reason VARCHAR(30)
+    CHECK (review_reason IN ('auto_absent', 'auto_on_leave', 'auto_checkout'));
+ALTER TABLE attendance_records ADD COLUMN IF NOT EXISTS reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE attendance_records ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_attendance_records_needs_review ON attendance_records(tenant_id, date) WHERE needs_review = true;
+
+-- Open records are looked up when a day is closed
+CREATE INDEX IF NOT EXISTS idx_attendance_records_open ON attendance_records(tenant_id, date)
+    WHERE check_in_time IS NOT NULL AND check_out_time IS NULL;
+
+-- Records created by the end-of-day close have their own source
+ALTER TABLE attendance_records DROP CONSTRAINT IF EXISTS attendance_records_source_check;
+ALTER TABLE attendance_records ADD CONSTRAINT attendance_records_source_check
+    CHECK (source IN ('manual', 'biometric', 'mobile', 'web', 'api', 'system'));
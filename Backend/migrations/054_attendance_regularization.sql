@@ -0,0 +1,56 @@
+-- Migration: 054_attendance_regularization.sql
+-- Description: Employee requests to correct attendance, approved by their manager
+
+-- Attendance Regularization Requests Table
+-- The approver is the employee's manager (or department head), else any HR user. On approval
+-- the attendance record of the day is patched and its previous values are kept here.
+CREATE TABLE IF NOT EXISTS attendance_regularization_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    employee_id UUID NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    attendance_record_id UUID REFERENCES attendance_records(id) ON DELETE SET NULL,
+    date DATE NOT NULL,
+    type VARCHAR(30) NOT NULL CHECK (type IN ('missed_check_in', 'missed_check_out', 'wrong_time', 'work_from_home')),
+    requested_check_in TIMESTAMP WITH TIME ZONE,
+    requested_check_out TIMESTAMP WITH TIME ZONE,
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'cancelled')),
+    approver_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    approver_role VARCHAR(50),
+    decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMP WITH TIME ZONE,
+    decision_comment TEXT,
+    original_values JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_attendance_regularizations_employee ON attendance_regularization_requests(tenant_id, employee_id, date);
+CREATE INDEX IF NOT EXISTS idx_attendance_regularizations_pending ON attendance_regularization_requests(tenant_id, approver_user_id) WHERE status = 'pending';
+
+-- An employee has at most one open request per day
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attendance_regularizations_open_day
+    ON attendance_regularization_requests(tenant_id, employee_id, date) WHERE status = 'pending';
+
+-- Regularized records point back to the request that changed them
+ALTER TABLE attendance_records ADD COLUMN IF NOT EXISTS regularization_request_id UUID
+    REFERENCES attendance_regularization_requests(id) ON DELETE SET NULL;
+
+ALTER TABLE attendance_records DROP CONSTRAINT IF EXISTS attendance_records_source_check;
+ALTER TABLE attendance_records ADD CONSTRAINT attendance_records_source_check
+    CHECK (source IN ('manual', 'biometric', 'mobile', 'web', 'api', 'system', 'regularized'));
+
+-- Enable RLS
+ALTER TABLE attendance_regularization_requests ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS attendance_regularization_requests_tenant_isolation ON attendance_regularization_requests;
+CREATE POLICY attendance_regularization_requests_tenant_isolation ON attendance_regularization_requests
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
+
+-- Update triggers
+DROP TRIGGER IF EXISTS update_attendance_regularization_requests_updated_at ON attendance_regularization_requests;
+CREATE TRIGGER update_attendance_regularization_requests_updated_at
+    BEFORE UPDATE ON attendance_regularization_requests
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
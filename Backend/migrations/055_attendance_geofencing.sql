@@ -0,0 +1,58 @@
+-- Migration: 055_attendance_geofencing.sql
+-- Description: Office geofences and allowed IP ranges for web and mobile check-in
+
+-- Attendance Zones Table
+-- A zone is an office area (a circle around a point) and/or the IP ranges of its network. A
+-- zone with a work location applies to employees whose profile work_location matches; a zone
+-- without one applies to everybody.
+CREATE TABLE IF NOT EXISTS attendance_zones (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    work_location VARCHAR(100),
+    latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    radius_meters INTEGER CHECK (radius_meters > 0),
+    allowed_ip_ranges TEXT[] NOT NULL DEFAULT '{}',
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    CHECK ((latitude IS NULL) = (longitude IS NULL) AND (latitude IS NULL) = (radius_meters IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_attendance_zones_tenant ON attendance_zones(tenant_id) WHERE deleted_at IS NULL;
+
+-- What happens to a check-in outside every zone: 'off' accepts it, 'flag' accepts it for HR
+-- review and 'reject' refuses it
+ALTER TABLE attendance_policies ADD COLUMN IF NOT EXISTS location_enforcement VARCHAR(10) NOT NULL DEFAULT 'off'
+    CHECK (location_enforcement IN ('off', 'flag', 'reject'));
+
+-- Employees allowed to check in from anywhere, optionally until a date
+ALTER TABLE employees ADD COLUMN IF NOT EXISTS remote_check_in_allowed BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE employees ADD COLUMN IF NOT EXISTS remote_check_in_until DATE;
+
+-- Where the check-in came from
+ALTER TABLE attendance_records ADD COLUMN IF NOT EXISTS check_in_latitude DOUBLE PRECISION;
+ALTER TABLE attendance_records ADD COLUMN IF NOT EXISTS check_in_longitude DOUBLE PRECISION;
+ALTER TABLE attendance_records ADD COLUMN IF NOT EXISTS check_in_ip VARCHAR(45);
+ALTER TABLE attendance_records ADD COLUMN IF NOT EXISTS check_in_zone_id UUID REFERENCES attendance_zones(id) ON DELETE SET NULL;
+
+ALTER TABLE attendance_records DROP CONSTRAINT IF EXISTS attendance_records_review_reason_check;
+ALTER TABLE attendance_records ADD CONSTRAINT attendance_records_review_reason_check
+    CHECK (review_reason IN ('auto_absent', 'auto_on_leave', 'auto_checkout', 'outside_zone'));
+
+-- Enable RLS
+ALTER TABLE attendance_zones ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS attendance_zones_tenant_isolation ON attendance_zones;
+CREATE POLICY attendance_zones_tenant_isolation ON attendance_zones
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
+
+-- Update triggers
+DROP TRIGGER IF EXISTS update_attendance_zones_updated_at ON attendance_zones;
+CREATE TRIGGER update_attendance_zones_updated_at
+    BEFORE UPDATE ON attendance_zones
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,44 @@
+-- Migration: 056_attendance_breaks.sql
+-- Description: Break intervals of attendance records
+
+-- Attendance Breaks Table
+-- Breaks taken between check-in and check-out, from the web API or biometric break events.
+-- Their durations are summed into attendance_records.break_duration_minutes.
+CREATE TABLE IF NOT EXISTS attendance_breaks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    attendance_record_id UUID NOT NULL REFERENCES attendance_records(id) ON DELETE CASCADE,
+    employee_id UUID NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE,
+    duration_minutes INTEGER,
+    source VARCHAR(20) NOT NULL DEFAULT 'manual',
+    device_id UUID REFERENCES biometric_devices(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (end_time IS NULL OR end_time >= start_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attendance_breaks_record ON attendance_breaks(attendance_record_id, start_time);
+
+-- A record has at most one break in progress
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attendance_breaks_open
+    ON attendance_breaks(attendance_record_id) WHERE end_time IS NULL;
+
+-- Set when the breaks of the day add up to more than the policy allows
+ALTER TABLE attendance_records ADD COLUMN IF NOT EXISTS is_excessive_break BOOLEAN NOT NULL DEFAULT false;
+
+-- Enable RLS
+ALTER TABLE attendance_breaks ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS attendance_breaks_tenant_isolation ON attendance_breaks;
+CREATE POLICY attendance_breaks_tenant_isolation ON attendance_breaks
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
+
+-- Update triggers
+DROP TRIGGER IF EXISTS update_attendance_breaks_updated_at ON attendance_breaks;
+CREATE TRIGGER update_attendance_breaks_updated_at
+    BEFORE UPDATE ON attendance_breaks
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,52 @@
+-- Migration: 057_attendance_sessions.sql
+-- Description: Several check-in/check-out sessions per attendance day
+
+-- Attendance Sessions Table
+-- Each check-in opens a session and each check-out closes it. The attendance record keeps the
+-- first check-in and the last check-out of the day, and its total_hours is the sum of the
+-- closed sessions.
+CREATE TABLE IF NOT EXISTS attendance_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    attendance_record_id UUID NOT NULL REFERENCES attendance_records(id) ON DELETE CASCADE,
+    employee_id UUID NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    check_in_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    check_out_time TIMESTAMP WITH TIME ZONE,
+    source VARCHAR(20) NOT NULL DEFAULT 'manual',
+    device_id UUID REFERENCES biometric_devices(id) ON DELETE SET NULL,
+    biometric_log_id UUID REFERENCES biometric_attendance_logs(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (check_out_time IS NULL OR check_out_time >= check_in_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attendance_sessions_record ON attendance_sessions(attendance_record_id, check_in_time);
+
+-- A record has at most one session in progress
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attendance_sessions_open
+    ON attendance_sessions(attendance_record_id) WHERE check_out_time IS NULL;
+
+-- Existing records become a single session
+INSERT INTO attendance_sessions (tenant_id, attendance_record_id, employee_id, check_in_time, check_out_time,
+                                 source, device_id, biometric_log_id)
+SELECT ar.tenant_id, ar.id, ar.employee_id, ar.check_in_time,
+       CASE WHEN ar.check_out_time >= ar.check_in_time THEN ar.check_out_time END,
+       ar.source, ar.device_id, ar.biometric_log_id
+FROM attendance_records ar
+WHERE ar.check_in_time IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM attendance_sessions s WHERE s.attendance_record_id = ar.id);
+
+-- Enable RLS
+ALTER TABLE attendance_sessions ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS attendance_sessions_tenant_isolation ON attendance_sessions;
+CREATE POLICY attendance_sessions_tenant_isolation ON attendance_sessions
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
+
+-- Update triggers
+DROP TRIGGER IF EXISTS update_attendance_sessions_updated_at ON attendance_sessions;
+CREATE TRIGGER update_attendance_sessions_updated_at
+    BEFORE UPDATE ON attendance_sessions
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
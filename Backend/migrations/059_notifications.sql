@@ -0,0 +1,46 @@
+-- Migration: 059_notifications.sql
+-- Description: In-app notification inbox
+
+-- Notifications Table
+-- One row per recipient. Rows are written in the transaction of the leave, attendance,
+-- payslip or billing change they report, unless the user turned the category off in
+-- user_preferences.
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category VARCHAR(20) NOT NULL CHECK (category IN ('leave', 'attendance', 'payslip', 'billing')),
+    type VARCHAR(50) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT,
+    link VARCHAR(500),
+    resource_type VARCHAR(50),
+    resource_id UUID,
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at DESC, id);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+-- Billing notifications are new; users who had preferences before get them on
+INSERT INTO user_preferences (user_id, tenant_id, preference_key, preference_value, preference_type)
+SELECT DISTINCT ON (user_id) user_id, tenant_id, 'billing_notifications', 'true', 'boolean'
+FROM user_preferences
+ON CONFLICT (user_id, preference_key) DO NOTHING;
+
+-- Enable RLS
+ALTER TABLE notifications ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS notifications_tenant_isolation ON notifications;
+CREATE POLICY notifications_tenant_isolation ON notifications
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
+
+-- Update triggers
+DROP TRIGGER IF EXISTS update_notifications_updated_at ON notifications;
+CREATE TRIGGER update_notifications_updated_at
+    BEFORE UPDATE ON notifications
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
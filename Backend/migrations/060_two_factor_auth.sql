@@ -0,0 +1,65 @@
+-- Migration: 060_two_factor_auth.sql
+-- Description: TOTP two-factor authentication with recovery codes, and roles tenants require it for
+
+-- User Two Factor Table
+-- One authenticator per user. The secret is encrypted with ENCRYPTION_KEY. confirmed_at is
+-- NULL while an enrolment waits for its first code. last_used_step keeps a code from being
+-- accepted twice; failed_attempts and locked_until slow down guessing.
+CREATE TABLE IF NOT EXISTS user_two_factor (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+    secret_encrypted TEXT NOT NULL,
+    confirmed_at TIMESTAMP WITH TIME ZONE,
+    last_used_step BIGINT,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_two_factor_tenant ON user_two_factor(tenant_id);
+
+-- Two Factor Recovery Codes Table
+-- One-time codes for users who lost their authenticator, stored as keyed hashes
+CREATE TABLE IF NOT EXISTS two_factor_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_two_factor_recovery_codes_hash ON two_factor_recovery_codes(user_id, code_hash);
+
+-- Roles whose users must sign in with two-factor authentication
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS two_factor_required_roles TEXT[] NOT NULL DEFAULT '{}';
+
+-- two_factor_enabled used to be a plain toggle; it now follows enrolment
+UPDATE security_settings SET two_factor_enabled = FALSE
+WHERE two_factor_enabled
+  AND NOT EXISTS (
+      SELECT 1 FROM user_two_factor tf
+      WHERE tf.user_id = security_settings.user_id AND tf.confirmed_at IS NOT NULL
+  );
+
+-- Enable RLS
+ALTER TABLE user_two_factor ENABLE ROW LEVEL SECURITY;
+ALTER TABLE two_factor_recovery_codes ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS user_two_factor_tenant_isolation ON user_two_factor;
+CREATE POLICY user_two_factor_tenant_isolation ON user_two_factor
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
+
+DROP POLICY IF EXISTS two_factor_recovery_codes_tenant_isolation ON two_factor_recovery_codes;
+CREATE POLICY two_factor_recovery_codes_tenant_isolation ON two_factor_recovery_codes
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
+
+-- Update triggers
+DROP TRIGGER IF EXISTS update_user_two_factor_updated_at ON user_two_factor;
+CREATE TRIGGER update_user_two_factor_updated_at
+    BEFORE UPDATE ON user_two_factor
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
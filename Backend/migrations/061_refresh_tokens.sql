@@ -0,0 +1,33 @@
+-- Migration: 061_refresh_tokens.sql
+-- Description: Server-side refresh tokens grouped into families, with rotation and revocation
+
+-- Refresh Tokens Table
+-- Tokens are stored as SHA-256 hashes. Each refresh spends a token (used_at) and issues the
+-- next one of its family (replaced_by); presenting a spent token revokes the whole family.
+-- Every token of a family shares the expiry of the first, so sessions have an absolute timeout.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    family_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    replaced_by UUID,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    revoke_reason VARCHAR(50),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_active ON refresh_tokens(user_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_tenant ON refresh_tokens(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires ON refresh_tokens(expires_at);
+
+-- Enable RLS
+ALTER TABLE refresh_tokens ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS refresh_tokens_tenant_isolation ON refresh_tokens;
+CREATE POLICY refresh_tokens_tenant_isolation ON refresh_tokens
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
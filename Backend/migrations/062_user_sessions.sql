@@ -0,0 +1,45 @@
+-- Migration: 062_user_sessions.sql
+-- Description: Signed-in devices of users, and security notifications for sign-ins from new devices
+
+-- User Sessions Table
+-- One row per sign-in. The ID is the family_id of the session's refresh tokens, and a session
+-- is active while its family has a live token. device_id comes from a long-lived cookie and
+-- tells new devices apart; ip_address and location are only kept when device tracking is on.
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+    device_id VARCHAR(64),
+    device_name VARCHAR(100) NOT NULL,
+    user_agent TEXT,
+    ip_address INET,
+    location VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user ON user_sessions(user_id, last_seen_at DESC);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_tenant ON user_sessions(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_expires ON user_sessions(expires_at);
+
+-- Sessions for refresh token families issued before sessions were recorded
+INSERT INTO user_sessions (id, user_id, tenant_id, device_name, created_at, last_seen_at, expires_at)
+SELECT family_id, MIN(user_id::TEXT)::UUID, MIN(tenant_id::TEXT)::UUID, 'Unknown device',
+       MIN(created_at), MAX(created_at), MAX(expires_at)
+FROM refresh_tokens
+GROUP BY family_id
+ON CONFLICT (id) DO NOTHING;
+
+-- Security notifications, such as sign-ins from new devices
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_category_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_category_check
+    CHECK (category IN ('leave', 'attendance', 'payslip', 'billing', 'security'));
+
+-- Enable RLS
+ALTER TABLE user_sessions ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS user_sessions_tenant_isolation ON user_sessions;
+CREATE POLICY user_sessions_tenant_isolation ON user_sessions
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
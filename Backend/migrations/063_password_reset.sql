@@ -0,0 +1,29 @@
+-- Migration: 063_password_reset.sql
+-- Description: Password reset links, and forcing a password change after sign-in with a temporary password
+
+-- Accounts created with a temporary password must change it before using anything else
+ALTER TABLE users ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Password Reset Tokens Table
+-- Tokens of emailed reset links, stored as SHA-256 hashes. A token works once, until it
+-- expires or a newer link is requested (used_at is then set).
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user ON password_reset_tokens(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_expires ON password_reset_tokens(expires_at);
+
+-- Enable RLS
+ALTER TABLE password_reset_tokens ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS password_reset_tokens_tenant_isolation ON password_reset_tokens;
+CREATE POLICY password_reset_tokens_tenant_isolation ON password_reset_tokens
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);
@@ -0,0 +1,26 @@
+-- Migration: 064_security_policy.sql
+-- Description: Organization defaults for IP restrictions, session timeout and password expiry,
+-- which users' own security settings may only tighten
+
+-- Tenant Security Defaults
+-- Apply to every user of the organization; the stricter of these and the user's own settings
+-- is enforced. NULL or 0 means no limit.
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS default_ip_restrictions TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS default_session_timeout INTEGER; -- in seconds
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS default_password_expiry_days INTEGER;
+
+ALTER TABLE tenants DROP CONSTRAINT IF EXISTS tenants_default_session_timeout_check;
+ALTER TABLE tenants ADD CONSTRAINT tenants_default_session_timeout_check
+    CHECK (default_session_timeout IS NULL OR default_session_timeout >= 0);
+ALTER TABLE tenants DROP CONSTRAINT IF EXISTS tenants_default_password_expiry_days_check;
+ALTER TABLE tenants ADD CONSTRAINT tenants_default_password_expiry_days_check
+    CHECK (default_password_expiry_days IS NULL OR default_password_expiry_days >= 0);
+
+-- Unset user settings leave the organization defaults alone
+ALTER TABLE security_settings ALTER COLUMN session_timeout DROP DEFAULT;
+ALTER TABLE security_settings ALTER COLUMN password_expiry_days DROP DEFAULT;
+
+-- The old column defaults were never enforced; clear rows still holding them so enforcement
+-- does not sign every user out after an hour or expire every password older than 90 days.
+UPDATE security_settings SET session_timeout = NULL WHERE session_timeout = 3600;
+UPDATE security_settings SET password_expiry_days = NULL WHERE password_expiry_days = 90;
@@ -0,0 +1,60 @@
+-- Migration: 065_leave_opening_balances.sql
+-- Description: Seed the leave ledger with the balances held in leave_balances before the ledger existed
+
+-- Opening entries carry a balance over from leave_balances. A year with an opening entry has
+-- already been credited, so accrual skips it.
+ALTER TABLE leave_ledger_entries DROP CONSTRAINT IF EXISTS leave_ledger_entries_entry_type_check;
+ALTER TABLE leave_ledger_entries ADD CONSTRAINT leave_ledger_entries_entry_type_check
+    CHECK (entry_type IN ('opening', 'accrual', 'debit', 'refund', 'carry_forward', 'expiry', 'adjustment'));
+
+-- One opening entry per employee, leave type and year
+CREATE UNIQUE INDEX IF NOT EXISTS idx_leave_ledger_opening_unique
+    ON leave_ledger_entries(tenant_id, employee_id, leave_type, year)
+    WHERE entry_type = 'opening';
+
+-- leave_balances was created by 001 (keyed by leave_type_id) or by 004 (keyed by the leave type
+-- name or code), depending on the database. Balances of the current year are seeded; the ledger
+-- key is the lowercased short code of the leave type, or its name. Rows that no longer match a
+-- leave type are skipped, as the ledger only tracks configured leave types.
+-- The opening entry is the legacy available balance less anything already in the ledger for the
+-- year, so accruals posted since 043 are not counted twice.
+DO $$
+DECLARE
+    source_query TEXT;
+BEGIN
+    IF NOT EXISTS (SELECT FROM information_schema.tables WHERE table_name = 'leave_balances') THEN
+        RETURN;
+    END IF;
+
+    IF EXISTS (SELECT FROM information_schema.columns WHERE table_name = 'leave_balances' AND column_name = 'leave_type_id') THEN
+        source_query := '
+            SELECT lb.tenant_id, lb.employee_id, lb.year,
+                   LOWER(COALESCE(NULLIF(lt.short_code, ''''), lt.name)) AS leave_type,
+                   COALESCE(lb.available_balance, 0) AS available_days
+            FROM leave_balances lb
+            JOIN leave_types lt ON lt.id = lb.leave_type_id AND lt.tenant_id = lb.tenant_id
+            WHERE lt.deleted_at IS NULL';
+    ELSE
+        source_query := '
+            SELECT DISTINCT ON (lb.id) lb.tenant_id, lb.employee_id, lb.year,
+                   LOWER(COALESCE(NULLIF(lt.short_code, ''''), lt.name)) AS leave_type,
+                   lb.available_days
+            FROM leave_balances lb
+            JOIN leave_types lt ON lt.tenant_id = lb.tenant_id AND lt.deleted_at IS NULL
+             AND (LOWER(lt.short_code) = LOWER(TRIM(lb.leave_type)) OR LOWER(lt.name) = LOWER(TRIM(lb.leave_type)))
+            ORDER BY lb.id, (LOWER(lt.short_code) = LOWER(TRIM(lb.leave_type))) DESC NULLS LAST';
+    END IF;
+
+    EXECUTE '
+        INSERT INTO leave_ledger_entries (tenant_id, employee_id, leave_type, year, entry_type, days, period, notes)
+        SELECT src.tenant_id, src.employee_id, src.leave_type, src.year, ''opening'',
+               src.available_days - COALESCE((
+                   SELECT SUM(l.days) FROM leave_ledger_entries l
+                   WHERE l.tenant_id = src.tenant_id AND l.employee_id = src.employee_id
+                     AND l.leave_type = src.leave_type AND l.year = src.year
+               ), 0),
+               src.year::TEXT, ''Opening balance from leave_balances''
+        FROM (' || source_query || ') src
+        WHERE src.year = EXTRACT(YEAR FROM CURRENT_DATE)::INTEGER
+        ON CONFLICT DO NOTHING';
+END $$;
@@ -0,0 +1,10 @@
+-- Migration: 066_attendance_policy_assignment.sql
+-- Description: Assign attendance policies to employees and departments
+
+-- An employee's own policy applies before their department's, and the department's before the
+-- tenant default. Deleted or inactive policies are skipped when resolving.
+ALTER TABLE employees ADD COLUMN IF NOT EXISTS attendance_policy_id UUID REFERENCES attendance_policies(id) ON DELETE SET NULL;
+ALTER TABLE departments ADD COLUMN IF NOT EXISTS attendance_policy_id UUID REFERENCES attendance_policies(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_employees_attendance_policy ON employees(attendance_policy_id) WHERE attendance_policy_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_departments_attendance_policy ON departments(attendance_policy_id) WHERE attendance_policy_id IS NOT NULL;
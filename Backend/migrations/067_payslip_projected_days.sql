@@ -0,0 +1,6 @@
+-- Migration: 067_payslip_projected_days.sql
+-- Description: Paid days a payslip counted before they happened, kept for audit
+
+-- Payroll calculated before the end of the pay period pays the working days still to come; they
+-- are included in paid_days and recorded here so HR can tell them from days actually worked.
+ALTER TABLE payslips ADD COLUMN IF NOT EXISTS projected_days DECIMAL(5,1) NOT NULL DEFAULT 0;
@@ -0,0 +1,28 @@
+-- Migration: 068_leave_year_closings.sql
+-- Description: Record which leave years have been rolled over, so later entries to them are carried forward
+
+-- Leave Year Closings Table
+-- A refund or adjustment posted to a closed year updates its expiry and carry-forward instead of
+-- staying behind in the closed year.
+CREATE TABLE IF NOT EXISTS leave_year_closings (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    year INTEGER NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, year)
+);
+
+-- Years rolled over before this migration are the ones with rollover expiry entries
+INSERT INTO leave_year_closings (tenant_id, year, closed_at)
+SELECT tenant_id, year, MIN(created_at)
+FROM leave_ledger_entries
+WHERE entry_type = 'expiry' AND period = year::TEXT
+GROUP BY tenant_id, year
+ON CONFLICT (tenant_id, year) DO NOTHING;
+
+-- Enable RLS
+ALTER TABLE leave_year_closings ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS leave_year_closings_tenant_isolation ON leave_year_closings;
+CREATE POLICY leave_year_closings_tenant_isolation ON leave_year_closings
+    FOR ALL
+    USING (tenant_id = current_setting('app.current_tenant_id', TRUE)::UUID);